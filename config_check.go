@@ -0,0 +1,102 @@
+package dashica
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+	alerting2 "github.com/sandstorm/dashica/lib/alerting"
+	"github.com/sandstorm/dashica/lib/clickhouse"
+	"github.com/sandstorm/dashica/lib/config"
+)
+
+// configCheckPingTimeout limits the connection check of a single ClickHouse server.
+const configCheckPingTimeout = 10 * time.Second
+
+// CheckConfig implements "dashica config check": it loads the configuration like New, prints it (with secrets
+// masked), and checks it more strictly than on startup:
+//   - dashica_config.yaml must not contain unknown (e.g. misspelled) keys
+//   - the ClickHouse server alert_storage must be configured
+//   - the alert definitions (src/*/alerts.yaml in projectFS) must parse, and all cron expressions must be valid
+//   - every configured ClickHouse server must be reachable
+//
+// All problems are printed; if there are any, an error is returned (so the command can exit non-zero). Call it from
+// your main function before New:
+//
+//	if len(os.Args) == 3 && os.Args[1] == "config" && os.Args[2] == "check" {
+//		if err := dashica.CheckConfig(projectFS); err != nil {
+//			log.Fatal(err)
+//		}
+//		return
+//	}
+func CheckConfig(projectFS fs.ReadFileFS) error {
+	cfg, err := config.LoadConfig(os.Getenv("APP_ENV"), false)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	config.PrintConfig(cfg)
+
+	var problems []error
+	for _, key := range cfg.UnknownKeys {
+		problems = append(problems, fmt.Errorf("unknown configuration key %s (misspelled?)", key))
+	}
+	if _, found := cfg.ClickHouse["alert_storage"]; !found {
+		problems = append(problems, errors.New("ClickHouse 'alert_storage' (needed for alert result storage) is not configured"))
+	}
+
+	alertManager := alerting2.NewAlertManager(cfg, zerolog.Nop(), projectFS, nil, nil, nil)
+	if err := alertManager.DiscoverAlertDefinitions(); err != nil {
+		problems = append(problems, fmt.Errorf("alert definitions: %w", err))
+	} else if err := alertManager.CheckSchedules(); err != nil {
+		problems = append(problems, err)
+	}
+
+	// the connection checks are not written to the query log
+	pingConfig := *cfg
+	pingConfig.QueryLog.Enabled = false
+	clickhouseClientManager := clickhouse.NewManager(&pingConfig, zerolog.Nop())
+	serverIds := make([]string, 0, len(cfg.ClickHouse))
+	for serverId := range cfg.ClickHouse {
+		serverIds = append(serverIds, serverId)
+	}
+	slices.Sort(serverIds)
+	for _, serverId := range serverIds {
+		if err := pingClickHouse(clickhouseClientManager, serverId); err != nil {
+			problems = append(problems, fmt.Errorf("ClickHouse '%s': %w", serverId, err))
+		} else {
+			fmt.Printf("ClickHouse '%s': connected\n", serverId)
+		}
+	}
+
+	if len(problems) > 0 {
+		fmt.Println("Problems:")
+		for _, problem := range problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+		return fmt.Errorf("configuration check failed with %d problem(s)", len(problems))
+	}
+	fmt.Println("Configuration OK")
+	return nil
+}
+
+func pingClickHouse(clickhouseClientManager *clickhouse.Manager, serverId string) error {
+	client, err := clickhouseClientManager.GetClient(serverId)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), configCheckPingTimeout)
+	defer cancel()
+	resp, err := client.Query(ctx, "SELECT 1", clickhouse.QueryOptions{})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
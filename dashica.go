@@ -1,6 +1,8 @@
 package dashica
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
@@ -11,30 +13,54 @@ import (
 
 	"github.com/rs/zerolog"
 	alerting2 "github.com/sandstorm/dashica/lib/alerting"
+	"github.com/sandstorm/dashica/lib/auth"
 	"github.com/sandstorm/dashica/lib/clickhouse"
 	"github.com/sandstorm/dashica/lib/config"
 	"github.com/sandstorm/dashica/lib/dashboard"
 	"github.com/sandstorm/dashica/lib/dashboard/rendering"
+	"github.com/sandstorm/dashica/lib/httpserver"
 	"github.com/sandstorm/dashica/lib/logging"
+	"github.com/sandstorm/dashica/lib/metrics"
 	"github.com/sandstorm/dashica/lib/util/handler_collector"
 	"github.com/sandstorm/dashica/public"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+// Dashica serves the registered dashboards; either via ListenAndServe, or as http.Handler with your own http.Server.
+//
+// The alert scheduler (started by New) registers its own handler for SIGINT, which disables Go's default "exit on
+// Ctrl-C". ListenAndServe handles SIGINT / SIGTERM itself; when using your own server, handle them as well, e.g.:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//	go server.ListenAndServe()
+//	<-ctx.Done()
+//	_ = server.Shutdown(shutdownCtx)
+//	_ = d.Shutdown(shutdownCtx)
 type Dashica interface {
 	http.Handler
 	Config() config.Config
 	Log() zerolog.Logger
+	// ListenAndServe serves Dashica until SIGINT / SIGTERM, then shuts down gracefully. It is the only place where
+	// these signals are handled (see Dashica).
 	ListenAndServe() error
-	RegisterDashboardGroup(title string) Dashica
+	// Shutdown gracefully stops the HTTP server and the alert scheduler; see DashicaImpl.Shutdown.
+	Shutdown(ctx context.Context) error
+	// RegisterDashboardGroup starts a new group of dashboards; if access rules are given, the group's menu entries
+	// and handlers are only available to matching users (see auth.Allowed).
+	RegisterDashboardGroup(title string, access ...auth.AccessRule) Dashica
 	RegisterDashboard(url string, dashboard dashboard.Dashboard) Dashica
+	// RestrictAlertingApi restricts the API for silencing and acknowledging alerts (see
+	// httpserver.NewAlertingApiHandler) to matching users. Without access rules, all authenticated users may use it.
+	RestrictAlertingApi(access ...auth.AccessRule) Dashica
 }
 
-func New(projectFS fs.ReadFileFS) Dashica {
+// New loads the configuration (see config.LoadConfig) and starts the alert scheduler. Errors in the configuration are
+// returned, so that embedders can handle them.
+func New(projectFS fs.ReadFileFS) (Dashica, error) {
 	cfg, err := config.LoadConfig(os.Getenv("APP_ENV"), false)
 	if err != nil {
-		println("Failed to load config: ", err.Error())
-		os.Exit(1)
+		return nil, fmt.Errorf("loading config: %w", err)
 	}
 
 	// --- Logger initialization ---
@@ -68,6 +94,17 @@ func New(projectFS fs.ReadFileFS) Dashica {
 	logger.Info().
 		Str(logging.EventDataset, logging.EventDataset_Dashica_Startup).
 		Msg("Logging initialized. Starting to boot Dashica...")
+	for _, key := range cfg.UnknownKeys {
+		logger.Warn().
+			Str(logging.EventDataset, logging.EventDataset_Dashica_Startup).
+			Str("key", key).
+			Msg("unknown configuration key in dashica_config.yaml is ignored (misspelled?)")
+	}
+
+	authenticator, err := auth.New(cfg.Auth, logger)
+	if err != nil {
+		return nil, fmt.Errorf("initializing authentication: %w", err)
+	}
 
 	mux := http.NewServeMux()
 	mux.Handle("/public/", http.StripPrefix("/public/", http.FileServer(http.FS(public.FS))))
@@ -77,13 +114,13 @@ func New(projectFS fs.ReadFileFS) Dashica {
 
 	alertTargetClickhouseClient, err := clickhouseClientManager.GetClient("alert_storage")
 	if err != nil {
-		logger.Fatal().
-			Str(logging.EventDataset, logging.EventDataset_Dashica_Startup).
-			Msg("did NOT find clickhouse 'alert_target' (needed for alert result storage) in dashica_config.yaml.")
+		return nil, fmt.Errorf("did NOT find clickhouse 'alert_storage' (needed for alert result storage) in dashica_config.yaml: %w", err)
 	}
 	alertResultStore := alerting2.NewAlertResultStore(logger, alertTargetClickhouseClient)
 	alertEvaluator := alerting2.NewAlertEvaluator(logger, clickhouseClientManager, timeProvider)
-	alertManager := alerting2.NewAlertManager(cfg, logger, projectFS, alertEvaluator, alertResultStore)
+	alertSilenceStore := alerting2.NewAlertSilenceStore(logger, alertTargetClickhouseClient)
+	alertManager := alerting2.NewAlertManager(cfg, logger, projectFS, alertEvaluator, alertResultStore, alertSilenceStore)
+	mux.Handle("/metrics", metrics.Default)
 
 	err = alertManager.DiscoverAlertDefinitions()
 	if err != nil {
@@ -93,24 +130,37 @@ func New(projectFS fs.ReadFileFS) Dashica {
 			Msg("Failed to discover alert definitions")
 	}
 
-	// Top-level signal handler. The gronx tasker (started by RunAlertScheduler) installs its own
-	// signal.Notify for os.Interrupt and only stops itself, blocking on wg.Wait() for in-flight
-	// tasks; further Ctrl-C is swallowed and the HTTP server / main never exit. This handler
-	// guarantees Ctrl-C (and SIGTERM) always terminate the process. signal.Notify multiplexes to
-	// all registered channels, so the tasker still receives the signal too.
+	// alertingCtx is cancelled on Shutdown; this stops the alert scheduler and reloading the alert definitions (running
+	// evaluations are finished, see AlertManager.Shutdown).
+	alertingCtx, stopAlerting := context.WithCancel(context.Background())
+
+	// SIGHUP reloads src/*/alerts.yaml (and the referenced SQL files) without restarting the scheduler.
 	go func() {
 		sig := make(chan os.Signal, 1)
-		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
-		s := <-sig
-		logger.Info().
-			Str(logging.EventDataset, logging.EventDataset_Dashica_Startup).
-			Str("signal", s.String()).
-			Msg("shutdown signal received, exiting")
-		os.Exit(0)
+		signal.Notify(sig, syscall.SIGHUP)
+		for range sig {
+			logger.Info().
+				Str(logging.EventDataset, logging.EventDataset_Dashica_Startup).
+				Msg("SIGHUP received, reloading alert definitions")
+			if err := alertManager.ReloadAlertDefinitions(alertingCtx); err != nil {
+				logger.Error().
+					Str(logging.EventDataset, logging.EventDataset_Dashica_Startup).
+					Err(err).
+					Msg("Failed to reload alert definitions")
+			}
+		}
 	}()
 
+	alertReloadInterval := cfg.Alerting.ReloadInterval
+	if alertReloadInterval == 0 && cfg.DevMode {
+		alertReloadInterval = 5 * time.Second
+	}
+	if alertReloadInterval > 0 {
+		go alertManager.WatchAlertDefinitions(alertingCtx, alertReloadInterval)
+	}
+
 	go func() {
-		if err := alertManager.RunAlertScheduler(); err != nil {
+		if err := alertManager.RunAlertScheduler(alertingCtx); err != nil {
 			logger.Error().
 				Str(logging.EventDataset, logging.EventDataset_Dashica_Startup).
 				Err(err).
@@ -128,13 +178,28 @@ func New(projectFS fs.ReadFileFS) Dashica {
 		AlertManager:            alertManager,
 	}
 
-	return &DashicaImpl{
+	d := &DashicaImpl{
 		cfg:              cfg,
 		log:              logger,
-		handler:          mux,
+		handler:          authenticator.Handler(mux),
 		handlerCollector: handler_collector.NewValidatingCollector(mux, logger),
 		deps:             deps,
+		alertManager:     alertManager,
+		stopAlerting:     stopAlerting,
 	}
+	// the access rules are read per request, as RestrictAlertingApi is called after New.
+	alertingApi := httpserver.NewAlertingApiHandler(logger, alertManager, alertSilenceStore)
+	mux.Handle(httpserver.AlertingApiPrefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.RequireAccess(d.alertingApiAccess)(alertingApi).ServeHTTP(w, r)
+	}))
+	d.server = httpserver.NewServer(cfg, logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/home", http.StatusFound)
+			return
+		}
+		d.ServeHTTP(w, r)
+	}))
+	return d, nil
 }
 
 type DashicaImpl struct {
@@ -144,6 +209,13 @@ type DashicaImpl struct {
 	dashboardGroups  []rendering.MenuGroup
 	handlerCollector handler_collector.HandlerCollector
 	deps             rendering.Dependencies
+	// server is started by ListenAndServe
+	server       *httpserver.Server
+	alertManager *alerting2.AlertManager
+	// stopAlerting stops the alert scheduler
+	stopAlerting context.CancelFunc
+	// alertingApiAccess restricts the alerting API, see RestrictAlertingApi
+	alertingApiAccess []auth.AccessRule
 
 	// exploreBaseURL is the registration URL of the Explore view once one is
 	// registered (empty otherwise). Every DashboardContext carries a pointer to
@@ -171,23 +243,73 @@ func (d *DashicaImpl) Log() zerolog.Logger {
 	return d.log
 }
 
+// ListenAndServe serves Dashica via HTTP, or via HTTPS if letsencrypt is enabled (see httpserver.Server), until
+// SIGINT / SIGTERM is received; then it shuts down gracefully (see Shutdown), waiting at most
+// server.shutdown_timeout.
 func (d *DashicaImpl) ListenAndServe() error {
-	addr := fmt.Sprintf(":%d", d.cfg.Server.Port)
+	// The gronx tasker (started by RunAlertScheduler) installs its own signal.Notify for os.Interrupt, which disables
+	// the default "exit on Ctrl-C"; so the signals must always be handled here. signal.Notify multiplexes to all
+	// registered channels, so the tasker still receives the signal too.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- d.server.ListenAndServe()
+	}()
+	select {
+	case err := <-serverErr:
+		return err
+	case s := <-sig:
+		d.log.Info().
+			Str(logging.EventDataset, logging.EventDataset_Dashica_Startup).
+			Str("signal", s.String()).
+			Dur("timeout", d.cfg.Server.ShutdownTimeout).
+			Msg("shutdown signal received, shutting down gracefully")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.cfg.Server.ShutdownTimeout)
+	defer cancel()
+	return d.Shutdown(ctx)
+}
+
+// Shutdown gracefully stops Dashica: the server started by ListenAndServe stops accepting connections and waits for
+// running requests (e.g. streamed query results); the alert scheduler stops, and running alert evaluations are
+// finished (including persisting and notifying their results). Finally, the buffered query log is written. Once ctx
+// is done, the remaining requests and evaluations are cancelled.
+//
+// When serving Dashica with your own http.Server, call its Shutdown as well.
+func (d *DashicaImpl) Shutdown(ctx context.Context) error {
+	d.stopAlerting()
+	alertingErr := make(chan error, 1)
+	go func() {
+		alertingErr <- d.alertManager.Shutdown(ctx)
+	}()
+	serverErr := d.server.Shutdown(ctx)
+	alertErr := <-alertingErr
+	// no more queries run now; write the buffered query log entries
+	err := errors.Join(serverErr, alertErr, d.deps.ClickhouseClientManager.Shutdown(ctx))
+	if err != nil {
+		d.log.Warn().
+			Str(logging.EventDataset, logging.EventDataset_Dashica_Startup).
+			Err(err).
+			Msg("graceful shutdown did not complete in time")
+		return err
+	}
 	d.log.Info().
-		Int("port", d.cfg.Server.Port).
-		Msg("Starting Dashica HTTP server")
+		Str(logging.EventDataset, logging.EventDataset_Dashica_Startup).
+		Msg("shutdown complete")
+	return nil
+}
 
-	return http.ListenAndServe(addr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/" {
-			http.Redirect(w, r, "/home", http.StatusFound)
-			return
-		}
-		d.ServeHTTP(w, r)
-	}))
+func (d *DashicaImpl) RegisterDashboardGroup(title string, access ...auth.AccessRule) Dashica {
+	d.dashboardGroups = append(d.dashboardGroups, rendering.MenuGroup{Title: title, Access: access})
+	return d
 }
 
-func (d *DashicaImpl) RegisterDashboardGroup(title string) Dashica {
-	d.dashboardGroups = append(d.dashboardGroups, rendering.MenuGroup{Title: title})
+func (d *DashicaImpl) RestrictAlertingApi(access ...auth.AccessRule) Dashica {
+	d.alertingApiAccess = access
 	return d
 }
 
@@ -203,6 +325,11 @@ func (d *DashicaImpl) RegisterDashboard(url string, dashb dashboard.Dashboard) D
 		d.exploreBaseURL = url
 	}
 
+	handlerCollector := d.handlerCollector.Nested(url)
+	if access := d.dashboardGroups[len(d.dashboardGroups)-1].Access; len(access) > 0 {
+		handlerCollector = handler_collector.WithMiddleware(handlerCollector, auth.RequireAccess(access))
+	}
+
 	err := dashb.CollectHandlers(
 		&rendering.DashboardContext{
 			MainMenu:          &d.dashboardGroups,
@@ -210,7 +337,7 @@ func (d *DashicaImpl) RegisterDashboard(url string, dashb dashboard.Dashboard) D
 			Deps:              d.deps,
 			ExploreBaseURL:    &d.exploreBaseURL,
 		},
-		d.handlerCollector.Nested(url),
+		handlerCollector,
 	)
 	if err != nil {
 		d.log.Fatal().
@@ -3,16 +3,82 @@ CREATE TABLE dashica_alert_events
 (
     alert_id_group         LowCardinality(String),
     alert_id_key           LowCardinality(String),
+    -- for alerts with group_by: the URL-encoded group_by column values identifying the alert instance
+    -- (e.g. host_name=web1); empty otherwise.
+    -- existing installations: ClickHouse cannot add a column in the middle of the sorting key, so the events are
+    -- copied into a new table (stop Dashica meanwhile, as every insert writes alert_labels):
+    --   1. create dashica_alert_events_new with the CREATE TABLE statement below (only the table name changed)
+    --   2. INSERT INTO dashica_alert_events_new (alert_id_group, alert_id_key, timestamp, status, message)
+    --          SELECT alert_id_group, alert_id_key, timestamp, status, message FROM dashica_alert_events;
+    --   3. EXCHANGE TABLES dashica_alert_events AND dashica_alert_events_new; -- needs the Atomic database engine
+    --   4. DROP TABLE dashica_alert_events_new;
+    alert_labels           String DEFAULT '',
     timestamp              DateTime,
 
-    status                 Enum ('unknown' = 1, 'OK' = 2, 'warn' = 3, 'error' = 4),
+    -- 'removed' is written once when the alert definition disappeared from alerts.yaml.
+    -- 'pending' / 'flapping': see "for", "consecutive" and "flapping" in alerts.yaml.
+    -- existing installations:
+    --   ALTER TABLE dashica_alert_events MODIFY COLUMN status Enum ('unknown' = 1, 'OK' = 2, 'warn' = 3, 'error' = 4, 'removed' = 5, 'pending' = 6, 'flapping' = 7, 'timeout' = 8);
+    status                 Enum ('unknown' = 1, 'OK' = 2, 'warn' = 3, 'error' = 4, 'removed' = 5, 'pending' = 6, 'flapping' = 7, 'timeout' = 8),
     -- alert_result_timestamp Nullable(Datetime),
     message                Nullable(String),
     -- auto_resolve_on        Nullable(DateTime)
 ) ENGINE = MergeTree() PARTITION BY toYYYYMMDD(timestamp)
-      ORDER BY (alert_id_group, alert_id_key, timestamp)
+      ORDER BY (alert_id_group, alert_id_key, alert_labels, timestamp)
       -- we store the alert events WAY LONGER than our normal viewing interval,
       -- to be relatively certain that ALWAYS an event exists with old history.
       TTL timestamp + INTERVAL 365 DAY
           DELETE
-      SETTINGS index_granularity = 8192;
\ No newline at end of file
+      SETTINGS index_granularity = 8192;
+
+-- silences and acknowledgements of alerts (see alerting.Silence); expiring a silence inserts a new version of the row.
+-- existing installations: create this table; until then, notifications are not silenced.
+DROP TABLE IF EXISTS dashica_alert_silences;
+CREATE TABLE dashica_alert_silences
+(
+    id                     String,
+    kind                   Enum ('silence' = 1, 'ack' = 2),
+    -- matchers; "*" matches any number of characters
+    alert_id_group         String,
+    alert_id_pattern       String,
+    starts_at              DateTime,
+    -- NULL for acknowledgements; they end once the alert is OK again
+    ends_at                Nullable(DateTime),
+    author                 String,
+    comment                String,
+    updated_at             DateTime64(3)
+) ENGINE = ReplacingMergeTree(updated_at)
+      ORDER BY id
+      TTL toDateTime(updated_at) + INTERVAL 365 DAY
+          DELETE
+      SETTINGS index_granularity = 8192;
+
+-- every query executed by Dashica, if query_log.enabled is set in dashica_config.yaml (see clickhouse.QueryLog).
+-- existing installations: create this table before enabling the query log.
+DROP TABLE IF EXISTS dashica_query_log;
+CREATE TABLE dashica_query_log
+(
+    timestamp              DateTime64(3),
+    -- the authenticated user; empty if authentication is disabled or for alerts
+    user                   LowCardinality(String),
+    -- dashboard URL, widget id and type; empty for alerts and internal queries
+    dashboard              LowCardinality(String),
+    widget                 LowCardinality(String),
+    widget_type            LowCardinality(String),
+    alert                  LowCardinality(String),
+    server                 LowCardinality(String),
+    -- hash of the final SQL (after filters and placeholders were applied)
+    query_hash             String,
+    -- until the result was streamed completely
+    duration_ms            UInt64,
+    -- from the X-ClickHouse-Summary header; may be incomplete for large, streamed results
+    read_rows              UInt64,
+    read_bytes             UInt64,
+    result_rows            UInt64,
+    -- empty if the query succeeded
+    error                  String
+) ENGINE = MergeTree() PARTITION BY toYYYYMMDD(timestamp)
+      ORDER BY (dashboard, widget, timestamp)
+      TTL toDateTime(timestamp) + INTERVAL 30 DAY
+          DELETE
+      SETTINGS index_granularity = 8192;
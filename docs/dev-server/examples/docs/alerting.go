@@ -49,10 +49,16 @@ alerts:
 | Field | Description |
 |-------|-------------|
 | ` + "`query_path`" + ` | Path to the SQL query file that generates metrics |
-| ` + "`params`" + ` | Parameters to inject into the SQL query |
-| ` + "`alert_if`" + ` | Condition that triggers the alert (e.g., ` + "`value_gt`" + `, ` + "`value_lt`" + `) |
+| ` + "`params`" + ` | Parameters to inject into the SQL query; values may reference ` + "`${env:NAME}`" + ` and ` + "`${config:NAME}`" + ` (see below) |
+| ` + "`clickhouse`" + ` | Optional ClickHouse server alias (key of ` + "`clickhouse`" + ` in ` + "`dashica_config.yaml`" + `) to run the query on; defaults to ` + "`default`" + ` |
+| ` + "`error_if`" + ` | Condition that moves the alert into ` + "`error`" + ` state (e.g., ` + "`value_gt`" + `, ` + "`value_lt`" + `). ` + "`alert_if`" + ` is the legacy name. |
+| ` + "`warn_if`" + ` | Optional condition that moves the alert into ` + "`warn`" + ` state |
 | ` + "`message`" + ` | Message to display when the alert triggers |
-| ` + "`check_every`" + ` | Frequency for checking the alert (cron-like expression) |
+| ` + "`warn_message`" + ` | Optional message for the ` + "`warn`" + ` state (defaults to ` + "`message`" + `) |
+| ` + "`group_by`" + ` | Optional list of result columns identifying an alert instance (see below) |
+| ` + "`notify`" + ` | Optional list of notifiers to send state changes to (see below); can also be set once at the top of ` + "`alerts.yaml`" + ` |
+| ` + "`check_every`" + ` | Frequency for checking the alert (cron expression like ` + "`*/5 * * * *`" + ` or ` + "`@15minutes`" + `; an optional leading seconds field like ` + "`*/30 * * * * *`" + ` is supported) |
+| ` + "`timeout`" + ` | Optional maximum duration of a single evaluation (e.g. ` + "`10s`" + `); defaults to ` + "`alerting.evaluation_timeout`" + ` (30s). A timed out evaluation is recorded (and notified) as ` + "`timeout`" + ` state. |
 
 ## SQL Query Format
 
@@ -114,14 +120,196 @@ Use curly braces to define parameters that can be injected:
 WHERE event_dataset = {event_dataset:String}
 ` + "```" + `
 
+## Multiple Environments / ClickHouse Servers
+
+` + "`clickhouse`" + ` and ` + "`params`" + ` can be set per alert, or once at the top of ` + "`alerts.yaml`" + ` for all alerts of the
+file (params of an alert are merged over the file-level params). Param values (and the ` + "`clickhouse`" + ` alias) may
+reference environment variables as ` + "`${env:NAME}`" + `, and values of ` + "`alerting.params`" + ` in ` + "`dashica_config.yaml`" + ` as
+` + "`${config:NAME}`" + `. An unset reference or an unknown server alias is reported as error when the alerts are loaded.
+
+To run the same alerts against staging and production from one Dashica instance, create one ` + "`alerts.yaml`" + ` per
+environment which reference the same SQL files:
+
+` + "```yaml" + `
+# src/staging/alerts.yaml
+clickhouse: staging
+params:
+  environment: staging
+  event_dataset: ${config:shop_dataset}
+alerts:
+  http500:
+    query_path: ../shared/http_errors.sql
+    error_if:
+      value_gt: 500
+    message: ERROR - too many failures
+    check_every: '@5minutes'
+` + "```" + `
+
 ## Alert Conditions
 
-Currently supported alert conditions:
+Supported alert conditions (usable in ` + "`error_if`" + ` and ` + "`warn_if`" + `):
 
 | Condition | Description |
 |-----------|-------------|
 | ` + "`value_gt`" + ` | Triggers when the value exceeds the specified threshold |
 | ` + "`value_lt`" + ` | Triggers when the value falls below the specified threshold |
+| ` + "`value_eq`" + ` | Triggers when the value equals the specified value |
+| ` + "`value_ne`" + ` | Triggers when the value differs from the specified value |
+| ` + "`value_between`" + ` | Triggers when ` + "`min <= value <= max`" + ` |
+| ` + "`value_outside`" + ` | Triggers when ` + "`value < min`" + ` or ` + "`value > max`" + ` |
+
+If several comparisons are set in one condition, any of them triggers. ` + "`error_if`" + ` is checked first, then
+` + "`warn_if`" + `; so a single alert can go ` + "`OK`" + ` → ` + "`warn`" + ` → ` + "`error`" + `:
+
+` + "```yaml" + `
+alerts:
+  diskUsage:
+    query_path: ./alerts/disk_usage.sql
+    warn_if:
+      value_between: {min: 80, max: 90}
+    error_if:
+      value_gt: 90
+    warn_message: WARNING - disk filling up
+    message: ERROR - disk almost full
+    check_every: '@5minutes'
+` + "```" + `
+
+### Dynamic Thresholds
+
+Instead of a fixed number, the SQL query can compute the threshold itself (e.g. a rolling baseline times 3) by
+returning an ` + "`alert_if_value_gt`" + ` and/or ` + "`alert_if_value_lt`" + ` column. If non-NULL, they override
+` + "`value_gt`" + ` / ` + "`value_lt`" + ` of ` + "`error_if`" + ` for this row (of ` + "`warn_if`" + `, if the alert has no
+` + "`error_if`" + `). Set ` + "`dynamic: true`" + ` if there is no static
+fallback threshold:
+
+` + "```yaml" + `
+    error_if:
+      dynamic: true
+` + "```" + `
+
+The alert detail chart draws the dynamic thresholds as dashed line per bucket.
+
+## Avoiding Notification Spam: Pending, Hysteresis and Flapping
+
+Values hovering around a threshold would otherwise notify on every evaluation. The following options apply to
+both the scheduler and the batch evaluator:
+
+` + "```yaml" + `
+alerts:
+  cpuUsage:
+    query_path: ./alerts/cpu_usage.sql
+    error_if:
+      value_gt: 90
+    # the condition must hold for 10 minutes AND 3 consecutive evaluations; until then, the alert is "pending"
+    for: 10m
+    consecutive: 3
+    # hysteresis: once firing, the alert only recovers when the value drops below 80
+    recover_if:
+      value_lt: 80
+    # 6 state changes within 1 hour mark the alert as "flapping" (notified once); it stops flapping once at most
+    # 3 changes are within the window.
+    flapping:
+      window: 1h
+      max_changes: 6
+    message: ERROR - CPU usage too high
+    check_every: '@5minutes'
+` + "```" + `
+
+` + "`pending`" + ` is recorded in the alert history, but not notified. ` + "`flapping`" + ` is notified like a warning.
+The evaluation history for ` + "`for`" + ` / ` + "`consecutive`" + ` / ` + "`flapping`" + ` is kept in memory; after a restart, it starts over.
+
+## One Alert per Host / Customer (group_by)
+
+With ` + "`group_by`" + `, a single alert definition fans out into one **alert instance** per distinct value of the
+given result columns. The query then returns one row per instance (and bucket); every instance has its own state,
+its own history in ` + "`dashica_alert_events`" + ` (column ` + "`alert_labels`" + `), and its own notification:
+
+` + "```yaml" + `
+alerts:
+  http500PerHost:
+    query_path: ./alerts/http_errors_per_host.sql
+    group_by: [host_name]
+    error_if:
+      value_gt: 500
+    message: ERROR - too many failures
+    check_every: '@5minutes'
+` + "```" + `
+
+The SQL query must add ` + "`host_name`" + ` to its ` + "`SELECT`" + ` and ` + "`GROUP BY`" + `. If an instance which is not ` + "`OK`" + `
+does not return a row anymore, it is evaluated as value 0 (i.e. it recovers).
+
+## Notifications
+
+State changes are sent to **notifiers**, which are defined in ` + "`dashica_config.yaml`" + `:
+
+` + "```yaml" + `
+alerting:
+  notifiers:
+    slack_ops:
+      type: slack            # Slack incoming webhook
+      url: https://hooks.slack.com/services/...
+    oncall:
+      type: alertmanager     # Prometheus Alertmanager v2 API
+      url: http://alertmanager:9093
+    ticketing:
+      type: webhook          # JSON POST, body rendered via Go text/template
+      url: https://tickets.example.com/api/alerts
+      headers:
+        Authorization: Bearer ...
+      body_template: '{"title": {{ json .Id }}, "state": {{ json .State }}, "text": {{ json .Message }}}'
+    mail_ops:
+      type: email
+      smtp_host: smtp.example.com
+      smtp_port: 587
+      smtp_user: dashica
+      smtp_password: ...
+      from: dashica@example.com
+      to: [ops@example.com]
+` + "```" + `
+
+Alerts select notifiers by name via ` + "`notify: [slack_ops, oncall]`" + `, either per alert or for all alerts
+of an ` + "`alerts.yaml`" + ` file (top-level ` + "`notify`" + `). Alerts without ` + "`notify`" + ` use the ` + "`helvetikit`" + `
+notifier, which is implicitly configured by ` + "`alerting.helvetikit_alerting_url`" + `.
+
+Alertmanager resolves alerts which are not sent again; so unlike the other notifiers, ` + "`alertmanager`" + ` notifiers
+receive firing alerts on every evaluation (not only on state changes). Their ` + "`endsAt`" + ` is three ` + "`check_every`" + `
+intervals ahead, so an alert only resolves there if Dashica stops evaluating it. The alerts carry the labels
+` + "`alertname`" + `, ` + "`alert_group`" + ` and ` + "`severity`" + ` plus the ` + "`group_by`" + ` columns; a ` + "`group_by`" + ` column with one of
+these reserved names is sent as ` + "`exported_<name>`" + `.
+
+## Silences and Acknowledgements
+
+A **silence** suppresses notifications of matching alerts during a time window (e.g. a deployment or planned
+maintenance); the alert state is still evaluated and recorded. Silences match the alert group (the path of the
+` + "`alerts.yaml`" + ` file) and / or the full alert id (e.g. ` + "`src/shop/alerts.yaml#http_500?host_name=web1`" + `);
+` + "`*`" + ` matches any number of characters.
+
+When a silence is over, alerts whose state changed during the silence are notified with their current state on the
+next evaluation (e.g. an alert which started firing during a deployment and is still firing); alerts which fired and
+recovered during the silence are not notified.
+
+**Acknowledging** a firing alert stops re-notifications for this alert instance until it is OK again; the
+recovery is notified as usual.
+
+Both can be managed in the alert overview widget, or via the HTTP API:
+
+` + "```bash" + `
+# list active and upcoming silences / acknowledgements
+curl http://localhost:8081/api/alerting/silences
+# silence all alerts of the shop for 2 hours (alternatively, pass starts_at / ends_at as "2025-03-01 10:00:00")
+curl -X POST http://localhost:8081/api/alerting/silences -H 'Content-Type: application/json' \
+  -d '{"alert_id_group": "src/shop/*", "duration": "2h", "author": "ops", "comment": "deployment"}'
+# expire a silence
+curl -X DELETE http://localhost:8081/api/alerting/silences/<id>
+# acknowledge a firing alert
+curl -X POST http://localhost:8081/api/alerting/acks -H 'Content-Type: application/json' \
+  -d '{"alert_id": "src/shop/alerts.yaml#http_500", "author": "ops"}'
+` + "```" + `
+
+POST requests must be sent with ` + "`Content-Type: application/json`" + `; other requests are rejected with 415, so
+that other websites cannot silence alerts via a plain HTML form.
+
+Silences are stored in the ` + "`dashica_alert_silences`" + ` table (see ` + "`schema.sql`" + `) next to ` + "`dashica_alert_events`" + `.
 
 ## Example: HTTP Error Alert
 
@@ -165,7 +353,8 @@ ORDER BY
 
 ## Development vs. Production
 
-- **Production**: Alerts are evaluated incrementally as scheduled by ` + "`check_every`" + `
+- **Production**: Alerts are evaluated incrementally as scheduled by ` + "`check_every`" + `. Evaluations run concurrently, at most ` + "`alerting.max_concurrent_evaluations`" + ` (default 4) at the same time; so a slow query does not delay the other alerts.
+- **Reloading**: Changes to ` + "`alerts.yaml`" + ` and the referenced SQL files are picked up without restart: send ` + "`SIGHUP`" + ` to the Dashica process, or set ` + "`alerting.reload_interval`" + ` (e.g. ` + "`30s`" + `) in ` + "`dashica_config.yaml`" + ` to poll for changes (in dev mode, files are polled every 5 seconds). Added and changed alerts are evaluated immediately. Removed alerts get a final ` + "`removed`" + ` event; if they were firing, the notifiers receive a resolve. If a file cannot be parsed, the previous alert definitions stay active.
 - **Development**: The ` + "`BatchEvaluator`" + ` can evaluate alerts for multiple time points at once, useful for testing and retrospective analysis. This can be triggered by pressing the ` + "`Calculate alerts for current time range`" + ` Button on the alerts screen
 
 ## Creating a New Alert
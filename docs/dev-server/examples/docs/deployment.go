@@ -145,6 +145,111 @@ alerting:
   check_interval: "5m"
 ` + "```" + `
 
+### Checking the Configuration
+
+Unknown keys in ` + "`dashica_config.yaml`" + ` (e.g. a misspelled ` + "`alert_cron_monitor_shedule`" + `) are ignored, and only
+logged as warnings on startup. Run the config check before deploying, e.g. in CI:
+
+` + "```bash" + `
+APP_ENV=production go run . config check
+` + "```" + `
+
+It prints the effective configuration (secrets masked) and reports unknown keys, a missing ` + "`alert_storage`" + ` server,
+alert definitions which do not parse, invalid cron expressions (` + "`check_every`" + `,
+` + "`alerting.alert_cron_monitor_schedule`" + `) and unreachable ClickHouse servers; it exits non-zero if there are
+problems. Your ` + "`main`" + ` function has to dispatch the command to ` + "`dashica.CheckConfig`" + `:
+
+` + "```go" + `
+if len(os.Args) == 3 && os.Args[1] == "config" && os.Args[2] == "check" {
+    if err := dashica.CheckConfig(projectFS); err != nil {
+        log.Fatal(err)
+    }
+    return
+}
+` + "```" + `
+
+### Secrets
+
+Values in the ` + "`clickhouse`" + `, ` + "`auth`" + ` and ` + "`alerting`" + ` sections may reference environment variables
+as ` + "`${NAME}`" + `; referencing an unset variable is a configuration error. Passwords can also be read from a file,
+e.g. a Docker or Kubernetes secret, with ` + "`password_file`" + ` (` + "`smtp_password_file`" + ` for email notifiers)
+instead of ` + "`password`" + `:
+
+` + "```yaml" + `
+clickhouse:
+  default:
+    url: "https://${CLICKHOUSE_HOST}:8443"
+    user: dashica
+    password_file: /run/secrets/clickhouse_password
+
+auth:
+  enabled: true
+  username: admin
+  password_file: /run/secrets/dashica_admin_hash   # bcrypt hash
+
+alerting:
+  notifiers:
+    ops_mail:
+      type: email
+      smtp_host: mail.example.com
+      smtp_user: dashica
+      smtp_password_file: /run/secrets/smtp_password
+` + "```" + `
+
+Trailing line breaks are stripped. The files are read again once they change, so rotated secrets are picked up
+without a restart.
+
+### ClickHouse Connection
+
+Each server under ` + "`clickhouse:`" + ` (keyed by its alias) supports these connection settings:
+
+` + "```yaml" + `
+clickhouse:
+  default:
+    url: "https://abc123.eu-central-1.aws.clickhouse.cloud:8443"
+    user: "dashica"
+    password: "..."
+    database: "default"
+    timeout: 30s              # per request, including reading the result
+    max_retries: 2            # retries if ClickHouse is not reachable
+    retry_backoff: 200ms      # doubled for each further retry
+    compression: gzip         # response compression: gzip, zstd or none
+    max_idle_connections: 10  # kept-alive connections
+    tls_ca_file: /etc/dashica/clickhouse-ca.pem     # default: system roots
+    tls_cert_file: /etc/dashica/client.pem          # optional client certificate
+    tls_key_file: /etc/dashica/client-key.pem
+` + "```" + `
+
+Queries are sent in the POST body (so long generated SQL is not limited by the URL length). Read-only queries are
+retried after connection errors and 502 / 503 / 504 responses (e.g. from a load balancer); writes (alert results)
+are only retried if the connection could not be established.
+
+### Query Guards
+
+Every server can limit the queries Dashica sends to it. The limits are passed as ClickHouse settings, so ClickHouse
+aborts a query as soon as it exceeds one:
+
+` + "```yaml" + `
+clickhouse:
+  default:
+    readonly: 2                # sent with read-only queries; 0 = not sent (if the ClickHouse profile is already read-only)
+    max_execution_time: 60s    # 0 = unlimited (default)
+    max_result_rows: 0
+    max_bytes_to_read: 0
+    explore:                   # stricter limits for ad-hoc queries (Explore, untrusted dashboards)
+      max_execution_time: 30s  # defaults
+      max_result_rows: 100000
+      max_bytes_to_read: 10737418240
+` + "```" + `
+
+The ` + "`explore`" + ` limits only tighten the server limits. Settings passed explicitly with a trusted query override
+the server limits; for untrusted queries the guards always win. As ClickHouse lets a query change its own settings
+(even with ` + "`readonly: 2`" + `), untrusted queries containing a ` + "`SETTINGS`" + ` clause are rejected. To enforce the
+limits in ClickHouse itself, use a read-only user whose settings profile has constraints on them.
+
+If a query is aborted by a guard (or tries to write on a read-only server), the widget shows an explanatory error
+with HTTP status 422 instead of a generic 500.
+
 ## Deployment Strategies
 
 ### Docker Compose
@@ -274,17 +379,183 @@ WantedBy=multi-user.target
    - Use read-only database user for Dashica
    - Restrict network access to ClickHouse
    - Use TLS for ClickHouse connections in production
+   - Serve Dashica via HTTPS (see [HTTPS](#https))
 
 2. **Authentication:**
-   - Place Dashica behind a reverse proxy with authentication
+   - Enable authentication (see [Authentication](#authentication) below), or place Dashica behind an authenticating reverse proxy
    - Use VPN or IP whitelisting for sensitive data
-   - Consider implementing OAuth2/OIDC
 
 3. **Configuration:**
    - Never commit ` + "`dashica_config.yaml`" + ` with passwords to Git
-   - Use environment variables or secrets management
+   - Use ` + "`${ENV}`" + ` references or ` + "`password_file`" + ` (see [Secrets](#secrets)) instead
    - Restrict file permissions on config files
 
+## Graceful Shutdown
+
+On SIGINT / SIGTERM, ` + "`d.ListenAndServe()`" + ` shuts down gracefully: it stops accepting connections, lets running
+requests (including streamed query results) and alert evaluations finish, and then returns. After
+` + "`server.shutdown_timeout`" + ` (default: 30s), whatever is still running is cancelled. In Kubernetes, keep
+` + "`terminationGracePeriodSeconds`" + ` above this timeout.
+
+If you serve Dashica with your own ` + "`http.Server`" + `, handle the signals yourself and call both
+` + "`server.Shutdown(ctx)`" + ` and ` + "`d.Shutdown(ctx)`" + `. This is required: the alert scheduler registers its own
+SIGINT handler, so without it, Ctrl-C does not stop the process.
+
+## HTTPS
+
+Dashica can terminate TLS itself (instead of a reverse proxy). When ` + "`letsencrypt.enabled`" + ` is set, it serves
+HTTPS on ` + "`dev_https_port`" + ` (default 443) and redirects HTTP on ` + "`dev_http_port`" + ` (default 80) to HTTPS;
+` + "`server.port`" + ` is not used then.
+
+**Let's Encrypt (ACME)** obtains and renews the certificate via the HTTP-01 challenge, so ` + "`domain`" + ` must
+resolve to Dashica and port 80 must be reachable:
+
+` + "```yaml" + `
+letsencrypt:
+  enabled: true
+  email: "ops@example.com"
+  domain: "dashica.example.com"
+  storage_path: "/data/certmagic"   # persist certificates and ACME account (e.g. a volume)
+  # dev_use_staging_ca: true        # Let's Encrypt staging CA, for testing
+  # dev_cert_renew_interval: 24h    # how often certificates are checked for renewal
+` + "```" + `
+
+**Manual certificates** are read from files, and re-read every ` + "`manual_refresh_interval`" + ` (default 24h), so
+certificates renewed by an external tool are picked up without restart. If a reload fails, the previous certificate
+is kept:
+
+` + "```yaml" + `
+letsencrypt:
+  enabled: true
+  manual_certfile: "/etc/ssl/dashica/fullchain.pem"
+  manual_keyfile: "/etc/ssl/dashica/privkey.pem"
+  manual_refresh_interval: 1h
+` + "```" + `
+
+**Testing with Pebble:** run the [Pebble](https://github.com/letsencrypt/pebble) ACME test server (its HTTP-01
+validation connects to port 5002 by default), and point Dashica at it:
+
+` + "```yaml" + `
+letsencrypt:
+  enabled: true
+  domain: "localhost"
+  dev_custom_ca: "https://localhost:14000/dir"
+  dev_custom_ca_root: "pebble/test/certs/pebble.minica.pem"
+  dev_http_port: 5002
+  dev_https_port: 8443
+` + "```" + `
+
+## Authentication
+
+Authentication is configured in the ` + "`auth`" + ` section of ` + "`dashica_config.yaml`" + `. All requests except for
+` + "`auth.public_paths`" + ` (default: ` + "`/public/`" + `) and the paths below them require an authenticated user; this
+includes ` + "`/metrics`" + `. Public paths match whole path segments: ` + "`/health`" + ` does not make ` + "`/healthz`" + ` public.
+
+**Basic auth** (` + "`mode: basic`" + `, the default) with bcrypt password hashes (generate them with ` + "`htpasswd -nbB user password`" + `):
+
+` + "```yaml" + `
+auth:
+  enabled: true
+  mode: basic
+  users:
+    alice: "$2y$10$..."
+    bob: "$2y$10$..."
+  groups:
+    ops: [alice]
+` + "```" + `
+
+**OIDC** (` + "`mode: oidc`" + `) logs users in via an OpenID Connect provider (e.g. Keycloak, Dex, Google) and keeps them
+in a signed session cookie. The groups are read from the ` + "`groups_claim`" + ` of the ID token; logout is at ` + "`/auth/logout`" + `.
+
+` + "```yaml" + `
+auth:
+  enabled: true
+  mode: oidc
+  oidc:
+    issuer_url: "https://sso.example.com/realms/main"
+    client_id: "dashica"
+    client_secret: "..."
+    redirect_url: "https://dashica.example.com/auth/callback"
+    session_secret: "at least 32 random characters ....."
+    # defaults:
+    # scopes: [openid, profile, email]
+    # username_claim: preferred_username
+    # groups_claim: groups
+    # session_duration: 12h
+` + "```" + `
+
+**Reverse proxy** (` + "`mode: proxy`" + `) trusts a user header set by an authenticating proxy (e.g. oauth2-proxy).
+The header is only accepted from ` + "`trusted_proxies`" + `:
+
+` + "```yaml" + `
+auth:
+  enabled: true
+  mode: proxy
+  proxy:
+    user_header: X-Forwarded-User      # default
+    groups_header: X-Forwarded-Groups  # default; comma-separated
+    trusted_proxies: ["10.0.0.0/8"]
+` + "```" + `
+
+In all modes, ` + "`auth.groups`" + ` adds users to additional groups. Access to a dashboard group is restricted in Go;
+its menu entries are hidden and all its handlers (including the queries) respond with 403 for other users:
+
+` + "```go" + `
+d.RegisterDashboardGroup("Operations", auth.AllowGroups("ops"), auth.AllowUsers("carol")).
+    RegisterDashboard("/ops/servers", ServersDashboard())
+` + "```" + `
+
+The API for silencing and acknowledging alerts (` + "`/api/alerting/`" + `) is available to all authenticated users by
+default. Restrict it, e.g. to the on-call team:
+
+` + "```go" + `
+d.RestrictAlertingApi(auth.AllowGroups("ops"))
+` + "```" + `
+
+## Query Cache
+
+Dashboard query results can be cached in memory, so that many people opening the same dashboard only run each
+query once:
+
+` + "```yaml" + `
+query_cache:
+  enabled: true
+  default_ttl: 1m                  # per query: sql.CacheTTL(...) / sql.NoCache()
+  max_size_bytes: 268435456        # all cached results (least recently used are evicted)
+  max_entry_size_bytes: 16777216   # larger results are not cached
+` + "```" + `
+
+The cache key is the final SQL, its parameters and settings, the ClickHouse server alias, and whether the query is
+untrusted (Explore queries run with stricter guards). Identical queries running at the same time are coalesced: only
+the first one is executed, the others wait for its result. Relative time ranges are rounded to the TTL (see [Queries](/docs/queries)). The ` + "`X-Dashica-Cache`" + ` response header reports
+` + "`HIT`" + `, ` + "`MISS`" + `, ` + "`COALESCED`" + ` or ` + "`BYPASS`" + ` (caching disabled for the query, or result too large).
+
+## Query Log
+
+Dashica can record every query it executes (dashboard widgets, Explore and alerts) in the ` + "`dashica_query_log`" + `
+table of the ` + "`alert_storage`" + ` server: the user, dashboard URL, widget id and type (or alert id), server alias, a hash of
+the final SQL, the duration until the result was streamed completely, the rows / bytes read and the error. Create the
+table from ` + "`schema.sql`" + `, then enable it:
+
+` + "```yaml" + `
+query_log:
+  enabled: true
+  flush_interval: 5s   # entries are inserted in batches
+  batch_size: 1000
+  buffer_size: 10000   # further entries are dropped while ClickHouse is unreachable
+` + "```" + `
+
+Rows and bytes read are taken from the ` + "`X-ClickHouse-Summary`" + ` header, so they may be incomplete for large streamed
+results. Results served from the query cache are not logged, as no query is executed.
+
+The built-in "Dashica self-monitoring" dashboard shows the slowest widgets, the most expensive dashboards and the
+failed queries:
+
+` + "```go" + `
+d.RegisterDashboardGroup("Operations").
+    RegisterDashboard("/self-monitoring", dashboard.SelfMonitoring())
+` + "```" + `
+
 ## Performance Tips
 
 1. **Frontend:**
@@ -304,13 +575,26 @@ WantedBy=multi-user.target
 
 ## Monitoring
 
-Monitor these metrics:
-
-- HTTP response times
-- ClickHouse query duration
-- Error rates
-- Memory usage
-- Active connections
+Dashica exposes metrics in the Prometheus text format at ` + "`/metrics`" + `:
+
+| Metric | Description |
+|--------|-------------|
+| ` + "`dashica_alert_state`" + ` | 1 for the current state of each alert instance (labels ` + "`alert_id_group`" + `, ` + "`alert_id_key`" + `, ` + "`alert_labels`" + `, ` + "`state`" + `) |
+| ` + "`dashica_alert_evaluation_duration_seconds`" + ` | Histogram of alert evaluation durations per alert |
+| ` + "`dashica_alert_evaluation_errors_total`" + ` | Failed alert evaluations per alert, by ` + "`reason`" + ` (` + "`error`" + ` / ` + "`timeout`" + `) |
+| ` + "`dashica_alert_scheduler_last_run_timestamp_seconds`" + ` | Last run of the alert scheduler; alert on ` + "`time() - ... > 300`" + ` to detect a stuck scheduler |
+| ` + "`dashica_clickhouse_queries_total`" + ` | ClickHouse queries per ` + "`server`" + ` alias and ` + "`status`" + ` (` + "`ok`" + ` / ` + "`error`" + `) |
+| ` + "`dashica_clickhouse_query_retries_total`" + ` | Retried ClickHouse requests per server alias |
+| ` + "`dashica_clickhouse_query_guard_errors_total`" + ` | Queries aborted by a query guard, per server alias and guard |
+| ` + "`dashica_clickhouse_query_duration_seconds`" + ` | Histogram of ClickHouse query durations (until the response headers) per server alias |
+| ` + "`dashica_clickhouse_read_rows_total`" + `, ` + "`dashica_clickhouse_read_bytes_total`" + ` | Rows / bytes read by ClickHouse per server alias (from the ` + "`X-ClickHouse-Summary`" + ` header; may be incomplete for large streamed results) |
+| ` + "`dashica_clickhouse_query_cache_requests_total`" + ` | Cacheable queries per ` + "`server`" + ` alias and cache ` + "`status`" + ` |
+| ` + "`dashica_clickhouse_query_cache_size_bytes`" + `, ` + "`dashica_clickhouse_query_cache_entries`" + ` | Memory used by / number of cached query results |
+| ` + "`dashica_clickhouse_query_log_dropped_total`" + ` | Query log entries which could not be written |
+| ` + "`dashica_http_requests_total`" + ` | HTTP requests per registered ` + "`handler`" + ` path and status ` + "`code`" + ` |
+| ` + "`dashica_http_request_duration_seconds`" + ` | Histogram of HTTP request durations per registered handler path |
+
+Additionally, monitor memory usage and active connections of the container.
 
 ## Next Steps
 
@@ -89,8 +89,9 @@ Create **main.go**:
 package main
 
 import (
+    "io/fs"
     "log"
-    "net/http"
+    "os"
 
     "github.com/sandstorm/dashica"
     "github.com/sandstorm/dashica/lib/components/layout"
@@ -100,7 +101,10 @@ import (
 )
 
 func main() {
-    d := dashica.New()
+    d, err := dashica.New(os.DirFS(".").(fs.ReadFileFS))
+    if err != nil {
+        log.Fatal(err)
+    }
 
     d.RegisterDashboardGroup("My Dashboards").
         RegisterDashboard("/", dashboard.New().
@@ -112,8 +116,10 @@ func main() {
             ),
         )
 
-    log.Println("Server running at http://127.0.0.1:8080")
-    log.Fatal(http.ListenAndServe("127.0.0.1:8080", d))
+    // serves on server.port (default: 8081); Ctrl-C shuts down gracefully
+    if err := d.ListenAndServe(); err != nil {
+        log.Fatal(err)
+    }
 }
 ` + "```" + `
 
@@ -121,7 +127,7 @@ func main() {
 
 ` + "```bash" + `
 go run main.go
-# Browse to http://127.0.0.1:8080
+# Browse to http://127.0.0.1:8081
 ` + "```" + `
 
 ## Deployment
@@ -40,6 +40,10 @@ Dashica follows a simple architecture:
 package main
 
 import (
+    "io/fs"
+    "log"
+    "os"
+
     "github.com/sandstorm/dashica"
     "github.com/sandstorm/dashica/lib/dashboard"
     "github.com/sandstorm/dashica/lib/dashboard/widget"
@@ -47,7 +51,10 @@ import (
 )
 
 func main() {
-    d := dashica.New()
+    d, err := dashica.New(os.DirFS(".").(fs.ReadFileFS))
+    if err != nil {
+        log.Fatal(err)
+    }
 
     d.RegisterDashboard("/", dashboard.New().
         Widget(
@@ -66,7 +73,10 @@ func main() {
         ),
     )
 
-    http.ListenAndServe(":8080", d)
+    // serves on server.port (default: 8081); Ctrl-C shuts down gracefully
+    if err := d.ListenAndServe(); err != nil {
+        log.Fatal(err)
+    }
 }
 ` + "```" + `
 
@@ -0,0 +1,104 @@
+package docs
+
+import (
+	"github.com/sandstorm/dashica/lib/components/layout"
+	"github.com/sandstorm/dashica/lib/dashboard"
+	"github.com/sandstorm/dashica/lib/dashboard/sql"
+	"github.com/sandstorm/dashica/lib/dashboard/widget"
+)
+
+func Kpi() dashboard.Dashboard {
+	return dashboard.New().
+		WithLayout(layout.DefaultPage).
+		Widget(
+			widget.NewMarkdown().
+				Title("KPI Widget").
+				Content(`
+# KPI Widget
+
+The KPI widget shows a single number as big tile: formatted with a unit, colored by thresholds, with a sparkline
+and the change against the same time range one day or one week earlier.
+
+**Ideal for:**
+- Headline numbers ("requests", "error rate", "p95 latency")
+- Judging at a glance whether a value is normal compared to last week
+
+## Data Requirements
+
+The query must aggregate to a **single value**, set with ` + "`.Value(field)`" + `. Everything else is derived:
+
+- ` + "`.Sparkline(sql.AutoBucket(\"timestamp\"))`" + ` groups the value by time bucket; the big number is computed
+  ` + "`WITH ROLLUP`" + ` in the same query, so it is the aggregate over the whole range (not the sum of the buckets).
+- ` + "`.CompareWith(\"1w\")`" + ` runs the query a second time over the selected time range shifted back by one week,
+  in the same request. You do not need to write a second query.
+`),
+		).
+		Widget(
+			widget.NewMarkdown().
+				Title("Example").
+				Content(`
+## Example: Requests and Latency
+
+` + "```go" + `
+widget.NewKpi(sql.New(sql.From("http_logs"))).
+    Title("Requests").
+    Value(sql.Count()).
+    Sparkline(sql.AutoBucket("timestamp")).
+    CompareWith("1d")
+
+widget.NewKpi(sql.New(sql.From("http_logs"))).
+    Title("Average response time").
+    Value(sql.Field("avg(response_time)").WithAlias("avg_response_time")).
+    Unit(widget.UnitMilliseconds).
+    Sparkline(sql.AutoBucket("timestamp")).
+    CompareWith("1w").
+    Thresholds(map[string]string{"0": "green", "200": "orange", "500": "red"})
+` + "```" + `
+`),
+		).
+		Widget(
+			widget.NewKpi(sql.New(sql.From("http_logs"))).
+				Title("Requests").
+				Value(sql.Count()).
+				Sparkline(sql.AutoBucket("timestamp")).
+				CompareWith("1d"),
+		).
+		Widget(
+			widget.NewKpi(sql.New(sql.From("http_logs"))).
+				Title("Average response time").
+				Value(sql.Field("avg(response_time)").WithAlias("avg_response_time")).
+				Unit(widget.UnitMilliseconds).
+				Sparkline(sql.AutoBucket("timestamp")).
+				CompareWith("1w").
+				Thresholds(map[string]string{"0": "green", "200": "orange", "500": "red"}),
+		).
+		Widget(
+			widget.NewMarkdown().
+				Title("KPI Configuration").
+				Content(`
+## Widget Options
+
+- ` + "`.Value(field)`" + ` - the aggregated value (required)
+- ` + "`.Sparkline(timestampedField)`" + ` - draw the value per time bucket below the number
+- ` + "`.Unit(unit)`" + ` - ` + "`widget.UnitBytes`" + `, ` + "`widget.UnitPercent`" + ` (0.5 = 50 %),
+  ` + "`widget.UnitMilliseconds`" + `, ` + "`widget.UnitSeconds`" + `; default: plain number with SI prefix (1.2k)
+- ` + "`.Suffix(string)`" + ` - text after the number, e.g. "req/s"
+- ` + "`.CompareWith(offset)`" + ` - show the change against the shifted time range: ` + "`\"1d\"`" + `,
+  ` + "`\"1w\"`" + ` or a duration like ` + "`\"12h\"`" + `. The previous period is drawn as dashed sparkline.
+- ` + "`.Thresholds(map)`" + ` - color steps: the key is the lower bound, the value a CSS color
+- ` + "`.Title(string)`" + `, ` + "`.Height(int)`" + `, ` + "`.Id(string)`" + `, ` + "`.AdjustQuery(opts...)`" + `
+
+## How the Comparison Works
+
+The comparison needs the dashboard time filter: the server shifts the resolved time range by the offset and runs
+the widget query again with the shifted ` + "`timestamp`" + ` condition. ` + "`{__from:DateTime}`" + ` /
+` + "`{__to:DateTime}`" + ` parameters used in the query are shifted as well. Both results are returned together,
+told apart by the ` + "`dashica_period`" + ` column (` + "`current`" + ` / ` + "`previous`" + `).
+
+## Next Steps
+
+- [Stats](/docs/widgets/stats) - Multiple labeled values from one query
+- [TimeBar](/docs/widgets/time-bar) - Time series visualization
+`),
+		)
+}
@@ -196,10 +196,84 @@ sql.Field("JSONExtractString(data, 'key')")
 3. Limit result sets for tables
 4. Use appropriate time buckets (15min, 1hour, 1day)
 
+### Query Result Cache
+
+If the query cache is enabled (see [Deployment](/docs/deployment)), results are cached for
+` + "`query_cache.default_ttl`" + `. Override the TTL per query (or per widget, via ` + "`AdjustQuery`" + `):
+
+` + "```go" + `
+// expensive overview: cache for 10 minutes
+sql.New(sql.From("logs"), sql.CacheTTL(10*time.Minute))
+
+// always fresh
+sql.FromFile("src/alerts/current.sql").With(sql.NoCache())
+` + "```" + `
+
+Relative time ranges ("last 24h") end at the current time rounded down to the TTL instead of ` + "`now()`" + `; so the
+most recent TTL of data is not shown, but everybody opening the same dashboard within the TTL gets the cached result.
+
+## Dashboard Variables
+
+Template variables (like Grafana's ` + "`$customer`" + ` dropdown) are declared on the dashboard and shown above its
+widgets. The options are the first column of a query; the selection is passed to **every** widget query of the
+dashboard as ClickHouse parameter ` + "`{name:Array(String)}`" + ` (an empty array if nothing is selected), and kept in
+the URL (` + "`?var-customer=acme&var-customer=globex`" + `), so links share it.
+
+` + "```go" + `
+dashboard.New().
+    Variable("customer",
+        sql.New(sql.From("customers"), sql.Select(sql.Field("DISTINCT name")), sql.OrderBy(sql.Field("name"))),
+        dashboard.VariableLabel("Customer"),
+        dashboard.VariableDefault("acme"),
+    ).
+    // project depends on customer: its options are re-loaded when the customer changes
+    Variable("project",
+        sql.New(
+            sql.From("projects"),
+            sql.Select(sql.Field("DISTINCT name")),
+            sql.Where("empty({customer:Array(String)}) OR has({customer:Array(String)}, customer)"),
+        ),
+        dashboard.VariableMulti(),
+        dashboard.VariableDependsOn("customer"),
+    ).
+    Widget(
+        widget.NewTimeBar(sql.New(
+            sql.From("http_logs"),
+            sql.Where("empty({customer:Array(String)}) OR has({customer:Array(String)}, customer)"),
+            sql.Where("empty({project:Array(String)}) OR has({project:Array(String)}, project)"),
+        )).X(sql.Timestamp15Min()).Y(sql.Count()),
+    )
+` + "```" + `
+
+Single-select variables offer an "All" entry, which selects nothing; the ` + "`empty(...) OR has(...)`" + ` pattern
+above treats that as "no filter". Options queries only receive the variables listed in ` + "`VariableDependsOn`" + `, and
+selected values which are no longer offered after a dependency changed are dropped.
+
+## Annotations
+
+Annotations mark events like deployments or incidents on **every** time chart (TimeBar, TimeLine, TimeHeatmap) of a
+dashboard: a dashed vertical line for a point in time, a shaded band for an event with an end. An annotation source is
+a query returning the columns ` + "`time`" + `, ` + "`end`" + ` (` + "`NULL`" + ` for a point in time), ` + "`text`" + ` (shown on hover)
+and ` + "`tags`" + ` (` + "`Array(String)`" + `; ` + "`error`" + ` and ` + "`warn`" + ` color the annotation red / orange):
+
+` + "```go" + `
+dashboard.New().
+    Annotations(sql.FromString(` + "`" + `
+        SELECT timestamp AS time, NULL AS end, concat('Deploy ', version) AS text, ['deploy'] AS tags
+        FROM deployments
+        WHERE {{DASHICA_FILTERS}}
+    ` + "`" + `)).
+    // warn / error states of the alerts whose group matches the ILIKE pattern, see Alerting
+    Annotations(dashboard.AlertEventAnnotations("http%"))
+` + "```" + `
+
+Sources are filtered by the dashboard time range (not by its SQL filter, which refers to the widget tables) and
+receive the variables and ` + "`{__from:DateTime}`" + ` / ` + "`{__to:DateTime}`" + ` as parameters. Annotations overlapping
+the time range are shown, at most 1000 per source (the most recent ones).
+
 ## Missing Features (TODO)
 
 - ❌ Global filters (SQL + time range UI)
-- ❌ Query parameters
 - ❌ ` + "`SkipFilters()`" + ` functionality
 - ❌ Query from file support
 - ❌ additional_table_filters
@@ -38,8 +38,9 @@ go get github.com/sandstorm/dashica
 package main
 
 import (
+    "io/fs"
     "log"
-    "net/http"
+    "os"
 
     "github.com/sandstorm/dashica"
     "github.com/sandstorm/dashica/lib/dashboard"
@@ -47,8 +48,11 @@ import (
 )
 
 func main() {
-    // Create a new Dashica instance
-    d := dashica.New()
+    // Create a new Dashica instance (reads dashica_config.yaml)
+    d, err := dashica.New(os.DirFS(".").(fs.ReadFileFS))
+    if err != nil {
+        log.Fatal(err)
+    }
 
     // Register a simple dashboard
     d.RegisterDashboard("/", dashboard.New().
@@ -59,9 +63,10 @@ func main() {
         ),
     )
 
-    // Start the server
-    log.Println("Server running on http://127.0.0.1:8080")
-    log.Fatal(http.ListenAndServe("127.0.0.1:8080", d))
+    // Start the server on server.port (default: 8081); Ctrl-C shuts down gracefully
+    if err := d.ListenAndServe(); err != nil {
+        log.Fatal(err)
+    }
 }
 ` + "```" + `
 
@@ -71,7 +76,7 @@ func main() {
 go run main.go
 ` + "```" + `
 
-Open your browser to http://127.0.0.1:8080 and see your dashboard!
+Open your browser to http://127.0.0.1:8081 and see your dashboard!
 
 ## Adding Data
 
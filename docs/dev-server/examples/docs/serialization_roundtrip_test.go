@@ -21,6 +21,7 @@ func TestExampleDashboards_RoundTrip(t *testing.T) {
 		"Queries":         Queries,
 		"QuickStart":      QuickStart,
 		"Stats":           Stats,
+		"Kpi":             Kpi,
 		"Table":           Table,
 		"TimeBar":         TimeBar,
 		"UsagePhilosophy": UsagePhilosophy,
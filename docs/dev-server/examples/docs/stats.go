@@ -125,6 +125,9 @@ Then specify which field is the label and which is the value:
 - ❌ Percentage displays
 - ❌ Sparklines
 
+For a single value with unit formatting, sparkline, comparison to the previous period and threshold colors, use
+the [KPI widget](/docs/widgets/kpi).
+
 ## Workarounds
 
 Until more aggregation functions are available, you can use raw SQL in ` + "`sql.Field()`" + `:
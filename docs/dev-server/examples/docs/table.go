@@ -190,6 +190,68 @@ Multiple ` + "`Where()`" + ` calls are combined with AND logic.
 				Title("Server Errors (Slow)").
 				Height(400),
 		).
+		Widget(
+			widget.NewMarkdown().
+				Title("Example 5: Large Tables, Formats, Colors and Links").
+				Content(`
+## Example 5: Large Tables, Formats, Colors and Links
+
+For large (log) tables, ` + "`PageSize`" + ` loads the rows page by page: paging and sorting (click a column header)
+run in ClickHouse via ` + "`ORDER BY`" + ` / ` + "`LIMIT`" + ` / ` + "`OFFSET`" + `, so only one page is transferred to the
+browser. ` + "`Limit`" + ` does not apply to paginated tables; the export (see the download button) contains all rows.
+
+- ` + "`ColumnFormats`" + ` formats values as ` + "`bytes`" + `, ` + "`percent`" + `, ` + "`ms`" + `, ` + "`s`" + `,
+  ` + "`timestamp`" + ` or ` + "`json`" + `.
+- ` + "`CellColors`" + ` colors the cells of a column by another query column holding a CSS color, so the coloring
+  rules are plain SQL. Color columns are hidden automatically.
+- ` + "`HiddenColumns`" + ` hides columns in the table; they are still shown in the record details and can be used in links.
+- ` + "`ColumnLinks`" + ` turns cells into links: ` + "`{column}`" + ` placeholders are replaced by the values of the row.
+  For links to a dashboard, the query parameters become its widget params, and the current time range is kept.
+
+**Code:**
+
+` + "```go" + `
+widget.NewTable(
+    sql.New(
+        sql.From("http_logs"),
+        sql.Select(sql.Field("timestamp")),
+        sql.Select(sql.Field("hostname")),
+        sql.Select(sql.Field("path")),
+        sql.Select(sql.Field("status")),
+        sql.Select(sql.Field("bytes_sent")),
+        sql.Select(sql.Field("multiIf(status >= 500, '#fca5a5', status >= 400, '#fde68a', '')").WithAlias("status_color")),
+        sql.OrderBy(sql.Field("timestamp DESC")),
+    ),
+).
+    Title("HTTP Requests (paginated)").
+    PageSize(50).
+    ColumnFormats(map[string]string{"bytes_sent": "bytes"}).
+    CellColors(map[string]string{"status": "status_color"}).
+    ColumnLinks(map[string]string{"hostname": "/docs/widgets/kpi?hostname={hostname}"}).
+    Height(400)
+` + "```" + `
+`),
+		).
+		Widget(
+			widget.NewTable(
+				sql.New(
+					sql.From("http_logs"),
+					sql.Select(sql.Field("timestamp")),
+					sql.Select(sql.Field("hostname")),
+					sql.Select(sql.Field("path")),
+					sql.Select(sql.Field("status")),
+					sql.Select(sql.Field("bytes_sent")),
+					sql.Select(sql.Field("multiIf(status >= 500, '#fca5a5', status >= 400, '#fde68a', '')").WithAlias("status_color")),
+					sql.OrderBy(sql.Field("timestamp DESC")),
+				),
+			).
+				Title("HTTP Requests (paginated)").
+				PageSize(50).
+				ColumnFormats(map[string]string{"bytes_sent": "bytes"}).
+				CellColors(map[string]string{"status": "status_color"}).
+				ColumnLinks(map[string]string{"hostname": "/docs/widgets/kpi?hostname={hostname}"}).
+				Height(400),
+		).
 		Widget(
 			widget.NewMarkdown().
 				Title("Configuration Reference").
@@ -203,6 +265,12 @@ Multiple ` + "`Where()`" + ` calls are combined with AND logic.
 .Height(int)                // Set height in pixels (default: 200)
 .Id(string)                 // Set custom widget ID (auto-generated if not set)
 .AdjustQuery(...options)    // Modify SQL query with additional options
+.Limit(int)                 // Maximum number of rows (default: 10000)
+.PageSize(int)              // Load rows page by page, sorted in ClickHouse
+.ColumnFormats(map[string]string) // column -> bytes, percent, ms, s, timestamp, json
+.CellColors(map[string]string)    // column -> column holding the CSS background color
+.HiddenColumns(...string)         // columns only shown in the record details
+.ColumnLinks(map[string]string)   // column -> URL template with {column} placeholders
 ` + "```" + `
 
 ## SQL Query Options
@@ -275,12 +275,28 @@ widget.NewTimeBar(baseQuery).
     Y(sql.Count())
 ` + "```" + `
 
+## Comparing with the Previous Period
+
+` + "`.CompareWith(\"1w\")`" + ` runs the same query a second time over the selected time range shifted back by one
+week, and draws the bucket totals of last week as dashed line on top of the bars. Use ` + "`\"1d\"`" + ` for
+yesterday, or any duration like ` + "`\"12h\"`" + `. The shifted query is derived automatically (the dashboard
+time filter and ` + "`{__from:DateTime}`" + ` / ` + "`{__to:DateTime}`" + ` parameters are shifted), so it only
+works for widgets using the dashboard time filter. TimeLine supports the same option; each line gets a dashed twin.
+
+` + "```go" + `
+widget.NewTimeBar(sql.New(sql.From("http_logs"))).
+    X(sql.AutoBucket("timestamp")).
+    Y(sql.Count()).
+    CompareWith("1w")
+` + "```" + `
+
 ## Widget Options
 
 **TimeBar-specific:**
 - ` + "`.X(field)`" + ` - temporal field for x-axis (required)
 - ` + "`.Y(field)`" + ` - numeric field for y-axis (required)
 - ` + "`.Fill(field)`" + ` - categorical field for stacking/coloring
+- ` + "`.CompareWith(offset)`" + ` - overlay the previous period (e.g. ` + "`\"1w\"`" + `) as dashed line
 - ` + "`.Title(string)`" + ` - chart title
 - ` + "`.Height(int)`" + ` - chart height in pixels
 
@@ -75,6 +75,24 @@ widget.NewStats(
 
 ---
 
+### [KPI](/docs/widgets/kpi)
+
+A single big number with unit, threshold color, sparkline and the change against last day / week.
+
+` + "```go" + `
+widget.NewKpi(sql.New(sql.From("http_logs"))).
+    Title("Requests").
+    Value(sql.Count()).
+    Sparkline(sql.AutoBucket("timestamp")).
+    CompareWith("1w")
+` + "```" + `
+
+**Use cases**: Headline numbers, "is this normal compared to last week?"
+
+[→ Full KPI documentation with live examples](/docs/widgets/kpi)
+
+---
+
 ### TimeHeatmap
 
 Heatmap for time-series data with color encoding.
@@ -192,6 +210,17 @@ Chart widgets support these common features:
 .Height(300)  // Set height in pixels
 ` + "```" + `
 
+### Data Export
+
+The download button in the toolbar of every chart (shown on hover, next to the debug button) exports the widget data
+as CSV, Parquet or JSON Lines. The export runs the widget query with the current time range, SQL filter, widget
+params and variables, and the same time buckets as the chart; the file is named after the widget title. The endpoint
+can also be used directly, e.g. from scripts:
+
+` + "```" + `
+<dashboard>/api/<widget id>/export?format=csv|parquet|jsonl&filters={"timeRange":"24h"}
+` + "```" + `
+
 ### Missing Features (TODO)
 
 These common features from the Observable Framework version are not yet available:
@@ -211,6 +240,7 @@ These common features from the Observable Framework version are not yet availabl
 - [TimeBar](/docs/widgets/time-bar) - Time series visualization with live examples
 - [BarVertical](/docs/widgets/bar-vertical) - Vertical bar charts with live examples
 - [Stats](/docs/widgets/stats) - KPI display with live examples
+- [KPI](/docs/widgets/kpi) - Single value with sparkline and comparison
 - [Queries](/docs/queries) - Learn SQL query patterns
 `),
 		)
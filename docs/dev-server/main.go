@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/sandstorm/dashica"
 	"github.com/sandstorm/dashica/docs/dev-server/examples/docs"
+	"github.com/sandstorm/dashica/lib/dashboard"
 	"github.com/sandstorm/dashica/lib/explore"
 )
 
@@ -18,7 +24,20 @@ func main() {
 		port = "8080"
 	}
 
-	d := dashica.New(os.DirFS(".").(fs.ReadFileFS))
+	projectFS := os.DirFS(".").(fs.ReadFileFS)
+
+	// "dev-server config check" validates dashica_config.yaml and the alert definitions, then exits
+	if len(os.Args) == 3 && os.Args[1] == "config" && os.Args[2] == "check" {
+		if err := dashica.CheckConfig(projectFS); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	d, err := dashica.New(projectFS)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Documentation section
 	d.RegisterDashboardGroup("📚 Documentation").
@@ -38,12 +57,17 @@ func main() {
 		RegisterDashboard("/docs/widgets/time-bar", docs.TimeBar()).
 		RegisterDashboard("/docs/widgets/bar-vertical", docs.BarVertical()).
 		RegisterDashboard("/docs/widgets/stats", docs.Stats()).
+		RegisterDashboard("/docs/widgets/kpi", docs.Kpi()).
 		RegisterDashboard("/docs/widgets/table", docs.Table())
 
 	// Explore — on-demand widget/dashboard builder (pure builder, no persistence).
 	d.RegisterDashboardGroup("🧪 Explore").
 		RegisterDashboard("/explore", explore.New())
 
+	// Self-monitoring from the query log (enable query_log in dashica_config.yaml)
+	d.RegisterDashboardGroup("🩺 Operations").
+		RegisterDashboard("/self-monitoring", dashboard.SelfMonitoring())
+
 	// Advanced Examples section (to be implemented)
 	// d.RegisterDashboardGroup("🚀 Advanced Examples").
 	//     RegisterDashboard("/examples/advanced/multi-widget", widgets.MultiWidgetDashboard()).
@@ -52,5 +76,20 @@ func main() {
 	addr := "127.0.0.1:" + port
 	log.Printf("Starting Dashica dev server on http://%s\n", addr)
 	log.Printf("📚 Documentation available at http://%s/docs/intro\n", addr)
-	log.Fatal(http.ListenAndServe(addr, d))
+	server := &http.Server{Addr: addr, Handler: d}
+	shutdownComplete := make(chan struct{})
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+		_ = d.Shutdown(ctx)
+		close(shutdownComplete)
+	}()
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	<-shutdownComplete
 }
@@ -1,8 +1,25 @@
 import * as Plot from "@observablehq/plot";
+import {html} from "htl";
 import type {QueryResult} from "../types";
 
+import './alertOverview.css';
+
 interface AlertOverviewProps {
     width?: number;
+    /** Base URL of the alerting API (see httpserver.AlertingApiPrefix); silence controls are hidden if not set. */
+    silenceApiUrl?: string;
+}
+
+// Silence mirrors alerting.Silence (Go)
+type Silence = {
+    id: string
+    kind: 'silence' | 'ack'
+    alert_id_group: string
+    alert_id_pattern: string
+    starts_at: string
+    ends_at: string | null
+    author: string
+    comment: string
 }
 
 function hoursMinutes(time: Date) {
@@ -19,13 +36,13 @@ async function _alertOverview(data: QueryResult, props: AlertOverviewProps): Pro
         domain = [data.dashicaResolvedTimeRange.from, data.dashicaResolvedTimeRange.to]
     }
 
-    return Plot.plot({
+    const plot = Plot.plot({
         width: props.width,
         marginLeft: 130,
         color: {
             legend: false,
-            domain: ['OK', 'warn', 'error'],
-            range: ['#56AF18', '#F8C666', '#DB5757'],
+            domain: ['OK', 'warn', 'error', 'removed', 'pending', 'flapping', 'timeout'],
+            range: ['#56AF18', '#F8C666', '#DB5757', '#BBBBBB', '#A9D18E', '#E67E22', '#7F8C8D'],
             unknown: '#8E44AD',
         },
         x: {
@@ -44,7 +61,7 @@ async function _alertOverview(data: QueryResult, props: AlertOverviewProps): Pro
             Plot.ruleY(data, {
                 x1: "start",
                 x2: "end",
-                y: "alert_id_key",
+                y: "alert_instance",
                 stroke: "status",
                 strokeWidth: (d: any) => d["status"] == 'OK' ? 3 : 10,
             }),
@@ -53,7 +70,7 @@ async function _alertOverview(data: QueryResult, props: AlertOverviewProps): Pro
                 Plot.pointerY({
                     x1: "start",
                     x2: "end",
-                    y: "alert_id_key",
+                    y: "alert_instance",
                     stroke: "status",
                     format: {
                         x1: (d: any) => hoursMinutes(d),
@@ -64,6 +81,89 @@ async function _alertOverview(data: QueryResult, props: AlertOverviewProps): Pro
             )
         ]
     }) as HTMLElement;
+
+    if (!props.silenceApiUrl) {
+        return plot;
+    }
+    return html`<div>${plot}${silencePanel(props.silenceApiUrl, data)}</div>`;
+}
+
+// firingAlertIds returns the alert instances whose latest status is warn, error, flapping or timeout; data is ordered by alert_id, timestamp.
+function firingAlertIds(data: QueryResult): string[] {
+    const latestStatus = new Map<string, string>();
+    for (const row of data.toArray() as any[]) {
+        latestStatus.set(row["alert_id"], row["status"]);
+    }
+    return [...latestStatus.entries()]
+        .filter(([, status]) => ['warn', 'error', 'flapping', 'timeout'].includes(status))
+        .map(([alertId]) => alertId);
+}
+
+async function callApi(url: string, method: string, body?: object): Promise<any> {
+    const response = await fetch(url, {
+        method,
+        headers: body ? {'Content-Type': 'application/json'} : undefined,
+        body: body ? JSON.stringify(body) : undefined,
+    });
+    if (!response.ok) {
+        throw new Error(`${method} ${url}: ${await response.text()}`);
+    }
+    return response.status === 204 ? null : response.json();
+}
+
+// silencePanel lists active silences / acknowledgements, and allows creating and expiring them.
+function silencePanel(apiUrl: string, data: QueryResult): HTMLElement {
+    const list = html`<ul class="dashica-alert-silences__list"></ul>` as HTMLElement;
+    const error = html`<div class="dashica-alert-silences__error"></div>` as HTMLElement;
+
+    const run = async (fn: () => Promise<any>) => {
+        error.textContent = '';
+        try {
+            await fn();
+            await refresh();
+        } catch (e: any) {
+            error.textContent = e.message;
+        }
+    };
+
+    const refresh = async () => {
+        const silences = await callApi(apiUrl + 'silences', 'GET') as Silence[];
+        list.replaceChildren(...silences.map((s) => html`<li>
+            <b>${s.kind === 'ack' ? 'Acknowledged' : 'Silenced'}</b>
+            ${[s.alert_id_group, s.alert_id_pattern].filter(Boolean).join(' ')}
+            (${s.starts_at} – ${s.ends_at ?? 'until OK'}, ${s.author}${s.comment ? ': ' + s.comment : ''})
+            <button type="button" onclick=${() => run(() => callApi(apiUrl + 'silences/' + encodeURIComponent(s.id), 'DELETE'))}>Expire</button>
+        </li>`));
+    };
+
+    const pattern = html`<input type="text" placeholder="alert id pattern, e.g. src/shop/alerts.yaml#*" size="40">` as HTMLInputElement;
+    const duration = html`<select>
+        <option value="1h">1 hour</option>
+        <option value="2h">2 hours</option>
+        <option value="8h">8 hours</option>
+        <option value="24h">1 day</option>
+    </select>` as HTMLSelectElement;
+    const comment = html`<input type="text" placeholder="comment">` as HTMLInputElement;
+    const createSilence = () => run(() => callApi(apiUrl + 'silences', 'POST', {
+        alert_id_pattern: pattern.value,
+        duration: duration.value,
+        comment: comment.value,
+    }));
+
+    const acknowledge = (alertId: string) => run(() => callApi(apiUrl + 'acks', 'POST', {alert_id: alertId}));
+
+    refresh().catch((e) => error.textContent = e.message);
+
+    return html`<div class="dashica-alert-silences">
+        ${firingAlertIds(data).map((alertId) => html`<div>
+            Firing: ${alertId} <button type="button" onclick=${() => acknowledge(alertId)}>Acknowledge</button>
+        </div>`)}
+        ${list}
+        <form onsubmit=${(e: Event) => { e.preventDefault(); createSilence(); }}>
+            ${pattern} ${duration} ${comment} <button type="submit">Silence</button>
+        </form>
+        ${error}
+    </div>` as HTMLElement;
 }
 
 export const alertOverview = _alertOverview;
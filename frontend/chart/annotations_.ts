@@ -0,0 +1,58 @@
+import * as Plot from "@observablehq/plot";
+import type {Markish} from "@observablehq/plot";
+import type {QueryResult} from "../types";
+
+// Annotation mirrors dashboard.Annotation (Go), loaded by components/dashboardAnnotations.js.
+export interface Annotation {
+    // UNIX milliseconds
+    time: number;
+    // UNIX milliseconds; null for a marker (a point in time)
+    end: number | null;
+    text: string;
+    tags: string[];
+}
+
+function annotationColor(a: Annotation): string {
+    if (a.tags?.includes('error')) return '#E74C3C';
+    if (a.tags?.includes('warn')) return '#F39C12';
+    return '#8E44AD';
+}
+
+/**
+ * annotationMarks draws the dashboard annotations (deployments, alert states, ...) on a time chart: a dashed vertical
+ * rule per marker, a translucent band per annotation with an end. Both show their text on hover.
+ *
+ * The annotations are clamped to the resolved time range of the query, so they do not widen the x domain.
+ */
+export function annotationMarks(annotations: Annotation[] | null | undefined, data: QueryResult): Markish[] {
+    if (!annotations || annotations.length === 0) {
+        return [];
+    }
+    const from = data.dashicaResolvedTimeRange?.from ?? -Infinity;
+    const to = data.dashicaResolvedTimeRange?.to ?? Infinity;
+    const visible = annotations.filter(a => a.time <= to && (a.end ?? a.time) >= from);
+
+    const markers = visible.filter(a => a.end == null);
+    const bands = visible
+        .filter(a => a.end != null)
+        .map(a => ({...a, time: Math.max(a.time, from), end: Math.min(a.end as number, to)}));
+
+    return [
+        Plot.rectX(bands, {
+            x1: "time",
+            x2: "end",
+            fill: annotationColor,
+            fillOpacity: 0.12,
+        }),
+        Plot.ruleX(markers, {
+            x: "time",
+            stroke: annotationColor,
+            strokeDasharray: "4,2",
+            strokeWidth: 1.5,
+        }),
+        Plot.tip([...markers, ...bands], Plot.pointerX({
+            x: "time",
+            title: (a: Annotation) => a.text + (a.tags?.length ? `\n${a.tags.join(', ')}` : ''),
+        })),
+    ];
+}
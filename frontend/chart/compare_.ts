@@ -0,0 +1,48 @@
+import type {QueryResult} from "../types";
+
+// see httpserver.ComparePeriodColumn
+export const PERIOD_COLUMN = 'dashica_period';
+
+/**
+ * splitPeriods splits the result of a comparing query (see QueryHandler.CompareOffset) into the rows of the selected
+ * time range and the rows of the previous period. The x values of the previous rows are shifted forward by the
+ * compare offset, so they line up with the current period on the same x axis.
+ *
+ * Without comparison, current is the unchanged query result and previous is empty.
+ */
+export function splitPeriods(data: QueryResult, x: string): {current: any, previous: any[]} {
+    const offset = data.dashicaCompareOffset ?? 0;
+    if (!offset) {
+        return {current: data, previous: []};
+    }
+
+    const current: any[] = [];
+    const previous: any[] = [];
+    for (const row of data.toArray()) {
+        const values = row.toJSON();
+        if (values[PERIOD_COLUMN] === 'previous') {
+            values[x] = Number(values[x]) + offset;
+            previous.push(values);
+        } else {
+            current.push(values);
+        }
+    }
+    return {current, previous};
+}
+
+/**
+ * compareLabel is the human-readable name of the previous period, for a compareWith offset like "1w".
+ */
+export function compareLabel(compareWith?: string): string {
+    switch (compareWith) {
+        case undefined:
+        case '':
+            return 'previous period';
+        case '1d':
+            return 'yesterday';
+        case '1w':
+            return 'last week';
+        default:
+            return `${compareWith} earlier`;
+    }
+}
@@ -0,0 +1,57 @@
+// Number formats shared by the Kpi and the Table (see widget.KpiUnit and Table.ColumnFormats).
+export type Unit = 'bytes' | 'percent' | 'ms' | 's';
+
+export function formatValue(value: number | null, unit?: Unit): string {
+    if (value == null || Number.isNaN(value)) {
+        return '–';
+    }
+    switch (unit) {
+        case 'bytes':
+            return formatBytes(value);
+        case 'percent':
+            return `${round(value * 100)} %`;
+        case 'ms':
+            return Math.abs(value) < 1000 ? `${round(value)} ms` : formatDuration(value / 1000);
+        case 's':
+            return formatDuration(value);
+        default:
+            return formatNumber(value);
+    }
+}
+
+export function formatNumber(value: number): string {
+    const prefixes = ['', 'k', 'M', 'G', 'T', 'P'];
+    let i = 0;
+    while (Math.abs(value) >= 1000 && i < prefixes.length - 1) {
+        value /= 1000;
+        i++;
+    }
+    return `${round(value)}${prefixes[i]}`;
+}
+
+function formatBytes(value: number): string {
+    const units = ['B', 'KiB', 'MiB', 'GiB', 'TiB', 'PiB'];
+    let i = 0;
+    while (Math.abs(value) >= 1024 && i < units.length - 1) {
+        value /= 1024;
+        i++;
+    }
+    return `${round(value)} ${units[i]}`;
+}
+
+function formatDuration(seconds: number): string {
+    if (Math.abs(seconds) < 60) {
+        return `${round(seconds)} s`;
+    }
+    if (Math.abs(seconds) < 3600) {
+        return `${round(seconds / 60)} min`;
+    }
+    if (Math.abs(seconds) < 86400) {
+        return `${round(seconds / 3600)} h`;
+    }
+    return `${round(seconds / 86400)} d`;
+}
+
+function round(value: number): number {
+    return Math.round(value * 100) / 100;
+}
@@ -0,0 +1,116 @@
+import * as Plot from "@observablehq/plot";
+import {html} from "htl";
+import type {QueryResult} from "../types";
+import {SchemaAnalyzer} from "../util/schema.js";
+import {compareLabel, PERIOD_COLUMN} from "./compare_";
+import {formatNumber, formatValue, type Unit} from "./format_";
+
+import './kpi.css';
+
+interface KpiProps {
+    title?: string;
+    height?: number;
+    value: string;
+    // the sparkline bucket column; the total is the ROLLUP row (bucket 1970-01-01)
+    x?: string;
+    xBucketSize?: number;
+    unit?: Unit;
+    suffix?: string;
+    compareWith?: string;
+    // sorted ascending by value
+    thresholds?: {value: number, color: string}[];
+}
+
+type Point = {x: number, value: number};
+
+async function _kpi(data: QueryResult, props: KpiProps): Promise<HTMLElement> {
+    const schema = new SchemaAnalyzer(data);
+    schema.requiredColumn(props.value, 'value');
+    if (props.x) {
+        schema.requiredColumn(props.x, 'x');
+    }
+
+    const rows = data.toArray() as any[];
+    const compareOffset = data.dashicaCompareOffset ?? 0;
+    const current = summarize(rows.filter(row => (row[PERIOD_COLUMN] ?? 'current') === 'current'), props);
+    const previous = compareOffset > 0
+        ? summarize(rows.filter(row => row[PERIOD_COLUMN] === 'previous'), props, compareOffset)
+        : null;
+
+    const color = thresholdColor(current.total, props.thresholds);
+    return html`<div class="dashica-kpi" style=${{minHeight: `${props.height ?? 150}px`}}>
+        ${props.title ? html`<div class="dashica-kpi__title">${props.title}</div>` : ''}
+        <div class="dashica-kpi__value" style=${color ? {color} : {}}>
+            ${formatValue(current.total, props.unit)}${props.suffix ? html`<span class="dashica-kpi__suffix">${props.suffix}</span>` : ''}
+        </div>
+        ${previous ? renderDelta(current.total, previous.total, props.compareWith) : ''}
+        ${props.x && current.points.length > 0 ? renderSparkline(current.points, previous?.points ?? [], color, data) : ''}
+    </div>`;
+}
+export const kpi = _kpi;
+
+// summarize splits the rows of one period into the total and the sparkline points. previous rows are shifted forward
+// by offset, so they line up with the current period.
+function summarize(rows: any[], props: KpiProps, offset = 0): {total: number | null, points: Point[]} {
+    if (!props.x) {
+        return {total: rows.length > 0 ? toNumber(rows[0][props.value]) : null, points: []};
+    }
+
+    let total: number | null = null;
+    const points: Point[] = [];
+    for (const row of rows) {
+        const x = row[props.x];
+        if (x == null || Number(x) === 0) {
+            total = toNumber(row[props.value]);
+            continue;
+        }
+        points.push({x: Number(x) + offset, value: toNumber(row[props.value]) ?? 0});
+    }
+    return {total, points};
+}
+
+function toNumber(value: any): number | null {
+    return value == null ? null : Number(value);
+}
+
+function thresholdColor(value: number | null, thresholds?: {value: number, color: string}[]): string | undefined {
+    if (value == null || !thresholds) {
+        return undefined;
+    }
+    let color = undefined;
+    for (const threshold of thresholds) {
+        if (value >= threshold.value) {
+            color = threshold.color;
+        }
+    }
+    return color;
+}
+
+function renderDelta(current: number | null, previous: number | null, compareWith?: string) {
+    const label = `vs. ${compareLabel(compareWith)}`;
+    if (current == null || previous == null || previous === 0) {
+        return html`<div class="dashica-kpi__delta">– ${label}</div>`;
+    }
+    const delta = (current - previous) / Math.abs(previous) * 100;
+    const arrow = delta > 0 ? '▲' : delta < 0 ? '▼' : '';
+    return html`<div class="dashica-kpi__delta" title=${`${formatNumber(previous)} ${label}`}>
+        ${arrow} ${Math.abs(delta).toFixed(1)} % ${label}
+    </div>`;
+}
+
+function renderSparkline(points: Point[], previousPoints: Point[], color: string | undefined, data: QueryResult) {
+    const domain = data.dashicaResolvedTimeRange?.from && data.dashicaResolvedTimeRange?.to
+        ? [data.dashicaResolvedTimeRange.from, data.dashicaResolvedTimeRange.to]
+        : undefined;
+    return Plot.plot({
+        height: 40,
+        margin: 2,
+        x: {type: "time", axis: null, domain},
+        y: {axis: null, zero: true},
+        marks: [
+            Plot.lineY(previousPoints, {x: "x", y: "value", stroke: "#9ca3af", strokeDasharray: "3,3"}),
+            Plot.areaY(points, {x: "x", y: "value", fill: color ?? "#4682B4", fillOpacity: 0.15}),
+            Plot.lineY(points, {x: "x", y: "value", stroke: color ?? "#4682B4"}),
+        ],
+    });
+}
@@ -1,9 +1,10 @@
 import {html} from "htl";
 import {DataType, Field} from "apache-arrow";
 import {TabulatorFull as Tabulator} from 'tabulator-tables';
-import type {ColumnDefinition, RowComponent, CellComponent} from 'tabulator-tables';
+import type {ColumnDefinition, RowComponent, CellComponent, Options} from 'tabulator-tables';
 import Alpine from '@alpinejs/csp';
 import {Maximize2, X, Pin, Copy, Braces, createElement} from 'lucide';
+import {formatValue} from './format_';
 import './table.css';
 
 const canvas = document.createElement('canvas');
@@ -96,6 +97,82 @@ function tryPrettyJson(raw: string): string | null {
     }
 }
 
+// Render a cell value in one of the formats of widget.Table.ColumnFormats. Returns nodes (not HTML strings), so values
+// are never interpreted as markup by Tabulator.
+function formatCell(value: any, format?: string): Node {
+    if (value == null) {
+        return document.createTextNode('');
+    }
+    switch (format) {
+        case 'timestamp': {
+            // ClickHouse DateTime is in seconds, JS Date expects milliseconds
+            // If value is a small number (< 10 billion), it's likely in seconds
+            const timestamp = value < 10000000000 ? value * 1000 : value;
+            const dt = new Date(timestamp);
+            const time = dt.toLocaleTimeString([], {
+                hour: '2-digit',
+                minute: '2-digit',
+                second: '2-digit',
+                hour12: false
+            });
+            const date = dt.toLocaleDateString([], {
+                day: '2-digit',
+                month: '2-digit',
+                year: '2-digit',
+            });
+            const el = document.createElement('div');
+            el.innerHTML = `${time} &nbsp; <span class="autoTable__timestampDate">${date}</span>`;
+            return el;
+        }
+        case 'json': {
+            const el = document.createElement('pre');
+            el.classList.add('autoTable__json');
+            el.textContent = tryPrettyJson(String(value)) ?? String(value);
+            return el;
+        }
+        case 'bytes':
+        case 'percent':
+        case 'ms':
+        case 's':
+            return document.createTextNode(formatValue(Number(value), format));
+        default:
+            return document.createTextNode(String(value));
+    }
+}
+
+// URL parameters which keep their meaning when following a link to another dashboard; all other parameters of a
+// relative link become widget params ("wp") of the target dashboard.
+const DASHBOARD_URL_PARAMS = ['time', 'range', 'refresh', 'sql', 'log', 'wp'];
+
+// Resolve a link template of widget.Table.ColumnLinks for a row: {column} placeholders are replaced by the (URL
+// encoded) values of the row. Relative links keep the current time range, so drilling down stays in the same period.
+function resolveLink(template: string, row: Record<string, any>): string {
+    const resolved = template.replace(/\{([^}]+)}/g, (_, column) => encodeURIComponent(String(row[column] ?? '')));
+    if (!resolved.startsWith('/') || resolved.startsWith('//')) {
+        return resolved;
+    }
+
+    const url = new URL(resolved, window.location.origin);
+    const widgetParams: Record<string, string> = {};
+    for (const [name, value] of [...url.searchParams.entries()]) {
+        if (!DASHBOARD_URL_PARAMS.includes(name) && !name.startsWith('var-')) {
+            widgetParams[name] = value;
+            url.searchParams.delete(name);
+        }
+    }
+    if (Object.keys(widgetParams).length > 0 && !url.searchParams.has('wp')) {
+        url.searchParams.set('wp', JSON.stringify(widgetParams));
+    }
+    const currentParams = new URLSearchParams(window.location.search);
+    for (const name of ['time', 'range']) {
+        const value = currentParams.get(name);
+        if (value !== null && !url.searchParams.has(name)) {
+            url.searchParams.set(name, value);
+        }
+    }
+    return url.pathname + url.search + url.hash;
+}
+
 // Colors cycled through for pinned tooltips + their source markers/rows.
 const PIN_COLORS = [
     '#2563eb', '#dc2626', '#16a34a', '#d97706',
@@ -461,7 +538,35 @@ export function table(queryResult: any, extProps: any) {
     props.width = props.width || {};
 
     // Convert Apache Arrow Table to plain JavaScript objects for Tabulator
-    const data = queryResult.toArray().map((row: any) => row.toJSON());
+    const toRows = (result: any) => result.toArray().map((row: any) => row.toJSON());
+    const data = toRows(queryResult);
+
+    // Server-side pagination (widget.Table.PageSize): the server returns one row more than a page if there is a next
+    // page; the first page (in the default order) is the initial query result.
+    const pageSize: number = props.pageSize || 0;
+    const dataOptions: Options = pageSize > 0 && props.fetchData ? {
+        pagination: true,
+        paginationMode: "remote",
+        paginationSize: pageSize,
+        sortMode: "remote",
+        ajaxURL: "dashica://table", // required by Tabulator, the data is loaded by ajaxRequestFunc
+        ajaxRequestFunc: async (url: string, config: any, params: any) => {
+            const page = params.page - 1;
+            const sorters = (params.sort || []).map((s: any) => ({field: s.field, dir: s.dir}));
+            let rows = data;
+            if (page > 0 || sorters.length > 0) {
+                const extraParams: Record<string, string> = {page: String(page)};
+                if (sorters.length > 0) {
+                    extraParams.sort = JSON.stringify(sorters);
+                }
+                rows = toRows(await props.fetchData(extraParams));
+            }
+            return {
+                last_page: rows.length > pageSize ? page + 2 : page + 1,
+                data: rows.slice(0, pageSize),
+            };
+        },
+    } : {data: data};
 
     // Create reactive state for search and selection
     const state = Alpine.reactive({
@@ -563,45 +668,42 @@ export function table(queryResult: any, extProps: any) {
         }
     ];
 
-    // Build columns with timestamp formatting
+    // Build columns with timestamp formatting and the column options of widget.Table
+    const columnFormats: Record<string, string> = props.columnFormats || {};
+    const cellColors: Record<string, string> = props.cellColors || {};
+    const columnLinks: Record<string, string> = props.columnLinks || {};
+    const hiddenColumns: string[] = props.hiddenColumns || [];
     const columns: ColumnDefinition[] = [];
     queryResult?.schema?.fields?.forEach((field: any) => {
+        if (hiddenColumns.includes(field.name)) {
+            return;
+        }
         const isTimestamp = DataType.isTimestamp(field) || field.name === 'timestamp';
-
-        if (isTimestamp) {
-            columns.push({
-                title: field.name,
-                field: field.name,
-                formatter: (cell: CellComponent) => {
-                    const value = cell.getValue();
-                    // ClickHouse DateTime is in seconds, JS Date expects milliseconds
-                    // If value is a small number (< 10 billion), it's likely in seconds
-                    const timestamp = value < 10000000000 ? value * 1000 : value;
-                    const dt = new Date(timestamp);
-                    const time = dt.toLocaleTimeString([], {
-                        hour: '2-digit',
-                        minute: '2-digit',
-                        second: '2-digit',
-                        hour12: false
-                    });
-                    const date = dt.toLocaleDateString([], {
-                        day: '2-digit',
-                        month: '2-digit',
-                        year: '2-digit',
-                    });
-                    const el = document.createElement('div');
-                    el.innerHTML = `${time} &nbsp; <span class="autoTable__timestampDate">${date}</span>`;
-                    return el;
-                },
-                contextMenu: timestampContextMenu(field.name)
-            });
-        } else {
-            columns.push({
-                title: field.name,
-                field: field.name,
-                contextMenu: generalContextMenu(field.name)
-            });
+        const format = columnFormats[field.name] || (isTimestamp ? 'timestamp' : undefined);
+
+        const column: ColumnDefinition = {
+            title: field.name,
+            field: field.name,
+            contextMenu: isTimestamp ? timestampContextMenu(field.name) : generalContextMenu(field.name)
+        };
+        if (format || cellColors[field.name] || columnLinks[field.name]) {
+            column.formatter = (cell: CellComponent) => {
+                const row = cell.getRow().getData();
+                let content = formatCell(cell.getValue(), format);
+                if (columnLinks[field.name] && cell.getValue() != null) {
+                    const link = document.createElement('a');
+                    link.href = resolveLink(columnLinks[field.name], row);
+                    link.classList.add('link');
+                    link.append(content);
+                    content = link;
+                }
+                if (cellColors[field.name]) {
+                    cell.getElement().style.backgroundColor = row[cellColors[field.name]] || '';
+                }
+                return content;
+            };
         }
+        columns.push(column);
     });
 
     // Create container structure
@@ -696,7 +798,7 @@ export function table(queryResult: any, extProps: any) {
     tabulatorTable = new Tabulator(tableRoot, {
         height: props.height,
         maxHeight: "100vh",
-        data: data,
+        ...dataOptions,
         layout: "fitData",
         columns: columns,
         movableColumns: true,
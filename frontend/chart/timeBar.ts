@@ -1,8 +1,10 @@
 import * as Plot from "@observablehq/plot";
-import type {ChannelValue, ChannelValueSpec, QueryResult, ViewOptions} from "../types";
+import type {AlertCondition, ChannelValue, ChannelValueSpec, QueryResult, ViewOptions} from "../types";
 //import {decorateChart} from "../component/decorateChart.js";
 import {SchemaAnalyzer} from "../util/schema";
 import {_brushMark} from "./timeBrush_.js";
+import {compareLabel, splitPeriods} from "./compare_";
+import {type Annotation, annotationMarks} from "./annotations_";
 import type {ScaleOptions} from "@observablehq/plot/src/scales";
 import type {Markish} from "@observablehq/plot";
 import type {TipOptions} from "@observablehq/plot/src/marks/tip";
@@ -86,6 +88,15 @@ interface ChartProps {
 
     /** Reverse the stacking order chosen by {@link order}. */
     reverse?: boolean;
+
+    /**
+     * The comparison offset (e.g. "1w"), if the query also returns the previous
+     * period (see compare_.ts). Its bucket totals are drawn as dashed line.
+     */
+    compareWith?: string;
+
+    /** The dashboard annotations (filled from components/chart.ts), see annotations_.ts. */
+    annotations?: Annotation[];
 }
 
 function _bars(data: QueryResult, props: ChartProps) {
@@ -116,6 +127,8 @@ function _bars(data: QueryResult, props: ChartProps) {
         numberOfUniqueXValues = Math.floor((data.dashicaResolvedTimeRange.to - data.dashicaResolvedTimeRange.from) / xBucketSize);
     }
 
+    const {current, previous} = splitPeriods(data, String(x));
+
     // @ts-ignore
     return Plot.plot({
         title: props.title,
@@ -143,7 +156,7 @@ function _bars(data: QueryResult, props: ChartProps) {
             legend: true,
         },
         marks: [
-            Plot.rectY(data, {
+            Plot.rectY(current, {
                 x1: x,
                 // @ts-ignore
                 x2: (d: any) => d[x] + xBucketSize,
@@ -162,16 +175,74 @@ function _bars(data: QueryResult, props: ChartProps) {
                 // HEURISTIC to determine whether to add an inset or not
                 insetLeft: numberOfUniqueXValues === undefined || !props.width || props.fx ? 0 : (numberOfUniqueXValues * 2 < props.width ? 1 : 0), // a bit of padding between the bars. - HEURISTIC
             }),
+            // the previous period: one dashed line through the middle of the buckets, summed over all fill series
+            previous.length > 0 && Plot.lineY(previous, Plot.groupX({y: "sum"}, {
+                // @ts-ignore
+                x: (d: any) => d[x] + xBucketSize / 2,
+                y: schema.requiredColumn(props.y, 'y'),
+                fx: props.fx,
+                fy: props.fy,
+                stroke: '#555',
+                strokeDasharray: '4,3',
+                clip: true,
+                channels: {period: () => compareLabel(props.compareWith)},
+                tip: true,
+            })),
             _brushMark,
             Plot.ruleY([0]),
             ...(props.extraMarks || []),
+            ...annotationMarks(props.annotations, data),
             // Alert threshold lines from X-Dashica-Alert-If header
-            data.dashicaAlertIf?.value_gt ? Plot.ruleY([data.dashicaAlertIf.value_gt], {stroke: "red", strokeWidth: 2}) : undefined,
-            data.dashicaAlertIf?.value_lt ? Plot.ruleY([data.dashicaAlertIf.value_lt], {stroke: "red", strokeWidth: 2}) : undefined,
+            ...alertThresholdMarks(data.dashicaAlertIf?.warn_if, "orange"),
+            ...alertThresholdMarks(data.dashicaAlertIf?.error_if, "red"),
+            ...dynamicAlertThresholdMarks(data, schema, x, xBucketSize),
         ].filter(Boolean)
     })
 }
 
+// dynamicAlertThresholdMarks draws the per-bucket thresholds computed by the alert SQL query
+// (alert_if_value_gt / alert_if_value_lt result columns) as horizontal segments above each bar.
+function dynamicAlertThresholdMarks(data: QueryResult, schema: SchemaAnalyzer, x: ChannelValueSpec, xBucketSize: number): Markish[] {
+    if (!data.dashicaAlertIf) {
+        return [];
+    }
+    return ["alert_if_value_gt", "alert_if_value_lt"]
+        .filter((column) => schema.hasColumn(column))
+        .map((column) => Plot.ruleY(data, {
+            y: column,
+            // @ts-ignore
+            x1: x,
+            // @ts-ignore
+            x2: (d: any) => d[x] + xBucketSize,
+            stroke: "red",
+            strokeWidth: 2,
+            strokeDasharray: "4,2",
+            filter: (d: any) => d[column] !== null && d[column] !== undefined,
+        }));
+}
+
+// alertThresholdMarks draws one horizontal rule per threshold value of an alert condition.
+function alertThresholdMarks(condition: AlertCondition | null | undefined, stroke: string): Markish[] {
+    if (!condition) {
+        return [];
+    }
+    const values: number[] = [];
+    for (const v of [condition.value_gt, condition.value_lt, condition.value_eq, condition.value_ne]) {
+        if (v !== null && v !== undefined) {
+            values.push(v);
+        }
+    }
+    for (const range of [condition.value_between, condition.value_outside]) {
+        if (range) {
+            values.push(range.min, range.max);
+        }
+    }
+    if (values.length === 0) {
+        return [];
+    }
+    return [Plot.ruleY(values, {stroke, strokeWidth: 2})];
+}
+
 
 //export const timeBar = decorateChart(_bars);
 export const timeBar = _bars;
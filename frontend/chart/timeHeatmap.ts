@@ -3,6 +3,7 @@ import type {ChannelValueSpec, QueryResult, ViewOptions} from "../types";
 //import {decorateChart} from "../component/decorateChart.js";
 import {SchemaAnalyzer} from "../util/schema.js";
 import {_brushMark} from "./timeBrush_.js";
+import {type Annotation, annotationMarks} from "./annotations_";
 //import {Generators} from "observablehq:stdlib";
 
 interface ChartProps {
@@ -32,6 +33,7 @@ interface ChartProps {
 
     // filled from components/chart.ts
     colorSchemeDark: boolean,
+    annotations?: Annotation[],
 }
 
 
@@ -81,7 +83,8 @@ async function _heatmap(data: QueryResult, props: ChartProps) {
                 fill: props.fill ? (d: any) => Number(d[props.fill as string]) : undefined,
             }),
             _brushMark,
-            Plot.ruleY([0])
+            Plot.ruleY([0]),
+            ...annotationMarks(props.annotations, data),
         ]
     })
 }
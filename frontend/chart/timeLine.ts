@@ -2,6 +2,8 @@ import * as Plot from "@observablehq/plot";
 import type {ChannelValue, ChannelValueSpec, QueryResult, ViewOptions} from "../types";
 import {SchemaAnalyzer} from "../util/schema";
 import {_brushMark} from "./timeBrush_.js";
+import {compareLabel, splitPeriods} from "./compare_";
+import {type Annotation, annotationMarks} from "./annotations_";
 import type {ScaleOptions} from "@observablehq/plot/src/scales";
 import type {Markish} from "@observablehq/plot";
 import type {TipOptions} from "@observablehq/plot/src/marks/tip";
@@ -29,6 +31,10 @@ interface ChartProps {
     fy?: ChannelValue;
     tip?: boolean | TipPointer | (TipOptions & PointerOptions & {pointer?: TipPointer});
     extraMarks?: Markish[];
+    // the comparison offset (e.g. "1w"), if the query also returns the previous period (see compare_.ts)
+    compareWith?: string;
+    // the dashboard annotations (filled from components/chart.ts), see annotations_.ts
+    annotations?: Annotation[];
 }
 
 export function timeLine(data: QueryResult, props: ChartProps) {
@@ -53,6 +59,8 @@ export function timeLine(data: QueryResult, props: ChartProps) {
         domain = [data.dashicaResolvedTimeRange.from, data.dashicaResolvedTimeRange.to];
     }
 
+    const {current, previous} = splitPeriods(data, String(x));
+
     return Plot.plot({
         title: props.title,
         height: props.height,
@@ -78,7 +86,20 @@ export function timeLine(data: QueryResult, props: ChartProps) {
             legend: true,
         },
         marks: [
-            Plot.line(data, {
+            previous.length > 0 && Plot.line(previous, {
+                x: x,
+                y: y,
+                z: props.z,
+                fx: props.fx,
+                fy: props.fy,
+                stroke: props.stroke || '#4682B4',
+                strokeDasharray: '4,3',
+                strokeOpacity: 0.6,
+                clip: true,
+                channels: {period: () => compareLabel(props.compareWith)},
+                tip: true,
+            }),
+            Plot.line(current, {
                 x: x,
                 y: y,
                 z: props.z,
@@ -95,6 +116,7 @@ export function timeLine(data: QueryResult, props: ChartProps) {
             _brushMark,
             Plot.ruleY([0]),
             ...(props.extraMarks || []),
+            ...annotationMarks(props.annotations, data),
         ].filter(Boolean),
     });
 }
@@ -7,9 +7,10 @@ import {barHorizontal} from '../chart/barHorizontal'
 import {timeHeatmap} from '../chart/timeHeatmap'
 import {timeHeatmapOrdinal} from '../chart/timeHeatmapOrdinal'
 import {stats} from '../chart/stats'
+import {kpi} from '../chart/kpi'
 import {table} from '../chart/table'
 import {alertOverview} from '../chart/alertOverview'
-import {query, queryPost} from "./util/clickhouse-new";
+import {exportUrl, query, queryPost} from "./util/clickhouse-new";
 import {getCombinedFilter, resolveScope} from "../store";
 
 // Exported so the Explore preview (frontend/explore) can render a widget from a
@@ -22,6 +23,7 @@ export const charts = {
     timeHeatmap,
     timeHeatmapOrdinal,
     stats,
+    kpi,
     table,
     alertOverview,
 }
@@ -30,6 +32,7 @@ Alpine.data('chart', () => ({
 
     _visible: false,
     _queryResult: null,
+    _fetchData: null,
     _debugInfo: null,
     _colorSchemeDark: false,
     _width: 0,
@@ -82,10 +85,14 @@ Alpine.data('chart', () => ({
             this._isLoading = true;
             try {
                 const filter = getCombinedFilter(this.$el);
-                const wp = resolveScope(this.$el)?.widgetParams ?? {};
-                this._queryResult = this._previewBase
-                    ? await queryPost(this._previewBase + "/query", this._previewBody, filter, wp)
-                    : await query(widgetBaseUrl + "/query", filter, wp)
+                const scope = resolveScope(this.$el);
+                const wp = scope?.widgetParams ?? {};
+                const vars = scope?.variables ?? {};
+                // re-runs the query with additional parameters, e.g. the page of a paginated table (see chart/table.ts)
+                this._fetchData = (extraParams: Record<string, string>) => this._previewBase
+                    ? queryPost(this._previewBase + "/query", this._previewBody, filter, wp, vars, extraParams)
+                    : query(widgetBaseUrl + "/query", filter, wp, vars, extraParams);
+                this._queryResult = await this._fetchData({});
             } catch (e) {
                 this.$refs.chartContainer.innerHTML = `<b>ERROR: ${e.message} (chart type: ${chartType})</b>`;
                 throw e
@@ -98,7 +105,9 @@ Alpine.data('chart', () => ({
             try {
                 if (this._queryResult) {
                     const viewOptions = this.$store.timeState.logScale ? ['VIEW_LOGARITHMIC'] : [];
-                    const finalChartProps = {...chartProps, width: this._width, colorSchemeDark: this._colorSchemeDark, viewOptions};
+                    // drawn by the time charts, see chart/annotations_.ts
+                    const annotations = resolveScope(this.$el)?.annotations ?? [];
+                    const finalChartProps = {...chartProps, width: this._width, colorSchemeDark: this._colorSchemeDark, viewOptions, annotations, fetchData: this._fetchData};
                     const chart = await charts[chartType](this._queryResult, finalChartProps);
                     this.$refs.chartContainer.innerHTML = '';
                     this.$refs.chartContainer.appendChild(chart);
@@ -118,7 +127,8 @@ Alpine.data('chart', () => ({
     async toggleDebug() {
         try {
             const qs = "?" + new URLSearchParams({
-                filters: JSON.stringify(getCombinedFilter(this.$el))
+                filters: JSON.stringify(getCombinedFilter(this.$el)),
+                vars: JSON.stringify(resolveScope(this.$el)?.variables ?? {}),
             });
             const response = this._previewBase
                 ? await fetch(this._previewBase + "/debug" + qs, {
@@ -147,6 +157,18 @@ Alpine.data('chart', () => ({
         }))
     },
 
+    // download streams the widget data with the current filters from the export endpoint; the file name is set by
+    // the server (Content-Disposition), so the page is not left.
+    download(format: 'csv' | 'parquet' | 'jsonl') {
+        const scope = resolveScope(this.$el);
+        const link = document.createElement('a');
+        link.href = exportUrl(this._widgetBaseUrl, format, getCombinedFilter(this.$el), scope?.widgetParams ?? {}, scope?.variables ?? {});
+        link.download = '';
+        document.body.appendChild(link);
+        link.click();
+        link.remove();
+    },
+
     handleResize(width: number, height: number) {
         this._width = width;
     }
@@ -0,0 +1,30 @@
+import Alpine from '@alpinejs/csp';
+import { getCombinedFilter, resolveScope } from '../store';
+
+// dashboardAnnotations loads the annotations of a dashboard (see lib/dashboard/annotations.go) whenever the time
+// range or the variables change, and stores them in the filter scope; the time charts draw them (chart/annotations_.ts).
+export default () => ({
+    init() {
+        const url = this.$el.dataset.url;
+        const scope = resolveScope(this.$el);
+        if (!scope) return;
+
+        Alpine.effect(async () => {
+            const filter = getCombinedFilter(this.$el);
+            const params = new URLSearchParams({
+                filters: JSON.stringify(filter),
+                vars: JSON.stringify(scope.variables ?? {}),
+            });
+            try {
+                const response = await fetch(url + '?' + params.toString());
+                if (!response.ok) {
+                    throw new Error(await response.text());
+                }
+                scope.setAnnotations(await response.json());
+            } catch (e) {
+                console.error('Failed to load annotations:', e);
+                scope.setAnnotations([]);
+            }
+        });
+    },
+});
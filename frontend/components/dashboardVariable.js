@@ -0,0 +1,84 @@
+import Alpine from '@alpinejs/csp';
+import { resolveScope } from '../store';
+
+// dashboardVariable is a dashboard template variable (see lib/dashboard/variables.go): a dropdown whose options are
+// loaded from the server, bound to the filter scope's variables[name]. Charts send all variables with their queries.
+export default () => ({
+    name: '',
+    options: [],
+    _optionsUrl: '',
+    _dependsOn: [],
+    _scope: null,
+
+    init() {
+        this.name = this.$el.dataset.name;
+        this._optionsUrl = this.$el.dataset.optionsUrl;
+        this._scope = resolveScope(this.$el);
+        try {
+            this._dependsOn = JSON.parse(this.$el.dataset.dependsOn || '[]');
+        } catch {
+            this._dependsOn = [];
+        }
+        let defaults = [];
+        try {
+            defaults = JSON.parse(this.$el.dataset.default || '[]');
+        } catch {}
+        // Seed the default selection synchronously (the URL state was already loaded by the scope), so charts
+        // that fire on first paint already see the variable.
+        if (this._scope && this._scope.variables[this.name] === undefined) {
+            this._scope.setVariable(this.name, defaults);
+        }
+
+        // (re-)load the options whenever a variable this one depends on changes; the effect fires on every variable
+        // change (setVariable replaces the whole map), so compare the relevant values.
+        let loadedFor = null;
+        Alpine.effect(() => {
+            const vars = {};
+            for (const dependency of this._dependsOn) {
+                vars[dependency] = this._scope?.variables[dependency] ?? [];
+            }
+            const key = JSON.stringify(vars);
+            if (key === loadedFor) return;
+            loadedFor = key;
+            this._loadOptions(vars);
+        });
+    },
+
+    async _loadOptions(vars) {
+        const params = new URLSearchParams();
+        if (Object.keys(vars).length > 0) {
+            params.set('vars', JSON.stringify(vars));
+        }
+        try {
+            const response = await fetch(this._optionsUrl + '?' + params.toString());
+            if (!response.ok) {
+                throw new Error(await response.text());
+            }
+            this.options = await response.json();
+        } catch (e) {
+            console.error(`Failed to load options of variable ${this.name}:`, e);
+            this.options = [];
+            return;
+        }
+
+        // drop selected values which are no longer offered (e.g. a project of the previously selected customer)
+        if (this._dependsOn.length > 0 && this._scope) {
+            const selected = this._scope.variables[this.name] ?? [];
+            const stillOffered = selected.filter(v => this.options.includes(v));
+            if (stillOffered.length !== selected.length) {
+                this._scope.setVariable(this.name, stillOffered);
+            }
+        }
+    },
+
+    isSelected(option) {
+        return (this._scope?.variables[this.name] ?? []).includes(option);
+    },
+
+    select(event) {
+        const values = Array.from(event.target.selectedOptions)
+            .map(option => option.value)
+            .filter(value => value !== '');
+        this._scope?.setVariable(this.name, values);
+    },
+});
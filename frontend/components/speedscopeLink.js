@@ -8,13 +8,18 @@ export default () => ({
     },
 
     href() {
-        const wp = resolveScope(this.$el)?.widgetParams ?? {};
+        const scope = resolveScope(this.$el);
+        const wp = scope?.widgetParams ?? {};
+        const vars = scope?.variables ?? {};
         const f = getCombinedFilter(this.$el);
         const u = new URLSearchParams();
         u.set('filters', JSON.stringify(f));
         if (Object.keys(wp).length > 0) {
             u.set('params', JSON.stringify(wp));
         }
+        if (Object.keys(vars).length > 0) {
+            u.set('vars', JSON.stringify(vars));
+        }
         const target = window.location.origin + this.widgetBaseUrl + '/speedscope-query?' + u.toString();
         return window.location.origin + this.widgetBaseUrl + '/viewer/#profileURL=' + encodeURIComponent(target);
     },
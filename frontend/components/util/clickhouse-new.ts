@@ -13,17 +13,27 @@ export type ClickhouseSchema = {
 }
 
 
-function filterParams(filters: any, widgetParams?: Record<string, string>): string {
-    const params = new URLSearchParams();
+function filterParams(filters: any, widgetParams?: Record<string, string>, variables?: Record<string, string[]>, extraParams?: Record<string, string>): string {
+    // e.g. the page of a paginated table
+    const params = new URLSearchParams(extraParams);
     if (filters) {
         params.append("filters", JSON.stringify(filters));
     }
     if (widgetParams && Object.keys(widgetParams).length > 0) {
         params.append("params", JSON.stringify(widgetParams));
     }
+    // dashboard variables, passed to ClickHouse as {name:Array(String)} parameters
+    if (variables && Object.keys(variables).length > 0) {
+        params.append("vars", JSON.stringify(variables));
+    }
     return params.toString();
 }
 
+// exportUrl is the download URL of the widget data (see QueryHandler.HandleExport), with the same filters as query().
+export function exportUrl(widgetBaseUrl: string, format: 'csv' | 'parquet' | 'jsonl', filters: any, widgetParams?: Record<string, string>, variables?: Record<string, string[]>): string {
+    return widgetBaseUrl + "/export?format=" + format + "&" + filterParams(filters, widgetParams, variables);
+}
+
 async function parseQueryResponse(response: Response): Promise<QueryResult> {
     if (response.status !== 200) {
         throw new Error(await response.text());
@@ -34,21 +44,25 @@ async function parseQueryResponse(response: Response): Promise<QueryResult> {
     if (xBucketSize != null) {
         result.dashicaBucketSize = parseInt(xBucketSize);
     }
+    const compareOffset = response.headers.get("X-Dashica-Compare-Offset")
+    if (compareOffset != null) {
+        result.dashicaCompareOffset = parseInt(compareOffset);
+    }
     result.clickhouseSummary = JSON.parse(response.headers.get("X-Clickhouse-Summary") || "null");
     result.dashicaAlertIf = JSON.parse(response.headers.get("X-Dashica-Alert-If") || "null");
     return result;
 }
 
-export async function query(baseUrl: string, filters: any, widgetParams?: Record<string, string>): Promise<QueryResult> {
-    const response = await fetch(baseUrl + "?" + filterParams(filters, widgetParams));
+export async function query(baseUrl: string, filters: any, widgetParams?: Record<string, string>, variables?: Record<string, string[]>, extraParams?: Record<string, string>): Promise<QueryResult> {
+    const response = await fetch(baseUrl + "?" + filterParams(filters, widgetParams, variables, extraParams));
     return parseQueryResponse(response);
 }
 
 // queryPost is query() for the Explore preview: the widget is described in the
 // POST body (a widget envelope) instead of being baked into a compiled /query
 // endpoint. Response format is identical, so the same chart renderer consumes it.
-export async function queryPost(baseUrl: string, body: string, filters: any, widgetParams?: Record<string, string>): Promise<QueryResult> {
-    const response = await fetch(baseUrl + "?" + filterParams(filters, widgetParams), {
+export async function queryPost(baseUrl: string, body: string, filters: any, widgetParams?: Record<string, string>, variables?: Record<string, string[]>, extraParams?: Record<string, string>): Promise<QueryResult> {
+    const response = await fetch(baseUrl + "?" + filterParams(filters, widgetParams, variables, extraParams), {
         method: "POST",
         headers: {"Content-Type": "application/json"},
         body,
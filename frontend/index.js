@@ -11,6 +11,8 @@ import filterScope from './components/filterScope'
 import searchBar from './components/searchBar'
 import textInput from './components/textInput'
 import checkboxGroup from './components/checkboxGroup'
+import dashboardVariable from './components/dashboardVariable'
+import dashboardAnnotations from './components/dashboardAnnotations'
 import speedscopeLink from './components/speedscopeLink'
 import "./store"
 import "./components/chart";
@@ -30,6 +32,8 @@ Alpine.data('filterScope', filterScope);
 Alpine.data('searchBar', searchBar);
 Alpine.data('textInput', textInput);
 Alpine.data('checkboxGroup', checkboxGroup);
+Alpine.data('dashboardVariable', dashboardVariable);
+Alpine.data('dashboardAnnotations', dashboardAnnotations);
 Alpine.data('speedscopeLink', speedscopeLink);
 Alpine.data('favorites', favorites);
 Alpine.data('sidebarSearch', sidebarSearch);
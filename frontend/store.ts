@@ -14,7 +14,7 @@ function debounce(func, timeout = 300){
 // The URL is split between two owners that touch DISJOINT keys, so each can
 // read-modify-write the same URLSearchParams without clobbering the other:
 //   - timeState  (global):      time / range / refresh / log
-//   - FilterScope (per-scope):  sql / wp
+//   - FilterScope (per-scope):  sql / wp / var-<name>
 // A commit only pushes a history entry when the string actually changed, so
 // the initial-load effects produce zero spurious entries.
 // ---------------------------------------------------------------------------
@@ -138,11 +138,17 @@ Alpine.store('timeState', {
 export interface FilterScope {
     sqlFilter: string;
     widgetParams: Record<string, string>;
+    // dashboard variables (see lib/dashboard/variables.go): name -> selected values
+    variables: Record<string, string[]>;
+    // dashboard annotations (see lib/dashboard/annotations.go), drawn on the time charts
+    annotations: any[];
     setSqlFilter(value: string): void;
     clearSqlFilter(): void;
     addFilter(queryPart: string): void;
     setWidgetParam(name: string, value: string): void;
     getWidgetParam(name: string, fallback?: string): string;
+    setVariable(name: string, values: string[]): void;
+    setAnnotations(annotations: any[]): void;
 }
 
 const scopeRegistry = new WeakMap<Element, FilterScope>();
@@ -164,6 +170,8 @@ export function createFilterScope(root: HTMLElement, opts: {syncUrl?: boolean} =
     const scope: FilterScope = Alpine.reactive({
         sqlFilter: '',
         widgetParams: {} as Record<string, string>,
+        variables: {} as Record<string, string[]>,
+        annotations: [] as any[],
 
         setSqlFilter(value: string) {
             this.sqlFilter = value;
@@ -184,6 +192,13 @@ export function createFilterScope(root: HTMLElement, opts: {syncUrl?: boolean} =
             const v = this.widgetParams[name];
             return v === undefined ? fallback : v;
         },
+        setVariable(name: string, values: string[]) {
+            // Replace the whole map (like setWidgetParam), so charts re-query.
+            this.variables = {...this.variables, [name]: values};
+        },
+        setAnnotations(annotations: any[]) {
+            this.annotations = annotations;
+        },
     });
 
     scopeRegistry.set(root, scope);
@@ -207,6 +222,7 @@ export function createFilterScope(root: HTMLElement, opts: {syncUrl?: boolean} =
                 } else {
                     p.delete('wp');
                 }
+                _writeVariables(p, scope.variables);
             });
         }, 200);
 
@@ -214,6 +230,7 @@ export function createFilterScope(root: HTMLElement, opts: {syncUrl?: boolean} =
             scope.sqlFilter;
             // deep-read so we re-fire when individual entries change
             JSON.stringify(scope.widgetParams);
+            JSON.stringify(scope.variables);
             debouncedUpdateUrl();
         });
     }
@@ -234,6 +251,32 @@ function _loadScopeFromUrl(scope: FilterScope) {
     } else {
         scope.widgetParams = {};
     }
+
+    const variables: Record<string, string[]> = {};
+    for (const key of new Set(params.keys())) {
+        if (key.startsWith(VARIABLE_URL_PREFIX)) {
+            // an empty selection is stored as a single empty value, so it does not fall back to the default
+            variables[key.slice(VARIABLE_URL_PREFIX.length)] = params.getAll(key).filter(v => v !== '');
+        }
+    }
+    scope.variables = variables;
+}
+
+// Dashboard variables are stored as repeated var-<name> keys, e.g. ?var-customer=acme&var-customer=globex.
+const VARIABLE_URL_PREFIX = 'var-';
+
+function _writeVariables(params: URLSearchParams, variables: Record<string, string[]>) {
+    for (const key of [...params.keys()]) {
+        if (key.startsWith(VARIABLE_URL_PREFIX)) params.delete(key);
+    }
+    for (const [name, values] of Object.entries(variables)) {
+        if (values.length === 0) {
+            params.append(VARIABLE_URL_PREFIX + name, '');
+        }
+        for (const value of values) {
+            params.append(VARIABLE_URL_PREFIX + name, value);
+        }
+    }
 }
 
 // getCombinedFilter merges the global time window with the nearest filter
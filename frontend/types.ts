@@ -12,6 +12,17 @@ import type {Table} from "apache-arrow";
 type ViewOption = 'VIEW_LOGARITHMIC';
 export type ViewOptions = ViewOption[];
 
+// AlertCondition mirrors alerting.AlertCondition (Go); see alerts.yaml error_if / warn_if.
+export type AlertCondition = {
+    value_gt?: number|null
+    value_lt?: number|null
+    value_eq?: number|null
+    value_ne?: number|null
+    value_between?: {min: number, max: number}|null
+    value_outside?: {min: number, max: number}|null
+    dynamic?: boolean
+}
+
 type QueryResultMetadata = {
     // the servers opinion about the selected time range (if any)
     dashicaResolvedTimeRange?: {
@@ -22,11 +33,15 @@ type QueryResultMetadata = {
     // the servers opinion about the bucket size (if any)
     dashicaBucketSize?: number|null
 
+    // the offset (in ms) of the previous period, if the widget compares with it; rows of the previous period have
+    // dashica_period = 'previous' and their x shifted back by this offset.
+    dashicaCompareOffset?: number|null
+
     clickhouseSummary?: any
 
     dashicaAlertIf?: {
-        value_gt?: number|null
-        value_lt?: number|null
+        error_if?: AlertCondition|null
+        warn_if?: AlertCondition|null
     }
 
     dashicaDevmode?: boolean
@@ -27,6 +27,10 @@ export class SchemaAnalyzer {
 
         return property;
     }
+
+    hasColumn(name: string): boolean {
+        return this.columnNames.includes(name);
+    }
 }
 
 
@@ -4,8 +4,11 @@ import (
 	"bufio"
 	"fmt"
 	"io/fs"
+	"maps"
+	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/goccy/go-yaml"
 	"github.com/sandstorm/dashica/lib/util"
@@ -13,6 +16,12 @@ import (
 
 // AlertConfiguration corresponds to a full alerts.yaml file.
 type AlertConfiguration struct {
+	// Notify is the default for AlertDefinition.Notify of all alerts in this file.
+	Notify []string `json:"notify"`
+	// ClickHouse is the default for AlertDefinition.ClickHouse of all alerts in this file.
+	ClickHouse string `json:"clickhouse"`
+	// Params are merged into AlertDefinition.Params of all alerts in this file (the alert's params win).
+	Params map[string]string           `json:"params"`
 	Alerts map[string]*AlertDefinition `json:"alerts"`
 }
 
@@ -24,48 +33,283 @@ type AlertDefinition struct {
 	Query string
 	// QueryBucketExpression contains the part after "--BUCKET:" in the SQL file (needed for batch alert evaluation)
 	QueryBucketExpression string
-	Params                map[string]string `json:"params"`
-	AlertIf               AlertCondition    `json:"alert_if"`
-	Message               string            `json:"message"`
+	// Params are passed as query parameters to the SQL query. Values may reference ${env:NAME} (environment
+	// variables) and ${config:NAME} (alerting.params in dashica_config.yaml); see resolveReferences.
+	Params map[string]string `json:"params"`
+	// ClickHouse is the server alias (key of "clickhouse" in dashica_config.yaml) the query runs on; see
+	// ClickHouseServer.
+	ClickHouse string `json:"clickhouse"`
+	// AlertIf is the legacy name of ErrorIf; only one of both may be set in alerts.yaml.
+	AlertIf AlertCondition `json:"alert_if"`
+	// ErrorIf moves the alert into AlertStateError when it matches.
+	ErrorIf AlertCondition `json:"error_if"`
+	// WarnIf moves the alert into AlertStateWarn when it matches (and ErrorIf does not).
+	WarnIf  AlertCondition `json:"warn_if"`
+	Message string         `json:"message"`
+	// WarnMessage is sent for AlertStateWarn; if empty, Message is used.
+	WarnMessage string `json:"warn_message"`
+	// For is the duration (e.g. "10m") error_if / warn_if must hold before the alert fires; until then, it is
+	// AlertStatePending.
+	For string `json:"for"`
+	// Consecutive is the number of consecutive evaluations error_if / warn_if must hold before the alert fires;
+	// until then, it is AlertStatePending. Can be combined with For (then both must be fulfilled).
+	Consecutive int `json:"consecutive"`
+	// RecoverIf adds hysteresis: a firing alert only becomes less severe once the value matches RecoverIf
+	// (e.g. error_if value_gt: 90, recover_if value_lt: 80).
+	RecoverIf AlertCondition `json:"recover_if"`
+	// Flapping enables flap detection: an alert changing its state too often is AlertStateFlapping instead of
+	// notifying on every change.
+	Flapping FlapDetection `json:"flapping"`
+	// GroupBy lists result columns (e.g. host_name) which identify an alert instance. If set, the query may return
+	// one row per instance (and bucket), and every instance is tracked, persisted and notified on its own.
+	GroupBy []string `json:"group_by"`
 	// The gronx CRON expression in which the query should be re-executed
 	CheckEvery string `json:"check_every"`
+	// Timeout (e.g. "10s") limits a single evaluation of the alert query; if exceeded, the alert is
+	// AlertStateTimeout. Defaults to alerting.evaluation_timeout from dashica_config.yaml.
+	Timeout string `json:"timeout"`
 	// Slack channel to alert to
 	SlackChannel string `json:"slack_channel"`
+	// Notify lists the names of the notifiers (see alerting.notifiers in dashica_config.yaml) to send state
+	// changes to. Defaults to the file-level "notify", and then to DefaultNotifierName.
+	Notify []string `json:"notify"`
 }
 
-// AlertId identifies an alert definition uniquely.
+// AlertId identifies an alert definition uniquely; and with Labels set, a single alert instance of a
+// definition with group_by.
 type AlertId struct {
 	// Group is the folder name leading to alerts.yml.
 	Group string
 	// Key is the key in the AlertConfiguration config
 	Key string
+	// Labels is the canonical (URL-encoded, sorted by key) form of the group_by column values of an alert
+	// instance, e.g. "customer_tenant=acme&host_name=web1". Empty for alerts without group_by.
+	Labels string
 }
 
 func AlertIdFromString(in string) AlertId {
 	parts := strings.SplitN(in, "#", 2)
-	return AlertId{
+	keyAndLabels := strings.SplitN(parts[1], "?", 2)
+	id := AlertId{
 		Group: parts[0],
-		Key:   parts[1],
+		Key:   keyAndLabels[0],
+	}
+	if len(keyAndLabels) == 2 {
+		id.Labels = keyAndLabels[1]
 	}
+	return id
 }
 
 func (id AlertId) String() string {
+	if id.Labels != "" {
+		return fmt.Sprintf("%s#%s?%s", id.Group, id.Key, id.Labels)
+	}
 	return fmt.Sprintf("%s#%s", id.Group, id.Key)
 }
 
-// AlertCondition defines when a single alert (defined by AlertDefinition) triggers.
+// DefinitionId returns the id of the AlertDefinition an alert instance belongs to (i.e. without Labels).
+func (id AlertId) DefinitionId() AlertId {
+	return AlertId{
+		Group: id.Group,
+		Key:   id.Key,
+	}
+}
+
+// WithLabels returns the id of the alert instance identified by labels.
+func (id AlertId) WithLabels(labels map[string]string) AlertId {
+	values := url.Values{}
+	for k, v := range labels {
+		values.Set(k, v)
+	}
+	id.Labels = values.Encode()
+	return id
+}
+
+// LabelValues decodes Labels.
+func (id AlertId) LabelValues() map[string]string {
+	labels := make(map[string]string)
+	values, _ := url.ParseQuery(id.Labels)
+	for k := range values {
+		labels[k] = values.Get(k)
+	}
+	return labels
+}
+
+// ErrorCondition returns the condition which leads to AlertStateError; this is ErrorIf, or the legacy AlertIf
+// if ErrorIf is not set.
+func (d AlertDefinition) ErrorCondition() AlertCondition {
+	if d.ErrorIf.IsEmpty() {
+		return d.AlertIf
+	}
+	return d.ErrorIf
+}
+
+// WarnMessageOrDefault returns the message to use for AlertStateWarn.
+func (d AlertDefinition) WarnMessageOrDefault() string {
+	if d.WarnMessage != "" {
+		return d.WarnMessage
+	}
+	return d.Message
+}
+
+// ClickHouseServer returns the server alias the alert query runs on ("default" if not configured).
+func (d AlertDefinition) ClickHouseServer() string {
+	if d.ClickHouse == "" {
+		return "default"
+	}
+	return d.ClickHouse
+}
+
+// forDuration returns For as duration (it is validated in ParseAlertConfiguration).
+func (d AlertDefinition) forDuration() time.Duration {
+	duration, _ := time.ParseDuration(d.For)
+	return duration
+}
+
+// timeout returns Timeout as duration (it is validated in ParseAlertConfiguration), or defaultTimeout if not set.
+func (d AlertDefinition) timeout(defaultTimeout time.Duration) time.Duration {
+	if timeout, err := time.ParseDuration(d.Timeout); err == nil {
+		return timeout
+	}
+	return defaultTimeout
+}
+
+// messageFor returns the message of the given (firing) state.
+func (d AlertDefinition) messageFor(state string) string {
+	if state == AlertStateWarn {
+		return d.WarnMessageOrDefault()
+	}
+	return d.Message
+}
+
+// FlapDetection configures flap detection of an AlertDefinition; it is disabled if MaxChanges is 0.
+type FlapDetection struct {
+	// Window is the duration (e.g. "1h") in which state changes are counted.
+	Window string `json:"window"`
+	// MaxChanges is the number of state changes within Window from which on the alert is flapping. It stops flapping
+	// once at most half of MaxChanges state changes happened within Window.
+	MaxChanges int `json:"max_changes"`
+}
+
+func (f FlapDetection) IsEnabled() bool {
+	return f.MaxChanges > 0
+}
+
+func (f FlapDetection) window() time.Duration {
+	window, _ := time.ParseDuration(f.Window)
+	return window
+}
+
+func (f FlapDetection) validate() error {
+	if !f.IsEnabled() {
+		return nil
+	}
+	window, err := time.ParseDuration(f.Window)
+	if err != nil {
+		return fmt.Errorf("window: %w", err)
+	}
+	if window <= 0 {
+		return fmt.Errorf("window must be positive")
+	}
+	if f.MaxChanges < 2 {
+		return fmt.Errorf("max_changes must be at least 2")
+	}
+	return nil
+}
+
+// AlertThresholds is the JSON structure sent to the frontend (X-Dashica-Alert-If header), so that charts can draw
+// the threshold lines of both levels.
+type AlertThresholds struct {
+	ErrorIf AlertCondition `json:"error_if"`
+	WarnIf  AlertCondition `json:"warn_if"`
+}
+
+func (d AlertDefinition) Thresholds() AlertThresholds {
+	return AlertThresholds{
+		ErrorIf: d.ErrorCondition(),
+		WarnIf:  d.WarnIf,
+	}
+}
+
+// AlertCondition defines when a single alert level (error_if / warn_if of an AlertDefinition) triggers.
+//
+// The value is taken from the result set:
+// - if 0 result rows returned, this counts as "0". Value is compared.
+// - if 1 result row returned, value is compared.
+// - if >1 result rows returned, error.
+//
+// If multiple comparisons are configured in one condition, the condition matches if ANY of them matches
+// (e.g. value_gt: 90 + value_lt: 10 triggers outside of [10, 90]).
 type AlertCondition struct {
-	// ValueGt
-	// - if 0 result rows returned, this counts as "0". Value is compared.
-	// - if 1 result row returned, value is compared.
-	// - if >1 result rows returned, error.
-	ValueGt *float64 `json:"value_gt"` // TODO: how to handle warning level??
-	// TODO: LATERValueGtDynamic    *bool `json:"value_gt"`
+	ValueGt *float64 `json:"value_gt"`
 	ValueLt *float64 `json:"value_lt"`
+	ValueEq *float64 `json:"value_eq"`
+	ValueNe *float64 `json:"value_ne"`
+	// ValueBetween matches if min <= value <= max
+	ValueBetween *ValueRange `json:"value_between"`
+	// ValueOutside matches if value < min or value > max
+	ValueOutside *ValueRange `json:"value_outside"`
+	// Dynamic marks that value_gt / value_lt are computed by the SQL query, via the alert_if_value_gt /
+	// alert_if_value_lt result columns. The columns override static thresholds whenever they are non-NULL; Dynamic
+	// is only needed to define an error_if (or warn_if) without any static fallback threshold.
+	Dynamic bool `json:"dynamic"`
 	//ResultsetNotEmpty *bool    `json:"resultset_not_empty"`
 	//ResultsetEmpty    *bool    `json:"resultset_empty"`
 }
 
+// ValueRange is a closed interval [Min, Max] used by range conditions.
+type ValueRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// IsEmpty returns true if no comparison is configured.
+func (c AlertCondition) IsEmpty() bool {
+	return c.ValueGt == nil && c.ValueLt == nil && c.ValueEq == nil && c.ValueNe == nil &&
+		c.ValueBetween == nil && c.ValueOutside == nil && !c.Dynamic
+}
+
+// Matches returns true if any configured comparison matches value. An empty (or purely Dynamic) condition never
+// matches.
+func (c AlertCondition) Matches(value float64) bool {
+	if c.ValueGt != nil && value > *c.ValueGt {
+		return true
+	}
+	if c.ValueLt != nil && value < *c.ValueLt {
+		return true
+	}
+	if c.ValueEq != nil && value == *c.ValueEq {
+		return true
+	}
+	if c.ValueNe != nil && value != *c.ValueNe {
+		return true
+	}
+	if c.ValueBetween != nil && value >= c.ValueBetween.Min && value <= c.ValueBetween.Max {
+		return true
+	}
+	if c.ValueOutside != nil && (value < c.ValueOutside.Min || value > c.ValueOutside.Max) {
+		return true
+	}
+	return false
+}
+
+func (c AlertCondition) validate() error {
+	if err := c.ValueBetween.validate(); err != nil {
+		return fmt.Errorf("value_between: %w", err)
+	}
+	if err := c.ValueOutside.validate(); err != nil {
+		return fmt.Errorf("value_outside: %w", err)
+	}
+	return nil
+}
+
+func (r *ValueRange) validate() error {
+	if r != nil && r.Min > r.Max {
+		return fmt.Errorf("min (%v) must not be greater than max (%v)", r.Min, r.Max)
+	}
+	return nil
+}
+
 func ParseAlertConfiguration(fileSystem fs.FS, filePath string) ([]AlertDefinition, error) {
 	contents, err := fs.ReadFile(fileSystem, filePath)
 	if err != nil {
@@ -79,10 +323,53 @@ func ParseAlertConfiguration(fileSystem fs.FS, filePath string) ([]AlertDefiniti
 	for k, definition := range config.Alerts {
 		definition.Id.Group = filePath
 		definition.Id.Key = k
+		if len(definition.Notify) == 0 {
+			definition.Notify = config.Notify
+		}
+		if definition.ClickHouse == "" {
+			definition.ClickHouse = config.ClickHouse
+		}
+		if len(config.Params) > 0 {
+			params := make(map[string]string, len(config.Params)+len(definition.Params))
+			maps.Copy(params, config.Params)
+			maps.Copy(params, definition.Params)
+			definition.Params = params
+		}
 
 		if definition.QueryPath == "" {
 			return nil, fmt.Errorf("%s - no query path defined", k)
 		}
+		if !definition.AlertIf.IsEmpty() && !definition.ErrorIf.IsEmpty() {
+			return nil, fmt.Errorf("%s - alert_if and error_if are mutually exclusive; use error_if", k)
+		}
+		if definition.ErrorCondition().IsEmpty() && definition.WarnIf.IsEmpty() {
+			return nil, fmt.Errorf("%s - neither error_if nor warn_if defined", k)
+		}
+		if err := definition.ErrorCondition().validate(); err != nil {
+			return nil, fmt.Errorf("%s - error_if: %w", k, err)
+		}
+		if err := definition.WarnIf.validate(); err != nil {
+			return nil, fmt.Errorf("%s - warn_if: %w", k, err)
+		}
+		if err := definition.RecoverIf.validate(); err != nil {
+			return nil, fmt.Errorf("%s - recover_if: %w", k, err)
+		}
+		if definition.For != "" {
+			if duration, err := time.ParseDuration(definition.For); err != nil || duration < 0 {
+				return nil, fmt.Errorf("%s - for: invalid duration '%s'", k, definition.For)
+			}
+		}
+		if definition.Timeout != "" {
+			if duration, err := time.ParseDuration(definition.Timeout); err != nil || duration <= 0 {
+				return nil, fmt.Errorf("%s - timeout: invalid duration '%s'", k, definition.Timeout)
+			}
+		}
+		if definition.Consecutive < 0 {
+			return nil, fmt.Errorf("%s - consecutive must not be negative", k)
+		}
+		if err := definition.Flapping.validate(); err != nil {
+			return nil, fmt.Errorf("%s - flapping: %w", k, err)
+		}
 		// replace SQL path with full contents
 		alertSqlFilePath := path.Clean(path.Join(path.Dir(filePath), definition.QueryPath))
 		alertContents, err := fs.ReadFile(fileSystem, alertSqlFilePath)
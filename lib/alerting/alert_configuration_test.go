@@ -3,6 +3,7 @@ package alerting
 import (
 	"testing"
 	"testing/fstest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -166,6 +167,228 @@ WHERE metric = 'cpu'
 			assert.Contains(t, err.Error(), "Bucket Expression --BUCKET: ... not found")
 		})
 
+		// Test warn_if / error_if levels
+		t.Run("WarnAndErrorLevels", func(t *testing.T) {
+			mockFSWithLevels := fstest.MapFS{
+				"alerts/test/alerts.yaml": &fstest.MapFile{
+					Data: []byte(`
+alerts:
+  disk_usage:
+    query_path: "queries/cpu_usage.sql"
+    warn_if:
+      value_between: {min: 80, max: 90}
+    error_if:
+      value_gt: 90
+    message: "Disk almost full"
+    warn_message: "Disk filling up"
+    check_every: "5m"`),
+				},
+				"alerts/test/queries/cpu_usage.sql": mockFS["alerts/test/queries/cpu_usage.sql"],
+			}
+
+			alertDefinitions, err := ParseAlertConfiguration(mockFSWithLevels, "alerts/test/alerts.yaml")
+			require.NoError(t, err)
+			require.Len(t, alertDefinitions, 1)
+			diskAlert := alertDefinitions[0]
+			assert.Equal(t, 90.0, *diskAlert.ErrorCondition().ValueGt)
+			assert.Equal(t, &ValueRange{Min: 80, Max: 90}, diskAlert.WarnIf.ValueBetween)
+			assert.Equal(t, "Disk filling up", diskAlert.WarnMessageOrDefault())
+		})
+
+		// Test file-level notify default
+		t.Run("NotifyDefault", func(t *testing.T) {
+			mockFSWithNotify := fstest.MapFS{
+				"alerts/test/alerts.yaml": &fstest.MapFile{
+					Data: []byte(`
+notify: [slack_ops]
+alerts:
+  cpu_usage:
+    query_path: "queries/cpu_usage.sql"
+    error_if:
+      value_gt: 90
+    check_every: "5m"
+  memory_usage:
+    query_path: "queries/cpu_usage.sql"
+    notify: [email_ops, alertmanager]
+    error_if:
+      value_gt: 90
+    check_every: "5m"`),
+				},
+				"alerts/test/queries/cpu_usage.sql": mockFS["alerts/test/queries/cpu_usage.sql"],
+			}
+
+			alertDefinitions, err := ParseAlertConfiguration(mockFSWithNotify, "alerts/test/alerts.yaml")
+			require.NoError(t, err)
+			require.Len(t, alertDefinitions, 2)
+			for _, definition := range alertDefinitions {
+				if definition.Id.Key == "cpu_usage" {
+					assert.Equal(t, []string{"slack_ops"}, definition.Notify)
+				} else {
+					assert.Equal(t, []string{"email_ops", "alertmanager"}, definition.Notify)
+				}
+			}
+		})
+
+		// Test file-level clickhouse and params defaults
+		t.Run("ClickHouseAndParamsDefault", func(t *testing.T) {
+			mockFSWithServer := fstest.MapFS{
+				"alerts/test/alerts.yaml": &fstest.MapFile{
+					Data: []byte(`
+clickhouse: staging
+params:
+  environment: staging
+  event_dataset: shop
+alerts:
+  cpu_usage:
+    query_path: "queries/cpu_usage.sql"
+    error_if:
+      value_gt: 90
+    check_every: "5m"
+  memory_usage:
+    query_path: "queries/cpu_usage.sql"
+    clickhouse: production
+    params:
+      event_dataset: checkout
+    error_if:
+      value_gt: 90
+    check_every: "5m"`),
+				},
+				"alerts/test/queries/cpu_usage.sql": mockFS["alerts/test/queries/cpu_usage.sql"],
+			}
+
+			alertDefinitions, err := ParseAlertConfiguration(mockFSWithServer, "alerts/test/alerts.yaml")
+			require.NoError(t, err)
+			require.Len(t, alertDefinitions, 2)
+			for _, definition := range alertDefinitions {
+				if definition.Id.Key == "cpu_usage" {
+					assert.Equal(t, "staging", definition.ClickHouseServer())
+					assert.Equal(t, map[string]string{"environment": "staging", "event_dataset": "shop"}, definition.Params)
+				} else {
+					assert.Equal(t, "production", definition.ClickHouseServer())
+					assert.Equal(t, map[string]string{"environment": "staging", "event_dataset": "checkout"}, definition.Params)
+				}
+			}
+		})
+
+		// Test alert_if and error_if are mutually exclusive
+		t.Run("AlertIfAndErrorIf", func(t *testing.T) {
+			mockFSWithError := fstest.MapFS{
+				"alerts/test/alerts_error.yaml": &fstest.MapFile{
+					Data: []byte(`
+alerts:
+  cpu_usage:
+    query_path: "queries/cpu_usage.sql"
+    alert_if:
+      value_gt: 90
+    error_if:
+      value_gt: 95
+    check_every: "5m"`),
+				},
+				"alerts/test/queries/cpu_usage.sql": mockFS["alerts/test/queries/cpu_usage.sql"],
+			}
+
+			_, err := ParseAlertConfiguration(mockFSWithError, "alerts/test/alerts_error.yaml")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "mutually exclusive")
+		})
+
+		// Test missing condition
+		t.Run("MissingCondition", func(t *testing.T) {
+			mockFSWithError := fstest.MapFS{
+				"alerts/test/alerts_error.yaml": &fstest.MapFile{
+					Data: []byte(`
+alerts:
+  cpu_usage:
+    query_path: "queries/cpu_usage.sql"
+    check_every: "5m"`),
+				},
+				"alerts/test/queries/cpu_usage.sql": mockFS["alerts/test/queries/cpu_usage.sql"],
+			}
+
+			_, err := ParseAlertConfiguration(mockFSWithError, "alerts/test/alerts_error.yaml")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "neither error_if nor warn_if defined")
+		})
+
+		// Test invalid range
+		t.Run("InvalidRange", func(t *testing.T) {
+			mockFSWithError := fstest.MapFS{
+				"alerts/test/alerts_error.yaml": &fstest.MapFile{
+					Data: []byte(`
+alerts:
+  cpu_usage:
+    query_path: "queries/cpu_usage.sql"
+    warn_if:
+      value_outside: {min: 20, max: 10}
+    check_every: "5m"`),
+				},
+				"alerts/test/queries/cpu_usage.sql": mockFS["alerts/test/queries/cpu_usage.sql"],
+			}
+
+			_, err := ParseAlertConfiguration(mockFSWithError, "alerts/test/alerts_error.yaml")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "warn_if: value_outside: min (20) must not be greater than max (10)")
+		})
+
+		// Test pending / hysteresis / flapping options
+		t.Run("PendingAndFlapping", func(t *testing.T) {
+			mockFSWithFlapping := fstest.MapFS{
+				"alerts/test/alerts.yaml": &fstest.MapFile{
+					Data: []byte(`
+alerts:
+  cpu_usage:
+    query_path: "queries/cpu_usage.sql"
+    error_if:
+      value_gt: 90
+    recover_if:
+      value_lt: 80
+    for: 10m
+    consecutive: 3
+    flapping:
+      window: 1h
+      max_changes: 6
+    timeout: 10s
+    check_every: "5m"`),
+				},
+				"alerts/test/queries/cpu_usage.sql": mockFS["alerts/test/queries/cpu_usage.sql"],
+			}
+
+			alertDefinitions, err := ParseAlertConfiguration(mockFSWithFlapping, "alerts/test/alerts.yaml")
+			require.NoError(t, err)
+			require.Len(t, alertDefinitions, 1)
+			assert.Equal(t, 10*time.Minute, alertDefinitions[0].forDuration())
+			assert.Equal(t, 3, alertDefinitions[0].Consecutive)
+			assert.Equal(t, 80.0, *alertDefinitions[0].RecoverIf.ValueLt)
+			assert.Equal(t, time.Hour, alertDefinitions[0].Flapping.window())
+			assert.Equal(t, 6, alertDefinitions[0].Flapping.MaxChanges)
+			assert.Equal(t, 10*time.Second, alertDefinitions[0].timeout(time.Minute))
+
+			invalidCases := map[string]string{
+				"for: 10 minutes":                          "for: invalid duration '10 minutes'",
+				"timeout: 0s":                              "timeout: invalid duration '0s'",
+				"flapping: {window: 1h, max_changes: 1}":   "flapping: max_changes must be at least 2",
+				"flapping: {window: soon, max_changes: 4}": "flapping: window: time: invalid duration",
+			}
+			for option, expectedError := range invalidCases {
+				mockFSWithError := fstest.MapFS{
+					"alerts/test/alerts_error.yaml": &fstest.MapFile{
+						Data: []byte(`
+alerts:
+  cpu_usage:
+    query_path: "queries/cpu_usage.sql"
+    error_if:
+      value_gt: 90
+    ` + option + `
+    check_every: "5m"`),
+					},
+					"alerts/test/queries/cpu_usage.sql": mockFS["alerts/test/queries/cpu_usage.sql"],
+				}
+				_, err := ParseAlertConfiguration(mockFSWithError, "alerts/test/alerts_error.yaml")
+				require.Error(t, err, option)
+				assert.Contains(t, err.Error(), expectedError)
+			}
+		})
+
 		// Test AlertId functionality
 		t.Run("AlertIdFunctions", func(t *testing.T) {
 			// Test creation from string
@@ -175,6 +398,13 @@ WHERE metric = 'cpu'
 
 			// Test string conversion
 			assert.Equal(t, "group1#alert1", id.String())
+
+			// Test alert instances
+			instanceId := id.WithLabels(map[string]string{"host_name": "web 1", "customer_tenant": "acme"})
+			assert.Equal(t, "group1#alert1?customer_tenant=acme&host_name=web+1", instanceId.String())
+			assert.Equal(t, instanceId, AlertIdFromString(instanceId.String()))
+			assert.Equal(t, id, instanceId.DefinitionId())
+			assert.Equal(t, map[string]string{"host_name": "web 1", "customer_tenant": "acme"}, instanceId.LabelValues())
 		})
 	})
 
@@ -220,3 +450,34 @@ SELECT * FROM table`
 		})
 	})
 }
+
+func TestAlertCondition_Matches(t *testing.T) {
+	testCases := []struct {
+		name      string
+		condition AlertCondition
+		value     float64
+		expected  bool
+	}{
+		{"empty never matches", AlertCondition{}, 0, false},
+		{"value_gt above", AlertCondition{ValueGt: f64Ptr(10)}, 11, true},
+		{"value_gt equal", AlertCondition{ValueGt: f64Ptr(10)}, 10, false},
+		{"value_lt below", AlertCondition{ValueLt: f64Ptr(10)}, 9, true},
+		{"value_lt equal", AlertCondition{ValueLt: f64Ptr(10)}, 10, false},
+		{"value_eq equal", AlertCondition{ValueEq: f64Ptr(0)}, 0, true},
+		{"value_eq different", AlertCondition{ValueEq: f64Ptr(0)}, 1, false},
+		{"value_ne different", AlertCondition{ValueNe: f64Ptr(1)}, 0, true},
+		{"value_ne equal", AlertCondition{ValueNe: f64Ptr(1)}, 1, false},
+		{"value_between lower bound", AlertCondition{ValueBetween: &ValueRange{Min: 5, Max: 10}}, 5, true},
+		{"value_between upper bound", AlertCondition{ValueBetween: &ValueRange{Min: 5, Max: 10}}, 10, true},
+		{"value_between outside", AlertCondition{ValueBetween: &ValueRange{Min: 5, Max: 10}}, 11, false},
+		{"value_outside below", AlertCondition{ValueOutside: &ValueRange{Min: 5, Max: 10}}, 4, true},
+		{"value_outside inside", AlertCondition{ValueOutside: &ValueRange{Min: 5, Max: 10}}, 7, false},
+		{"any of multiple comparisons", AlertCondition{ValueGt: f64Ptr(90), ValueLt: f64Ptr(10)}, 5, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.condition.Matches(tc.value))
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package alerting
+
+import "reflect"
+
+type alertDefinitionList []AlertDefinition
+
+func (l alertDefinitionList) ids() []string {
+	ids := make([]string, 0, len(l))
+	for _, alertDefinition := range l {
+		ids = append(ids, alertDefinition.Id.String())
+	}
+	return ids
+}
+
+// alertDefinitionDiff describes how the alert definitions changed between two DiscoverAlertDefinitions runs.
+type alertDefinitionDiff struct {
+	added   alertDefinitionList
+	changed alertDefinitionList
+	removed alertDefinitionList
+
+	// previous contains the old version of all changed (and removed) definitions
+	previous map[AlertId]AlertDefinition
+}
+
+func (d alertDefinitionDiff) isEmpty() bool {
+	return len(d.added) == 0 && len(d.changed) == 0 && len(d.removed) == 0
+}
+
+// diffAlertDefinitions compares alert definitions by Id; a definition counts as changed if any field (including
+// the SQL query, which is read from the referenced file) differs.
+func diffAlertDefinitions(previousDefinitions, currentDefinitions []AlertDefinition) alertDefinitionDiff {
+	diff := alertDefinitionDiff{
+		previous: make(map[AlertId]AlertDefinition, len(previousDefinitions)),
+	}
+	for _, alertDefinition := range previousDefinitions {
+		diff.previous[alertDefinition.Id] = alertDefinition
+	}
+
+	current := make(map[AlertId]bool, len(currentDefinitions))
+	for _, alertDefinition := range currentDefinitions {
+		current[alertDefinition.Id] = true
+		previous, existed := diff.previous[alertDefinition.Id]
+		if !existed {
+			diff.added = append(diff.added, alertDefinition)
+		} else if !reflect.DeepEqual(previous, alertDefinition) {
+			diff.changed = append(diff.changed, alertDefinition)
+		}
+	}
+	for _, alertDefinition := range previousDefinitions {
+		if !current[alertDefinition.Id] {
+			diff.removed = append(diff.removed, alertDefinition)
+		}
+	}
+	return diff
+}
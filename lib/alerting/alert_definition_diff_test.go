@@ -0,0 +1,103 @@
+package alerting
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffAlertDefinitions(t *testing.T) {
+	cpu := AlertDefinition{Id: AlertId{Group: "test", Key: "cpu"}, Query: "SELECT 1", CheckEvery: "* * * * *"}
+	memory := AlertDefinition{Id: AlertId{Group: "test", Key: "memory"}, Query: "SELECT 2", CheckEvery: "* * * * *"}
+	disk := AlertDefinition{Id: AlertId{Group: "test", Key: "disk"}, Query: "SELECT 3", CheckEvery: "* * * * *"}
+
+	changedMemory := memory
+	changedMemory.Query = "SELECT 42"
+
+	diff := diffAlertDefinitions(
+		[]AlertDefinition{cpu, memory, disk},
+		[]AlertDefinition{cpu, changedMemory, {Id: AlertId{Group: "test", Key: "network"}}},
+	)
+
+	assert.Equal(t, []string{"test#network"}, diff.added.ids())
+	assert.Equal(t, []string{"test#memory"}, diff.changed.ids())
+	assert.Equal(t, []string{"test#disk"}, diff.removed.ids())
+	assert.Equal(t, "SELECT 2", diff.previous[memory.Id].Query)
+
+	t.Run("Unchanged", func(t *testing.T) {
+		assert.True(t, diffAlertDefinitions([]AlertDefinition{cpu, memory}, []AlertDefinition{memory, cpu}).isEmpty())
+	})
+}
+
+func TestDueAlertDefinitions(t *testing.T) {
+	everyMinute := AlertDefinition{Id: AlertId{Group: "test", Key: "every_minute"}, CheckEvery: "* * * * *"}
+	everyFifteen := AlertDefinition{Id: AlertId{Group: "test", Key: "every_fifteen"}, CheckEvery: "@15minutes"}
+	invalid := AlertDefinition{Id: AlertId{Group: "test", Key: "invalid"}, CheckEvery: "5m"}
+	definitions := []AlertDefinition{everyMinute, everyFifteen, invalid}
+
+	due, err := dueAlertDefinitions(definitions, time.Date(2025, 3, 1, 10, 15, 0, 300_000_000, time.UTC))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "test#invalid")
+	assert.Equal(t, []string{"test#every_minute", "test#every_fifteen"}, alertDefinitionList(due).ids())
+
+	due, _ = dueAlertDefinitions(definitions, time.Date(2025, 3, 1, 10, 16, 0, 0, time.UTC))
+	assert.Equal(t, []string{"test#every_minute"}, alertDefinitionList(due).ids())
+
+	// 5-field expressions are only due in the first second of the minute
+	due, _ = dueAlertDefinitions(definitions, time.Date(2025, 3, 1, 10, 16, 23, 0, time.UTC))
+	assert.Empty(t, due)
+
+	t.Run("SecondsField", func(t *testing.T) {
+		everyThirtySeconds := AlertDefinition{Id: AlertId{Group: "test", Key: "every_thirty_seconds"}, CheckEvery: "*/30 * * * * *"}
+		atSecondFifteen := AlertDefinition{Id: AlertId{Group: "test", Key: "at_second_fifteen"}, CheckEvery: "15 * * * * *"}
+		definitions := []AlertDefinition{everyMinute, everyThirtySeconds, atSecondFifteen}
+
+		due, err := dueAlertDefinitions(definitions, time.Date(2025, 3, 1, 10, 16, 0, 0, time.UTC))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"test#every_minute", "test#every_thirty_seconds"}, alertDefinitionList(due).ids())
+
+		due, _ = dueAlertDefinitions(definitions, time.Date(2025, 3, 1, 10, 16, 15, 0, time.UTC))
+		assert.Equal(t, []string{"test#at_second_fifteen"}, alertDefinitionList(due).ids())
+
+		due, _ = dueAlertDefinitions(definitions, time.Date(2025, 3, 1, 10, 16, 30, 500_000_000, time.UTC))
+		assert.Equal(t, []string{"test#every_thirty_seconds"}, alertDefinitionList(due).ids())
+
+		due, _ = dueAlertDefinitions(definitions, time.Date(2025, 3, 1, 10, 16, 31, 0, time.UTC))
+		assert.Empty(t, due)
+	})
+}
+
+func TestAlertManager_ReloadAlertDefinitions(t *testing.T) {
+	mockFS := fstest.MapFS{
+		"src/test/alerts.yaml": &fstest.MapFile{Data: []byte(`
+alerts:
+  cpu_usage:
+    query_path: "cpu_usage.sql"
+    error_if:
+      value_gt: 90
+    message: "CPU usage is above 90%"
+    check_every: "* * * * *"`)},
+		"src/test/cpu_usage.sql": &fstest.MapFile{Data: []byte("--BUCKET: 5m\nSELECT 1 AS value")},
+	}
+	alertManager := NewAlertManager(&config.Config{}, zerolog.Nop(), mockFS, nil, nil, nil)
+	require.NoError(t, alertManager.DiscoverAlertDefinitions())
+
+	// the SQL file changes -> the new query is active without restart
+	mockFS["src/test/cpu_usage.sql"] = &fstest.MapFile{Data: []byte("--BUCKET: 5m\nSELECT 2 AS value")}
+	require.NoError(t, alertManager.ReloadAlertDefinitions(context.Background()))
+	alertDefinition := alertManager.GetAlertDefinition(AlertId{Group: "src/test/alerts.yaml", Key: "cpu_usage"})
+	require.NotNil(t, alertDefinition)
+	assert.Contains(t, alertDefinition.Query, "SELECT 2")
+
+	t.Run("BrokenFileKeepsPreviousDefinitions", func(t *testing.T) {
+		mockFS["src/test/alerts.yaml"] = &fstest.MapFile{Data: []byte(`alerts: [`)}
+		require.Error(t, alertManager.ReloadAlertDefinitions(context.Background()))
+		assert.NotNil(t, alertManager.GetAlertDefinition(AlertId{Group: "src/test/alerts.yaml", Key: "cpu_usage"}))
+	})
+}
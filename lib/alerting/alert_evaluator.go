@@ -2,6 +2,7 @@ package alerting
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"slices"
 	"strings"
@@ -24,12 +25,32 @@ const AlertStateWarn = "warn"
 
 const AlertStateOk = "OK"
 
+// AlertStatePending is used while error_if / warn_if matches, but not yet long enough (see AlertDefinition.For and
+// AlertDefinition.Consecutive). It is not notified.
+const AlertStatePending = "pending"
+
+// AlertStateFlapping is used while an alert changes its state too often (see AlertDefinition.Flapping).
+const AlertStateFlapping = "flapping"
+
+// AlertStateTimeout is used when the alert query did not finish within the alert timeout (see
+// AlertDefinition.Timeout); it is notified like AlertStateError.
+const AlertStateTimeout = "timeout"
+
+// AlertStateRemoved is never evaluated; it is persisted once when an alert definition is removed from alerts.yaml.
+const AlertStateRemoved = "removed"
+
 var allAlertStates = []string{AlertStateError, AlertStateWarn, AlertStateOk}
 
 type AlertResult struct {
-	// State is one of AlertStateError, AlertStateWarn, AlertStateOk
+	// AlertId is the id of the evaluated alert instance (i.e. the AlertDefinition.Id, plus Labels for alerts
+	// with group_by)
+	AlertId AlertId
+	// State is one of AlertStateError, AlertStateWarn, AlertStateOk or AlertStateTimeout; after
+	// alertStateTracker.apply, also AlertStatePending or AlertStateFlapping.
 	State   string
 	Message string
+	// Value is the evaluated value of the result row (0 if no row was returned)
+	Value float64
 
 	// Execution Timestamp
 	Timestamp config.Time
@@ -50,10 +71,71 @@ func NewAlertEvaluator(logger zerolog.Logger, clickhouseManager *clickhouse.Mana
 type alertResultRow struct {
 	TimeTs int64   `json:"time_ts"`
 	Value  float64 `json:"value"`
-	// TODO: IMPL ME
+	// AlertIfValueGt is a dynamic threshold computed by the SQL query (e.g. a rolling baseline); if set, it
+	// overrides value_gt of the error_if condition for this row.
 	AlertIfValueGt *float64 `json:"alert_if_value_gt,omitempty"`
-	// TODO: IMPL ME
-	AlertIfValueLt *float64 `json:"alert_if_value_lt"`
+	// AlertIfValueLt is a dynamic threshold computed by the SQL query; if set, it overrides value_lt of the
+	// error_if condition for this row.
+	AlertIfValueLt *float64 `json:"alert_if_value_lt,omitempty"`
+
+	// Labels contains all other result columns (stringified); the group_by columns of the AlertDefinition
+	// identify the alert instance.
+	Labels map[string]string `json:"-"`
+}
+
+func (a *alertResultRow) UnmarshalJSON(data []byte) error {
+	// plainAlertResultRow has no UnmarshalJSON method; so we do not recurse.
+	type plainAlertResultRow alertResultRow
+	if err := json.Unmarshal(data, (*plainAlertResultRow)(a)); err != nil {
+		return err
+	}
+
+	var columns map[string]json.RawMessage
+	if err := json.Unmarshal(data, &columns); err != nil {
+		return err
+	}
+	a.Labels = make(map[string]string, len(columns))
+	for name, rawValue := range columns {
+		switch name {
+		case "time_ts", "value", "alert_if_value_gt", "alert_if_value_lt":
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(rawValue, &value); err != nil {
+			// not a string (e.g. a number) -> take the JSON representation
+			value = string(rawValue)
+		}
+		a.Labels[name] = value
+	}
+	return nil
+}
+
+// instanceId returns the alert instance this row belongs to, based on the group_by columns of definition.
+func (a alertResultRow) instanceId(definition AlertDefinition) (AlertId, error) {
+	labels := make(map[string]string, len(definition.GroupBy))
+	for _, column := range definition.GroupBy {
+		value, found := a.Labels[column]
+		if !found {
+			return AlertId{}, fmt.Errorf("group_by column '%s' not found in result set", column)
+		}
+		labels[column] = value
+	}
+	return definition.Id.WithLabels(labels), nil
+}
+
+// withDynamicThresholds returns condition, with value_gt / value_lt replaced by the thresholds
+// computed in SQL for this row (if any). An empty condition stays empty.
+func (a alertResultRow) withDynamicThresholds(condition AlertCondition) AlertCondition {
+	if condition.IsEmpty() {
+		return condition
+	}
+	if a.AlertIfValueGt != nil {
+		condition.ValueGt = a.AlertIfValueGt
+	}
+	if a.AlertIfValueLt != nil {
+		condition.ValueLt = a.AlertIfValueLt
+	}
+	return condition
 }
 
 func (a alertResultRow) MarshalZerologObject(e *zerolog.Event) {
@@ -74,8 +156,24 @@ func (ar alertResultRows) MarshalZerologArray(a *zerolog.Array) {
 	}
 }
 
-func (e AlertEvaluator) EvaluateAlert(definition AlertDefinition) (*AlertResult, error) {
-	clickhouseClient, err := e.clickhouseManager.GetClient("default")
+// EvaluateAlert evaluates an alert without group_by, i.e. with exactly one alert instance.
+func (e AlertEvaluator) EvaluateAlert(ctx context.Context, definition AlertDefinition) (*AlertResult, error) {
+	if len(definition.GroupBy) > 0 {
+		return nil, fmt.Errorf("alert %s has group_by; use EvaluateAlertInstances", definition.Id.String())
+	}
+	alertResults, err := e.EvaluateAlertInstances(ctx, definition, nil)
+	if err != nil {
+		return nil, err
+	}
+	return alertResults[0], nil
+}
+
+// EvaluateAlertInstances evaluates an alert and returns one AlertResult per alert instance.
+//
+// knownInstances are instances of this alert which are not OK currently; if the query does not return a row
+// for them anymore, they are evaluated like an empty result set (i.e. value 0), so that they can recover.
+func (e AlertEvaluator) EvaluateAlertInstances(ctx context.Context, definition AlertDefinition, knownInstances []AlertId) ([]*AlertResult, error) {
+	clickhouseClient, err := e.clickhouseManager.GetClient(definition.ClickHouseServer())
 	if err != nil {
 		return nil, fmt.Errorf("loading clickhouse client for %s: %w", definition.QueryPath, err)
 	}
@@ -84,17 +182,61 @@ func (e AlertEvaluator) EvaluateAlert(definition AlertDefinition) (*AlertResult,
 	queryOpts := clickhouse.DefaultQueryOptions()
 	queryOpts.Parameters = definition.Params
 
-	resultset, err := clickhouse.QueryJSON[alertResultRow](context.Background(), clickhouseClient, alertSql, queryOpts)
+	ctx = clickhouse.WithQuerySource(ctx, clickhouse.QuerySource{Alert: definition.Id.String()})
+	resultset, err := clickhouse.QueryJSON[alertResultRow](ctx, clickhouseClient, alertSql, queryOpts)
 	if err != nil {
 		return nil, fmt.Errorf("running alert SQL query: %w", err)
 	}
 
-	return e.evaluateThreshold(definition, resultset.Data)
+	return e.evaluateInstances(definition, resultset.Data, knownInstances)
 }
 
-// evaluateThreshold evaluates the alert threshold for a SINGLE alert row (i.e. a specific timestamp).
-// data usually contains 0 or 1 row; and the depending on the AlertIf condition configured throws errors if
-// it contains more than 1 row.
+// evaluateInstances splits data by alert instance (see AlertDefinition.GroupBy), and evaluates the threshold
+// for each instance. Without group_by, there is always exactly one instance.
+func (e AlertEvaluator) evaluateInstances(definition AlertDefinition, data []alertResultRow, knownInstances []AlertId) ([]*AlertResult, error) {
+	if len(definition.GroupBy) == 0 {
+		alertResult, err := e.evaluateThreshold(definition, data)
+		if err != nil {
+			return nil, err
+		}
+		alertResult.AlertId = definition.Id
+		return []*AlertResult{alertResult}, nil
+	}
+
+	// instances keeps the order in which the instances appear in the result set
+	instances := make([]AlertId, 0, len(data))
+	rowsByInstance := make(map[AlertId][]alertResultRow)
+	for _, row := range data {
+		instanceId, err := row.instanceId(definition)
+		if err != nil {
+			return nil, fmt.Errorf("alert %s: %w", definition.Id.String(), err)
+		}
+		if _, seen := rowsByInstance[instanceId]; !seen {
+			instances = append(instances, instanceId)
+		}
+		rowsByInstance[instanceId] = append(rowsByInstance[instanceId], row)
+	}
+	for _, instanceId := range knownInstances {
+		if _, seen := rowsByInstance[instanceId]; !seen && instanceId.DefinitionId() == definition.Id {
+			instances = append(instances, instanceId)
+			rowsByInstance[instanceId] = nil
+		}
+	}
+
+	alertResults := make([]*AlertResult, 0, len(instances))
+	for _, instanceId := range instances {
+		alertResult, err := e.evaluateThreshold(definition, rowsByInstance[instanceId])
+		if err != nil {
+			return nil, err
+		}
+		alertResult.AlertId = instanceId
+		alertResults = append(alertResults, alertResult)
+	}
+	return alertResults, nil
+}
+
+// evaluateThreshold evaluates the alert threshold for a SINGLE alert row (i.e. a specific timestamp and alert
+// instance). data usually contains 0 or 1 row; and throws errors if it contains more than 1 row.
 func (e AlertEvaluator) evaluateThreshold(definition AlertDefinition, data []alertResultRow) (*AlertResult, error) {
 	var results alertResultRows = data
 
@@ -106,54 +248,53 @@ func (e AlertEvaluator) evaluateThreshold(definition AlertDefinition, data []ale
 		Array("results", results).
 		Msg("received result set")
 
-	if definition.AlertIf.ValueGt != nil {
-		return e.zeroOrSingleRowWithTimestamp(data, func(value float64) AlertResult {
-			if value > *definition.AlertIf.ValueGt {
-				return AlertResult{
-					State:   AlertStateError,
-					Message: definition.Message,
-				}
-			} else {
-				return AlertResult{
-					State: AlertStateOk,
-				}
-			}
-		}), nil
-	}
-
-	if definition.AlertIf.ValueLt != nil {
-		return e.zeroOrSingleRowWithTimestamp(data, func(value float64) AlertResult {
-			if value < *definition.AlertIf.ValueLt {
-				return AlertResult{
-					State:   AlertStateError,
-					Message: definition.Message,
-				}
-			} else {
-				return AlertResult{
-					State: AlertStateOk,
-				}
-			}
-		}), nil
+	errorIf := definition.ErrorCondition()
+	if errorIf.IsEmpty() && definition.WarnIf.IsEmpty() {
+		return &AlertResult{
+			State:   AlertStateError,
+			Message: "INTERNAL ERROR: no error_if / warn_if condition found.",
+		}, nil
 	}
 
-	return &AlertResult{
-		State:   AlertStateError,
-		Message: "INTERNAL ERROR: no alert_if condition found.",
-	}, nil
+	return e.zeroOrSingleRowWithTimestamp(data, func(row alertResultRow) AlertResult {
+		value := row.Value
+		// the dynamic thresholds belong to error_if; only alerts without error_if apply them to warn_if.
+		rowErrorIf, rowWarnIf := row.withDynamicThresholds(errorIf), definition.WarnIf
+		if errorIf.IsEmpty() {
+			rowWarnIf = row.withDynamicThresholds(rowWarnIf)
+		}
+		if rowErrorIf.Matches(value) {
+			return AlertResult{
+				State:   AlertStateError,
+				Message: definition.Message,
+			}
+		}
+		if rowWarnIf.Matches(value) {
+			return AlertResult{
+				State:   AlertStateWarn,
+				Message: definition.WarnMessageOrDefault(),
+			}
+		}
+		return AlertResult{
+			State: AlertStateOk,
+		}
+	}), nil
 }
 
-func (e AlertEvaluator) zeroOrSingleRowWithTimestamp(resultRows []alertResultRow, resultFn func(value float64) AlertResult) *AlertResult {
-	value := 0.0
+func (e AlertEvaluator) zeroOrSingleRowWithTimestamp(resultRows []alertResultRow, resultFn func(row alertResultRow) AlertResult) *AlertResult {
+	// no row counts as value 0 (without dynamic thresholds)
+	row := alertResultRow{}
 	if len(resultRows) == 1 {
-		value = resultRows[0].Value
+		row = resultRows[0]
 	} else if len(resultRows) > 1 {
 		return e.withTimestamp(&AlertResult{
 			State:   AlertStateError,
-			Message: fmt.Sprintf("QUERY ERROR: found %d result rows, but only 0 or 1 allowed", len(resultRows)),
+			Message: fmt.Sprintf("QUERY ERROR: found %d result rows, but only 0 or 1 allowed (per alert instance, see group_by)", len(resultRows)),
 		})
 	}
 
-	alertResult := resultFn(value)
+	alertResult := resultFn(row)
+	alertResult.Value = row.Value
 	if !slices.Contains(allAlertStates, alertResult.State) {
 		return e.withTimestamp(&AlertResult{
 			State:   AlertStateError,
@@ -1,6 +1,7 @@
 package alerting
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -22,7 +23,7 @@ func TestAlertEvaluatorE2E(t *testing.T) {
 	timeProvider := config.NewVirtualTimeProvider()
 	alertEvaluator := NewAlertEvaluator(logger, clickhouseManager, timeProvider)
 
-	alertManager := NewAlertManager(cfg, logger, os.DirFS("."), alertEvaluator, nil)
+	alertManager := NewAlertManager(cfg, logger, os.DirFS("."), alertEvaluator, nil, nil)
 	alertManager.alertDefinitionPattern = "lib/alerting/test_fixtures/alert_evaluator_e2e_alerts.yaml"
 	err := alertManager.DiscoverAlertDefinitions()
 	if err != nil {
@@ -51,7 +52,7 @@ func TestAlertEvaluatorE2E(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			require.NoError(t, timeProvider.SetTime(tc.now))
-			alertResult, err := alertEvaluator.EvaluateAlert(tc.alertDefinition)
+			alertResult, err := alertEvaluator.EvaluateAlert(context.Background(), tc.alertDefinition)
 
 			if tc.expectedError {
 				require.Error(t, err)
@@ -1,6 +1,8 @@
 package alerting
 
 import (
+	"context"
+	"encoding/json"
 	"os"
 	"testing"
 
@@ -69,7 +71,7 @@ func TestAlertEvaluatorSimple(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			alertResult, err := alertEvaluator.EvaluateAlert(tc.alertDefinition)
+			alertResult, err := alertEvaluator.EvaluateAlert(context.Background(), tc.alertDefinition)
 
 			if tc.expectedError {
 				require.Error(t, err)
@@ -82,6 +84,150 @@ func TestAlertEvaluatorSimple(t *testing.T) {
 	}
 }
 
+// TestAlertEvaluator_EvaluateThreshold tests the OK -> warn -> error levels without a database
+func TestAlertEvaluator_EvaluateThreshold(t *testing.T) {
+	alertEvaluator := NewAlertEvaluator(zerolog.Nop(), nil, config.NewVirtualTimeProvider())
+	definition := AlertDefinition{
+		WarnIf:      AlertCondition{ValueGt: f64Ptr(10)},
+		ErrorIf:     AlertCondition{ValueGt: f64Ptr(20)},
+		Message:     "Value too high",
+		WarnMessage: "Value rising",
+	}
+
+	testCases := []struct {
+		name          string
+		rows          []alertResultRow
+		expectedState string
+		expectedMsg   string
+	}{
+		{"no rows counts as 0", nil, AlertStateOk, ""},
+		{"below warn threshold", []alertResultRow{{Value: 5}}, AlertStateOk, ""},
+		{"above warn threshold", []alertResultRow{{Value: 15}}, AlertStateWarn, "Value rising"},
+		{"above error threshold", []alertResultRow{{Value: 25}}, AlertStateError, "Value too high"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			alertResult, err := alertEvaluator.evaluateThreshold(definition, tc.rows)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedState, alertResult.State)
+			require.Equal(t, tc.expectedMsg, alertResult.Message)
+		})
+	}
+}
+
+// TestAlertEvaluator_DynamicThresholds tests that alert_if_value_gt / alert_if_value_lt result columns override
+// the static thresholds per row
+func TestAlertEvaluator_DynamicThresholds(t *testing.T) {
+	alertEvaluator := NewAlertEvaluator(zerolog.Nop(), nil, config.NewVirtualTimeProvider())
+
+	testCases := []struct {
+		name          string
+		definition    AlertDefinition
+		rows          []alertResultRow
+		expectedState string
+	}{
+		{
+			name:          "dynamic value_gt overrides static threshold",
+			definition:    AlertDefinition{ErrorIf: AlertCondition{ValueGt: f64Ptr(100)}},
+			rows:          []alertResultRow{{Value: 50, AlertIfValueGt: f64Ptr(30)}},
+			expectedState: AlertStateError,
+		},
+		{
+			name:          "static threshold used if dynamic column is NULL",
+			definition:    AlertDefinition{ErrorIf: AlertCondition{ValueGt: f64Ptr(100)}},
+			rows:          []alertResultRow{{Value: 50}},
+			expectedState: AlertStateOk,
+		},
+		{
+			name:          "dynamic-only condition - below threshold",
+			definition:    AlertDefinition{ErrorIf: AlertCondition{Dynamic: true}},
+			rows:          []alertResultRow{{Value: 5, AlertIfValueLt: f64Ptr(10)}},
+			expectedState: AlertStateError,
+		},
+		{
+			name:          "warn_if only - dynamic threshold applies to warn_if",
+			definition:    AlertDefinition{WarnIf: AlertCondition{ValueGt: f64Ptr(100)}},
+			rows:          []alertResultRow{{Value: 50, AlertIfValueGt: f64Ptr(30)}},
+			expectedState: AlertStateWarn,
+		},
+		{
+			name:          "warn_if only - below dynamic threshold",
+			definition:    AlertDefinition{WarnIf: AlertCondition{Dynamic: true}},
+			rows:          []alertResultRow{{Value: 20, AlertIfValueGt: f64Ptr(30)}},
+			expectedState: AlertStateOk,
+		},
+		{
+			name:          "error_if and warn_if - dynamic threshold applies to error_if only",
+			definition:    AlertDefinition{ErrorIf: AlertCondition{ValueGt: f64Ptr(100)}, WarnIf: AlertCondition{ValueGt: f64Ptr(40)}},
+			rows:          []alertResultRow{{Value: 50, AlertIfValueGt: f64Ptr(80)}},
+			expectedState: AlertStateWarn,
+		},
+		{
+			name:          "dynamic-only condition - no rows never matches",
+			definition:    AlertDefinition{ErrorIf: AlertCondition{Dynamic: true}},
+			rows:          nil,
+			expectedState: AlertStateOk,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			alertResult, err := alertEvaluator.evaluateThreshold(tc.definition, tc.rows)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedState, alertResult.State)
+		})
+	}
+}
+
+// TestAlertEvaluator_EvaluateInstances tests that alerts with group_by fan out into one result per instance
+func TestAlertEvaluator_EvaluateInstances(t *testing.T) {
+	alertEvaluator := NewAlertEvaluator(zerolog.Nop(), nil, config.NewVirtualTimeProvider())
+	definition := AlertDefinition{
+		Id:      AlertId{Group: "g1", Key: "http500"},
+		GroupBy: []string{"host_name"},
+		ErrorIf: AlertCondition{ValueGt: f64Ptr(10)},
+		Message: "too many errors",
+	}
+
+	var rows []alertResultRow
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"time_ts": 1, "value": 20, "host_name": "web1"},
+		{"time_ts": 1, "value": 5, "host_name": "web2"}
+	]`), &rows))
+	require.Equal(t, map[string]string{"host_name": "web1"}, rows[0].Labels)
+
+	web1 := definition.Id.WithLabels(map[string]string{"host_name": "web1"})
+	web2 := definition.Id.WithLabels(map[string]string{"host_name": "web2"})
+	web3 := definition.Id.WithLabels(map[string]string{"host_name": "web3"})
+	otherAlert := AlertId{Group: "g1", Key: "other"}.WithLabels(map[string]string{"host_name": "web4"})
+
+	alertResults, err := alertEvaluator.evaluateInstances(definition, rows, []AlertId{web3, otherAlert})
+	require.NoError(t, err)
+	require.Len(t, alertResults, 3)
+	require.Equal(t, web1, alertResults[0].AlertId)
+	require.Equal(t, AlertStateError, alertResults[0].State)
+	require.Equal(t, web2, alertResults[1].AlertId)
+	require.Equal(t, AlertStateOk, alertResults[1].State)
+	// web3 was firing before but is not in the result anymore -> recovers
+	require.Equal(t, web3, alertResults[2].AlertId)
+	require.Equal(t, AlertStateOk, alertResults[2].State)
+
+	t.Run("missing group_by column", func(t *testing.T) {
+		definition := definition
+		definition.GroupBy = []string{"customer_tenant"}
+		_, err := alertEvaluator.evaluateInstances(definition, rows, nil)
+		require.ErrorContains(t, err, "group_by column 'customer_tenant' not found")
+	})
+
+	t.Run("multiple rows per instance", func(t *testing.T) {
+		alertResults, err := alertEvaluator.evaluateInstances(definition, append(rows, rows[0]), nil)
+		require.NoError(t, err)
+		require.Equal(t, AlertStateError, alertResults[0].State)
+		require.Contains(t, alertResults[0].Message, "QUERY ERROR: found 2 result rows")
+	})
+}
+
 func f64Ptr(in float64) *float64 {
 	return &in
 }
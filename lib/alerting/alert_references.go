@@ -0,0 +1,61 @@
+package alerting
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/sandstorm/dashica/lib/config"
+)
+
+// referencePattern matches ${env:NAME} and ${config:NAME}
+var referencePattern = regexp.MustCompile(`\$\{(env|config):([^}]+)\}`)
+
+// resolveReferences replaces ${env:NAME} with the environment variable NAME, and ${config:NAME} with NAME of
+// alerting.params in dashica_config.yaml. Unset references are an error, so that an alert never silently runs with
+// an empty parameter.
+func resolveReferences(value string, configParams map[string]string) (string, error) {
+	var errs []error
+	resolved := referencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		match := referencePattern.FindStringSubmatch(reference)
+		source, name := match[1], match[2]
+		var resolvedValue string
+		var found bool
+		if source == "env" {
+			resolvedValue, found = os.LookupEnv(name)
+		} else {
+			resolvedValue, found = configParams[name]
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("%s not set", reference))
+		}
+		return resolvedValue
+	})
+	return resolved, errors.Join(errs...)
+}
+
+// resolveAlertDefinition resolves the references (see resolveReferences) in the params and the clickhouse server
+// alias of definition; and checks that the server alias is configured.
+func resolveAlertDefinition(definition AlertDefinition, cfg *config.Config) (AlertDefinition, error) {
+	var err error
+	if len(definition.Params) > 0 {
+		params := make(map[string]string, len(definition.Params))
+		for name, value := range definition.Params {
+			if params[name], err = resolveReferences(value, cfg.Alerting.Params); err != nil {
+				return definition, fmt.Errorf("%s - params.%s: %w", definition.Id.Key, name, err)
+			}
+		}
+		definition.Params = params
+	}
+
+	if definition.ClickHouse, err = resolveReferences(definition.ClickHouse, cfg.Alerting.Params); err != nil {
+		return definition, fmt.Errorf("%s - clickhouse: %w", definition.Id.Key, err)
+	}
+	if definition.ClickHouse != "" {
+		if _, exists := cfg.ClickHouse[definition.ClickHouse]; !exists {
+			return definition, fmt.Errorf("%s - clickhouse: server '%s' not configured in dashica_config.yaml", definition.Id.Key, definition.ClickHouse)
+		}
+	}
+	return definition, nil
+}
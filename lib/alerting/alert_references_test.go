@@ -0,0 +1,70 @@
+package alerting
+
+import (
+	"testing"
+
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveReferences(t *testing.T) {
+	t.Setenv("DASHICA_TEST_DATASET", "shop_orders")
+	configParams := map[string]string{"environment": "staging"}
+
+	tests := []struct {
+		name          string
+		value         string
+		expected      string
+		expectedError string
+	}{
+		{"plain value", "shop_orders", "shop_orders", ""},
+		{"env", "${env:DASHICA_TEST_DATASET}", "shop_orders", ""},
+		{"config", "${config:environment}", "staging", ""},
+		{"embedded", "${config:environment}_${env:DASHICA_TEST_DATASET}", "staging_shop_orders", ""},
+		{"unset env", "${env:DASHICA_TEST_UNSET}", "", "${env:DASHICA_TEST_UNSET} not set"},
+		{"unset config", "${config:cluster}", "", "${config:cluster} not set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := resolveReferences(tt.value, configParams)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, resolved)
+		})
+	}
+}
+
+func TestResolveAlertDefinition(t *testing.T) {
+	cfg := &config.Config{
+		ClickHouse: map[string]config.ClickHouseConfig{
+			"default":    {},
+			"production": {},
+		},
+		Alerting: config.AlertingConfig{
+			Params: map[string]string{"cluster": "production", "dataset": "orders"},
+		},
+	}
+	definition := AlertDefinition{
+		Id:         AlertId{Group: "src/shop/alerts.yaml", Key: "orders"},
+		ClickHouse: "${config:cluster}",
+		Params:     map[string]string{"event_dataset": "${config:dataset}"},
+	}
+
+	resolved, err := resolveAlertDefinition(definition, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "production", resolved.ClickHouseServer())
+	assert.Equal(t, map[string]string{"event_dataset": "orders"}, resolved.Params)
+	assert.Equal(t, "${config:dataset}", definition.Params["event_dataset"], "the parsed definition is not modified")
+
+	definition.ClickHouse = "staging"
+	_, err = resolveAlertDefinition(definition, cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server 'staging' not configured")
+
+	assert.Equal(t, "default", AlertDefinition{}.ClickHouseServer())
+}
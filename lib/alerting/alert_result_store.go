@@ -40,19 +40,21 @@ const LOAD_QUERY = `
 SELECT
     alert_id_group,
     alert_id_key,
+    alert_labels,
     max(timestamp) AS latest_timestamp,
 
     -- select last status,message of timestamp
     argMax(status, timestamp) AS latest_status,
     argMax(message, timestamp) AS latest_message
 FROM dashica_alert_events
-GROUP BY alert_id_group, alert_id_key
-ORDER BY alert_id_group, alert_id_key
+GROUP BY alert_id_group, alert_id_key, alert_labels
+ORDER BY alert_id_group, alert_id_key, alert_labels
 `
 
 type currentAlertStatus struct {
 	AlertIdGroup    string      `json:"alert_id_group"`
 	AlertIdKey      string      `json:"alert_id_key"`
+	AlertLabels     string      `json:"alert_labels"`
 	LatestTimestamp config.Time `json:"latest_timestamp"`
 	LatestStatus    string      `json:"latest_status"`
 	LatestMessage   string      `json:"latest_message"`
@@ -60,8 +62,9 @@ type currentAlertStatus struct {
 
 func (s currentAlertStatus) AlertId() AlertId {
 	return AlertId{
-		Group: s.AlertIdGroup,
-		Key:   s.AlertIdKey,
+		Group:  s.AlertIdGroup,
+		Key:    s.AlertIdKey,
+		Labels: s.AlertLabels,
 	}
 }
 
@@ -73,8 +76,10 @@ func (s *AlertResultStore) LoadAlertStatusIntoMemory() error {
 	}
 
 	statusIndexedById := make(map[AlertId]*currentAlertStatus)
+	alertStateGauge.Reset()
 	for _, row := range resultset.Data {
 		statusIndexedById[row.AlertId()] = &row
+		setAlertStateMetric(row.AlertId(), "", row.LatestStatus)
 	}
 	s.mu.Lock()
 	s.currentAlertStatus = statusIndexedById
@@ -83,9 +88,54 @@ func (s *AlertResultStore) LoadAlertStatusIntoMemory() error {
 	return nil
 }
 
+// FiringInstances returns all alert instances of the given alert definition (with group_by) which are
+// currently not OK.
+func (s *AlertResultStore) FiringInstances(definitionId AlertId) []AlertId {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	instances := make([]AlertId, 0)
+	for id, status := range s.currentAlertStatus {
+		if id.Labels != "" && id.DefinitionId() == definitionId && status.LatestStatus != AlertStateOk && status.LatestStatus != AlertStateRemoved {
+			instances = append(instances, id)
+		}
+	}
+	return instances
+}
+
+// KnownInstances returns all alert instances of the given alert definition which have a stored status
+// (except AlertStateRemoved).
+func (s *AlertResultStore) KnownInstances(definitionId AlertId) []AlertId {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	instances := make([]AlertId, 0)
+	for id, status := range s.currentAlertStatus {
+		if id.DefinitionId() == definitionId && status.LatestStatus != AlertStateRemoved {
+			instances = append(instances, id)
+		}
+	}
+	return instances
+}
+
+// LatestState returns the last stored state of the alert instance; or "" if none is known.
+func (s *AlertResultStore) LatestState(id AlertId) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.latestStateLocked(id)
+}
+
+// latestStateLocked is LatestState; s.mu must be held.
+func (s *AlertResultStore) latestStateLocked(id AlertId) string {
+	if status := s.currentAlertStatus[id]; status != nil {
+		return status.LatestStatus
+	}
+	return ""
+}
+
 const PERSIST_RESULT_QUERY = `
-INSERT INTO dashica_alert_events(alert_id_group, alert_id_key, timestamp, status, message)
-VALUES({alert_id_group:String}, {alert_id_key:String}, {timestamp:DateTime}, {status:String}, {message:String})
+INSERT INTO dashica_alert_events(alert_id_group, alert_id_key, alert_labels, timestamp, status, message)
+VALUES({alert_id_group:String}, {alert_id_key:String}, {alert_labels:String}, {timestamp:DateTime}, {status:String}, {message:String})
 `
 
 // TODO: maybe implement me: if({alert_result_timestamp:String} != '', toDateTime( {alert_result_timestamp:String}), NULL)
@@ -138,6 +188,7 @@ func (s *AlertResultStore) PersistResultAndNotifyIfChanged(id AlertId, result *A
 	queryOpts := clickhouse.DefaultQueryOptions()
 	queryOpts.Parameters["alert_id_group"] = id.Group
 	queryOpts.Parameters["alert_id_key"] = id.Key
+	queryOpts.Parameters["alert_labels"] = id.Labels
 	queryOpts.Parameters["timestamp"] = result.Timestamp.ToDbStr()
 	queryOpts.Parameters["status"] = result.State // TODO: state vs status
 	// queryOpts.Parameters["alert_result_timestamp"] = "" // TODO IMPLEMENT ME??
@@ -151,10 +202,12 @@ func (s *AlertResultStore) PersistResultAndNotifyIfChanged(id AlertId, result *A
 
 	// persist succeeded, we need to update our in-memory map
 	s.mu.Lock()
+	setAlertStateMetric(id, s.latestStateLocked(id), result.State)
 	if _, exists := s.currentAlertStatus[id]; !exists {
 		s.currentAlertStatus[id] = &currentAlertStatus{
 			AlertIdGroup:    id.Group,
 			AlertIdKey:      id.Key,
+			AlertLabels:     id.Labels,
 			LatestTimestamp: result.Timestamp,
 			LatestStatus:    result.State,
 			LatestMessage:   result.Message,
@@ -181,6 +234,7 @@ func (s *AlertResultStore) ClearAll() error {
 	}
 
 	s.mu.Lock()
+	alertStateGauge.Reset()
 	s.currentAlertStatus = make(map[AlertId]*currentAlertStatus)
 	s.mu.Unlock()
 	return nil
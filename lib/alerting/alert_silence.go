@@ -0,0 +1,92 @@
+package alerting
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sandstorm/dashica/lib/config"
+)
+
+const (
+	// SilenceKindSilence suppresses notifications of all matching alerts between StartsAt and EndsAt
+	// (e.g. during a deployment or a planned maintenance window).
+	SilenceKindSilence = "silence"
+	// SilenceKindAck acknowledges a single firing alert instance: no re-notifications are sent until it is OK again.
+	SilenceKindAck = "ack"
+)
+
+// Silence suppresses notifications for matching alerts; the alert state is still evaluated and recorded.
+type Silence struct {
+	Id   string `json:"id"`
+	Kind string `json:"kind"`
+
+	// AlertIdGroup matches the alert group, i.e. the path of the alerts.yaml file (e.g. "src/shop/alerts.yaml").
+	// "*" matches any number of characters. Empty matches all groups.
+	AlertIdGroup string `json:"alert_id_group"`
+	// AlertIdPattern matches the full alert (instance) id, e.g. "src/shop/alerts.yaml#http_*" or
+	// "src/shop/alerts.yaml#http_500?host_name=web1". "*" matches any number of characters. Empty matches all
+	// alerts (of AlertIdGroup). For SilenceKindAck, this is the exact alert instance id.
+	AlertIdPattern string `json:"alert_id_pattern"`
+
+	StartsAt config.Time `json:"starts_at"`
+	// EndsAt is nil for acknowledgements; they end once the alert instance is OK again.
+	EndsAt *config.Time `json:"ends_at"`
+
+	Author  string `json:"author"`
+	Comment string `json:"comment"`
+}
+
+func (s Silence) validate() error {
+	switch s.Kind {
+	case SilenceKindSilence:
+		if s.AlertIdGroup == "" && s.AlertIdPattern == "" {
+			return errors.New("either alert_id_group or alert_id_pattern must be set")
+		}
+		if s.EndsAt == nil {
+			return errors.New("ends_at must be set")
+		}
+		if !time.Time(*s.EndsAt).After(time.Time(s.StartsAt)) {
+			return errors.New("ends_at must be after starts_at")
+		}
+	case SilenceKindAck:
+		if s.AlertIdPattern == "" {
+			return errors.New("alert_id_pattern must be set")
+		}
+	default:
+		return errors.New("kind must be '" + SilenceKindSilence + "' or '" + SilenceKindAck + "'")
+	}
+	return nil
+}
+
+// IsActiveAt returns true if the silence suppresses notifications at time t.
+func (s Silence) IsActiveAt(t time.Time) bool {
+	if t.Before(time.Time(s.StartsAt)) {
+		return false
+	}
+	return s.EndsAt == nil || t.Before(time.Time(*s.EndsAt))
+}
+
+// Matches returns true if the silence applies to the alert instance id (independent of time).
+func (s Silence) Matches(id AlertId) bool {
+	if s.Kind == SilenceKindAck {
+		return s.AlertIdPattern == id.String()
+	}
+	if s.AlertIdGroup != "" && !globMatch(s.AlertIdGroup, id.Group) {
+		return false
+	}
+	if s.AlertIdPattern != "" && !globMatch(s.AlertIdPattern, id.String()) {
+		return false
+	}
+	return true
+}
+
+// globMatch matches s against pattern, where "*" matches any number of characters (including "/").
+func globMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$").MatchString(s)
+}
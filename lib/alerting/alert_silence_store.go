@@ -0,0 +1,189 @@
+package alerting
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/clickhouse"
+	"github.com/sandstorm/dashica/lib/config"
+)
+
+// NewAlertSilenceStore creates the persistence adapter for silences and acknowledgements (see Silence); stored in
+// the dashica_alert_silences table next to dashica_alert_events.
+func NewAlertSilenceStore(logger zerolog.Logger, clickhouseClient *clickhouse.Client) *AlertSilenceStore {
+	return &AlertSilenceStore{
+		logger:           logger,
+		clickhouseClient: clickhouseClient,
+		silences:         make(map[string]Silence),
+	}
+}
+
+type AlertSilenceStore struct {
+	logger           zerolog.Logger
+	clickhouseClient *clickhouse.Client
+
+	// mutex protecting silences
+	mu sync.RWMutex
+	// silences indexed by Silence.Id
+	silences map[string]Silence
+}
+
+// dashica_alert_silences is a ReplacingMergeTree; expiring a silence inserts a new version of the row.
+const LOAD_SILENCES_QUERY = `
+SELECT
+    id,
+    kind::String AS kind,
+    alert_id_group,
+    alert_id_pattern,
+    starts_at,
+    ends_at,
+    author,
+    comment
+FROM dashica_alert_silences FINAL
+WHERE ends_at IS NULL OR ends_at > now()
+ORDER BY starts_at
+`
+
+func (s *AlertSilenceStore) LoadSilencesIntoMemory() error {
+	resultset, err := clickhouse.QueryJSON[Silence](context.Background(), s.clickhouseClient, LOAD_SILENCES_QUERY, clickhouse.DefaultQueryOptions())
+	if err != nil {
+		return fmt.Errorf("loading alert silences into memory: %w", err)
+	}
+
+	silences := make(map[string]Silence, len(resultset.Data))
+	for _, silence := range resultset.Data {
+		silences[silence.Id] = silence
+	}
+	s.mu.Lock()
+	s.silences = silences
+	s.mu.Unlock()
+	return nil
+}
+
+// Silences returns all silences and acknowledgements which have not ended at t (i.e. active and upcoming ones),
+// ordered by StartsAt.
+func (s *AlertSilenceStore) Silences(t time.Time) []Silence {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	silences := make([]Silence, 0, len(s.silences))
+	for _, silence := range s.silences {
+		if silence.EndsAt == nil || t.Before(time.Time(*silence.EndsAt)) {
+			silences = append(silences, silence)
+		}
+	}
+	slices.SortFunc(silences, func(a, b Silence) int {
+		return time.Time(a.StartsAt).Compare(time.Time(b.StartsAt))
+	})
+	return silences
+}
+
+// ActiveSilence returns a silence (or acknowledgement) suppressing notifications for the alert instance id at t;
+// or nil if notifications should be sent.
+func (s *AlertSilenceStore) ActiveSilence(id AlertId, t time.Time) *Silence {
+	for _, silence := range s.Silences(t) {
+		if silence.IsActiveAt(t) && silence.Matches(id) {
+			return &silence
+		}
+	}
+	return nil
+}
+
+// CreateSilence validates and stores silence; the Id is generated.
+func (s *AlertSilenceStore) CreateSilence(silence Silence) (Silence, error) {
+	if err := silence.validate(); err != nil {
+		return Silence{}, fmt.Errorf("invalid silence: %w", err)
+	}
+	id, err := newSilenceId()
+	if err != nil {
+		return Silence{}, err
+	}
+	silence.Id = id
+
+	if err := s.persist(silence); err != nil {
+		return Silence{}, err
+	}
+	return silence, nil
+}
+
+// ErrSilenceNotFound is returned by ExpireSilence for unknown (or already removed) silences.
+var ErrSilenceNotFound = errors.New("silence not found")
+
+// ExpireSilence ends the silence with the given id at t.
+func (s *AlertSilenceStore) ExpireSilence(id string, t time.Time) error {
+	s.mu.RLock()
+	silence, exists := s.silences[id]
+	s.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("silence %s: %w", id, ErrSilenceNotFound)
+	}
+
+	endsAt := config.Time(t)
+	silence.EndsAt = &endsAt
+	return s.persist(silence)
+}
+
+// EndAcknowledgements expires all acknowledgements of the alert instance id at t (called when it is OK again).
+func (s *AlertSilenceStore) EndAcknowledgements(id AlertId, t time.Time) error {
+	s.mu.RLock()
+	acks := make([]string, 0)
+	for _, silence := range s.silences {
+		if silence.Kind == SilenceKindAck && silence.EndsAt == nil && silence.Matches(id) {
+			acks = append(acks, silence.Id)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, ackId := range acks {
+		if err := s.ExpireSilence(ackId, t); err != nil {
+			return fmt.Errorf("ending acknowledgement of %s: %w", id.String(), err)
+		}
+	}
+	return nil
+}
+
+const PERSIST_SILENCE_QUERY = `
+INSERT INTO dashica_alert_silences(id, kind, alert_id_group, alert_id_pattern, starts_at, ends_at, author, comment, updated_at)
+VALUES({id:String}, {kind:String}, {alert_id_group:String}, {alert_id_pattern:String}, {starts_at:DateTime}, {ends_at:Nullable(DateTime)}, {author:String}, {comment:String}, now64(3))
+`
+
+func (s *AlertSilenceStore) persist(silence Silence) error {
+	queryOpts := clickhouse.DefaultQueryOptions()
+	queryOpts.Parameters["id"] = silence.Id
+	queryOpts.Parameters["kind"] = silence.Kind
+	queryOpts.Parameters["alert_id_group"] = silence.AlertIdGroup
+	queryOpts.Parameters["alert_id_pattern"] = silence.AlertIdPattern
+	queryOpts.Parameters["starts_at"] = silence.StartsAt.ToDbStr()
+	// \N is NULL for Nullable query parameters
+	queryOpts.Parameters["ends_at"] = `\N`
+	if silence.EndsAt != nil {
+		queryOpts.Parameters["ends_at"] = silence.EndsAt.ToDbStr()
+	}
+	queryOpts.Parameters["author"] = silence.Author
+	queryOpts.Parameters["comment"] = silence.Comment
+
+	_, err := s.clickhouseClient.Execute(context.Background(), PERSIST_SILENCE_QUERY, queryOpts)
+	if err != nil {
+		return fmt.Errorf("persisting silence %s: %w", silence.Id, err)
+	}
+
+	s.mu.Lock()
+	s.silences[silence.Id] = silence
+	s.mu.Unlock()
+	return nil
+}
+
+func newSilenceId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating silence id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,187 @@
+package alerting
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func configTime(t time.Time) *config.Time {
+	ct := config.Time(t)
+	return &ct
+}
+
+func TestSilence_Matches(t *testing.T) {
+	web1 := AlertId{Group: "src/shop/alerts.yaml", Key: "http_500", Labels: "host_name=web1"}
+	checkout := AlertId{Group: "src/shop/alerts.yaml", Key: "checkout"}
+	other := AlertId{Group: "src/blog/alerts.yaml", Key: "http_500"}
+
+	tests := []struct {
+		name    string
+		silence Silence
+		matches []AlertId
+	}{
+		{
+			name:    "group",
+			silence: Silence{Kind: SilenceKindSilence, AlertIdGroup: "src/shop/alerts.yaml"},
+			matches: []AlertId{web1, checkout},
+		},
+		{
+			name:    "group glob",
+			silence: Silence{Kind: SilenceKindSilence, AlertIdGroup: "src/*"},
+			matches: []AlertId{web1, checkout, other},
+		},
+		{
+			name:    "alert id glob",
+			silence: Silence{Kind: SilenceKindSilence, AlertIdPattern: "*#http_*"},
+			matches: []AlertId{web1, other},
+		},
+		{
+			name:    "group and alert id",
+			silence: Silence{Kind: SilenceKindSilence, AlertIdGroup: "src/shop/alerts.yaml", AlertIdPattern: "*#http_*"},
+			matches: []AlertId{web1},
+		},
+		{
+			name:    "ack matches exactly",
+			silence: Silence{Kind: SilenceKindAck, AlertIdPattern: "src/shop/alerts.yaml#http_500?host_name=web1"},
+			matches: []AlertId{web1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, id := range []AlertId{web1, checkout, other} {
+				assert.Equal(t, slices.Contains(tt.matches, id), tt.silence.Matches(id), id.String())
+			}
+		})
+	}
+}
+
+func TestSilence_IsActiveAt(t *testing.T) {
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	silence := Silence{Kind: SilenceKindSilence, StartsAt: config.Time(start), EndsAt: configTime(start.Add(time.Hour))}
+
+	assert.False(t, silence.IsActiveAt(start.Add(-time.Minute)))
+	assert.True(t, silence.IsActiveAt(start))
+	assert.True(t, silence.IsActiveAt(start.Add(59*time.Minute)))
+	assert.False(t, silence.IsActiveAt(start.Add(time.Hour)))
+
+	ack := Silence{Kind: SilenceKindAck, StartsAt: config.Time(start)}
+	assert.True(t, ack.IsActiveAt(start.Add(24*time.Hour)), "acks without ends_at are active until the alert recovers")
+}
+
+func TestSilence_Validate(t *testing.T) {
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		silence       Silence
+		expectedError string
+	}{
+		{"valid", Silence{Kind: SilenceKindSilence, AlertIdGroup: "src/*", StartsAt: config.Time(start), EndsAt: configTime(start.Add(time.Hour))}, ""},
+		{"no matcher", Silence{Kind: SilenceKindSilence, StartsAt: config.Time(start), EndsAt: configTime(start.Add(time.Hour))}, "either alert_id_group or alert_id_pattern must be set"},
+		{"no end", Silence{Kind: SilenceKindSilence, AlertIdGroup: "src/*", StartsAt: config.Time(start)}, "ends_at must be set"},
+		{"end before start", Silence{Kind: SilenceKindSilence, AlertIdGroup: "src/*", StartsAt: config.Time(start), EndsAt: configTime(start)}, "ends_at must be after starts_at"},
+		{"unknown kind", Silence{Kind: "mute"}, "kind must be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.silence.validate()
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestAlertManager_NotifyUnlessSilenced(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC)
+	notifier := &fakeNotifier{}
+	silenceStore := NewAlertSilenceStore(zerolog.Nop(), nil)
+	silenceStore.silences["maintenance"] = Silence{
+		Id:           "maintenance",
+		Kind:         SilenceKindSilence,
+		AlertIdGroup: "src/shop/alerts.yaml",
+		StartsAt:     config.Time(now.Add(-time.Hour)),
+		EndsAt:       configTime(now.Add(time.Hour)),
+	}
+	silenceStore.silences["ack"] = Silence{
+		Id:             "ack",
+		Kind:           SilenceKindAck,
+		AlertIdPattern: "src/blog/alerts.yaml#http_500",
+		StartsAt:       config.Time(now.Add(-time.Hour)),
+	}
+	alertManager := &AlertManager{
+		logger:            zerolog.Nop(),
+		notifiers:         map[string]Notifier{DefaultNotifierName: notifier},
+		alertSilenceStore: silenceStore,
+	}
+
+	notify := func(id AlertId, at time.Time) {
+		alertResult := &AlertResult{AlertId: id, State: AlertStateError, Timestamp: config.Time(at)}
+		require.NoError(t, alertManager.notifyUnlessSilenced(AlertDefinition{Id: id}, alertResult, AlertStateOk))
+	}
+
+	notify(AlertId{Group: "src/shop/alerts.yaml", Key: "checkout"}, now)
+	assert.Len(t, notifier.notifications, 0, "silenced during maintenance window")
+
+	notify(AlertId{Group: "src/blog/alerts.yaml", Key: "http_500"}, now)
+	assert.Len(t, notifier.notifications, 0, "acknowledged")
+
+	notify(AlertId{Group: "src/shop/alerts.yaml", Key: "checkout"}, now.Add(2*time.Hour))
+	assert.Len(t, notifier.notifications, 1, "maintenance window is over")
+
+	notify(AlertId{Group: "src/blog/alerts.yaml", Key: "comments"}, now)
+	assert.Len(t, notifier.notifications, 2, "not silenced")
+}
+
+func TestAlertManager_NotifyAfterSilence(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC)
+	notifier := &fakeNotifier{}
+	silenceStore := NewAlertSilenceStore(zerolog.Nop(), nil)
+	silenceStore.silences["maintenance"] = Silence{
+		Id:           "maintenance",
+		Kind:         SilenceKindSilence,
+		AlertIdGroup: "src/shop/alerts.yaml",
+		StartsAt:     config.Time(now.Add(-time.Hour)),
+		EndsAt:       configTime(now.Add(time.Hour)),
+	}
+	alertManager := &AlertManager{
+		logger:            zerolog.Nop(),
+		notifiers:         map[string]Notifier{DefaultNotifierName: notifier},
+		alertSilenceStore: silenceStore,
+	}
+	result := func(key string, state string, at time.Time) *AlertResult {
+		return &AlertResult{AlertId: AlertId{Group: "src/shop/alerts.yaml", Key: key}, State: state, Timestamp: config.Time(at)}
+	}
+
+	// "checkout" starts firing during the maintenance window; "search" fires and recovers during it
+	require.NoError(t, alertManager.notifyUnlessSilenced(AlertDefinition{}, result("checkout", AlertStateError, now), AlertStateOk))
+	require.NoError(t, alertManager.notifyUnlessSilenced(AlertDefinition{}, result("search", AlertStateError, now), AlertStateOk))
+	require.NoError(t, alertManager.notifyUnlessSilenced(AlertDefinition{}, result("search", AlertStateOk, now), AlertStateError))
+	assert.Len(t, notifier.notifications, 0)
+
+	notified, err := alertManager.notifyAfterSilence(AlertDefinition{}, result("checkout", AlertStateError, now.Add(30*time.Minute)))
+	require.NoError(t, err)
+	assert.False(t, notified, "maintenance window is not over yet")
+
+	notified, err = alertManager.notifyAfterSilence(AlertDefinition{}, result("checkout", AlertStateError, now.Add(2*time.Hour)))
+	require.NoError(t, err)
+	assert.True(t, notified)
+	require.Len(t, notifier.notifications, 1)
+	assert.Equal(t, "src/shop/alerts.yaml#checkout", notifier.notifications[0].Id)
+
+	notified, err = alertManager.notifyAfterSilence(AlertDefinition{}, result("search", AlertStateOk, now.Add(2*time.Hour)))
+	require.NoError(t, err)
+	assert.False(t, notified, "the notifiers never saw search firing")
+
+	notified, err = alertManager.notifyAfterSilence(AlertDefinition{}, result("checkout", AlertStateError, now.Add(3*time.Hour)))
+	require.NoError(t, err)
+	assert.False(t, notified, "only notified once")
+}
@@ -0,0 +1,164 @@
+package alerting
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// alertStateTracker turns the raw evaluation result of an alert instance (which only depends on the current value)
+// into its effective state, based on the previous evaluations:
+//
+//   - hysteresis (AlertDefinition.RecoverIf): a firing alert only becomes less severe if recover_if matches.
+//   - pending (AlertDefinition.For / AlertDefinition.Consecutive): a matching alert is AlertStatePending until the
+//     condition held long enough.
+//   - flap detection (AlertDefinition.Flapping): an alert with too many state changes is AlertStateFlapping.
+//
+// The evaluation history is kept in memory only; after a restart, pending alerts start over.
+type alertStateTracker struct {
+	// mutex protecting instances
+	mu        sync.Mutex
+	instances map[AlertId]*trackedInstance
+}
+
+type trackedInstance struct {
+	// matchingSince is the time of the first evaluation of the current streak of matching (non-OK) evaluations;
+	// zero if the last evaluation was OK.
+	matchingSince time.Time
+	// consecutive is the number of evaluations in the current streak of matching evaluations.
+	consecutive int
+
+	// lastState is the last state (before flap detection) taken into account for flap detection.
+	lastState string
+	// stateChanges are the times of the state changes within the flap detection window.
+	stateChanges []time.Time
+	flapping     bool
+}
+
+func newAlertStateTracker() *alertStateTracker {
+	return &alertStateTracker{
+		instances: make(map[AlertId]*trackedInstance),
+	}
+}
+
+// isFiring returns true for states which have been notified as problem.
+func isFiring(state string) bool {
+	return state == AlertStateError || state == AlertStateWarn || state == AlertStateFlapping || state == AlertStateTimeout
+}
+
+// severity orders AlertStateOk < AlertStateWarn < AlertStateError.
+func severity(state string) int {
+	switch state {
+	case AlertStateError:
+		return 2
+	case AlertStateWarn:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// needsNotification returns false for state changes which are not notified: pending alerts have not fired yet.
+func needsNotification(previousState, state string) bool {
+	if state == AlertStatePending {
+		return false
+	}
+	if state == AlertStateOk && previousState == AlertStatePending {
+		return false
+	}
+	return true
+}
+
+// apply computes the effective result for alertResult (the raw evaluation result at alertResult.Timestamp), where
+// previousState is the last persisted state of the alert instance.
+func (t *alertStateTracker) apply(definition AlertDefinition, alertResult *AlertResult, previousState string) *AlertResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	instance := t.instances[alertResult.AlertId]
+	if instance == nil {
+		instance = &trackedInstance{}
+		t.instances[alertResult.AlertId] = instance
+	}
+	now := time.Time(alertResult.Timestamp)
+	result := *alertResult
+
+	// hysteresis: stay in the previous (more severe) state until recover_if matches.
+	firingState := previousState
+	if previousState == AlertStateFlapping {
+		firingState = instance.lastState
+	}
+	if !definition.RecoverIf.IsEmpty() && isFiring(firingState) && severity(result.State) < severity(firingState) && !definition.RecoverIf.Matches(result.Value) {
+		result.State = firingState
+		result.Message = definition.messageFor(firingState)
+	}
+
+	// pending: the condition has to hold long enough before the alert fires.
+	if result.State == AlertStateOk {
+		instance.matchingSince = time.Time{}
+		instance.consecutive = 0
+	} else {
+		if instance.consecutive == 0 {
+			instance.matchingSince = now
+		}
+		instance.consecutive++
+
+		if !isFiring(previousState) {
+			if now.Sub(instance.matchingSince) < definition.forDuration() || instance.consecutive < definition.Consecutive {
+				result.Message = fmt.Sprintf("pending since %s: %s", instance.matchingSince.Format(time.DateTime), result.Message)
+				result.State = AlertStatePending
+			}
+		}
+	}
+
+	if !definition.Flapping.IsEnabled() {
+		return &result
+	}
+
+	// flap detection: pending is not notified, so it does not count as a state change.
+	flapState := result.State
+	if flapState == AlertStatePending {
+		flapState = AlertStateOk
+	}
+	if instance.lastState != "" && instance.lastState != flapState {
+		instance.stateChanges = append(instance.stateChanges, now)
+	}
+	instance.lastState = flapState
+	windowStart := now.Add(-definition.Flapping.window())
+	for len(instance.stateChanges) > 0 && !instance.stateChanges[0].After(windowStart) {
+		instance.stateChanges = instance.stateChanges[1:]
+	}
+
+	if len(instance.stateChanges) >= definition.Flapping.MaxChanges {
+		instance.flapping = true
+	} else if len(instance.stateChanges) <= definition.Flapping.MaxChanges/2 {
+		instance.flapping = false
+	}
+	if instance.flapping {
+		result.State = AlertStateFlapping
+		result.Message = fmt.Sprintf("flapping: %d state changes within %s (currently %s)", len(instance.stateChanges), definition.Flapping.Window, flapState)
+	}
+	return &result
+}
+
+// retain forgets the tracked instances of definitionId which are not in evaluated (e.g. group_by values which
+// disappeared from the result set); so the tracker does not grow with churning instances.
+func (t *alertStateTracker) retain(definitionId AlertId, evaluated []AlertId) {
+	keep := make(map[AlertId]struct{}, len(evaluated))
+	for _, id := range evaluated {
+		keep[id] = struct{}{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id := range t.instances {
+		if _, exists := keep[id]; !exists && id.DefinitionId() == definitionId {
+			delete(t.instances, id)
+		}
+	}
+}
+
+// forget removes all tracked instances of definitionId, e.g. when the alert definition was removed.
+func (t *alertStateTracker) forget(definitionId AlertId) {
+	t.retain(definitionId, nil)
+}
@@ -0,0 +1,135 @@
+package alerting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// runTracker evaluates values (one per minute) against definition, and returns the effective states, feeding the
+// previous effective state back like the AlertResultStore does.
+func runTracker(definition AlertDefinition, values []float64) []string {
+	alertEvaluator := NewAlertEvaluator(zerolog.Nop(), nil, config.NewVirtualTimeProvider())
+	tracker := newAlertStateTracker()
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	states := make([]string, 0, len(values))
+	previousState := ""
+	for i, value := range values {
+		alertResult, _ := alertEvaluator.evaluateThreshold(definition, []alertResultRow{{Value: value}})
+		alertResult.AlertId = definition.Id
+		alertResult.Timestamp = config.Time(start.Add(time.Duration(i) * time.Minute))
+		alertResult = tracker.apply(definition, alertResult, previousState)
+		states = append(states, alertResult.State)
+		previousState = alertResult.State
+	}
+	return states
+}
+
+func TestAlertStateTracker(t *testing.T) {
+	const (
+		ok       = AlertStateOk
+		warn     = AlertStateWarn
+		errr     = AlertStateError
+		pending  = AlertStatePending
+		flapping = AlertStateFlapping
+	)
+	errorAbove10 := AlertDefinition{
+		Id:      AlertId{Group: "g", Key: "k"},
+		ErrorIf: AlertCondition{ValueGt: f64Ptr(10)},
+		Message: "too high",
+	}
+
+	testCases := []struct {
+		name           string
+		configure      func(d *AlertDefinition)
+		values         []float64
+		expectedStates []string
+	}{
+		{
+			name:           "without options, every evaluation counts",
+			configure:      func(d *AlertDefinition) {},
+			values:         []float64{20, 5, 20},
+			expectedStates: []string{errr, ok, errr},
+		},
+		{
+			name:           "consecutive",
+			configure:      func(d *AlertDefinition) { d.Consecutive = 3 },
+			values:         []float64{20, 20, 5, 20, 20, 20, 20, 5},
+			expectedStates: []string{pending, pending, ok, pending, pending, errr, errr, ok},
+		},
+		{
+			name:           "for",
+			configure:      func(d *AlertDefinition) { d.For = "2m" },
+			values:         []float64{20, 20, 20, 20, 5},
+			expectedStates: []string{pending, pending, errr, errr, ok},
+		},
+		{
+			name: "hysteresis",
+			configure: func(d *AlertDefinition) {
+				d.RecoverIf = AlertCondition{ValueLt: f64Ptr(8)}
+			},
+			values:         []float64{20, 9, 11, 9, 7, 9},
+			expectedStates: []string{errr, errr, errr, errr, ok, ok},
+		},
+		{
+			name: "hysteresis from error to warn",
+			configure: func(d *AlertDefinition) {
+				d.WarnIf = AlertCondition{ValueGt: f64Ptr(5)}
+				d.RecoverIf = AlertCondition{ValueLt: f64Ptr(8)}
+			},
+			values:         []float64{20, 9, 6, 3},
+			expectedStates: []string{errr, errr, warn, ok},
+		},
+		{
+			name: "flapping",
+			configure: func(d *AlertDefinition) {
+				d.Flapping = FlapDetection{Window: "10m", MaxChanges: 4}
+			},
+			// 4 changes within the window -> flapping; stops once at most 2 changes are within the window
+			values:         []float64{20, 5, 20, 5, 20, 20, 20, 20, 20, 20, 20, 20, 20},
+			expectedStates: []string{errr, ok, errr, ok, flapping, flapping, flapping, flapping, flapping, flapping, flapping, flapping, errr},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			definition := errorAbove10
+			tc.configure(&definition)
+			assert.Equal(t, tc.expectedStates, runTracker(definition, tc.values))
+		})
+	}
+}
+
+func TestAlertStateTracker_Retain(t *testing.T) {
+	definition := AlertDefinition{Id: AlertId{Group: "g1", Key: "http500"}, GroupBy: []string{"host_name"}}
+	web1 := definition.Id.WithLabels(map[string]string{"host_name": "web1"})
+	web2 := definition.Id.WithLabels(map[string]string{"host_name": "web2"})
+	other := AlertId{Group: "g1", Key: "other"}.WithLabels(map[string]string{"host_name": "web3"})
+
+	tracker := newAlertStateTracker()
+	for _, id := range []AlertId{web1, web2, other} {
+		tracker.apply(definition, &AlertResult{AlertId: id, State: AlertStateError}, "")
+	}
+
+	// web2 disappeared from the result set; instances of other alerts are kept
+	tracker.retain(definition.Id, []AlertId{web1})
+	assert.Len(t, tracker.instances, 2)
+	assert.Contains(t, tracker.instances, web1)
+	assert.Contains(t, tracker.instances, other)
+
+	tracker.forget(definition.Id)
+	assert.Len(t, tracker.instances, 1)
+	assert.Contains(t, tracker.instances, other)
+}
+
+func TestNeedsNotification(t *testing.T) {
+	assert.False(t, needsNotification(AlertStateOk, AlertStatePending))
+	assert.False(t, needsNotification(AlertStatePending, AlertStateOk))
+	assert.True(t, needsNotification(AlertStatePending, AlertStateError))
+	assert.True(t, needsNotification(AlertStateError, AlertStateOk))
+	assert.True(t, needsNotification(AlertStateOk, AlertStateFlapping))
+}
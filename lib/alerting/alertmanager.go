@@ -2,15 +2,17 @@ package alerting
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"net/http"
-	"net/url"
-	"strings"
+	"slices"
 	"sync"
+	"time"
 
+	"github.com/adhocore/gronx"
 	"github.com/adhocore/gronx/pkg/tasker"
 	"github.com/rs/zerolog"
 	"github.com/sandstorm/dashica/lib/config"
@@ -23,26 +25,82 @@ type AlertManager struct {
 	fileSystem       fs.FS
 	alertEvaluator   *AlertEvaluator
 	alertResultStore *AlertResultStore
+	// alertSilenceStore is optional; without it, notifications are never silenced.
+	alertSilenceStore *AlertSilenceStore
+	// silencedStates holds, for alert instances whose state change was suppressed by a silence, the state last sent
+	// to the notifiers; once the silence is over, a changed state is notified (see notifyAfterSilence). It is not
+	// persisted, so state changes during a silence are not notified after a restart.
+	silencedStates   map[AlertId]string
+	silencedStatesMu sync.Mutex
 	// alertDefinitionPattern is not configurable from userland, but helpful for overriding during tests.
 	alertDefinitionPattern string
+	// notifiers indexed by name, see config.AlertingConfig.Notifiers
+	notifiers map[string]Notifier
+	// stateTracker applies pending / hysteresis / flap detection to the evaluation results
+	stateTracker *alertStateTracker
+	// evaluationSlots is a semaphore limiting the number of concurrent alert evaluations
+	// (see config.AlertingConfig.MaxConcurrentEvaluations).
+	evaluationSlots chan struct{}
+	// evaluationTimeout is the default timeout of an alert evaluation (see AlertDefinition.Timeout).
+	evaluationTimeout time.Duration
+	// shutdownCtx is cancelled by Shutdown once its drain timeout is exceeded; this cancels running evaluations.
+	shutdownCtx    context.Context
+	cancelShutdown context.CancelFunc
+	// runningEvaluations tracks the running evaluateAll calls, so that Shutdown can wait for them.
+	runningEvaluations sync.WaitGroup
+	// inFlight holds the alerts being evaluated; an alert is not evaluated again before its previous evaluation
+	// finished (which may take longer than its check interval).
+	inFlight   map[AlertId]struct{}
+	inFlightMu sync.Mutex
 
 	// mutex protecting LoadedAlertsDefinition
 	mu                     sync.RWMutex
 	loadedAlertDefinitions []AlertDefinition
+	// schedulerRunning is set once RunAlertScheduler did the startup evaluation; from then on,
+	// ReloadAlertDefinitions evaluates changed alerts immediately.
+	schedulerRunning bool
+	// shuttingDown is set by Shutdown; from then on, no new evaluations are started.
+	shuttingDown bool
 }
 
-func NewAlertManager(config *config.Config, logger zerolog.Logger, fileSystem fs.FS, alertEvaluator *AlertEvaluator, alertResultStore *AlertResultStore) *AlertManager {
+// errShuttingDown is returned for evaluations requested after Shutdown.
+var errShuttingDown = errors.New("alert manager is shutting down")
+
+func NewAlertManager(config *config.Config, logger zerolog.Logger, fileSystem fs.FS, alertEvaluator *AlertEvaluator, alertResultStore *AlertResultStore, alertSilenceStore *AlertSilenceStore) *AlertManager {
 	logger = logger.With().
 		Str(logging.EventDataset, logging.EventDataset_Dashica_Alerting_Manager).
 		Logger()
 
+	notifiers, err := newNotifiers(config.Alerting)
+	if err != nil {
+		logger.Error().Err(err).Msg("could not create alert notifiers; alerts will not be notified")
+	}
+
+	maxConcurrentEvaluations := config.Alerting.MaxConcurrentEvaluations
+	if maxConcurrentEvaluations < 1 {
+		maxConcurrentEvaluations = 1
+	}
+	evaluationTimeout := config.Alerting.EvaluationTimeout
+	if evaluationTimeout <= 0 {
+		evaluationTimeout = 30 * time.Second
+	}
+
+	shutdownCtx, cancelShutdown := context.WithCancel(context.Background())
+
 	return &AlertManager{
 		config:                 config,
 		logger:                 logger,
 		fileSystem:             fileSystem,
 		alertEvaluator:         alertEvaluator,
 		alertResultStore:       alertResultStore,
+		alertSilenceStore:      alertSilenceStore,
 		alertDefinitionPattern: "src/*/alerts.yaml",
+		notifiers:              notifiers,
+		stateTracker:           newAlertStateTracker(),
+		evaluationSlots:        make(chan struct{}, maxConcurrentEvaluations),
+		evaluationTimeout:      evaluationTimeout,
+		shutdownCtx:            shutdownCtx,
+		cancelShutdown:         cancelShutdown,
 	}
 }
 
@@ -62,6 +120,11 @@ func (a *AlertManager) DiscoverAlertDefinitions() error {
 		if err != nil {
 			return fmt.Errorf("processing %s: %w", filePath, err)
 		}
+		for i, alertDefinition := range alertDefinitions {
+			if alertDefinitions[i], err = resolveAlertDefinition(alertDefinition, a.config); err != nil {
+				return fmt.Errorf("processing %s: %w", filePath, err)
+			}
+		}
 		fullAlertDefinitions = append(fullAlertDefinitions, alertDefinitions...)
 	}
 
@@ -77,26 +140,113 @@ func (a *AlertManager) DiscoverAlertDefinitions() error {
 	return nil
 }
 
+// CheckSchedules validates the check_every cron expressions of the discovered alert definitions, and
+// alerting.alert_cron_monitor_schedule; invalid ones are skipped by the scheduler.
+func (a *AlertManager) CheckSchedules() error {
+	var errs []error
+	for _, alertDefinition := range a.alertDefinitions() {
+		if !gronx.IsValid(alertDefinition.CheckEvery) {
+			errs = append(errs, fmt.Errorf("alert %s: invalid check_every '%s'", alertDefinition.Id.String(), alertDefinition.CheckEvery))
+		}
+	}
+	if schedule := a.config.Alerting.AlertCronMonitorSchedule; schedule != "" && !gronx.IsValid(schedule) {
+		errs = append(errs, fmt.Errorf("alerting.alert_cron_monitor_schedule: invalid cron expression '%s'", schedule))
+	}
+	return errors.Join(errs...)
+}
+
+// GetAlertDefinition returns the alert definition for id; for alert instances (see AlertDefinition.GroupBy), the
+// definition they belong to is returned.
 func (a *AlertManager) GetAlertDefinition(id AlertId) *AlertDefinition {
-	a.mu.RLock()
-	loadedAlertDefinitions := a.loadedAlertDefinitions[:]
-	a.mu.RUnlock()
-	for _, alertDefinition := range loadedAlertDefinitions {
-		if alertDefinition.Id == id {
+	for _, alertDefinition := range a.alertDefinitions() {
+		if alertDefinition.Id == id.DefinitionId() {
 			return &alertDefinition
 		}
 	}
 	return nil
 }
 
-func (a *AlertManager) RunAlertScheduler() error {
+// alertDefinitions returns the currently loaded alert definitions.
+func (a *AlertManager) alertDefinitions() []AlertDefinition {
 	a.mu.RLock()
-	loadedAlertDefinitions := a.loadedAlertDefinitions[:]
+	defer a.mu.RUnlock()
+	return a.loadedAlertDefinitions[:]
+}
+
+// ReloadAlertDefinitions re-discovers all alert definitions, and applies the changes to the running scheduler:
+//   - added and changed alerts are evaluated immediately, and from then on scheduled according to their check_every.
+//   - for all instances of removed alerts, a final AlertStateRemoved event is written (and, if they were not OK,
+//     a resolve notification is sent).
+//
+// If the alert definitions cannot be parsed, the previously loaded definitions stay active.
+func (a *AlertManager) ReloadAlertDefinitions(ctx context.Context) error {
+	previousDefinitions := a.alertDefinitions()
+	if err := a.DiscoverAlertDefinitions(); err != nil {
+		return fmt.Errorf("reloading alert definitions (keeping previous definitions): %w", err)
+	}
+
+	diff := diffAlertDefinitions(previousDefinitions, a.alertDefinitions())
+	if diff.isEmpty() {
+		return nil
+	}
+	a.logger.Info().
+		Strs("added", diff.added.ids()).
+		Strs("changed", diff.changed.ids()).
+		Strs("removed", diff.removed.ids()).
+		Msg("alert definitions reloaded")
+
+	a.mu.RLock()
+	schedulerRunning := a.schedulerRunning
 	a.mu.RUnlock()
+	if !schedulerRunning {
+		// the scheduler evaluates everything on startup anyway.
+		return nil
+	}
+
+	var errs []error
+	for _, alertDefinition := range diff.removed {
+		errs = append(errs, a.persistRemoved(alertDefinition))
+	}
+	for _, alertDefinition := range diff.changed {
+		if previous := diff.previous[alertDefinition.Id]; !slices.Equal(previous.GroupBy, alertDefinition.GroupBy) {
+			// the alert instances change with group_by; so the old ones will never be evaluated again.
+			errs = append(errs, a.persistRemoved(previous))
+		}
+	}
+	errs = append(errs, a.evaluateAll(ctx, append(diff.changed, diff.added...)))
+	return errors.Join(errs...)
+}
+
+// WatchAlertDefinitions calls ReloadAlertDefinitions every interval, until ctx is done. Polling is used (instead of
+// fsnotify), as the project file system is an arbitrary fs.FS.
+func (a *AlertManager) WatchAlertDefinitions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// only log each distinct reload error once; otherwise a broken alerts.yaml would spam the log every interval.
+	lastErr := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := a.ReloadAlertDefinitions(ctx)
+			if err == nil {
+				lastErr = ""
+			} else if err.Error() != lastErr {
+				lastErr = err.Error()
+				a.logger.Error().Err(err).Msg("reloading alert definitions failed")
+			}
+		}
+	}
+}
 
+// RunAlertScheduler evaluates all alerts once, and then according to their check_every, until ctx is done.
+// Cancelling ctx only stops scheduling new evaluations; running evaluations are finished (see Shutdown).
+func (a *AlertManager) RunAlertScheduler(ctx context.Context) error {
 	taskr := tasker.New(tasker.Option{
 		Verbose: true,
-	})
+	}).WithContext(ctx)
 	// log taskr logs via Zerolog
 	taskr.Log = log.New(a.logger, "", log.LstdFlags)
 
@@ -104,42 +254,32 @@ func (a *AlertManager) RunAlertScheduler() error {
 	if err != nil {
 		return fmt.Errorf("loading alert status into memory: %w", err)
 	}
-
-	if len(loadedAlertDefinitions) == 0 {
-		// no alerts, nothing needs to be done
-		return nil
-	}
-	for _, alertDefinition := range loadedAlertDefinitions {
-		// on startup, evaluate all alerts once; and if needed, also send notifications
-		{
-			alertResult, err := a.alertEvaluator.EvaluateAlert(alertDefinition)
-			if err != nil {
-				a.logger.Error().Err(err).Str("alertId", alertDefinition.Id.String()).Msg("startup evaluation failed, will retry on next schedule")
-			} else {
-				err = a.alertResultStore.PersistResultAndNotifyIfChanged(alertDefinition.Id, alertResult, func() error {
-					return a.notifyAlertChange(alertDefinition, alertResult)
-				})
-				if err != nil {
-					a.logger.Error().Err(err).Str("alertId", alertDefinition.Id.String()).Msg("persisting startup alert result failed, will retry on next schedule")
-				}
-			}
+	if a.alertSilenceStore != nil {
+		if err := a.alertSilenceStore.LoadSilencesIntoMemory(); err != nil {
+			// not fatal: alerting still works, only without the existing silences.
+			a.logger.Error().Err(err).Msg("could not load alert silences; notifications will not be silenced")
 		}
+	}
 
-		taskr.Task(alertDefinition.CheckEvery, func(ctx context.Context) (int, error) {
-			alertResult, err := a.alertEvaluator.EvaluateAlert(alertDefinition)
-			if err != nil {
-				return 1, fmt.Errorf("evaluating alert %s: %w", alertDefinition.Id.String(), err)
-			}
-			err = a.alertResultStore.PersistResultAndNotifyIfChanged(alertDefinition.Id, alertResult, func() error {
-				return a.notifyAlertChange(alertDefinition, alertResult)
-			})
-			if err != nil {
-				return 1, fmt.Errorf("persisting alert result and notifying %s: %w", alertDefinition.Id.String(), err)
-			}
-			// then return exit code and error, for eg: if everything okay
-			return 0, nil
-		})
+	// on startup, evaluate all alerts once; and if needed, also send notifications
+	if err := a.evaluateAll(ctx, a.alertDefinitions()); err != nil {
+		a.logger.Error().Err(err).Msg("startup evaluation failed, will retry on next schedule")
 	}
+	a.mu.Lock()
+	a.schedulerRunning = true
+	a.mu.Unlock()
+
+	// Instead of registering one task per alert (which cannot be removed from the tasker again), a single task
+	// runs every second and evaluates all due alerts; so that ReloadAlertDefinitions takes effect immediately.
+	// It runs every second (and not every minute) because check_every may contain a seconds field.
+	taskr.Task("* * * * * *", func(ctx context.Context) (int, error) {
+		schedulerLastRun.Set(float64(time.Now().Unix()))
+		if err := a.evaluateDueAlerts(ctx, time.Now()); err != nil {
+			return 1, err
+		}
+		// then return exit code and error, for eg: if everything okay
+		return 0, nil
+	})
 
 	// the cronjob to test alerts is running
 	if a.config.Alerting.AlertCronMonitorSchedule != "" && a.config.Alerting.AlertCronMonitorUrl != "" {
@@ -159,50 +299,375 @@ func (a *AlertManager) RunAlertScheduler() error {
 	return nil
 }
 
-func (a *AlertManager) notifyAlertChange(alertDefinition AlertDefinition, alertResult *AlertResult) error {
-	if a.config.Alerting.HelvetikitAlertingUrl == "" {
-		a.logger.Warn().Msg("No Helvetikit server found; will not trigger alert")
+// evaluateDueAlerts evaluates all alerts whose check_every is due at now.
+func (a *AlertManager) evaluateDueAlerts(ctx context.Context, now time.Time) error {
+	dueDefinitions, err := dueAlertDefinitions(a.alertDefinitions(), now)
+	if err != nil {
+		a.logger.Error().Err(err).Msg("invalid check_every; alert is not scheduled")
+	}
+	return a.evaluateAll(ctx, dueDefinitions)
+}
+
+// evaluateAll evaluates (and persists) alertDefinitions in parallel, with at most
+// config.AlertingConfig.MaxConcurrentEvaluations evaluations running at the same time.
+func (a *AlertManager) evaluateAll(ctx context.Context, alertDefinitions []AlertDefinition) error {
+	a.mu.Lock()
+	if a.shuttingDown {
+		a.mu.Unlock()
+		return errShuttingDown
+	}
+	a.runningEvaluations.Add(1)
+	a.mu.Unlock()
+	defer a.runningEvaluations.Done()
+
+	// Running evaluations are not cancelled together with ctx (e.g. when the scheduler stops), as this could abort
+	// them between notifying and persisting; only Shutdown cancels them once its drain timeout is exceeded.
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	stopCancelOnShutdown := context.AfterFunc(a.shutdownCtx, cancel)
+	defer stopCancelOnShutdown()
+
+	var wg sync.WaitGroup
+	var errsMu sync.Mutex
+	var errs []error
+	for _, alertDefinition := range alertDefinitions {
+		if !a.startEvaluation(alertDefinition.Id) {
+			a.logger.Warn().
+				Str("alertId", alertDefinition.Id.String()).
+				Msg("previous evaluation is still running; skipping alert evaluation")
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer a.finishEvaluation(alertDefinition.Id)
+			if err := a.evaluateAndPersist(ctx, alertDefinition); err != nil {
+				errsMu.Lock()
+				errs = append(errs, err)
+				errsMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// startEvaluation marks id as being evaluated; false if it already is.
+func (a *AlertManager) startEvaluation(id AlertId) bool {
+	a.inFlightMu.Lock()
+	defer a.inFlightMu.Unlock()
+	if _, running := a.inFlight[id]; running {
+		return false
+	}
+	if a.inFlight == nil {
+		a.inFlight = make(map[AlertId]struct{})
+	}
+	a.inFlight[id] = struct{}{}
+	return true
+}
+
+func (a *AlertManager) finishEvaluation(id AlertId) {
+	a.inFlightMu.Lock()
+	defer a.inFlightMu.Unlock()
+	delete(a.inFlight, id)
+}
+
+// Shutdown stops evaluating alerts: no new evaluations are started, and running evaluations (including persisting
+// and notifying their results) are awaited until ctx is done - then they are cancelled. The scheduler itself is
+// stopped by cancelling the context passed to RunAlertScheduler.
+func (a *AlertManager) Shutdown(ctx context.Context) error {
+	a.mu.Lock()
+	a.shuttingDown = true
+	a.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		a.runningEvaluations.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
 		return nil
+	case <-ctx.Done():
+		a.cancelShutdown()
+		<-finished
+		return fmt.Errorf("running alert evaluations were cancelled: %w", ctx.Err())
 	}
+}
 
-	formBody := url.Values{}
-	formBody.Add("group", "dashica_"+a.config.Alerting.HelvetikitIdGroup)
-	formBody.Add("id", alertDefinition.Id.String())
+// dueAlertDefinitions returns the definitions whose check_every cron expression is due in the second of now.
+// Definitions with an invalid check_every are skipped and reported in the returned error.
+func dueAlertDefinitions(definitions []AlertDefinition, now time.Time) ([]AlertDefinition, error) {
+	// the scheduler ticks every second; 5-field expressions are only due in the first second of the minute.
+	tick := now.Truncate(time.Second)
+	gron := gronx.New()
+
+	due := make([]AlertDefinition, 0, len(definitions))
+	var errs []error
+	for _, alertDefinition := range definitions {
+		isDue, err := gron.IsDue(alertDefinition.CheckEvery, tick)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("alert %s: check_every '%s': %w", alertDefinition.Id.String(), alertDefinition.CheckEvery, err))
+			continue
+		}
+		if isDue {
+			due = append(due, alertDefinition)
+		}
+	}
+	return due, errors.Join(errs...)
+}
 
-	if alertResult.State == AlertStateOk {
-		formBody.Add("state", "NORMAL")
-	} else if alertResult.State == AlertStateWarn {
-		formBody.Add("state", "WARNING")
-	} else {
-		formBody.Add("state", "ERROR")
+// evaluateAndPersist evaluates all instances of alertDefinition; and persists (and notifies) their results.
+//
+// It waits for a free evaluation slot first. If the evaluation does not finish within the alert timeout, all
+// instances are persisted as AlertStateTimeout (instead of only logging the error).
+func (a *AlertManager) evaluateAndPersist(ctx context.Context, alertDefinition AlertDefinition) error {
+	select {
+	case a.evaluationSlots <- struct{}{}:
+		defer func() { <-a.evaluationSlots }()
+	case <-ctx.Done():
+		return fmt.Errorf("evaluating alert %s: %w", alertDefinition.Id.String(), ctx.Err())
 	}
 
-	formBody.Add("message", alertResult.Message)
-	if alertDefinition.SlackChannel != "" {
-		formBody.Add("slack_channel", alertDefinition.SlackChannel)
+	timeout := alertDefinition.timeout(a.evaluationTimeout)
+	evaluationCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	alertResults, err := a.alertEvaluator.EvaluateAlertInstances(evaluationCtx, alertDefinition, a.alertResultStore.FiringInstances(alertDefinition.Id))
+	evaluationDuration.Observe(time.Since(start).Seconds(), alertDefinition.Id.Group, alertDefinition.Id.Key)
+	timedOut := err != nil && ctx.Err() == nil && errors.Is(evaluationCtx.Err(), context.DeadlineExceeded)
+	if timedOut {
+		evaluationErrorsTotal.Inc(alertDefinition.Id.Group, alertDefinition.Id.Key, "timeout")
+		a.logger.Warn().
+			Err(err).
+			Str("alertId", alertDefinition.Id.String()).
+			Dur("timeout", timeout).
+			Msg("alert evaluation timed out")
+		alertResults = a.timeoutResults(alertDefinition, timeout)
+	} else if err != nil {
+		evaluationErrorsTotal.Inc(alertDefinition.Id.Group, alertDefinition.Id.Key, "error")
+		return fmt.Errorf("evaluating alert %s: %w", alertDefinition.Id.String(), err)
 	}
 
-	// TODO: deduplication_mode=NO for alerting on individual log lines
+	if !timedOut {
+		evaluated := make([]AlertId, 0, len(alertResults))
+		for _, alertResult := range alertResults {
+			evaluated = append(evaluated, alertResult.AlertId)
+		}
+		a.stateTracker.retain(alertDefinition.Id, evaluated)
+	}
 
-	resp, err := http.Post(a.config.Alerting.HelvetikitAlertingUrl, "application/x-www-form-urlencoded", strings.NewReader(formBody.Encode()))
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+	var errs []error
+	for _, alertResult := range alertResults {
+		previousState := a.alertResultStore.LatestState(alertResult.AlertId)
+		if !timedOut {
+			// a timeout says nothing about the alert condition; so it does not count for pending / flapping.
+			alertResult = a.stateTracker.apply(alertDefinition, alertResult, previousState)
+		}
+		notified := false
+		err = a.alertResultStore.PersistResultAndNotifyIfChanged(alertResult.AlertId, alertResult, func() error {
+			if !needsNotification(previousState, alertResult.State) {
+				return noNotification()
+			}
+			notified = true
+			return a.notifyUnlessSilenced(alertDefinition, alertResult, previousState)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("persisting alert result and notifying %s: %w", alertResult.AlertId.String(), err))
+		}
+		if !notified {
+			notified, err = a.notifyAfterSilence(alertDefinition, alertResult)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("notifying %s after silence: %w", alertResult.AlertId.String(), err))
+			}
+		}
+		if !notified && isFiring(alertResult.State) {
+			if err := a.refreshFiring(alertDefinition, alertResult); err != nil {
+				errs = append(errs, fmt.Errorf("re-sending firing alert %s: %w", alertResult.AlertId.String(), err))
+			}
+		}
 	}
-	defer func(Body io.ReadCloser) {
-		_ = Body.Close()
-	}(resp.Body)
+	return errors.Join(errs...)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, readErr := io.ReadAll(resp.Body)
-		if readErr != nil {
-			return fmt.Errorf("request failed with status %d and error reading body: %w",
-				resp.StatusCode, readErr)
+// timeoutResults returns an AlertStateTimeout result for every known instance of alertDefinition (or for the
+// alert itself, if no instances are known yet).
+func (a *AlertManager) timeoutResults(alertDefinition AlertDefinition, timeout time.Duration) []*AlertResult {
+	instanceIds := []AlertId{alertDefinition.Id}
+	if len(alertDefinition.GroupBy) > 0 {
+		if knownInstances := a.alertResultStore.KnownInstances(alertDefinition.Id); len(knownInstances) > 0 {
+			instanceIds = knownInstances
 		}
-		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
-	return nil
+	alertResults := make([]*AlertResult, 0, len(instanceIds))
+	for _, instanceId := range instanceIds {
+		alertResults = append(alertResults, a.alertEvaluator.withTimestamp(&AlertResult{
+			AlertId: instanceId,
+			State:   AlertStateTimeout,
+			Message: fmt.Sprintf("evaluation timed out after %s", timeout),
+		}))
+	}
+	return alertResults
+}
 
+// persistRemoved writes a final AlertStateRemoved event for all instances of alertDefinition. Instances which
+// were not OK are resolved at the notifiers.
+func (a *AlertManager) persistRemoved(alertDefinition AlertDefinition) error {
+	a.stateTracker.forget(alertDefinition.Id)
+	var errs []error
+	for _, instanceId := range a.alertResultStore.KnownInstances(alertDefinition.Id) {
+		previousState := a.alertResultStore.LatestState(instanceId)
+		alertResult := a.alertEvaluator.withTimestamp(&AlertResult{
+			AlertId: instanceId,
+			State:   AlertStateRemoved,
+			Message: "alert definition removed",
+		})
+		err := a.alertResultStore.PersistResultAndNotifyIfChanged(instanceId, alertResult, func() error {
+			if !isFiring(previousState) {
+				return noNotification()
+			}
+			return a.notifyUnlessSilenced(alertDefinition, &AlertResult{
+				AlertId:   instanceId,
+				State:     AlertStateOk,
+				Message:   alertResult.Message,
+				Timestamp: alertResult.Timestamp,
+			}, previousState)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("persisting removal of %s: %w", instanceId.String(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notifyUnlessSilenced calls notifyAlertChange, except if a silence or acknowledgement is active for the alert
+// instance. Once an acknowledged alert instance is OK again, its acknowledgement ends and the recovery is notified.
+// previousState is the state before alertResult; if the notification is suppressed, it is remembered for
+// notifyAfterSilence.
+func (a *AlertManager) notifyUnlessSilenced(alertDefinition AlertDefinition, alertResult *AlertResult, previousState string) error {
+	if a.alertSilenceStore != nil {
+		evaluatedAt := time.Time(alertResult.Timestamp)
+		if alertResult.State == AlertStateOk {
+			if err := a.alertSilenceStore.EndAcknowledgements(alertResult.AlertId, evaluatedAt); err != nil {
+				a.logger.Error().Err(err).Str("alertId", alertResult.AlertId.String()).Msg("could not end acknowledgement")
+			}
+		}
+		if silence := a.alertSilenceStore.ActiveSilence(alertResult.AlertId, evaluatedAt); silence != nil {
+			a.logger.Info().
+				Str("alertId", alertResult.AlertId.String()).
+				Str("state", alertResult.State).
+				Str("silenceId", silence.Id).
+				Str("silenceKind", silence.Kind).
+				Msg("notification suppressed by silence")
+			a.silencedStatesMu.Lock()
+			if a.silencedStates == nil {
+				a.silencedStates = make(map[AlertId]string)
+			}
+			if _, exists := a.silencedStates[alertResult.AlertId]; !exists {
+				// the notifiers know the state before the first suppressed change
+				a.silencedStates[alertResult.AlertId] = previousState
+			}
+			a.silencedStatesMu.Unlock()
+			return nil
+		}
+	}
+	a.silencedStatesMu.Lock()
+	delete(a.silencedStates, alertResult.AlertId)
+	a.silencedStatesMu.Unlock()
+	return a.notifyAlertChange(alertDefinition, alertResult)
+}
+
+// notifyAfterSilence notifies the (unchanged) alertResult if state changes of the alert instance were suppressed
+// by a silence which is over now, and the notifiers know a different state; e.g. an alert which started firing
+// during a maintenance window and is still firing afterwards. Returns whether a notification was sent.
+func (a *AlertManager) notifyAfterSilence(alertDefinition AlertDefinition, alertResult *AlertResult) (bool, error) {
+	a.silencedStatesMu.Lock()
+	notifiedState, exists := a.silencedStates[alertResult.AlertId]
+	a.silencedStatesMu.Unlock()
+	if !exists || a.alertSilenceStore == nil || a.alertSilenceStore.ActiveSilence(alertResult.AlertId, time.Time(alertResult.Timestamp)) != nil {
+		return false, nil
+	}
+
+	a.silencedStatesMu.Lock()
+	delete(a.silencedStates, alertResult.AlertId)
+	a.silencedStatesMu.Unlock()
+	if alertResult.State == notifiedState || !(isFiring(alertResult.State) || isFiring(notifiedState)) {
+		return false, nil
+	}
+	a.logger.Info().
+		Str("alertId", alertResult.AlertId.String()).
+		Str("state", alertResult.State).
+		Msg("silence is over; notifying state changed during silence")
+	return true, a.notifyAlertChange(alertDefinition, alertResult)
+}
+
+// refreshFiring re-sends the still firing alertResult to the notifiers of alertDefinition which expire alerts that
+// are not sent again (see repeatingNotifier), unless a silence or acknowledgement is active for the alert instance.
+func (a *AlertManager) refreshFiring(alertDefinition AlertDefinition, alertResult *AlertResult) error {
+	if a.alertSilenceStore != nil && a.alertSilenceStore.ActiveSilence(alertResult.AlertId, time.Time(alertResult.Timestamp)) != nil {
+		return nil
+	}
+	return a.notify(alertDefinition, alertResult, true)
+}
+
+// Acknowledge acknowledges the firing alert instance id: until it is OK again, no notifications are sent for it.
+func (a *AlertManager) Acknowledge(id AlertId, author string, comment string) (Silence, error) {
+	if a.alertSilenceStore == nil {
+		return Silence{}, errors.New("acknowledging alerts is not supported without alert silence store")
+	}
+	state := a.alertResultStore.LatestState(id)
+	if !isFiring(state) {
+		return Silence{}, fmt.Errorf("alert %s is not firing (state: '%s')", id.String(), state)
+	}
+
+	return a.alertSilenceStore.CreateSilence(Silence{
+		Kind:           SilenceKindAck,
+		AlertIdPattern: id.String(),
+		StartsAt:       config.Time(time.Now()),
+		Author:         author,
+		Comment:        comment,
+	})
+}
+
+// notifyAlertChange sends the alertResult to all notifiers of alertDefinition (see AlertDefinition.Notify).
+func (a *AlertManager) notifyAlertChange(alertDefinition AlertDefinition, alertResult *AlertResult) error {
+	return a.notify(alertDefinition, alertResult, false)
+}
+
+// notify sends the alertResult to the notifiers of alertDefinition; if onlyRepeating is set, only to those which
+// re-send firing alerts (see repeatingNotifier).
+func (a *AlertManager) notify(alertDefinition AlertDefinition, alertResult *AlertResult, onlyRepeating bool) error {
+	notifierNames := alertDefinition.Notify
+	if len(notifierNames) == 0 {
+		if _, exists := a.notifiers[DefaultNotifierName]; !exists {
+			if !onlyRepeating {
+				a.logger.Warn().Msg("No Helvetikit server found; will not trigger alert")
+			}
+			return nil
+		}
+		notifierNames = []string{DefaultNotifierName}
+	}
+
+	notification := newNotification(alertDefinition, alertResult)
+	var errs []error
+	for _, notifierName := range notifierNames {
+		notifier, exists := a.notifiers[notifierName]
+		if !exists {
+			if !onlyRepeating {
+				// already reported with the state change
+				errs = append(errs, fmt.Errorf("notifier '%s' not configured in alerting.notifiers", notifierName))
+			}
+			continue
+		}
+		if _, repeating := notifier.(repeatingNotifier); onlyRepeating && !repeating {
+			continue
+		}
+		if err := notifier.Notify(context.Background(), notification); err != nil {
+			errs = append(errs, fmt.Errorf("notifier '%s': %w", notifierName, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 func (a *AlertManager) sendCronHeartBeat(cronMonitorUrl string) error {
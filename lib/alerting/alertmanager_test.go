@@ -0,0 +1,250 @@
+package alerting
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/clickhouse"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/sandstorm/dashica/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClickhouse answers alert queries with value 1; queries containing "slow" block until they are cancelled.
+// INSERTs (i.e. persisted alert results) always succeed.
+type fakeClickhouse struct {
+	mu               sync.Mutex
+	running          int
+	maxRunning       int
+	executedQueryCnt int
+}
+
+func (f *fakeClickhouse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	query := string(body)
+	if strings.Contains(query, "INSERT") {
+		return
+	}
+
+	f.mu.Lock()
+	f.running++
+	f.executedQueryCnt++
+	f.maxRunning = max(f.maxRunning, f.running)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.running--
+		f.mu.Unlock()
+	}()
+
+	if strings.Contains(query, "slow") {
+		<-r.Context().Done()
+		return
+	}
+	time.Sleep(20 * time.Millisecond)
+	_, _ = w.Write([]byte(`{"data": [{"time_ts": 0, "value": 1}], "rows": 1}`))
+}
+
+func newAlertManagerWithFakeClickhouse(t *testing.T, alerting config.AlertingConfig) (*AlertManager, *fakeClickhouse) {
+	fake := &fakeClickhouse{}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{
+		ClickHouse: map[string]config.ClickHouseConfig{
+			"default": {URL: server.URL, Database: "default"},
+		},
+		Alerting: alerting,
+	}
+	alertEvaluator := NewAlertEvaluator(zerolog.Nop(), clickhouse.NewManager(cfg, zerolog.Nop()), config.NewVirtualTimeProvider())
+	alertStorageClient, err := clickhouse.NewClient(&config.ClickHouseConfig{URL: server.URL}, "alert_storage", zerolog.Nop())
+	require.NoError(t, err)
+	alertResultStore := NewAlertResultStore(zerolog.Nop(), alertStorageClient)
+	alertResultStore.currentAlertStatus = make(map[AlertId]*currentAlertStatus)
+
+	return NewAlertManager(cfg, zerolog.Nop(), nil, alertEvaluator, alertResultStore, nil), fake
+}
+
+func TestAlertManager_EvaluateAllLimitsConcurrency(t *testing.T) {
+	alertManager, fake := newAlertManagerWithFakeClickhouse(t, config.AlertingConfig{MaxConcurrentEvaluations: 2})
+
+	alertDefinitions := make([]AlertDefinition, 0, 6)
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		alertDefinitions = append(alertDefinitions, AlertDefinition{
+			Id:      AlertId{Group: "src/test/alerts.yaml", Key: key},
+			Query:   "SELECT 1 AS value",
+			ErrorIf: AlertCondition{ValueGt: f64Ptr(10)},
+		})
+	}
+	require.NoError(t, alertManager.evaluateAll(context.Background(), alertDefinitions))
+
+	assert.Equal(t, 6, fake.executedQueryCnt)
+	assert.Equal(t, 2, fake.maxRunning)
+	for _, alertDefinition := range alertDefinitions {
+		assert.Equal(t, AlertStateOk, alertManager.alertResultStore.LatestState(alertDefinition.Id))
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.Default.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, recorder.Body.String(), `dashica_alert_state{alert_id_group="src/test/alerts.yaml",alert_id_key="a",alert_labels="",state="OK"} 1`)
+	assert.Contains(t, recorder.Body.String(), `dashica_alert_evaluation_duration_seconds_count{alert_id_group="src/test/alerts.yaml",alert_id_key="a"} 1`)
+}
+
+func TestAlertManager_EvaluationTimeout(t *testing.T) {
+	alertManager, _ := newAlertManagerWithFakeClickhouse(t, config.AlertingConfig{MaxConcurrentEvaluations: 1})
+	slow := AlertDefinition{
+		Id:      AlertId{Group: "src/test/alerts.yaml", Key: "slow"},
+		Query:   "SELECT 1 AS value -- slow",
+		ErrorIf: AlertCondition{ValueGt: f64Ptr(10)},
+		Timeout: "50ms",
+	}
+	fast := AlertDefinition{
+		Id:      AlertId{Group: "src/test/alerts.yaml", Key: "fast"},
+		Query:   "SELECT 1 AS value",
+		ErrorIf: AlertCondition{ValueGt: f64Ptr(10)},
+	}
+
+	// the slow alert must not block the fast one forever, even with a single evaluation slot.
+	require.NoError(t, alertManager.evaluateAll(context.Background(), []AlertDefinition{slow, fast}))
+	assert.Equal(t, AlertStateTimeout, alertManager.alertResultStore.LatestState(slow.Id))
+	assert.Equal(t, AlertStateOk, alertManager.alertResultStore.LatestState(fast.Id))
+
+	recorder := httptest.NewRecorder()
+	metrics.Default.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, recorder.Body.String(), `dashica_alert_evaluation_errors_total{alert_id_group="src/test/alerts.yaml",alert_id_key="slow",reason="timeout"} 1`)
+
+	t.Run("CancelledContextIsNoTimeout", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		slow.Id.Key = "slow_cancelled"
+		slow.Timeout = ""
+		require.Error(t, alertManager.evaluateAndPersist(ctx, slow))
+		assert.Equal(t, "", alertManager.alertResultStore.LatestState(slow.Id))
+	})
+}
+
+func TestAlertManager_SkipsRunningEvaluation(t *testing.T) {
+	alertManager, fake := newAlertManagerWithFakeClickhouse(t, config.AlertingConfig{MaxConcurrentEvaluations: 2})
+	slow := AlertDefinition{
+		Id:      AlertId{Group: "src/test/alerts.yaml", Key: "slow"},
+		Query:   "SELECT 1 AS value -- slow",
+		ErrorIf: AlertCondition{ValueGt: f64Ptr(10)},
+		Timeout: "1m",
+	}
+	evaluationErr := make(chan error, 1)
+	go func() { evaluationErr <- alertManager.evaluateAll(context.Background(), []AlertDefinition{slow}) }()
+	require.Eventually(t, func() bool {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		return fake.running > 0
+	}, time.Second, time.Millisecond)
+
+	// the next tick must not evaluate the alert again while it is still running
+	require.NoError(t, alertManager.evaluateAll(context.Background(), []AlertDefinition{slow}))
+	fake.mu.Lock()
+	assert.Equal(t, 1, fake.executedQueryCnt)
+	fake.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_ = alertManager.Shutdown(ctx)
+	<-evaluationErr
+	assert.True(t, alertManager.startEvaluation(slow.Id), "finished evaluations are not in flight anymore")
+}
+
+func TestAlertManager_Shutdown(t *testing.T) {
+	waitUntilRunning := func(fake *fakeClickhouse) {
+		require.Eventually(t, func() bool {
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			return fake.running > 0
+		}, time.Second, time.Millisecond)
+	}
+
+	t.Run("RunningEvaluationsAreFinished", func(t *testing.T) {
+		alertManager, fake := newAlertManagerWithFakeClickhouse(t, config.AlertingConfig{MaxConcurrentEvaluations: 1})
+		alertDefinition := AlertDefinition{
+			Id:      AlertId{Group: "src/test/alerts.yaml", Key: "draining"},
+			Query:   "SELECT 1 AS value",
+			ErrorIf: AlertCondition{ValueGt: f64Ptr(10)},
+		}
+		// the scheduler context is already cancelled; this must not abort the evaluation.
+		schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+		evaluationErr := make(chan error, 1)
+		go func() { evaluationErr <- alertManager.evaluateAll(schedulerCtx, []AlertDefinition{alertDefinition}) }()
+		waitUntilRunning(fake)
+		stopScheduler()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, alertManager.Shutdown(ctx))
+		require.NoError(t, <-evaluationErr)
+		assert.Equal(t, AlertStateOk, alertManager.alertResultStore.LatestState(alertDefinition.Id))
+
+		assert.ErrorIs(t, alertManager.evaluateAll(context.Background(), []AlertDefinition{alertDefinition}), errShuttingDown)
+	})
+
+	t.Run("EvaluationsAreCancelledAfterDrainTimeout", func(t *testing.T) {
+		alertManager, fake := newAlertManagerWithFakeClickhouse(t, config.AlertingConfig{MaxConcurrentEvaluations: 1})
+		slow := AlertDefinition{
+			Id:      AlertId{Group: "src/test/alerts.yaml", Key: "slow"},
+			Query:   "SELECT 1 AS value -- slow",
+			ErrorIf: AlertCondition{ValueGt: f64Ptr(10)},
+			Timeout: "1m",
+		}
+		evaluationErr := make(chan error, 1)
+		go func() { evaluationErr <- alertManager.evaluateAll(context.Background(), []AlertDefinition{slow}) }()
+		waitUntilRunning(fake)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, alertManager.Shutdown(ctx), context.DeadlineExceeded)
+		assert.ErrorIs(t, <-evaluationErr, context.Canceled)
+		assert.Equal(t, "", alertManager.alertResultStore.LatestState(slow.Id), "a cancelled evaluation is no timeout")
+	})
+}
+
+func TestAlertManager_RefreshFiring(t *testing.T) {
+	alertManager, _ := newAlertManagerWithFakeClickhouse(t, config.AlertingConfig{})
+	alertmanager := &fakeRepeatingNotifier{}
+	slack := &fakeNotifier{}
+	alertManager.notifiers = map[string]Notifier{"alertmanager": alertmanager, "slack": slack}
+	alertDefinition := AlertDefinition{
+		Id:         AlertId{Group: "src/test/alerts.yaml", Key: "firing"},
+		Query:      "SELECT 1 AS value",
+		ErrorIf:    AlertCondition{ValueGt: f64Ptr(0)},
+		CheckEvery: "*/5 * * * *",
+		Notify:     []string{"alertmanager", "slack"},
+	}
+
+	for range 3 {
+		require.NoError(t, alertManager.evaluateAll(context.Background(), []AlertDefinition{alertDefinition}))
+	}
+	assert.Equal(t, AlertStateError, alertManager.alertResultStore.LatestState(alertDefinition.Id))
+	// the state change is sent to all notifiers; the still firing alert only to the Alertmanager
+	assert.Len(t, slack.notifications, 1)
+	require.Len(t, alertmanager.notifications, 3)
+	assert.Equal(t, AlertStateError, alertmanager.notifications[2].State)
+	assert.True(t, alertmanager.notifications[2].ExpiresAt.After(alertmanager.notifications[2].Timestamp))
+}
+
+func TestAlertManager_CheckSchedules(t *testing.T) {
+	alertManager, _ := newAlertManagerWithFakeClickhouse(t, config.AlertingConfig{AlertCronMonitorSchedule: "*/5 * * *"})
+	alertManager.loadedAlertDefinitions = []AlertDefinition{
+		{Id: AlertId{Group: "src/test/alerts.yaml", Key: "valid"}, CheckEvery: "*/5 * * * *"},
+		{Id: AlertId{Group: "src/test/alerts.yaml", Key: "invalid"}, CheckEvery: "every 5 minutes"},
+	}
+
+	err := alertManager.CheckSchedules()
+	assert.ErrorContains(t, err, "alert src/test/alerts.yaml#invalid: invalid check_every 'every 5 minutes'")
+	assert.ErrorContains(t, err, "alerting.alert_cron_monitor_schedule: invalid cron expression '*/5 * * *'")
+	assert.NotContains(t, err.Error(), "#valid")
+}
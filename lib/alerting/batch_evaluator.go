@@ -93,7 +93,7 @@ func (e *BatchEvaluator) EvaluateSingleAlert(ctx context.Context, start, end tim
 		Times("executionTimes", executionTimes).
 		Msg("times where alert should be evaluated")
 
-	buckets, err := e.calculateBuckets(context.Background(), executionTimes, alertDefinition.QueryBucketExpression)
+	buckets, err := e.calculateBuckets(ctx, executionTimes, alertDefinition.QueryBucketExpression)
 	if err != nil {
 		return err
 	}
@@ -101,7 +101,8 @@ func (e *BatchEvaluator) EvaluateSingleAlert(ctx context.Context, start, end tim
 		Times("buckets", buckets).
 		Msg("times where alert should be evaluated")
 
-	err = e.evaluateAlertForTimePoints(context.Background(), executionTimes, buckets, alertDefinition)
+	// pending / hysteresis / flap detection are evaluated along the execution times, like in production.
+	err = e.evaluateAlertForTimePoints(ctx, executionTimes, buckets, alertDefinition, newAlertStateTracker())
 	if err != nil {
 		return err
 	}
@@ -192,12 +193,12 @@ func (b *BatchEvaluator) calculateBuckets(ctx context.Context, executionTimes []
 }
 
 // evaluateAlertForTimePoints runs the alert query for the given time span and evaluates each result
-func (b *BatchEvaluator) evaluateAlertForTimePoints(ctx context.Context, executionTimes, buckets []time.Time, alertDefinition AlertDefinition) error {
+func (b *BatchEvaluator) evaluateAlertForTimePoints(ctx context.Context, executionTimes, buckets []time.Time, alertDefinition AlertDefinition, stateTracker *alertStateTracker) error {
 	if len(executionTimes) == 0 {
 		return nil
 	}
 
-	clickhouseClient, err := b.alertEvaluator.clickhouseManager.GetClient("default")
+	clickhouseClient, err := b.alertEvaluator.clickhouseManager.GetClient(alertDefinition.ClickHouseServer())
 	if err != nil {
 		return fmt.Errorf("loading clickhouse client for %s: %w", alertDefinition.QueryPath, err)
 	}
@@ -208,7 +209,7 @@ func (b *BatchEvaluator) evaluateAlertForTimePoints(ctx context.Context, executi
 	// DIFFERENCE to regular AlertEvaluator: There, we execute the query in a way that only ONE row is returned;
 	// here, we return the full result set as we want to execute a batch query.
 	// TODO: coarse timestamp selection.
-	resultset, err := clickhouse.QueryJSON[alertResultRow](context.Background(), clickhouseClient, alertDefinition.Query, queryOpts)
+	resultset, err := clickhouse.QueryJSON[alertResultRow](ctx, clickhouseClient, alertDefinition.Query, queryOpts)
 	if err != nil {
 		return fmt.Errorf("running batch alert SQL query: %w", err)
 	}
@@ -218,31 +219,38 @@ func (b *BatchEvaluator) evaluateAlertForTimePoints(ctx context.Context, executi
 		// find the corresponding bucket timestamp for each execution time
 		bucket := buckets[i]
 
-		// find the result for this bucket in the resultset (or 0)
-		resultsetRow := findResultsetRowWithBucket(resultset.Data, bucket)
+		// find the result rows for this bucket in the resultset (or 0); one per alert instance.
+		resultsetRows := findResultsetRowsWithBucket(resultset.Data, bucket)
 
-		alertResult, err := b.alertEvaluator.evaluateThreshold(alertDefinition, resultsetRow)
+		// the results are persisted in order, so the in-memory state of the store reflects the
+		// previous execution time.
+		alertResults, err := b.alertEvaluator.evaluateInstances(alertDefinition, resultsetRows, b.alertResultStore.FiringInstances(alertDefinition.Id))
 		if err != nil {
 			return fmt.Errorf("evaluating resultset row: %w", err)
 		}
-		// Persist the result with the timestamp from the execution time
-		alertResult.Timestamp = config.Time(executionTime)
-
-		err = b.alertResultStore.PersistResultAndNotifyIfChanged(alertDefinition.Id, alertResult, noNotification)
-		if err != nil {
-			return fmt.Errorf("persisting alert result: %w", err)
+		for _, alertResult := range alertResults {
+			// Persist the result with the timestamp from the execution time
+			alertResult.Timestamp = config.Time(executionTime)
+			alertResult = stateTracker.apply(alertDefinition, alertResult, b.alertResultStore.LatestState(alertResult.AlertId))
+
+			err = b.alertResultStore.PersistResultAndNotifyIfChanged(alertResult.AlertId, alertResult, noNotification)
+			if err != nil {
+				return fmt.Errorf("persisting alert result: %w", err)
+			}
 		}
 	}
 
 	return nil
 }
 
-func findResultsetRowWithBucket(results []alertResultRow, bucket time.Time) []alertResultRow {
-	for i, result := range results {
+// findResultsetRowsWithBucket returns all rows of the given bucket; i.e. 0 or 1 row for alerts without group_by,
+// and one row per alert instance otherwise.
+func findResultsetRowsWithBucket(results []alertResultRow, bucket time.Time) []alertResultRow {
+	rows := make([]alertResultRow, 0, 1)
+	for _, result := range results {
 		if result.TimeTs == bucket.Unix() {
-			return results[i : i+1]
+			rows = append(rows, result)
 		}
 	}
-	// Empty slice
-	return results[0:0]
+	return rows
 }
@@ -0,0 +1,36 @@
+package alerting
+
+import (
+	"github.com/sandstorm/dashica/lib/metrics"
+)
+
+var (
+	alertStateGauge = metrics.Default.NewGaugeVec(
+		"dashica_alert_state",
+		"Current state of each alert instance; 1 for the current state (the series of the previous state is removed).",
+		"alert_id_group", "alert_id_key", "alert_labels", "state")
+	evaluationDuration = metrics.Default.NewHistogramVec(
+		"dashica_alert_evaluation_duration_seconds",
+		"Duration of alert evaluations (including the alert query), by alert definition.",
+		metrics.DefaultDurationBuckets,
+		"alert_id_group", "alert_id_key")
+	evaluationErrorsTotal = metrics.Default.NewCounterVec(
+		"dashica_alert_evaluation_errors_total",
+		"Number of failed alert evaluations by alert definition and reason (error / timeout).",
+		"alert_id_group", "alert_id_key", "reason")
+	schedulerLastRun = metrics.Default.NewGaugeVec(
+		"dashica_alert_scheduler_last_run_timestamp_seconds",
+		"Unix timestamp of the last run of the alert scheduler (every minute).")
+)
+
+// setAlertStateMetric moves the dashica_alert_state series of id from previousState to state.
+func setAlertStateMetric(id AlertId, previousState string, state string) {
+	if previousState != "" && previousState != state {
+		alertStateGauge.Delete(id.Group, id.Key, id.Labels, previousState)
+	}
+	if state == AlertStateRemoved {
+		alertStateGauge.Delete(id.Group, id.Key, id.Labels, state)
+		return
+	}
+	alertStateGauge.Set(1, id.Group, id.Key, id.Labels, state)
+}
@@ -0,0 +1,147 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/adhocore/gronx"
+	"github.com/sandstorm/dashica/lib/config"
+)
+
+// Notifier sends a notification about an alert state change (e.g. "X is red now", "Y is OK again") to an
+// external system.
+//
+// Notifiers are configured in dashica_config.yaml (alerting.notifiers), and selected per alert or per
+// alerts.yaml file via "notify: [name, ...]".
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// repeatingNotifier is implemented by notifiers whose alerts expire unless they are re-sent, like the Prometheus
+// Alertmanager (see its resolve_timeout). Firing alert instances are re-sent to them on every evaluation, not only
+// on state changes (see AlertManager.refreshFiring).
+type repeatingNotifier interface {
+	Notifier
+	repeatsFiringAlerts()
+}
+
+// Notification is the payload handed to a Notifier. Its fields are also available in the body_template of
+// webhook notifiers, e.g. {{ .Id }} or {{ json .Message }}.
+type Notification struct {
+	// Id is the string form of the alert instance id (group#key, or group#key?labels for alerts with group_by)
+	Id     string
+	Group  string
+	Key    string
+	Labels map[string]string
+	// State is one of AlertStateError, AlertStateWarn, AlertStateOk
+	State     string
+	Message   string
+	Timestamp time.Time
+	// ExpiresAt is when a firing alert is considered resolved if it is not sent again (firingExpiryChecks runs of
+	// check_every after Timestamp; zero if check_every is invalid). Only used by a repeatingNotifier.
+	ExpiresAt time.Time
+	// SlackChannel is the slack_channel of the alert definition (may be empty)
+	SlackChannel string
+}
+
+func newNotification(alertDefinition AlertDefinition, alertResult *AlertResult) Notification {
+	return Notification{
+		Id:           alertResult.AlertId.String(),
+		Group:        alertResult.AlertId.Group,
+		Key:          alertResult.AlertId.Key,
+		Labels:       alertResult.AlertId.LabelValues(),
+		State:        alertResult.State,
+		Message:      alertResult.Message,
+		Timestamp:    time.Time(alertResult.Timestamp),
+		ExpiresAt:    firingExpiry(alertDefinition.CheckEvery, time.Time(alertResult.Timestamp)),
+		SlackChannel: alertDefinition.SlackChannel,
+	}
+}
+
+// firingExpiryChecks is the number of evaluations a firing alert sent to a repeatingNotifier may miss (e.g. during
+// a restart) before it is resolved there.
+const firingExpiryChecks = 3
+
+// firingExpiry returns the time of the firingExpiryChecks'th run of the cron expression checkEvery after timestamp;
+// zero if checkEvery is invalid.
+func firingExpiry(checkEvery string, timestamp time.Time) time.Time {
+	expiry := timestamp
+	for range firingExpiryChecks {
+		next, err := gronx.NextTickAfter(checkEvery, expiry, false)
+		if err != nil {
+			return time.Time{}
+		}
+		expiry = next
+	}
+	return expiry
+}
+
+// DefaultNotifierName is used for alerts without "notify"; it refers to the implicit Helvetikit notifier
+// built from alerting.helvetikit_alerting_url (unless a notifier with this name is configured explicitly).
+const DefaultNotifierName = "helvetikit"
+
+// notifierHttpClient is shared by all HTTP based notifiers.
+var notifierHttpClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// NewNotifier creates the Notifier for a single alerting.notifiers entry.
+func NewNotifier(notifierConfig config.NotifierConfig) (Notifier, error) {
+	switch notifierConfig.Type {
+	case config.NotifierTypeHelvetikit:
+		return &helvetikitNotifier{url: notifierConfig.URL, idGroup: notifierConfig.IdGroup}, nil
+	case config.NotifierTypeSlack:
+		return &slackNotifier{url: notifierConfig.URL, channel: notifierConfig.Channel}, nil
+	case config.NotifierTypeWebhook:
+		return newWebhookNotifier(notifierConfig)
+	case config.NotifierTypeEmail:
+		return newEmailNotifier(notifierConfig), nil
+	case config.NotifierTypeAlertmanager:
+		return &alertmanagerNotifier{url: notifierConfig.URL, headers: notifierConfig.Headers}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type '%s'", notifierConfig.Type)
+	}
+}
+
+// newNotifiers creates all notifiers configured in alertingConfig, plus the implicit Helvetikit notifier.
+func newNotifiers(alertingConfig config.AlertingConfig) (map[string]Notifier, error) {
+	notifiers := make(map[string]Notifier, len(alertingConfig.Notifiers)+1)
+	if alertingConfig.HelvetikitAlertingUrl != "" {
+		notifiers[DefaultNotifierName] = &helvetikitNotifier{
+			url:     alertingConfig.HelvetikitAlertingUrl,
+			idGroup: alertingConfig.HelvetikitIdGroup,
+		}
+	}
+	for name, notifierConfig := range alertingConfig.Notifiers {
+		notifier, err := NewNotifier(notifierConfig)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %s: %w", name, err)
+		}
+		notifiers[name] = notifier
+	}
+	return notifiers, nil
+}
+
+// postAndCheckStatus sends req and returns an error for non-2xx responses.
+func postAndCheckStatus(req *http.Request) error {
+	resp, err := notifierHttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, readErr := io.ReadAll(resp.Body)
+		if readErr != nil {
+			return fmt.Errorf("request failed with status %d and error reading body: %w",
+				resp.StatusCode, readErr)
+		}
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// alertmanagerNotifier pushes alert state changes to the Prometheus Alertmanager v2 API.
+// see https://github.com/prometheus/alertmanager/blob/main/api/v2/openapi.yaml
+//
+// Alertmanager resolves alerts which are not sent again until their EndsAt (or resolve_timeout); so firing alerts
+// are re-sent on every evaluation (see repeatingNotifier), with EndsAt a few check intervals ahead.
+type alertmanagerNotifier struct {
+	// url is the Alertmanager base URL, e.g. http://alertmanager:9093
+	url     string
+	headers map[string]string
+}
+
+type alertmanagerAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      *time.Time        `json:"endsAt,omitempty"`
+}
+
+func (a *alertmanagerNotifier) Notify(ctx context.Context, notification Notification) error {
+	// Alertmanager identifies alerts by their label set; as "severity" is a label, the warn and error level of
+	// an alert are distinct Alertmanager alerts. We always send both: the current one as firing, and all others
+	// as resolved (so OK resolves both; and warn -> error resolves the warning).
+	// a flapping alert is firing with severity "warn"; a timed out one with severity "error".
+	firingSeverity := notification.State
+	switch firingSeverity {
+	case AlertStateFlapping:
+		firingSeverity = AlertStateWarn
+	case AlertStateTimeout:
+		firingSeverity = AlertStateError
+	}
+	alerts := make([]alertmanagerAlert, 0, 2)
+	for _, severity := range []string{AlertStateWarn, AlertStateError} {
+		labels := make(map[string]string, len(notification.Labels)+3)
+		for k, v := range notification.Labels {
+			labels[k] = v
+		}
+		// the group_by labels must not override the labels used for routing and deduplication; like Prometheus
+		// does for conflicting target labels, they are kept with an "exported_" prefix.
+		reservedLabels := map[string]string{
+			"alertname":   notification.Key,
+			"alert_group": notification.Group,
+			"severity":    severity,
+		}
+		for k, v := range reservedLabels {
+			if userValue, exists := labels[k]; exists {
+				labels["exported_"+k] = userValue
+			}
+			labels[k] = v
+		}
+		alert := alertmanagerAlert{
+			Labels: labels,
+			Annotations: map[string]string{
+				"summary": notification.Message,
+			},
+			StartsAt: notification.Timestamp,
+		}
+		if severity != firingSeverity {
+			alert.EndsAt = &notification.Timestamp
+		} else if !notification.ExpiresAt.IsZero() {
+			alert.EndsAt = &notification.ExpiresAt
+		}
+		alerts = append(alerts, alert)
+	}
+
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(a.url, "/")+"/api/v2/alerts", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range a.headers {
+		req.Header.Set(key, value)
+	}
+	return postAndCheckStatus(req)
+}
+
+func (a *alertmanagerNotifier) repeatsFiringAlerts() {}
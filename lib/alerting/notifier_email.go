@@ -0,0 +1,88 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sandstorm/dashica/lib/config"
+)
+
+// emailNotifier sends alert state changes as plain text mail via SMTP.
+type emailNotifier struct {
+	addr string
+	host string
+	user string
+	from string
+	to   []string
+
+	// readPassword is config.NotifierConfig.ReadSmtpPassword; called per mail, so that password files can rotate.
+	readPassword func() (string, error)
+
+	// sendMail is smtp.SendMail; overridden in tests.
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func newEmailNotifier(notifierConfig config.NotifierConfig) *emailNotifier {
+	port := notifierConfig.SmtpPort
+	if port == 0 {
+		port = 587
+	}
+	return &emailNotifier{
+		addr:         net.JoinHostPort(notifierConfig.SmtpHost, strconv.Itoa(port)),
+		host:         notifierConfig.SmtpHost,
+		user:         notifierConfig.SmtpUser,
+		readPassword: notifierConfig.ReadSmtpPassword,
+		from:         notifierConfig.From,
+		to:           notifierConfig.To,
+		sendMail:     smtp.SendMail,
+	}
+}
+
+func (e *emailNotifier) Notify(ctx context.Context, notification Notification) error {
+	var auth smtp.Auth
+	if e.user != "" {
+		password, err := e.readPassword()
+		if err != nil {
+			return fmt.Errorf("smtp_password_file: %w", err)
+		}
+		auth = smtp.PlainAuth("", e.user, password, e.host)
+	}
+
+	// smtp.SendMail does not support a context; so we only check for cancellation up front.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := e.sendMail(e.addr, auth, e.from, e.to, e.message(notification)); err != nil {
+		return fmt.Errorf("sending mail via %s: %w", e.addr, err)
+	}
+	return nil
+}
+
+// headerLineReplacer removes line breaks, which would start a new mail header.
+var headerLineReplacer = strings.NewReplacer("\r", " ", "\n", " ")
+
+func (e *emailNotifier) message(notification Notification) []byte {
+	var msg strings.Builder
+	msg.WriteString("From: " + e.from + "\r\n")
+	msg.WriteString("To: " + strings.Join(e.to, ", ") + "\r\n")
+	// the alert id contains label values from the query result; so it must not break out of the header.
+	subject := fmt.Sprintf("[Dashica] %s %s", strings.ToUpper(notification.State), headerLineReplacer.Replace(notification.Id))
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	msg.WriteString("Date: " + notification.Timestamp.Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(fmt.Sprintf("Alert:   %s\r\n", notification.Id))
+	msg.WriteString(fmt.Sprintf("State:   %s\r\n", notification.State))
+	msg.WriteString(fmt.Sprintf("Time:    %s\r\n", notification.Timestamp.Format(config.CLICKHOUSE_TIME_FORMAT)))
+	if notification.Message != "" {
+		msg.WriteString("\r\n" + notification.Message + "\r\n")
+	}
+	return []byte(msg.String())
+}
@@ -0,0 +1,44 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// helvetikitNotifier posts alert state changes as form to Helvetikit.
+type helvetikitNotifier struct {
+	url     string
+	idGroup string
+}
+
+func (h *helvetikitNotifier) Notify(ctx context.Context, notification Notification) error {
+	formBody := url.Values{}
+	formBody.Add("group", "dashica_"+h.idGroup)
+	// for alerts with group_by, every alert instance is its own Helvetikit alert.
+	formBody.Add("id", notification.Id)
+
+	if notification.State == AlertStateOk {
+		formBody.Add("state", "NORMAL")
+	} else if notification.State == AlertStateWarn || notification.State == AlertStateFlapping {
+		formBody.Add("state", "WARNING")
+	} else {
+		formBody.Add("state", "ERROR")
+	}
+
+	formBody.Add("message", notification.Message)
+	if notification.SlackChannel != "" {
+		formBody.Add("slack_channel", notification.SlackChannel)
+	}
+
+	// TODO: deduplication_mode=NO for alerting on individual log lines
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, strings.NewReader(formBody.Encode()))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return postAndCheckStatus(req)
+}
@@ -0,0 +1,56 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// slackNotifier posts alert state changes to a Slack incoming webhook.
+// see https://api.slack.com/messaging/webhooks
+type slackNotifier struct {
+	url string
+	// channel is only respected by legacy incoming webhooks; the slack_channel of the alert definition wins.
+	channel string
+}
+
+type slackMessage struct {
+	Text    string `json:"text"`
+	Channel string `json:"channel,omitempty"`
+}
+
+func (s *slackNotifier) Notify(ctx context.Context, notification Notification) error {
+	message := slackMessage{
+		Text:    fmt.Sprintf("%s *%s* `%s`\n%s", slackStateEmoji(notification.State), notification.State, notification.Id, notification.Message),
+		Channel: s.channel,
+	}
+	if notification.SlackChannel != "" {
+		message.Channel = notification.SlackChannel
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return postAndCheckStatus(req)
+}
+
+func slackStateEmoji(state string) string {
+	switch state {
+	case AlertStateOk:
+		return ":large_green_circle:"
+	case AlertStateFlapping:
+		return ":warning:"
+	case AlertStateWarn:
+		return ":large_yellow_circle:"
+	default:
+		return ":red_circle:"
+	}
+}
@@ -0,0 +1,243 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingServer is a local stand-in for the notification targets; it records the last request.
+type recordingServer struct {
+	*httptest.Server
+	lastPath   string
+	lastHeader http.Header
+	lastBody   []byte
+}
+
+func newRecordingServer(t *testing.T, status int) *recordingServer {
+	rs := &recordingServer{}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rs.lastPath = r.URL.Path
+		rs.lastHeader = r.Header.Clone()
+		rs.lastBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+var testNotification = Notification{
+	Id:           "src/shop/alerts.yaml#http500?host_name=web1",
+	Group:        "src/shop/alerts.yaml",
+	Key:          "http500",
+	Labels:       map[string]string{"host_name": "web1"},
+	State:        AlertStateError,
+	Message:      `too many "errors"`,
+	Timestamp:    time.Date(2025, 4, 2, 10, 15, 0, 0, time.UTC),
+	SlackChannel: "#ops",
+}
+
+func TestNotifiers(t *testing.T) {
+	t.Run("helvetikit", func(t *testing.T) {
+		server := newRecordingServer(t, http.StatusOK)
+		notifier, err := NewNotifier(config.NotifierConfig{Type: config.NotifierTypeHelvetikit, URL: server.URL, IdGroup: "prod"})
+		require.NoError(t, err)
+		require.NoError(t, notifier.Notify(context.Background(), testNotification))
+
+		form, err := url.ParseQuery(string(server.lastBody))
+		require.NoError(t, err)
+		assert.Equal(t, "dashica_prod", form.Get("group"))
+		assert.Equal(t, testNotification.Id, form.Get("id"))
+		assert.Equal(t, "ERROR", form.Get("state"))
+		assert.Equal(t, "#ops", form.Get("slack_channel"))
+	})
+
+	t.Run("slack", func(t *testing.T) {
+		server := newRecordingServer(t, http.StatusOK)
+		notifier, err := NewNotifier(config.NotifierConfig{Type: config.NotifierTypeSlack, URL: server.URL, Channel: "#default"})
+		require.NoError(t, err)
+		require.NoError(t, notifier.Notify(context.Background(), testNotification))
+
+		var message slackMessage
+		require.NoError(t, json.Unmarshal(server.lastBody, &message))
+		assert.Equal(t, "#ops", message.Channel)
+		assert.Contains(t, message.Text, ":red_circle: *error*")
+		assert.Contains(t, message.Text, testNotification.Message)
+	})
+
+	t.Run("webhook with default template", func(t *testing.T) {
+		server := newRecordingServer(t, http.StatusNoContent)
+		notifier, err := NewNotifier(config.NotifierConfig{Type: config.NotifierTypeWebhook, URL: server.URL, Headers: map[string]string{"Authorization": "Bearer s3cr3t"}})
+		require.NoError(t, err)
+		require.NoError(t, notifier.Notify(context.Background(), testNotification))
+
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(server.lastBody, &body))
+		assert.Equal(t, testNotification.Message, body["message"])
+		assert.Equal(t, map[string]any{"host_name": "web1"}, body["labels"])
+		assert.Equal(t, "Bearer s3cr3t", server.lastHeader.Get("Authorization"))
+	})
+
+	t.Run("webhook with custom template", func(t *testing.T) {
+		server := newRecordingServer(t, http.StatusOK)
+		notifier, err := NewNotifier(config.NotifierConfig{Type: config.NotifierTypeWebhook, URL: server.URL, BodyTemplate: `{"text": {{ json (printf "%s: %s" .Id .Message) }}}`})
+		require.NoError(t, err)
+		require.NoError(t, notifier.Notify(context.Background(), testNotification))
+		assert.JSONEq(t, `{"text": "src/shop/alerts.yaml#http500?host_name=web1: too many \"errors\""}`, string(server.lastBody))
+	})
+
+	t.Run("webhook with invalid template", func(t *testing.T) {
+		_, err := NewNotifier(config.NotifierConfig{Type: config.NotifierTypeWebhook, URL: "http://localhost", BodyTemplate: `{{ .Id `})
+		require.ErrorContains(t, err, "parsing body_template")
+	})
+
+	t.Run("alertmanager", func(t *testing.T) {
+		server := newRecordingServer(t, http.StatusOK)
+		notifier, err := NewNotifier(config.NotifierConfig{Type: config.NotifierTypeAlertmanager, URL: server.URL + "/"})
+		require.NoError(t, err)
+		require.NoError(t, notifier.Notify(context.Background(), testNotification))
+
+		assert.Equal(t, "/api/v2/alerts", server.lastPath)
+		var alerts []alertmanagerAlert
+		require.NoError(t, json.Unmarshal(server.lastBody, &alerts))
+		require.Len(t, alerts, 2)
+		// warn is resolved, error is firing
+		assert.Equal(t, "warn", alerts[0].Labels["severity"])
+		assert.NotNil(t, alerts[0].EndsAt)
+		assert.Equal(t, "error", alerts[1].Labels["severity"])
+		assert.Nil(t, alerts[1].EndsAt)
+
+		// re-sent firing alerts expire a few check intervals ahead
+		notification := testNotification
+		notification.ExpiresAt = notification.Timestamp.Add(15 * time.Minute)
+		require.NoError(t, notifier.Notify(context.Background(), notification))
+		require.NoError(t, json.Unmarshal(server.lastBody, &alerts))
+		require.NotNil(t, alerts[1].EndsAt)
+		assert.True(t, notification.ExpiresAt.Equal(*alerts[1].EndsAt))
+		assert.Equal(t, "http500", alerts[1].Labels["alertname"])
+		assert.Equal(t, "web1", alerts[1].Labels["host_name"])
+
+		// group_by columns named like reserved labels do not corrupt routing and deduplication
+		notification.Labels = map[string]string{"severity": "critical", "alertname": "disk", "host_name": "web1"}
+		require.NoError(t, notifier.Notify(context.Background(), notification))
+		var collidingAlerts []alertmanagerAlert
+		require.NoError(t, json.Unmarshal(server.lastBody, &collidingAlerts))
+		require.Len(t, collidingAlerts, 2)
+		assert.Equal(t, map[string]string{
+			"alertname":          "http500",
+			"alert_group":        "src/shop/alerts.yaml",
+			"severity":           "error",
+			"exported_alertname": "disk",
+			"exported_severity":  "critical",
+			"host_name":          "web1",
+		}, collidingAlerts[1].Labels)
+	})
+
+	t.Run("non-2xx status is an error", func(t *testing.T) {
+		server := newRecordingServer(t, http.StatusInternalServerError)
+		notifier, err := NewNotifier(config.NotifierConfig{Type: config.NotifierTypeSlack, URL: server.URL})
+		require.NoError(t, err)
+		require.ErrorContains(t, notifier.Notify(context.Background(), testNotification), "request failed with status 500")
+	})
+
+	t.Run("email", func(t *testing.T) {
+		notifier := newEmailNotifier(config.NotifierConfig{Type: config.NotifierTypeEmail, SmtpHost: "mail.local", SmtpUser: "dashica", SmtpPassword: "pw", From: "dashica@example.com", To: []string{"ops@example.com"}})
+		var sentAddr string
+		var sentMsg []byte
+		notifier.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			sentAddr = addr
+			sentMsg = msg
+			assert.NotNil(t, a)
+			assert.Equal(t, []string{"ops@example.com"}, to)
+			return nil
+		}
+		require.NoError(t, notifier.Notify(context.Background(), testNotification))
+		assert.Equal(t, "mail.local:587", sentAddr)
+		assert.Contains(t, string(sentMsg), "Subject: [Dashica] ERROR src/shop/alerts.yaml#http500?host_name=web1\r\n")
+		assert.Contains(t, string(sentMsg), testNotification.Message)
+	})
+
+	t.Run("email subject is encoded", func(t *testing.T) {
+		notifier := newEmailNotifier(config.NotifierConfig{Type: config.NotifierTypeEmail, SmtpHost: "mail.local", From: "dashica@example.com", To: []string{"ops@example.com"}})
+		var sentMsg []byte
+		notifier.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			sentMsg = msg
+			return nil
+		}
+		notification := testNotification
+		notification.Id = "src/shop/alerts.yaml#http500?host_name=web1\r\nBcc: attacker@example.com"
+		require.NoError(t, notifier.Notify(context.Background(), notification))
+		message, err := mail.ReadMessage(bytes.NewReader(sentMsg))
+		require.NoError(t, err)
+		assert.Empty(t, message.Header.Get("Bcc"))
+
+		notification.Id = "src/shop/alerts.yaml#http500?city=Zürich"
+		require.NoError(t, notifier.Notify(context.Background(), notification))
+		message, err = mail.ReadMessage(bytes.NewReader(sentMsg))
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(message.Header.Get("Subject"), "=?utf-8?q?"))
+		subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+		require.NoError(t, err)
+		assert.Equal(t, "[Dashica] ERROR src/shop/alerts.yaml#http500?city=Zürich", subject)
+	})
+}
+
+type fakeNotifier struct {
+	notifications []Notification
+}
+
+func (f *fakeNotifier) Notify(_ context.Context, notification Notification) error {
+	f.notifications = append(f.notifications, notification)
+	return nil
+}
+
+// fakeRepeatingNotifier is a fakeNotifier which re-sends firing alerts, like the Alertmanager notifier.
+type fakeRepeatingNotifier struct {
+	fakeNotifier
+}
+
+func (f *fakeRepeatingNotifier) repeatsFiringAlerts() {}
+
+func TestFiringExpiry(t *testing.T) {
+	timestamp := time.Date(2025, 4, 2, 10, 16, 30, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 4, 2, 10, 30, 0, 0, time.UTC), firingExpiry("*/5 * * * *", timestamp))
+	assert.True(t, firingExpiry("every 5 minutes", timestamp).IsZero())
+}
+
+func TestAlertManager_NotifyAlertChange(t *testing.T) {
+	helvetikit := &fakeNotifier{}
+	slack := &fakeNotifier{}
+	alertManager := &AlertManager{
+		logger:    zerolog.Nop(),
+		notifiers: map[string]Notifier{DefaultNotifierName: helvetikit, "slack_ops": slack},
+	}
+	alertResult := &AlertResult{AlertId: AlertId{Group: "g1", Key: "k1"}, State: AlertStateWarn}
+
+	require.NoError(t, alertManager.notifyAlertChange(AlertDefinition{}, alertResult))
+	assert.Len(t, helvetikit.notifications, 1)
+	assert.Len(t, slack.notifications, 0)
+
+	require.NoError(t, alertManager.notifyAlertChange(AlertDefinition{Notify: []string{"slack_ops"}}, alertResult))
+	assert.Len(t, helvetikit.notifications, 1)
+	assert.Len(t, slack.notifications, 1)
+	assert.Equal(t, "g1#k1", slack.notifications[0].Id)
+
+	err := alertManager.notifyAlertChange(AlertDefinition{Notify: []string{"slack_ops", "missing"}}, alertResult)
+	require.ErrorContains(t, err, "notifier 'missing' not configured")
+	assert.Len(t, slack.notifications, 2)
+}
@@ -0,0 +1,64 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+
+	"github.com/sandstorm/dashica/lib/config"
+)
+
+// defaultWebhookBodyTemplate is used if no body_template is configured.
+const defaultWebhookBodyTemplate = `{"id": {{ json .Id }}, "group": {{ json .Group }}, "key": {{ json .Key }}, "labels": {{ json .Labels }}, "state": {{ json .State }}, "message": {{ json .Message }}, "timestamp": {{ json .Timestamp }}}`
+
+// webhookNotifier posts alert state changes as JSON to an arbitrary URL; the body is rendered from a
+// text/template, so that it can match the API of the target system.
+type webhookNotifier struct {
+	url          string
+	headers      map[string]string
+	bodyTemplate *template.Template
+}
+
+var webhookTemplateFuncs = template.FuncMap{
+	// json encodes a value as JSON, e.g. {{ json .Message }} renders a properly escaped JSON string.
+	"json": func(v any) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+}
+
+func newWebhookNotifier(notifierConfig config.NotifierConfig) (*webhookNotifier, error) {
+	bodyTemplate := notifierConfig.BodyTemplate
+	if bodyTemplate == "" {
+		bodyTemplate = defaultWebhookBodyTemplate
+	}
+	tpl, err := template.New("body").Funcs(webhookTemplateFuncs).Parse(bodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parsing body_template: %w", err)
+	}
+	return &webhookNotifier{
+		url:          notifierConfig.URL,
+		headers:      notifierConfig.Headers,
+		bodyTemplate: tpl,
+	}, nil
+}
+
+func (w *webhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	var body bytes.Buffer
+	if err := w.bodyTemplate.Execute(&body, notification); err != nil {
+		return fmt.Errorf("rendering body_template: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, &body)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.headers {
+		req.Header.Set(key, value)
+	}
+	return postAndCheckStatus(req)
+}
@@ -0,0 +1,85 @@
+// Package auth authenticates requests (basic auth, OIDC or a trusted reverse proxy; see config.AuthConfig), and
+// authorizes them against the AccessRules of dashboard groups.
+package auth
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+// User is the authenticated user of a request.
+type User struct {
+	Name   string
+	Groups []string
+}
+
+type userContextKey struct{}
+
+// WithUser returns a copy of ctx carrying user.
+func WithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns the authenticated user; nil if authentication is disabled.
+func UserFromContext(ctx context.Context) *User {
+	user, _ := ctx.Value(userContextKey{}).(*User)
+	return user
+}
+
+// AccessRule allows access to the listed users, and to all members of the listed groups.
+type AccessRule struct {
+	Users  []string
+	Groups []string
+}
+
+// AllowUsers allows access for the given user names.
+func AllowUsers(users ...string) AccessRule {
+	return AccessRule{Users: users}
+}
+
+// AllowGroups allows access for all members of the given groups (see config.AuthConfig.Groups, and the groups
+// from OIDC or the proxy groups header).
+func AllowGroups(groups ...string) AccessRule {
+	return AccessRule{Groups: groups}
+}
+
+func (r AccessRule) allows(user *User) bool {
+	if slices.Contains(r.Users, user.Name) {
+		return true
+	}
+	for _, group := range user.Groups {
+		if slices.Contains(r.Groups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed returns true if the user of ctx matches one of rules. Without rules, everybody is allowed; and if
+// authentication is disabled (no user in ctx), all rules are ignored.
+func Allowed(ctx context.Context, rules []AccessRule) bool {
+	user := UserFromContext(ctx)
+	if user == nil || len(rules) == 0 {
+		return true
+	}
+	for _, rule := range rules {
+		if rule.allows(user) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireAccess returns a middleware responding with 403 Forbidden to users not matching rules (see Allowed).
+func RequireAccess(rules []AccessRule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !Allowed(r.Context(), rules) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// serve sends r through the Authenticator, and returns the response plus the user seen by the wrapped handler.
+func serve(t *testing.T, cfg config.AuthConfig, r *http.Request) (*httptest.ResponseRecorder, *User) {
+	authenticator, err := New(cfg, zerolog.Nop())
+	require.NoError(t, err)
+
+	var seenUser *User
+	handler := authenticator.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenUser = UserFromContext(r.Context())
+	}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, r)
+	return recorder, seenUser
+}
+
+func TestAuthenticator_Basic(t *testing.T) {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	cfg := config.AuthConfig{
+		Enabled:     true,
+		Mode:        config.AuthModeBasic,
+		Users:       map[string]string{"alice": string(passwordHash)},
+		Groups:      map[string][]string{"ops": {"alice"}},
+		PublicPaths: []string{"/public/", "/health"},
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		username     string
+		password     string
+		expectedCode int
+		expectedUser *User
+	}{
+		{name: "ValidPassword", path: "/home", username: "alice", password: "secret", expectedCode: http.StatusOK, expectedUser: &User{Name: "alice", Groups: []string{"ops"}}},
+		{name: "WrongPassword", path: "/home", username: "alice", password: "wrong", expectedCode: http.StatusUnauthorized},
+		{name: "UnknownUser", path: "/home", username: "bob", password: "secret", expectedCode: http.StatusUnauthorized},
+		{name: "NoCredentials", path: "/home", expectedCode: http.StatusUnauthorized},
+		{name: "PublicPath", path: "/public/dist/main.js", expectedCode: http.StatusOK},
+		{name: "PublicPathWithoutTrailingSlash", path: "/health", expectedCode: http.StatusOK},
+		{name: "BelowPublicPathWithoutTrailingSlash", path: "/health/ready", expectedCode: http.StatusOK},
+		{name: "PublicPathIsNoStringPrefix", path: "/healthz-admin/users", expectedCode: http.StatusUnauthorized},
+		{name: "PublicPathIsNoStringPrefixWithSlash", path: "/publicity", expectedCode: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			if tt.username != "" {
+				r.SetBasicAuth(tt.username, tt.password)
+			}
+			recorder, user := serve(t, cfg, r)
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			assert.Equal(t, tt.expectedUser, user)
+			if tt.expectedCode == http.StatusUnauthorized {
+				assert.Contains(t, recorder.Header().Get("WWW-Authenticate"), "Basic")
+			}
+		})
+	}
+
+	t.Run("PlainTextPasswordIsRejected", func(t *testing.T) {
+		_, err := New(config.AuthConfig{Enabled: true, Mode: config.AuthModeBasic, Username: "alice", Password: "secret"}, zerolog.Nop())
+		assert.ErrorContains(t, err, "not a bcrypt hash")
+	})
+}
+
+func TestAuthenticator_Proxy(t *testing.T) {
+	cfg := config.AuthConfig{
+		Enabled: true,
+		Mode:    config.AuthModeProxy,
+		Proxy: config.ProxyAuthConfig{
+			UserHeader:     "X-Forwarded-User",
+			GroupsHeader:   "X-Forwarded-Groups",
+			TrustedProxies: []string{"10.0.0.0/8"},
+		},
+	}
+
+	t.Run("TrustedProxy", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/home", nil)
+		r.RemoteAddr = "10.1.2.3:4711"
+		r.Header.Set("X-Forwarded-User", "alice")
+		r.Header.Set("X-Forwarded-Groups", "ops, dev")
+		recorder, user := serve(t, cfg, r)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, &User{Name: "alice", Groups: []string{"dev", "ops"}}, user)
+	})
+	t.Run("UntrustedProxy", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/home", nil)
+		r.RemoteAddr = "192.168.1.1:4711"
+		r.Header.Set("X-Forwarded-User", "alice")
+		recorder, user := serve(t, cfg, r)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Nil(t, user)
+	})
+}
+
+func TestAuthenticator_OIDCSession(t *testing.T) {
+	cfg := config.AuthConfig{
+		Enabled: true,
+		Mode:    config.AuthModeOIDC,
+		OIDC: config.OIDCConfig{
+			IssuerURL:       "https://idp.example.com",
+			ClientID:        "dashica",
+			RedirectURL:     "https://dashica.example.com/auth/callback",
+			SessionSecret:   "0123456789abcdef0123456789abcdef",
+			SessionDuration: time.Hour,
+		},
+	}
+	login := newOIDCLogin(cfg.OIDC, zerolog.Nop())
+	sessionRecorder := httptest.NewRecorder()
+	require.NoError(t, login.cookies.set(sessionRecorder, sessionCookieName, session{User: "alice", Groups: []string{"ops"}, Expires: time.Now().Add(time.Hour).Unix()}, time.Hour))
+	sessionCookie := sessionRecorder.Result().Cookies()[0]
+
+	t.Run("ValidSession", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/home", nil)
+		r.AddCookie(sessionCookie)
+		recorder, user := serve(t, cfg, r)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, &User{Name: "alice", Groups: []string{"ops"}}, user)
+	})
+	t.Run("TamperedSession", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/home", nil)
+		r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "x" + sessionCookie.Value})
+		recorder, user := serve(t, cfg, r)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Nil(t, user)
+	})
+	t.Run("LoginCookieIsNoSession", func(t *testing.T) {
+		// every visitor gets a signed login cookie from /auth/login
+		loginRecorder := httptest.NewRecorder()
+		require.NoError(t, login.cookies.set(loginRecorder, loginCookieName, loginState{State: "s", Expires: time.Now().Add(time.Hour).Unix()}, time.Hour))
+		loginCookie := loginRecorder.Result().Cookies()[0]
+
+		r := httptest.NewRequest("GET", "/home", nil)
+		r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: loginCookie.Value})
+		recorder, user := serve(t, cfg, r)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Nil(t, user)
+	})
+	t.Run("SessionWithoutUser", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		require.NoError(t, login.cookies.set(recorder, sessionCookieName, session{Expires: time.Now().Add(time.Hour).Unix()}, time.Hour))
+
+		r := httptest.NewRequest("GET", "/home", nil)
+		r.AddCookie(recorder.Result().Cookies()[0])
+		recorder, user := serve(t, cfg, r)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Nil(t, user)
+	})
+	t.Run("BrowserIsRedirectedToLogin", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/home?x=1", nil)
+		r.Header.Set("Accept", "text/html")
+		recorder, _ := serve(t, cfg, r)
+		assert.Equal(t, http.StatusFound, recorder.Code)
+		assert.Equal(t, "/auth/login?redirect=%2Fhome%3Fx%3D1", recorder.Header().Get("Location"))
+	})
+	t.Run("SafeRedirect", func(t *testing.T) {
+		assert.Equal(t, "/home?x=1", safeRedirect("/home?x=1"))
+		assert.Equal(t, "/", safeRedirect("//evil.example.com"))
+		assert.Equal(t, "/", safeRedirect("https://evil.example.com"))
+	})
+}
+
+func TestAllowed(t *testing.T) {
+	alice := WithUser(context.Background(), &User{Name: "alice", Groups: []string{"ops"}})
+	bob := WithUser(context.Background(), &User{Name: "bob"})
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		rules    []AccessRule
+		expected bool
+	}{
+		{name: "NoRules", ctx: bob, expected: true},
+		{name: "AuthenticationDisabled", ctx: context.Background(), rules: []AccessRule{AllowUsers("alice")}, expected: true},
+		{name: "AllowedUser", ctx: alice, rules: []AccessRule{AllowUsers("alice")}, expected: true},
+		{name: "AllowedGroup", ctx: alice, rules: []AccessRule{AllowUsers("carol"), AllowGroups("ops")}, expected: true},
+		{name: "Forbidden", ctx: bob, rules: []AccessRule{AllowUsers("alice"), AllowGroups("ops")}, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Allowed(tt.ctx, tt.rules))
+		})
+	}
+
+	recorder := httptest.NewRecorder()
+	RequireAccess([]AccessRule{AllowGroups("ops")})(http.NotFoundHandler()).
+		ServeHTTP(recorder, httptest.NewRequest("GET", "/home", nil).WithContext(bob))
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+}
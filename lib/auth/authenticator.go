@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/sandstorm/dashica/lib/logging"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Authenticator is the authentication middleware (see Handler).
+type Authenticator struct {
+	config config.AuthConfig
+	logger zerolog.Logger
+
+	// basic auth: bcrypt password hashes indexed by user name
+	passwordHashes map[string][]byte
+	// verifiedPasswords caches successful bcrypt verifications (user name -> sha256 of hash and password), as bcrypt
+	// is deliberately slow and every query of a dashboard sends the credentials again.
+	verifiedPasswords sync.Map
+
+	// proxy mode
+	trustedProxies []*net.IPNet
+
+	// oidc mode
+	oidc *oidcLogin
+}
+
+// dummyHash is compared for unknown users, so that the response time does not reveal which users exist.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// New creates the Authenticator for cfg; if authentication is disabled, Handler passes all requests through.
+func New(cfg config.AuthConfig, logger zerolog.Logger) (*Authenticator, error) {
+	a := &Authenticator{
+		config: cfg,
+		logger: logger.With().
+			Str(logging.EventDataset, logging.EventDataset_Dashica_Auth).
+			Logger(),
+	}
+	if !cfg.Enabled {
+		return a, nil
+	}
+
+	switch cfg.Mode {
+	case config.AuthModeBasic:
+		a.passwordHashes = make(map[string][]byte, len(cfg.Users)+1)
+		for username, passwordHash := range cfg.Users {
+			a.passwordHashes[username] = []byte(passwordHash)
+		}
+		if cfg.Username != "" {
+			passwordHash, err := cfg.ReadPassword()
+			if err != nil {
+				return nil, fmt.Errorf("password of user '%s': %w", cfg.Username, err)
+			}
+			a.passwordHashes[cfg.Username] = []byte(passwordHash)
+		}
+		for username, passwordHash := range a.passwordHashes {
+			if _, err := bcrypt.Cost(passwordHash); err != nil {
+				return nil, fmt.Errorf("password of user '%s' is not a bcrypt hash (generate one with 'htpasswd -nbB user password'): %w", username, err)
+			}
+		}
+	case config.AuthModeProxy:
+		for _, cidr := range cfg.Proxy.TrustedProxies {
+			_, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("proxy.trusted_proxies: %w", err)
+			}
+			a.trustedProxies = append(a.trustedProxies, ipNet)
+		}
+	case config.AuthModeOIDC:
+		a.oidc = newOIDCLogin(cfg.OIDC, a.logger)
+	default:
+		return nil, fmt.Errorf("unknown auth mode '%s'", cfg.Mode)
+	}
+	return a, nil
+}
+
+// Handler authenticates all requests to next (except for config.AuthConfig.PublicPaths), and stores the User in the
+// request context (see UserFromContext). In OIDC mode, it also serves the /auth/ login routes.
+func (a *Authenticator) Handler(next http.Handler) http.Handler {
+	if !a.config.Enabled {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.isPublicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if a.oidc != nil && strings.HasPrefix(r.URL.Path, oidcPathPrefix) {
+			a.oidc.ServeHTTP(w, r)
+			return
+		}
+
+		user := a.authenticate(r)
+		if user == nil {
+			a.unauthorized(w, r)
+			return
+		}
+		user.Groups = a.withConfiguredGroups(user.Name, user.Groups)
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	})
+}
+
+// isPublicPath returns true if path is one of the configured public paths, or below one of them. Public paths match
+// whole path segments, so "/health" does not make "/healthz-admin" public.
+func (a *Authenticator) isPublicPath(path string) bool {
+	for _, publicPath := range a.config.PublicPaths {
+		publicPath = strings.TrimSuffix(publicPath, "/")
+		if path == publicPath || strings.HasPrefix(path, publicPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate returns the user of r, or nil if r is not authenticated.
+func (a *Authenticator) authenticate(r *http.Request) *User {
+	switch a.config.Mode {
+	case config.AuthModeBasic:
+		username, password, ok := r.BasicAuth()
+		if ok && a.verifyPassword(username, password) {
+			return &User{Name: username}
+		}
+	case config.AuthModeProxy:
+		username := r.Header.Get(a.config.Proxy.UserHeader)
+		if username == "" {
+			return nil
+		}
+		if !a.isTrustedProxy(r.RemoteAddr) {
+			a.logger.Warn().
+				Str("remoteAddr", r.RemoteAddr).
+				Str("header", a.config.Proxy.UserHeader).
+				Msg("ignoring user header from untrusted proxy")
+			return nil
+		}
+		return &User{Name: username, Groups: splitGroups(r.Header.Get(a.config.Proxy.GroupsHeader))}
+	case config.AuthModeOIDC:
+		return a.oidc.sessionUser(r)
+	}
+	return nil
+}
+
+func (a *Authenticator) verifyPassword(username, password string) bool {
+	passwordHash, exists := a.passwordHash(username)
+	if !exists {
+		_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return false
+	}
+
+	// the hash is part of the checksum, so a rotated password file invalidates the cache
+	passwordSum := sha256.Sum256(append(append([]byte{}, passwordHash...), password...))
+	if verifiedSum, found := a.verifiedPasswords.Load(username); found {
+		if subtle.ConstantTimeCompare(verifiedSum.([]byte), passwordSum[:]) == 1 {
+			return true
+		}
+	}
+	if bcrypt.CompareHashAndPassword(passwordHash, []byte(password)) != nil {
+		return false
+	}
+	a.verifiedPasswords.Store(username, passwordSum[:])
+	return true
+}
+
+// passwordHash returns the bcrypt hash of username; the hash of config.AuthConfig.Username is read from its
+// password_file again if that changed.
+func (a *Authenticator) passwordHash(username string) ([]byte, bool) {
+	if username != "" && username == a.config.Username && a.config.PasswordFile != "" {
+		passwordHash, err := a.config.ReadPassword()
+		if err != nil {
+			a.logger.Error().Err(err).Str("user", username).Msg("could not read password file")
+			return nil, false
+		}
+		return []byte(passwordHash), true
+	}
+	passwordHash, exists := a.passwordHashes[username]
+	return passwordHash, exists
+}
+
+func (a *Authenticator) isTrustedProxy(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, trustedProxy := range a.trustedProxies {
+		if trustedProxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// unauthorized asks the client to authenticate: via basic auth challenge, or (for OIDC) by redirecting browsers
+// to the login.
+func (a *Authenticator) unauthorized(w http.ResponseWriter, r *http.Request) {
+	switch a.config.Mode {
+	case config.AuthModeBasic:
+		w.Header().Set("WWW-Authenticate", `Basic realm="Dashica", charset="UTF-8"`)
+	case config.AuthModeOIDC:
+		if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+			a.oidc.redirectToLogin(w, r)
+			return
+		}
+	}
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// withConfiguredGroups adds the groups of config.AuthConfig.Groups the user is a member of.
+func (a *Authenticator) withConfiguredGroups(username string, groups []string) []string {
+	for group, members := range a.config.Groups {
+		if slices.Contains(members, username) && !slices.Contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	slices.Sort(groups)
+	return groups
+}
+
+func splitGroups(header string) []string {
+	groups := make([]string, 0)
+	for _, group := range strings.Split(header, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
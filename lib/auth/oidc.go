@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/config"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcPathPrefix    = "/auth/"
+	sessionCookieName = "dashica_session"
+	// loginCookieName holds the loginState between /auth/login and /auth/callback
+	loginCookieName = "dashica_login"
+	loginTimeout    = 10 * time.Minute
+)
+
+// oidcLogin implements the OIDC authorization code flow at /auth/login, /auth/callback and /auth/logout; the
+// logged-in user is kept in a signed session cookie.
+type oidcLogin struct {
+	config  config.OIDCConfig
+	logger  zerolog.Logger
+	cookies signedCookies
+
+	// mutex protecting verifier and oauth2Config, which are initialized on the first login (so that Dashica starts
+	// even if the OIDC provider is not reachable).
+	mu           sync.Mutex
+	verifier     *oidc.IDTokenVerifier
+	oauth2Config *oauth2.Config
+}
+
+// session is stored in the session cookie.
+type session struct {
+	User    string   `json:"u"`
+	Groups  []string `json:"g"`
+	Expires int64    `json:"e"`
+}
+
+// loginState is stored in the login cookie.
+type loginState struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Redirect string `json:"r"`
+	Expires  int64  `json:"e"`
+}
+
+func newOIDCLogin(cfg config.OIDCConfig, logger zerolog.Logger) *oidcLogin {
+	return &oidcLogin{
+		config:  cfg,
+		logger:  logger,
+		cookies: signedCookies{secret: []byte(cfg.SessionSecret), secure: strings.HasPrefix(cfg.RedirectURL, "https://")},
+	}
+}
+
+func (o *oidcLogin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case oidcPathPrefix + "login":
+		o.login(w, r)
+	case oidcPathPrefix + "callback":
+		o.callback(w, r)
+	case oidcPathPrefix + "logout":
+		o.cookies.clear(w, sessionCookieName)
+		http.Redirect(w, r, "/", http.StatusFound)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// provider returns the lazily initialized ID token verifier and OAuth2 config.
+func (o *oidcLogin) provider(r *http.Request) (*oidc.IDTokenVerifier, *oauth2.Config, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.verifier != nil {
+		return o.verifier, o.oauth2Config, nil
+	}
+
+	provider, err := oidc.NewProvider(r.Context(), o.config.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("discovering OIDC provider %s: %w", o.config.IssuerURL, err)
+	}
+	o.verifier = provider.Verifier(&oidc.Config{ClientID: o.config.ClientID})
+	o.oauth2Config = &oauth2.Config{
+		ClientID:     o.config.ClientID,
+		ClientSecret: o.config.ClientSecret,
+		RedirectURL:  o.config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       o.config.Scopes,
+	}
+	return o.verifier, o.oauth2Config, nil
+}
+
+func (o *oidcLogin) redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, oidcPathPrefix+"login?redirect="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+}
+
+func (o *oidcLogin) login(w http.ResponseWriter, r *http.Request) {
+	_, oauth2Config, err := o.provider(r)
+	if err != nil {
+		o.logger.Error().Err(err).Msg("OIDC login failed")
+		http.Error(w, "OIDC provider not available", http.StatusBadGateway)
+		return
+	}
+
+	state := loginState{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Redirect: safeRedirect(r.URL.Query().Get("redirect")),
+		Expires:  time.Now().Add(loginTimeout).Unix(),
+	}
+	if err := o.cookies.set(w, loginCookieName, state, loginTimeout); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, oauth2Config.AuthCodeURL(state.State, oidc.Nonce(state.Nonce)), http.StatusFound)
+}
+
+func (o *oidcLogin) callback(w http.ResponseWriter, r *http.Request) {
+	verifier, oauth2Config, err := o.provider(r)
+	if err != nil {
+		o.logger.Error().Err(err).Msg("OIDC callback failed")
+		http.Error(w, "OIDC provider not available", http.StatusBadGateway)
+		return
+	}
+
+	var state loginState
+	if err := o.cookies.get(r, loginCookieName, &state); err != nil || state.Expires < time.Now().Unix() || state.State != r.URL.Query().Get("state") {
+		http.Error(w, "invalid or expired login, please try again", http.StatusBadRequest)
+		return
+	}
+	o.cookies.clear(w, loginCookieName)
+	if errorCode := r.URL.Query().Get("error"); errorCode != "" {
+		http.Error(w, "login failed: "+errorCode+" "+r.URL.Query().Get("error_description"), http.StatusForbidden)
+		return
+	}
+
+	token, err := oauth2Config.Exchange(r.Context(), r.URL.Query().Get("code"))
+	if err != nil {
+		o.logger.Warn().Err(err).Msg("OIDC code exchange failed")
+		http.Error(w, "login failed", http.StatusForbidden)
+		return
+	}
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		http.Error(w, "login failed: no id_token received", http.StatusForbidden)
+		return
+	}
+	idToken, err := verifier.Verify(r.Context(), rawIdToken)
+	if err != nil || idToken.Nonce != state.Nonce {
+		o.logger.Warn().Err(err).Msg("OIDC id token verification failed")
+		http.Error(w, "login failed", http.StatusForbidden)
+		return
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		http.Error(w, "login failed: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	userSession := session{
+		User:    idToken.Subject,
+		Groups:  stringsClaim(claims[o.config.GroupsClaim]),
+		Expires: time.Now().Add(o.config.SessionDuration).Unix(),
+	}
+	if username, ok := claims[o.config.UsernameClaim].(string); ok && username != "" {
+		userSession.User = username
+	}
+	if err := o.cookies.set(w, sessionCookieName, userSession, o.config.SessionDuration); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	o.logger.Info().Str("user", userSession.User).Strs("groups", userSession.Groups).Msg("OIDC login")
+	http.Redirect(w, r, state.Redirect, http.StatusFound)
+}
+
+// sessionUser returns the user of the session cookie; nil if there is no valid session.
+func (o *oidcLogin) sessionUser(r *http.Request) *User {
+	var userSession session
+	if err := o.cookies.get(r, sessionCookieName, &userSession); err != nil || userSession.Expires < time.Now().Unix() || userSession.User == "" {
+		return nil
+	}
+	return &User{Name: userSession.User, Groups: userSession.Groups}
+}
+
+// safeRedirect only allows local redirect targets (no open redirect).
+func safeRedirect(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/"
+	}
+	return target
+}
+
+// stringsClaim converts a claim which is a list of strings (or a single string).
+func stringsClaim(claim any) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// signedCookies stores JSON values in cookies, signed with HMAC-SHA256 (so they cannot be forged; they are NOT
+// encrypted). The signature covers the cookie name, so the value of one cookie (e.g. the login state every visitor
+// gets) is not accepted as another (the session).
+type signedCookies struct {
+	secret []byte
+	secure bool
+}
+
+func (c signedCookies) sign(name string, payload string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(name + "." + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c signedCookies) set(w http.ResponseWriter, name string, value any, maxAge time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encoding cookie %s: %w", name, err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    payload + "." + c.sign(name, payload),
+		Path:     "/",
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   c.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func (c signedCookies) get(r *http.Request, name string, value any) error {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return err
+	}
+	payload, signature, found := strings.Cut(cookie.Value, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(c.sign(name, payload))) {
+		return errors.New("invalid cookie signature")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+func (c signedCookies) clear(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{Name: name, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, Secure: c.secure})
+}
@@ -1,6 +1,7 @@
 package clickhouse
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
@@ -15,15 +16,26 @@ type Manager struct {
 	// mutex protecting clients map
 	mu     sync.RWMutex
 	logger zerolog.Logger
+	// cache is shared by all clients; nil if the query cache is disabled
+	cache *QueryCache
+	// queryLog is shared by all clients; nil if the query log is disabled
+	queryLog *QueryLog
 }
 
 // NewManager creates a new Clickhouse client manager
 func NewManager(config *config.Config, logger zerolog.Logger) *Manager {
-	return &Manager{
+	manager := &Manager{
 		config:  config,
 		clients: make(map[string]*Client),
 		logger:  logger,
 	}
+	if config.QueryCache.Enabled {
+		manager.cache = NewQueryCache(config.QueryCache)
+	}
+	if config.QueryLog.Enabled {
+		manager.queryLog = newQueryLog(config.QueryLog, logger, manager)
+	}
+	return manager
 }
 
 // GetClient returns a client for the specified server ID
@@ -53,7 +65,26 @@ func (cm *Manager) GetClient(serverId string) (*Client, error) {
 	}
 
 	// Create new client
-	client = NewClient(&serverConfig, serverId, cm.logger)
+	client, err := NewClient(&serverConfig, serverId, cm.logger)
+	if err != nil {
+		return nil, err
+	}
+	client.cache = cm.cache
+	client.queryLog = cm.queryLog
 	cm.clients[serverId] = client
 	return client, nil
 }
+
+// QueryCache returns the query cache shared by all clients, or nil if it is disabled.
+func (cm *Manager) QueryCache() *QueryCache {
+	return cm.cache
+}
+
+// Shutdown writes the buffered query log entries (if the query log is enabled); it waits until they are written or
+// ctx is done.
+func (cm *Manager) Shutdown(ctx context.Context) error {
+	if cm.queryLog == nil {
+		return nil
+	}
+	return cm.queryLog.Shutdown(ctx)
+}
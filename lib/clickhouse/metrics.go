@@ -0,0 +1,46 @@
+package clickhouse
+
+import (
+	"github.com/sandstorm/dashica/lib/metrics"
+)
+
+var (
+	queriesTotal = metrics.Default.NewCounterVec(
+		"dashica_clickhouse_queries_total",
+		"Number of ClickHouse queries by server alias and status (ok / error).",
+		"server", "status")
+	queryDuration = metrics.Default.NewHistogramVec(
+		"dashica_clickhouse_query_duration_seconds",
+		"Duration of ClickHouse queries until the response headers are received, by server alias.",
+		metrics.DefaultDurationBuckets,
+		"server")
+	queryRetriesTotal = metrics.Default.NewCounterVec(
+		"dashica_clickhouse_query_retries_total",
+		"Number of retried ClickHouse requests (connection errors, 502 / 503 / 504 responses), by server alias.",
+		"server")
+	queryGuardErrorsTotal = metrics.Default.NewCounterVec(
+		"dashica_clickhouse_query_guard_errors_total",
+		"Number of queries aborted by a query guard (e.g. max_execution_time), by server alias and guard.",
+		"server", "guard")
+	rowsReadTotal = metrics.Default.NewCounterVec(
+		"dashica_clickhouse_read_rows_total",
+		"Rows read by ClickHouse (from the X-ClickHouse-Summary response header), by server alias.",
+		"server")
+	bytesReadTotal = metrics.Default.NewCounterVec(
+		"dashica_clickhouse_read_bytes_total",
+		"Bytes read by ClickHouse (from the X-ClickHouse-Summary response header), by server alias.",
+		"server")
+	queryLogDroppedTotal = metrics.Default.NewCounterVec(
+		"dashica_clickhouse_query_log_dropped_total",
+		"Number of query log entries which could not be written (buffer full or insert failed).")
+	queryCacheRequestsTotal = metrics.Default.NewCounterVec(
+		"dashica_clickhouse_query_cache_requests_total",
+		"Number of cacheable queries by server alias and cache status (HIT / MISS / COALESCED / BYPASS).",
+		"server", "status")
+	queryCacheSizeBytes = metrics.Default.NewGaugeVec(
+		"dashica_clickhouse_query_cache_size_bytes",
+		"Approximate memory used by the cached query results.")
+	queryCacheEntries = metrics.Default.NewGaugeVec(
+		"dashica_clickhouse_query_cache_entries",
+		"Number of cached query results.")
+)
@@ -11,6 +11,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -22,21 +23,27 @@ type Client struct {
 	serverConfig *config.ClickHouseConfig
 	httpClient   *http.Client
 	logger       zerolog.Logger
+	// cache is nil if the query cache is disabled
+	cache *QueryCache
+	// queryLog is nil if the query log is disabled
+	queryLog *QueryLog
 
 	introspectedSchemaMutex  sync.Mutex
 	introspectedSchemaCached *IntrospectedSchema
 }
 
 // NewClient creates a new Clickhouse HTTP client for a specific server
-func NewClient(serverConfig *config.ClickHouseConfig, id string, logger zerolog.Logger) *Client {
+func NewClient(serverConfig *config.ClickHouseConfig, id string, logger zerolog.Logger) (*Client, error) {
+	httpClient, err := newHTTPClient(serverConfig)
+	if err != nil {
+		return nil, fmt.Errorf("clickhouse server '%s': %w", id, err)
+	}
 	return &Client{
 		Id:           id,
 		serverConfig: serverConfig,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		logger: logger,
-	}
+		httpClient:   httpClient,
+		logger:       logger,
+	}, nil
 }
 
 // QueryOptions represents options that can be set when making a query
@@ -44,7 +51,13 @@ type QueryOptions struct {
 	Format      string            // Output format (default: JSONEachRow)
 	Settings    map[string]string // Clickhouse settings
 	Parameters  map[string]string // Query parameters
-	Compression bool              // Enable compression
+	Compression bool              // Enable response compression (the server's compression, default gzip)
+	// Untrusted applies the stricter Explore guards of the server (see config.QueryGuards).
+	Untrusted bool
+	// Cache enables the query cache (if configured) for QueryToHandler; the result is cached for CacheTTL (0: the
+	// default TTL of the cache).
+	Cache    bool
+	CacheTTL time.Duration
 }
 
 // DefaultQueryOptions returns the default query options
@@ -66,16 +79,17 @@ func DefaultQueryOptions() QueryOptions {
 
 // Query executes a READ ONLY SQL query and returns the response body
 func (c *Client) Query(ctx context.Context, query string, options QueryOptions) (*http.Response, error) {
-	return c.queryInternal(ctx, "GET", query, options)
+	return c.queryInternal(ctx, true, query, options)
 }
 
 // Execute a read/write SQL query and returns the response body
 func (c *Client) Execute(ctx context.Context, query string, options QueryOptions) (*http.Response, error) {
-	return c.queryInternal(ctx, "POST", query, options)
+	return c.queryInternal(ctx, false, query, options)
 }
 
-// Query executes a SQL query and returns the response body
-func (c *Client) queryInternal(ctx context.Context, method string, query string, options QueryOptions) (*http.Response, error) {
+// queryInternal sends the query in the POST body (so that long queries do not exceed URL length limits) and returns
+// the (decompressed) response body.
+func (c *Client) queryInternal(ctx context.Context, readOnly bool, query string, options QueryOptions) (*http.Response, error) {
 	c.logger.Debug().
 		Str("query", query).
 		Str("clientId", c.Id).
@@ -83,6 +97,10 @@ func (c *Client) queryInternal(ctx context.Context, method string, query string,
 			Fields(options.Parameters)).
 		Msg("executing SQL query")
 
+	if options.Untrusted && hasSettingsClause(query) {
+		return nil, &QueryGuardError{ServerId: c.Id, Guard: "settings"}
+	}
+
 	// Build URL query parameters
 	params := url.Values{}
 
@@ -97,8 +115,21 @@ func (c *Client) queryInternal(ctx context.Context, method string, query string,
 	}
 	params.Add("default_format", options.Format)
 
-	// Add settings
+	// ClickHouse only enforces read-only mode for GET requests; as the query is POSTed, request it explicitly.
+	// readonly=2 still allows changing settings (which we pass as URL parameters).
+	if readOnly && c.serverConfig.ReadOnly != 0 {
+		params.Add("readonly", strconv.Itoa(c.serverConfig.ReadOnly))
+	}
+
+	// Add settings; the guards of the server apply unless the caller sets them explicitly.
+	settings := guardSettings(effectiveGuards(c.serverConfig, options.Untrusted))
 	for setting, value := range options.Settings {
+		if _, isGuard := settings[setting]; isGuard && options.Untrusted {
+			continue
+		}
+		settings[setting] = value
+	}
+	for setting, value := range settings {
 		params.Add(setting, value)
 	}
 
@@ -107,70 +138,140 @@ func (c *Client) queryInternal(ctx context.Context, method string, query string,
 		params.Add(fmt.Sprintf("param_%s", param), value)
 	}
 
-	params.Add("query", query)
+	compression := ""
+	if options.Compression && c.serverConfig.Compression != CompressionNone {
+		compression = c.serverConfig.Compression
+		if compression == "" {
+			compression = CompressionGzip
+		}
+		params.Add("enable_http_compression", "1")
+	}
+
 	// Build URL
 	reqURL := fmt.Sprintf("%s?%s", c.serverConfig.URL, params.Encode())
 
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, method, reqURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, strings.NewReader(query))
+		if err != nil {
+			return nil, err
+		}
 
-	// Set authentication
-	if c.serverConfig.User != "" {
-		req.SetBasicAuth(c.serverConfig.User, c.serverConfig.Password)
-	}
+		// Set authentication
+		if c.serverConfig.User != "" {
+			password, err := c.serverConfig.ReadPassword()
+			if err != nil {
+				return nil, err
+			}
+			req.SetBasicAuth(c.serverConfig.User, password)
+		}
 
-	// Set headers
-	req.Header.Set("Content-Type", "text/plain")
+		// Set headers
+		req.Header.Set("Content-Type", "text/plain")
 
-	// Enable compression if requested
-	if options.Compression {
-		req.Header.Set("Accept-Encoding", "gzip")
+		// Enable compression if requested; the response is decompressed by decompressBody (as setting
+		// Accept-Encoding disables the transparent decompression of net/http).
+		if compression != "" {
+			req.Header.Set("Accept-Encoding", compression)
+		}
+		return req, nil
 	}
 
 	// Execute request
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("executing request: %w", err)
+	logEntry := c.queryLog.newEntry(ctx, c.Id, query)
+	start := time.Now()
+	resp, err := c.doWithRetries(ctx, readOnly, newRequest)
+	queryDuration.Observe(time.Since(start).Seconds(), c.Id)
+	if err == nil {
+		resp, err = c.checkResponse(resp, settings)
+	} else {
+		queriesTotal.Inc(c.Id, "error")
+		err = fmt.Errorf("executing request: %w", err)
+	}
+	c.queryLog.finishEntry(logEntry, resp, err)
+	return resp, err
+}
+
+// checkResponse decompresses a response, and converts unsuccessful responses to errors.
+func (c *Client) checkResponse(resp *http.Response, settings map[string]string) (*http.Response, error) {
+	if err := decompressBody(resp); err != nil {
+		queriesTotal.Inc(c.Id, "error")
+		resp.Body.Close()
+		return nil, err
 	}
 
 	// Check for errors in response
 	if resp.StatusCode != http.StatusOK {
+		queriesTotal.Inc(c.Id, "error")
 		defer resp.Body.Close()
 		body, _ := io.ReadAll(resp.Body)
+		if guardErr := guardError(c.Id, settings, resp.Header, string(body)); guardErr != nil {
+			queryGuardErrorsTotal.Inc(c.Id, guardErr.Guard)
+			return nil, guardErr
+		}
 		return nil, fmt.Errorf("unsuccessful clickhouse response (status %d): %s", resp.StatusCode, string(body))
 	}
+	queriesTotal.Inc(c.Id, "ok")
+	summary := parseQuerySummary(resp.Header)
+	rowsReadTotal.Add(float64(summary.ReadRows), c.Id)
+	bytesReadTotal.Add(float64(summary.ReadBytes), c.Id)
 
 	return resp, nil
 }
 
-// QueryToHandler executes a SQL query and pipes the results directly to an HTTP response writer
+// querySummary is the X-ClickHouse-Summary response header. It contains the statistics at the time the response
+// headers were sent; for large, streamed results they may be incomplete.
+type querySummary struct {
+	ReadRows   uint64 `json:"read_rows,string"`
+	ReadBytes  uint64 `json:"read_bytes,string"`
+	ResultRows uint64 `json:"result_rows,string"`
+}
+
+// parseQuerySummary returns the X-ClickHouse-Summary of a response; missing or invalid headers count as zero.
+func parseQuerySummary(header http.Header) querySummary {
+	var summary querySummary
+	if value := header.Get("X-ClickHouse-Summary"); value != "" {
+		_ = json.Unmarshal([]byte(value), &summary)
+	}
+	return summary
+}
+
+// QueryToHandler executes a SQL query and pipes the results directly to an HTTP response writer. If
+// options.Cache is set and the query cache is enabled, the result is served from / stored in the cache, and the
+// cache status is sent in the CacheStatusHeader.
 func (c *Client) QueryToHandler(ctx context.Context, query string, options QueryOptions, w http.ResponseWriter) error {
-	// Arrow cannot serialize JSON/Object/Dynamic/Variant result columns; rewrite
-	// the query to cast exactly those to String when needed (no-op otherwise).
-	query, err := c.ensureArrowCompatible(ctx, query, options)
-	if err != nil {
+	execute := func(ctx context.Context) (*http.Response, error) {
+		// Arrow cannot serialize JSON/Object/Dynamic/Variant result columns; rewrite
+		// the query to cast exactly those to String when needed (no-op otherwise).
+		query, err := c.ensureArrowCompatible(ctx, query, options)
+		if err != nil {
+			return nil, err
+		}
+		return c.Query(ctx, query, options)
+	}
+
+	if c.cache != nil && options.Cache {
+		ttl := options.CacheTTL
+		if ttl <= 0 {
+			ttl = c.cache.DefaultTTL()
+		}
+		status, err := c.cache.serve(ctx, cacheKey(c.Id, query, options), ttl, getContentType(options.Format), w, execute)
+		queryCacheRequestsTotal.Inc(c.Id, status)
 		return err
 	}
 
 	// Execute query
-	resp, err := c.Query(ctx, query, options)
+	resp, err := execute(ctx)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	// Copy headers from Clickhouse response to the output response
-	for key, values := range resp.Header {
-		for _, value := range values {
-			w.Header().Add(key, value)
-		}
+	// Copy headers from Clickhouse response to the output response, and set content type based on format
+	status := ""
+	if c.cache != nil {
+		status = CacheStatusBypass
 	}
-
-	// Set content type based on format
-	w.Header().Set("Content-Type", getContentType(options.Format))
+	copyHeaders(w, resp.Header, getContentType(options.Format), status)
 
 	// Stream the response body to the output
 	_, err = io.Copy(w, resp.Body)
@@ -218,7 +319,6 @@ func QueryJSON[T any](ctx context.Context, client *Client, query string, opts Qu
 	if err := json.Unmarshal(queryResult, &result); err != nil {
 		return nil, fmt.Errorf("parse JSON response: %w", err)
 	}
-
 	return &result, nil
 }
 
@@ -0,0 +1,276 @@
+package clickhouse
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sandstorm/dashica/lib/config"
+)
+
+// CacheStatusHeader reports how a query result was served, if the query cache is enabled: CacheStatusHit,
+// CacheStatusMiss, CacheStatusCoalesced or CacheStatusBypass.
+const CacheStatusHeader = "X-Dashica-Cache"
+
+const (
+	// CacheStatusHit - the result was served from the cache.
+	CacheStatusHit = "HIT"
+	// CacheStatusMiss - the query was executed (and its result stored).
+	CacheStatusMiss = "MISS"
+	// CacheStatusCoalesced - an identical query was already running; its result was shared.
+	CacheStatusCoalesced = "COALESCED"
+	// CacheStatusBypass - the query was executed without the cache (caching disabled for the query, or the result
+	// was too large).
+	CacheStatusBypass = "BYPASS"
+)
+
+// QueryCache is a size-bounded in-memory LRU cache of query results, shared by all clients of a Manager. Concurrent
+// identical queries are coalesced: only the first one runs, the others wait for (and share) its result.
+//
+// Results are keyed by server alias, final SQL, format, settings and parameters - so queries with relative time
+// ranges only hit the cache if "now" is rounded (see httpserver.DashboardFilters.RoundNow).
+type QueryCache struct {
+	defaultTTL    time.Duration
+	maxSizeBytes  int64
+	maxEntryBytes int64
+	now           func() time.Time
+
+	// mutex protecting all fields below
+	mu        sync.Mutex
+	entries   map[string]*list.Element
+	lru       *list.List // of *cachedResult; most recently used first
+	sizeBytes int64
+	inFlight  map[string]*inFlightQuery
+}
+
+type cachedResult struct {
+	key     string
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// inFlightQuery is closed (done) when the first of several identical queries finished. result is nil if the result
+// could not be shared (too large, or the query was cancelled); waiters then run the query themselves.
+type inFlightQuery struct {
+	done   chan struct{}
+	result *cachedResult
+	err    error
+}
+
+// NewQueryCache creates a cache holding at most cfg.MaxSizeBytes of results; a single result may use at most
+// cfg.MaxEntrySizeBytes (larger results are streamed without caching).
+func NewQueryCache(cfg config.QueryCacheConfig) *QueryCache {
+	return &QueryCache{
+		defaultTTL:    cfg.DefaultTTL,
+		maxSizeBytes:  cfg.MaxSizeBytes,
+		maxEntryBytes: min(cfg.MaxEntrySizeBytes, cfg.MaxSizeBytes),
+		now:           time.Now,
+		entries:       make(map[string]*list.Element),
+		lru:           list.New(),
+		inFlight:      make(map[string]*inFlightQuery),
+	}
+}
+
+// DefaultTTL is used for queries which do not specify QueryOptions.CacheTTL.
+func (qc *QueryCache) DefaultTTL() time.Duration {
+	return qc.defaultTTL
+}
+
+// cacheKey identifies a query result; maps are sorted so that the key is deterministic. Untrusted queries run with
+// stricter guards (see effectiveGuards); so their results are cached separately.
+func cacheKey(serverId string, query string, options QueryOptions) string {
+	h := sha256.New()
+	write := func(parts ...string) {
+		for _, part := range parts {
+			// length prefix, so that no two different inputs produce the same byte stream
+			fmt.Fprintf(h, "%d:%s", len(part), part)
+		}
+	}
+	writeMap := func(m map[string]string) {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		write(fmt.Sprint(len(keys)))
+		for _, k := range keys {
+			write(k, m[k])
+		}
+	}
+	write(serverId, options.Format, query, strconv.FormatBool(options.Untrusted))
+	writeMap(options.Settings)
+	writeMap(options.Parameters)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns the unexpired result for key, or nil.
+func (qc *QueryCache) get(key string) *cachedResult {
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+	return qc.getLocked(key)
+}
+
+func (qc *QueryCache) getLocked(key string) *cachedResult {
+	element, found := qc.entries[key]
+	if !found {
+		return nil
+	}
+	result := element.Value.(*cachedResult)
+	if !qc.now().Before(result.expires) {
+		qc.removeLocked(element)
+		return nil
+	}
+	qc.lru.MoveToFront(element)
+	return result
+}
+
+// put stores result, evicting the least recently used results if the cache is full.
+func (qc *QueryCache) put(result *cachedResult) {
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+	if element, found := qc.entries[result.key]; found {
+		qc.removeLocked(element)
+	}
+	qc.entries[result.key] = qc.lru.PushFront(result)
+	qc.sizeBytes += result.size()
+	for qc.sizeBytes > qc.maxSizeBytes {
+		qc.removeLocked(qc.lru.Back())
+	}
+	queryCacheSizeBytes.Set(float64(qc.sizeBytes))
+	queryCacheEntries.Set(float64(len(qc.entries)))
+}
+
+func (qc *QueryCache) removeLocked(element *list.Element) {
+	result := qc.lru.Remove(element).(*cachedResult)
+	delete(qc.entries, result.key)
+	qc.sizeBytes -= result.size()
+	queryCacheSizeBytes.Set(float64(qc.sizeBytes))
+	queryCacheEntries.Set(float64(len(qc.entries)))
+}
+
+// size approximates the memory used by the result.
+func (r *cachedResult) size() int64 {
+	size := int64(len(r.key) + len(r.body))
+	for key, values := range r.header {
+		size += int64(len(key))
+		for _, value := range values {
+			size += int64(len(value))
+		}
+	}
+	return size
+}
+
+// serve writes the result of query to w: from the cache, from an identical running query, or by executing it via
+// execute (whose result is then stored for ttl). It returns the cache status, which is also sent in CacheStatusHeader.
+func (qc *QueryCache) serve(ctx context.Context, key string, ttl time.Duration, contentType string, w http.ResponseWriter, execute func(context.Context) (*http.Response, error)) (string, error) {
+	qc.mu.Lock()
+	if result := qc.getLocked(key); result != nil {
+		qc.mu.Unlock()
+		return CacheStatusHit, writeCachedResult(w, result, contentType, CacheStatusHit)
+	}
+	if running, found := qc.inFlight[key]; found {
+		qc.mu.Unlock()
+		select {
+		case <-running.done:
+		case <-ctx.Done():
+			return CacheStatusCoalesced, ctx.Err()
+		}
+		if running.err != nil {
+			return CacheStatusCoalesced, running.err
+		}
+		if running.result != nil {
+			return CacheStatusCoalesced, writeCachedResult(w, running.result, contentType, CacheStatusCoalesced)
+		}
+		// the result could not be shared; so run the query ourselves.
+		return qc.executeUncached(ctx, contentType, w, execute)
+	}
+	running := &inFlightQuery{done: make(chan struct{})}
+	qc.inFlight[key] = running
+	qc.mu.Unlock()
+
+	defer func() {
+		qc.mu.Lock()
+		delete(qc.inFlight, key)
+		qc.mu.Unlock()
+		close(running.done)
+	}()
+
+	resp, err := execute(ctx)
+	if err != nil {
+		// a cancelled request must not fail the waiting ones.
+		if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			running.err = err
+		}
+		return CacheStatusMiss, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, qc.maxEntryBytes+1))
+	if err != nil {
+		return CacheStatusMiss, fmt.Errorf("reading response: %w", err)
+	}
+	if int64(len(body)) > qc.maxEntryBytes {
+		// too large to be cached: stream the already read part, and the rest.
+		copyHeaders(w, resp.Header, contentType, CacheStatusBypass)
+		if _, err := io.Copy(w, io.MultiReader(bytes.NewReader(body), resp.Body)); err != nil {
+			return CacheStatusBypass, fmt.Errorf("streaming response: %w", err)
+		}
+		return CacheStatusBypass, nil
+	}
+
+	result := &cachedResult{
+		key:     key,
+		header:  resp.Header.Clone(),
+		body:    body,
+		expires: qc.now().Add(ttl),
+	}
+	qc.put(result)
+	running.result = result
+	return CacheStatusMiss, writeCachedResult(w, result, contentType, CacheStatusMiss)
+}
+
+func (qc *QueryCache) executeUncached(ctx context.Context, contentType string, w http.ResponseWriter, execute func(context.Context) (*http.Response, error)) (string, error) {
+	resp, err := execute(ctx)
+	if err != nil {
+		return CacheStatusBypass, err
+	}
+	defer resp.Body.Close()
+	copyHeaders(w, resp.Header, contentType, CacheStatusBypass)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return CacheStatusBypass, fmt.Errorf("streaming response: %w", err)
+	}
+	return CacheStatusBypass, nil
+}
+
+func writeCachedResult(w http.ResponseWriter, result *cachedResult, contentType string, status string) error {
+	copyHeaders(w, result.header, contentType, status)
+	if _, err := w.Write(result.body); err != nil {
+		return fmt.Errorf("writing cached response: %w", err)
+	}
+	return nil
+}
+
+// copyHeaders copies the headers of the ClickHouse response (must happen before the body is written), and sets the
+// Content-Type and - unless empty - the cache status.
+func copyHeaders(w http.ResponseWriter, header http.Header, contentType string, status string) {
+	for key, values := range header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	if status != "" {
+		w.Header().Set(CacheStatusHeader, status)
+	}
+}
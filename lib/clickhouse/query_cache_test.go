@@ -0,0 +1,165 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestQueryCache(maxSizeBytes, maxEntrySizeBytes int64) *QueryCache {
+	return NewQueryCache(config.QueryCacheConfig{
+		Enabled:           true,
+		DefaultTTL:        time.Minute,
+		MaxSizeBytes:      maxSizeBytes,
+		MaxEntrySizeBytes: maxEntrySizeBytes,
+	})
+}
+
+// newCachingTestClient returns a client for a fake ClickHouse server, which answers every query with its query string
+// (repeated `repeat` times); the returned counter counts the received queries.
+func newCachingTestClient(t *testing.T, cache *QueryCache, repeat int) (*Client, *atomic.Int32) {
+	var queries atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries.Add(1)
+		query, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-ClickHouse-Query-Id", fmt.Sprint(queries.Load()))
+		_, _ = w.Write(bytes.Repeat(query, repeat))
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClient(&config.ClickHouseConfig{URL: server.URL}, "default", zerolog.Nop())
+	require.NoError(t, err)
+	client.cache = cache
+	return client, &queries
+}
+
+func queryToRecorder(t *testing.T, client *Client, query string, options QueryOptions) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	require.NoError(t, client.QueryToHandler(context.Background(), query, options, recorder))
+	return recorder
+}
+
+func TestQueryToHandler_Cache(t *testing.T) {
+	cache := newTestQueryCache(1<<20, 1<<20)
+	client, queries := newCachingTestClient(t, cache, 1)
+	options := QueryOptions{Format: "JSON", Parameters: map[string]string{"a": "1"}, Cache: true}
+
+	first := queryToRecorder(t, client, "SELECT 1", options)
+	assert.Equal(t, CacheStatusMiss, first.Header().Get(CacheStatusHeader))
+	assert.Equal(t, "application/json", first.Header().Get("Content-Type"))
+
+	second := queryToRecorder(t, client, "SELECT 1", options)
+	assert.Equal(t, CacheStatusHit, second.Header().Get(CacheStatusHeader))
+	assert.Equal(t, "SELECT 1", second.Body.String())
+	assert.Equal(t, "1", second.Header().Get("X-ClickHouse-Query-Id"), "headers of the cached response are kept")
+	assert.EqualValues(t, 1, queries.Load())
+
+	otherParams := queryToRecorder(t, client, "SELECT 1", QueryOptions{Format: "JSON", Parameters: map[string]string{"a": "2"}, Cache: true})
+	assert.Equal(t, CacheStatusMiss, otherParams.Header().Get(CacheStatusHeader))
+
+	uncached := queryToRecorder(t, client, "SELECT 1", QueryOptions{Format: "JSON", Parameters: map[string]string{"a": "1"}})
+	assert.Equal(t, CacheStatusBypass, uncached.Header().Get(CacheStatusHeader))
+	assert.EqualValues(t, 3, queries.Load())
+}
+
+func TestQueryToHandler_CacheSkipsLargeResults(t *testing.T) {
+	cache := newTestQueryCache(1<<20, 100)
+	client, queries := newCachingTestClient(t, cache, 100)
+	options := QueryOptions{Format: "JSON", Cache: true}
+
+	for range 2 {
+		recorder := queryToRecorder(t, client, "SELECT 1", options)
+		assert.Equal(t, CacheStatusBypass, recorder.Header().Get(CacheStatusHeader))
+		assert.Equal(t, strings.Repeat("SELECT 1", 100), recorder.Body.String(), "the full result is streamed")
+	}
+	assert.EqualValues(t, 2, queries.Load())
+}
+
+func TestQueryCache_Coalescing(t *testing.T) {
+	cache := newTestQueryCache(1<<20, 1<<20)
+	release := make(chan struct{})
+	var executions atomic.Int32
+	execute := func(ctx context.Context) (*http.Response, error) {
+		executions.Add(1)
+		<-release
+		recorder := httptest.NewRecorder()
+		_, _ = recorder.WriteString("result")
+		return recorder.Result(), nil
+	}
+
+	const requests = 10
+	var wg sync.WaitGroup
+	statuses := make([]string, requests)
+	recorders := make([]*httptest.ResponseRecorder, requests)
+	for i := range requests {
+		recorders[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			statuses[i], err = cache.serve(context.Background(), "key", time.Minute, "text/plain", recorders[i], execute)
+			assert.NoError(t, err)
+		}()
+	}
+	require.Eventually(t, func() bool { return executions.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond) // let the other requests wait for the running query
+	close(release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, executions.Load())
+	assert.Contains(t, statuses, CacheStatusMiss)
+	for i := range requests {
+		assert.Contains(t, []string{CacheStatusMiss, CacheStatusCoalesced, CacheStatusHit}, statuses[i])
+		assert.Equal(t, "result", recorders[i].Body.String())
+	}
+}
+
+func TestQueryCache_ExpiryAndEviction(t *testing.T) {
+	cache := newTestQueryCache(250, 250)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	store := func(key string) {
+		cache.put(&cachedResult{key: key, body: make([]byte, 100), expires: now.Add(time.Minute)})
+	}
+
+	store("a")
+	store("b")
+	require.NotNil(t, cache.get("a"), "a is now the most recently used result")
+	store("c")
+	assert.NotNil(t, cache.get("a"))
+	assert.Nil(t, cache.get("b"), "the least recently used result is evicted")
+	assert.NotNil(t, cache.get("c"))
+
+	now = now.Add(time.Minute)
+	assert.Nil(t, cache.get("a"), "expired")
+	assert.Nil(t, cache.get("c"), "expired")
+	assert.Empty(t, cache.entries)
+	assert.Zero(t, cache.sizeBytes)
+}
+
+func TestCacheKey(t *testing.T) {
+	options := func(params map[string]string) QueryOptions {
+		return QueryOptions{Format: "Arrow", Settings: map[string]string{"x": "1", "y": "2"}, Parameters: params}
+	}
+	key := cacheKey("default", "SELECT 1", options(map[string]string{"a": "1", "b": "2"}))
+	assert.Equal(t, key, cacheKey("default", "SELECT 1", options(map[string]string{"b": "2", "a": "1"})))
+	assert.NotEqual(t, key, cacheKey("other", "SELECT 1", options(map[string]string{"a": "1", "b": "2"})))
+	assert.NotEqual(t, key, cacheKey("default", "SELECT 2", options(map[string]string{"a": "1", "b": "2"})))
+	assert.NotEqual(t, key, cacheKey("default", "SELECT 1", options(map[string]string{"a": "12"})))
+	assert.NotEqual(t, key, cacheKey("default", "SELECT 1", options(map[string]string{"a": "1", "b": "3"})))
+	untrusted := options(map[string]string{"a": "1", "b": "2"})
+	untrusted.Untrusted = true
+	assert.NotEqual(t, key, cacheKey("default", "SELECT 1", untrusted), "different guards")
+}
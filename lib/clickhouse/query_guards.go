@@ -0,0 +1,179 @@
+package clickhouse
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sandstorm/dashica/lib/config"
+)
+
+// ClickHouse error codes of the query guards, see
+// https://github.com/ClickHouse/ClickHouse/blob/master/src/Common/ErrorCodes.cpp
+const (
+	errorCodeTooManyRows        = 158 // max_rows_to_read (only configurable in ClickHouse)
+	errorCodeTimeoutExceeded    = 159
+	errorCodeReadonly           = 164
+	errorCodeTooManyBytes       = 307
+	errorCodeTooManyRowsOrBytes = 396
+)
+
+// QueryGuardError is returned if ClickHouse aborted a query because it exceeded a guard (see config.QueryGuards),
+// or tried to write via a read-only query, or if an untrusted query tried to change settings. Its message is meant
+// to be shown to the user.
+type QueryGuardError struct {
+	ServerId string
+	// Guard is the name of the exceeded setting, e.g. max_execution_time; "settings" for a forbidden SETTINGS clause
+	Guard string
+	// Limit is the value of Guard (empty for readonly, or if the limit is configured in ClickHouse)
+	Limit string
+	// Exception is the original ClickHouse error message
+	Exception string
+}
+
+func (e *QueryGuardError) Error() string {
+	if e.Guard == "readonly" {
+		return fmt.Sprintf("the query tried to modify data, but only read-only queries are allowed on ClickHouse server '%s'", e.ServerId)
+	}
+	if e.Guard == "settings" {
+		return fmt.Sprintf("the query must not contain a SETTINGS clause; ad-hoc queries to ClickHouse server '%s' run with fixed limits", e.ServerId)
+	}
+	limit := e.Guard
+	if e.Limit != "" {
+		limit += "=" + e.Limit
+	}
+	return fmt.Sprintf("the query exceeded the limit %s of ClickHouse server '%s'; narrow the time range or add filters", limit, e.ServerId)
+}
+
+// effectiveGuards returns the guards for a query: the server's, tightened by the Explore guards for untrusted
+// queries.
+func effectiveGuards(serverConfig *config.ClickHouseConfig, untrusted bool) config.QueryGuards {
+	guards := serverConfig.QueryGuards
+	if untrusted {
+		guards.MaxExecutionTime = stricter(guards.MaxExecutionTime, serverConfig.Explore.MaxExecutionTime)
+		guards.MaxResultRows = stricter(guards.MaxResultRows, serverConfig.Explore.MaxResultRows)
+		guards.MaxBytesToRead = stricter(guards.MaxBytesToRead, serverConfig.Explore.MaxBytesToRead)
+	}
+	return guards
+}
+
+// stricter returns the smaller limit; 0 means unlimited.
+func stricter[T int64 | time.Duration](a, b T) T {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// guardSettings converts guards to ClickHouse settings.
+func guardSettings(guards config.QueryGuards) map[string]string {
+	settings := make(map[string]string)
+	if guards.MaxExecutionTime > 0 {
+		settings["max_execution_time"] = strconv.FormatFloat(guards.MaxExecutionTime.Seconds(), 'f', -1, 64)
+	}
+	if guards.MaxResultRows > 0 {
+		settings["max_result_rows"] = strconv.FormatInt(guards.MaxResultRows, 10)
+		settings["result_overflow_mode"] = "throw"
+	}
+	if guards.MaxBytesToRead > 0 {
+		settings["max_bytes_to_read"] = strconv.FormatInt(guards.MaxBytesToRead, 10)
+		settings["read_overflow_mode"] = "throw"
+	}
+	return settings
+}
+
+var exceptionCodeRe = regexp.MustCompile(`^Code: (\d+)\.`)
+
+// guardError returns a QueryGuardError if the failed ClickHouse response was caused by a guard, and nil otherwise.
+func guardError(serverId string, settings map[string]string, header http.Header, body string) *QueryGuardError {
+	code, err := strconv.Atoi(header.Get("X-ClickHouse-Exception-Code"))
+	if err != nil {
+		match := exceptionCodeRe.FindStringSubmatch(strings.TrimSpace(body))
+		if match == nil {
+			return nil
+		}
+		code, _ = strconv.Atoi(match[1])
+	}
+
+	guard := ""
+	switch code {
+	case errorCodeTimeoutExceeded:
+		guard = "max_execution_time"
+	case errorCodeTooManyRows:
+		guard = "max_rows_to_read"
+	case errorCodeTooManyBytes:
+		guard = "max_bytes_to_read"
+	case errorCodeTooManyRowsOrBytes:
+		guard = "max_result_rows"
+	case errorCodeReadonly:
+		guard = "readonly"
+	default:
+		return nil
+	}
+	limit := settings[guard]
+	if guard == "max_execution_time" && limit != "" {
+		limit += "s"
+	}
+	return &QueryGuardError{
+		ServerId:  serverId,
+		Guard:     guard,
+		Limit:     limit,
+		Exception: strings.TrimSpace(body),
+	}
+}
+
+var settingsClauseRe = regexp.MustCompile("(?i)\\bSETTINGS(\\s+|\\s*[`\"])\\w+[`\"]?\\s*=")
+
+// hasSettingsClause reports whether query contains a SETTINGS clause (outside of string literals and comments).
+// ClickHouse applies query-level settings after the URL settings, and readonly=2 allows changing them, so an
+// untrusted query could lift its own guards with e.g. SETTINGS max_execution_time=0.
+func hasSettingsClause(query string) bool {
+	return settingsClauseRe.MatchString(stripLiteralsAndComments(query))
+}
+
+// stripLiteralsAndComments replaces string literals and comments of query by a space; quoted identifiers are kept.
+func stripLiteralsAndComments(query string) string {
+	var result strings.Builder
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			// quotes are escaped by a backslash or doubled
+			start := i
+			for i++; i < len(query); i++ {
+				if query[i] == '\\' {
+					i++
+				} else if query[i] == c {
+					if i+1 < len(query) && query[i+1] == c {
+						i++
+					} else {
+						break
+					}
+				}
+			}
+			if c == '\'' {
+				result.WriteByte(' ')
+			} else {
+				result.WriteString(query[start:min(i+1, len(query))])
+			}
+		case strings.HasPrefix(query[i:], "--") || c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			result.WriteByte(' ')
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += 2 + end + 1
+			}
+			result.WriteByte(' ')
+		default:
+			result.WriteByte(c)
+		}
+	}
+	return result.String()
+}
@@ -0,0 +1,112 @@
+package clickhouse
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_QueryGuards(t *testing.T) {
+	serverConfig := config.ClickHouseConfig{
+		QueryGuards: config.QueryGuards{MaxExecutionTime: time.Minute, MaxBytesToRead: 1000},
+		Explore:     config.QueryGuards{MaxExecutionTime: 10 * time.Second, MaxResultRows: 100, MaxBytesToRead: 5000},
+	}
+
+	tests := []struct {
+		name     string
+		options  QueryOptions
+		expected map[string]string
+	}{
+		{
+			name:     "Trusted",
+			options:  QueryOptions{},
+			expected: map[string]string{"max_execution_time": "60", "max_result_rows": "", "max_bytes_to_read": "1000"},
+		},
+		{
+			name:     "TrustedCallerOverridesGuard",
+			options:  QueryOptions{Settings: map[string]string{"max_execution_time": "300"}},
+			expected: map[string]string{"max_execution_time": "300", "max_result_rows": "", "max_bytes_to_read": "1000"},
+		},
+		{
+			name:     "UntrustedUsesStricterGuards",
+			options:  QueryOptions{Untrusted: true, Settings: map[string]string{"max_execution_time": "300"}},
+			expected: map[string]string{"max_execution_time": "10", "max_result_rows": "100", "max_bytes_to_read": "1000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTransportTestClient(t, serverConfig, func(w http.ResponseWriter, r *http.Request) {
+				for setting, value := range tt.expected {
+					assert.Equal(t, value, r.URL.Query().Get(setting), setting)
+				}
+			})
+			resp, err := client.Query(context.Background(), "SELECT 1", tt.options)
+			require.NoError(t, err)
+			resp.Body.Close()
+		})
+	}
+}
+
+func TestClient_QueryGuardError(t *testing.T) {
+	client := newTransportTestClient(t, config.ClickHouseConfig{Explore: config.QueryGuards{MaxExecutionTime: 10 * time.Second}}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-ClickHouse-Exception-Code", "159")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("Code: 159. DB::Exception: Timeout exceeded: elapsed 10.000731 seconds, maximum: 10. (TIMEOUT_EXCEEDED)"))
+	})
+
+	_, err := client.Query(context.Background(), "SELECT 1", QueryOptions{Untrusted: true})
+	var guardErr *QueryGuardError
+	require.ErrorAs(t, err, &guardErr)
+	assert.Equal(t, "max_execution_time", guardErr.Guard)
+	assert.Equal(t, "the query exceeded the limit max_execution_time=10s of ClickHouse server 'default'; narrow the time range or add filters", err.Error())
+
+	otherError := guardError("default", nil, http.Header{}, "Code: 62. DB::Exception: Syntax error")
+	assert.Nil(t, otherError)
+	readonlyError := guardError("default", nil, http.Header{}, "Code: 164. DB::Exception: Cannot execute query in readonly mode. (READONLY)")
+	require.NotNil(t, readonlyError)
+	assert.Equal(t, "readonly", readonlyError.Guard)
+}
+
+func TestClient_UntrustedSettingsClause(t *testing.T) {
+	queried := false
+	client := newTransportTestClient(t, config.ClickHouseConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		queried = true
+	})
+
+	_, err := client.Query(context.Background(), "SELECT * FROM logs SETTINGS max_execution_time = 0, max_bytes_to_read = 0", QueryOptions{Untrusted: true})
+	var guardErr *QueryGuardError
+	require.ErrorAs(t, err, &guardErr)
+	assert.Equal(t, "settings", guardErr.Guard)
+	assert.False(t, queried)
+
+	// trusted (dashboard) queries may set settings
+	resp, err := client.Query(context.Background(), "SELECT * FROM logs SETTINGS max_threads=1", QueryOptions{})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.True(t, queried)
+}
+
+func TestHasSettingsClause(t *testing.T) {
+	for _, query := range []string{
+		"SELECT 1 SETTINGS max_execution_time=0",
+		"select 1\nsettings\n  max_bytes_to_read = 0",
+		"SELECT 1 SETTINGS/**/max_execution_time=0",
+		"SELECT 1 SETTINGS `max_execution_time`=0",
+		"SELECT 1 AS `it's` SETTINGS max_execution_time=0 -- '",
+	} {
+		assert.True(t, hasSettingsClause(query), query)
+	}
+	for _, query := range []string{
+		"SELECT * FROM system.settings WHERE name = 'max_threads'",
+		"SELECT 'SETTINGS max_execution_time=0'",
+		"SELECT 1 -- SETTINGS max_execution_time=0",
+		"SELECT settings_json = '{}' FROM logs",
+	} {
+		assert.False(t, hasSettingsClause(query), query)
+	}
+}
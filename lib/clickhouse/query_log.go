@@ -0,0 +1,234 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/auth"
+	"github.com/sandstorm/dashica/lib/config"
+)
+
+// QueryLogServer is the ClickHouse server alias the query log is stored in.
+const QueryLogServer = "alert_storage"
+
+// QueryLogTable is the table of the query log, see schema.sql.
+const QueryLogTable = "dashica_query_log"
+
+// QuerySource describes what triggered a query (a dashboard widget or an alert), for the query log.
+type QuerySource struct {
+	// Dashboard is the URL of the dashboard.
+	Dashboard string
+	// Widget is the id of the widget; WidgetType its type (e.g. timeBar).
+	Widget     string
+	WidgetType string
+	// Alert is the id of the evaluated alert.
+	Alert string
+}
+
+type querySourceContextKey struct{}
+
+type skipQueryLogContextKey struct{}
+
+// WithQuerySource returns a copy of ctx whose queries are logged with source.
+func WithQuerySource(ctx context.Context, source QuerySource) context.Context {
+	return context.WithValue(ctx, querySourceContextKey{}, source)
+}
+
+// QueryLogEntry is a row of the query log table.
+type QueryLogEntry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	User       string    `json:"user"`
+	Dashboard  string    `json:"dashboard"`
+	Widget     string    `json:"widget"`
+	WidgetType string    `json:"widget_type"`
+	Alert      string    `json:"alert"`
+	Server     string    `json:"server"`
+	// QueryHash identifies the final SQL (after filters and placeholders were applied).
+	QueryHash  string `json:"query_hash"`
+	DurationMs int64  `json:"duration_ms"`
+	ReadRows   uint64 `json:"read_rows"`
+	ReadBytes  uint64 `json:"read_bytes"`
+	ResultRows uint64 `json:"result_rows"`
+	Error      string `json:"error"`
+}
+
+// QueryLog records every query executed by Dashica in QueryLogTable (if query_log.enabled is set). Entries are
+// buffered and inserted in batches via the QueryLogServer client; if the buffer is full (e.g. because ClickHouse is
+// down), entries are dropped.
+type QueryLog struct {
+	config  config.QueryLogConfig
+	logger  zerolog.Logger
+	manager *Manager
+
+	entries chan QueryLogEntry
+	// stop ends the flush loop, which closes stopped after the last flush
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+func newQueryLog(cfg config.QueryLogConfig, logger zerolog.Logger, manager *Manager) *QueryLog {
+	l := &QueryLog{
+		config:  cfg,
+		logger:  logger,
+		manager: manager,
+		entries: make(chan QueryLogEntry, cfg.BufferSize),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// record adds an entry without blocking the query.
+func (l *QueryLog) record(entry QueryLogEntry) {
+	select {
+	case l.entries <- entry:
+	default:
+		queryLogDroppedTotal.Inc()
+	}
+}
+
+func (l *QueryLog) run() {
+	defer close(l.stopped)
+	ticker := time.NewTicker(l.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]QueryLogEntry, 0, l.config.BatchSize)
+	for {
+		select {
+		case entry := <-l.entries:
+			batch = append(batch, entry)
+			if len(batch) >= l.config.BatchSize {
+				batch = l.flush(batch)
+			}
+		case <-ticker.C:
+			batch = l.flush(batch)
+		case <-l.stop:
+			for {
+				select {
+				case entry := <-l.entries:
+					batch = append(batch, entry)
+				default:
+					l.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush inserts batch, and returns it emptied for reuse.
+func (l *QueryLog) flush(batch []QueryLogEntry) []QueryLogEntry {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := l.insert(batch); err != nil {
+		queryLogDroppedTotal.Add(float64(len(batch)))
+		l.logger.Warn().Err(err).Int("entries", len(batch)).Msg("failed to write query log")
+	}
+	return batch[:0]
+}
+
+func (l *QueryLog) insert(batch []QueryLogEntry) error {
+	client, err := l.manager.GetClient(QueryLogServer)
+	if err != nil {
+		return err
+	}
+
+	var query bytes.Buffer
+	query.WriteString("INSERT INTO " + QueryLogTable + " FORMAT JSONEachRow\n")
+	encoder := json.NewEncoder(&query)
+	for _, entry := range batch {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	opts := DefaultQueryOptions()
+	opts.Settings["date_time_input_format"] = "best_effort"
+	ctx := context.WithValue(context.Background(), skipQueryLogContextKey{}, true)
+	resp, err := client.Execute(ctx, query.String(), opts)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Shutdown writes the buffered entries; it waits until they are written or ctx is done.
+func (l *QueryLog) Shutdown(ctx context.Context) error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	select {
+	case <-l.stopped:
+		return nil
+	case <-ctx.Done():
+		return errors.New("query log: buffered entries not written before shutdown timeout")
+	}
+}
+
+// newEntry starts the log entry of a query; it is completed by finishEntry.
+func (l *QueryLog) newEntry(ctx context.Context, serverId, query string) *QueryLogEntry {
+	if l == nil || ctx.Value(skipQueryLogContextKey{}) != nil {
+		return nil
+	}
+	source, _ := ctx.Value(querySourceContextKey{}).(QuerySource)
+	hash := sha256.Sum256([]byte(query))
+	entry := &QueryLogEntry{
+		Timestamp:  time.Now(),
+		Dashboard:  source.Dashboard,
+		Widget:     source.Widget,
+		WidgetType: source.WidgetType,
+		Alert:      source.Alert,
+		Server:     serverId,
+		QueryHash:  hex.EncodeToString(hash[:8]),
+	}
+	if user := auth.UserFromContext(ctx); user != nil {
+		entry.User = user.Name
+	}
+	return entry
+}
+
+// finishEntry records a failed query immediately; for successful queries, it records the entry once the response
+// body is closed, so that the duration includes streaming the result.
+func (l *QueryLog) finishEntry(entry *QueryLogEntry, resp *http.Response, err error) {
+	if entry == nil {
+		return
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		entry.DurationMs = time.Since(entry.Timestamp).Milliseconds()
+		l.record(*entry)
+		return
+	}
+
+	summary := parseQuerySummary(resp.Header)
+	entry.ReadRows = summary.ReadRows
+	entry.ReadBytes = summary.ReadBytes
+	entry.ResultRows = summary.ResultRows
+	resp.Body = &loggedBody{ReadCloser: resp.Body, finish: func() {
+		entry.DurationMs = time.Since(entry.Timestamp).Milliseconds()
+		l.record(*entry)
+	}}
+}
+
+// loggedBody calls finish once the response body is closed.
+type loggedBody struct {
+	io.ReadCloser
+	finishOnce sync.Once
+	finish     func()
+}
+
+func (b *loggedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finishOnce.Do(b.finish)
+	return err
+}
@@ -0,0 +1,80 @@
+package clickhouse
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/auth"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLog(t *testing.T) {
+	var mu sync.Mutex
+	var inserts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		query := string(body)
+		switch {
+		case strings.HasPrefix(query, "INSERT INTO "+QueryLogTable):
+			mu.Lock()
+			inserts = append(inserts, query)
+			mu.Unlock()
+		case query == "SELECT broken":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("Code: 62. DB::Exception: Syntax error"))
+		default:
+			w.Header().Set("X-ClickHouse-Summary", `{"read_rows":"10","read_bytes":"2048","result_rows":"1"}`)
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	manager := NewManager(&config.Config{
+		ClickHouse: map[string]config.ClickHouseConfig{
+			"default":      {URL: server.URL},
+			QueryLogServer: {URL: server.URL},
+		},
+		QueryLog: config.QueryLogConfig{Enabled: true, FlushInterval: time.Hour, BatchSize: 100, BufferSize: 100},
+	}, zerolog.Nop())
+	client, err := manager.GetClient("default")
+	require.NoError(t, err)
+
+	ctx := auth.WithUser(context.Background(), &auth.User{Name: "alice"})
+	ctx = WithQuerySource(ctx, QuerySource{Dashboard: "/sales", Widget: "widget-2", WidgetType: "timeBar"})
+	resp, err := client.Query(ctx, "SELECT 1", QueryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", readBody(t, resp))
+	_, err = client.Query(ctx, "SELECT broken", QueryOptions{})
+	require.Error(t, err)
+
+	require.NoError(t, manager.Shutdown(context.Background()))
+	require.Len(t, inserts, 1, "the entries are inserted in one batch on shutdown, the insert itself is not logged")
+	rows := strings.Split(strings.TrimSpace(inserts[0]), "\n")[1:]
+	require.Len(t, rows, 2)
+
+	var ok, failed QueryLogEntry
+	require.NoError(t, json.Unmarshal([]byte(rows[0]), &ok))
+	require.NoError(t, json.Unmarshal([]byte(rows[1]), &failed))
+	assert.Equal(t, "alice", ok.User)
+	assert.Equal(t, "/sales", ok.Dashboard)
+	assert.Equal(t, "widget-2", ok.Widget)
+	assert.Equal(t, "timeBar", ok.WidgetType)
+	assert.Equal(t, "default", ok.Server)
+	assert.EqualValues(t, 10, ok.ReadRows)
+	assert.EqualValues(t, 2048, ok.ReadBytes)
+	assert.EqualValues(t, 1, ok.ResultRows)
+	assert.Empty(t, ok.Error)
+	assert.Len(t, ok.QueryHash, 16)
+	assert.NotEqual(t, ok.QueryHash, failed.QueryHash)
+	assert.Contains(t, failed.Error, "Syntax error")
+}
@@ -0,0 +1,152 @@
+package clickhouse
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/sandstorm/dashica/lib/config"
+)
+
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+	CompressionNone = "none"
+)
+
+// newHTTPClient creates the HTTP client for a server, with its own connection pool and - for https:// URLs - the
+// configured CA and client certificate.
+func newHTTPClient(serverConfig *config.ClickHouseConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = serverConfig.MaxIdleConnections
+	transport.MaxIdleConns = serverConfig.MaxIdleConnections
+
+	if serverConfig.TLSCAFile != "" || serverConfig.TLSCertFile != "" {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if serverConfig.TLSCAFile != "" {
+			caPem, err := os.ReadFile(serverConfig.TLSCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("reading tls_ca_file: %w", err)
+			}
+			roots := x509.NewCertPool()
+			if !roots.AppendCertsFromPEM(caPem) {
+				return nil, fmt.Errorf("tls_ca_file %s contains no PEM certificates", serverConfig.TLSCAFile)
+			}
+			tlsConfig.RootCAs = roots
+		}
+		if serverConfig.TLSCertFile != "" {
+			certificate, err := tls.LoadX509KeyPair(serverConfig.TLSCertFile, serverConfig.TLSKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("loading client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{certificate}
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{
+		Timeout:   serverConfig.Timeout,
+		Transport: transport,
+	}, nil
+}
+
+// doWithRetries sends the request created by newRequest, and retries it with exponential backoff (starting at
+// RetryBackoff) up to MaxRetries times if the connection to ClickHouse could not be established. Read-only queries
+// are also retried after 502 / 503 / 504 responses (e.g. from a load balancer in front of ClickHouse); queries
+// which may write never run twice. Timeouts are never retried: a query exceeding Timeout would only put the same
+// load on an already overloaded server again.
+func (c *Client) doWithRetries(ctx context.Context, readOnly bool, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	backoff := c.serverConfig.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		resp, err := c.httpClient.Do(req)
+		retryable := false
+		if err != nil {
+			retryable = isDialError(err) && !isTimeout(err)
+		} else if readOnly {
+			switch resp.StatusCode {
+			case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+				retryable = true
+			}
+		}
+		if !retryable || attempt >= c.serverConfig.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		if resp != nil {
+			c.logger.Warn().Str("clientId", c.Id).Int("status", resp.StatusCode).Int("attempt", attempt+1).Msg("retrying ClickHouse query")
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		} else {
+			c.logger.Warn().Err(err).Str("clientId", c.Id).Int("attempt", attempt+1).Msg("retrying ClickHouse query")
+		}
+		queryRetriesTotal.Inc(c.Id)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// decompressBody transparently decodes a gzip / zstd compressed response (see QueryOptions.Compression), and
+// removes the Content-Encoding header so that it is not copied to the client of Dashica.
+func decompressBody(resp *http.Response) error {
+	var decoded io.ReadCloser
+	switch resp.Header.Get("Content-Encoding") {
+	case "":
+		return nil
+	case CompressionGzip:
+		reader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("decoding gzip response: %w", err)
+		}
+		decoded = reader
+	case CompressionZstd:
+		reader, err := zstd.NewReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("decoding zstd response: %w", err)
+		}
+		decoded = reader.IOReadCloser()
+	default:
+		return fmt.Errorf("unsupported Content-Encoding %s", resp.Header.Get("Content-Encoding"))
+	}
+	resp.Body = &decompressedBody{ReadCloser: decoded, compressed: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	return nil
+}
+
+// decompressedBody closes both the decoder and the underlying (compressed) response body.
+type decompressedBody struct {
+	io.ReadCloser
+	compressed io.ReadCloser
+}
+
+func (b *decompressedBody) Close() error {
+	return errors.Join(b.ReadCloser.Close(), b.compressed.Close())
+}
@@ -0,0 +1,148 @@
+package clickhouse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/sandstorm/dashica/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransportTestClient(t *testing.T, serverConfig config.ClickHouseConfig, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	serverConfig.URL = server.URL
+	client, err := NewClient(&serverConfig, "default", zerolog.Nop())
+	require.NoError(t, err)
+	return client
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestClient_QueryIsPostedInBody(t *testing.T) {
+	longQuery := "SELECT '" + strings.Repeat("x", 100_000) + "'"
+	client := newTransportTestClient(t, config.ClickHouseConfig{ReadOnly: 2}, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Empty(t, r.URL.Query().Get("query"))
+		assert.Equal(t, "2", r.URL.Query().Get("readonly"))
+		assert.Equal(t, "42", r.URL.Query().Get("param_answer"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, longQuery, string(body))
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	resp, err := client.Query(context.Background(), longQuery, QueryOptions{Parameters: map[string]string{"answer": "42"}})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", readBody(t, resp))
+}
+
+func TestClient_Retries(t *testing.T) {
+	serverConfig := config.ClickHouseConfig{MaxRetries: 2, RetryBackoff: time.Millisecond}
+	var requests atomic.Int32
+	client := newTransportTestClient(t, serverConfig, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	resp, err := client.Query(context.Background(), "SELECT 1", QueryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", readBody(t, resp))
+	assert.EqualValues(t, 3, requests.Load())
+
+	requests.Store(0)
+	_, err = client.Execute(context.Background(), "INSERT INTO x VALUES (1)", QueryOptions{})
+	assert.ErrorContains(t, err, "status 503", "queries which may write are not retried after a response")
+	assert.EqualValues(t, 1, requests.Load())
+
+	requests.Store(0)
+	_, err = newTransportTestClient(t, config.ClickHouseConfig{MaxRetries: 1, RetryBackoff: time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}).Query(context.Background(), "SELECT 1", QueryOptions{})
+	assert.ErrorContains(t, err, "status 503")
+	assert.EqualValues(t, 2, requests.Load())
+	requests.Store(0)
+	_, err = newTransportTestClient(t, config.ClickHouseConfig{MaxRetries: 2, RetryBackoff: time.Millisecond, Timeout: 50 * time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(500 * time.Millisecond):
+		}
+	}).Query(context.Background(), "SELECT 1 -- heavy", QueryOptions{})
+	assert.Error(t, err)
+	assert.EqualValues(t, 1, requests.Load(), "read-only queries exceeding the timeout are not retried")
+}
+
+func TestClient_Compression(t *testing.T) {
+	compressors := map[string]func(w io.Writer) io.WriteCloser{
+		CompressionGzip: func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		CompressionZstd: func(w io.Writer) io.WriteCloser {
+			encoder, _ := zstd.NewWriter(w)
+			return encoder
+		},
+	}
+	for compression, newCompressor := range compressors {
+		t.Run(compression, func(t *testing.T) {
+			client := newTransportTestClient(t, config.ClickHouseConfig{Compression: compression}, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "1", r.URL.Query().Get("enable_http_compression"))
+				assert.Equal(t, compression, r.Header.Get("Accept-Encoding"))
+				var compressed bytes.Buffer
+				compressor := newCompressor(&compressed)
+				_, _ = compressor.Write([]byte("compressed result"))
+				_ = compressor.Close()
+				w.Header().Set("Content-Encoding", compression)
+				_, _ = w.Write(compressed.Bytes())
+			})
+
+			resp, err := client.Query(context.Background(), "SELECT 1", QueryOptions{Compression: true})
+			require.NoError(t, err)
+			assert.Empty(t, resp.Header.Get("Content-Encoding"))
+			assert.Equal(t, "compressed result", readBody(t, resp))
+		})
+	}
+}
+
+func TestClient_ReadStatisticsMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.HasPrefix(string(body), "DESCRIBE") {
+			_, _ = w.Write([]byte(`{"data": [{"name": "count", "type": "UInt64"}], "rows": 1}`))
+			return
+		}
+		w.Header().Set("X-ClickHouse-Summary", `{"read_rows":"10","read_bytes":"2048","result_rows":"1"}`)
+		_, _ = w.Write([]byte("arrow result"))
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClient(&config.ClickHouseConfig{URL: server.URL}, "read_statistics", zerolog.Nop())
+	require.NoError(t, err)
+
+	// dashboard queries are streamed as Arrow, and never parsed as JSON
+	recorder := httptest.NewRecorder()
+	require.NoError(t, client.QueryToHandler(context.Background(), "SELECT count() FROM hits", QueryOptions{Format: "Arrow"}, recorder))
+	assert.Equal(t, "arrow result", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	metrics.Default.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, recorder.Body.String(), `dashica_clickhouse_read_rows_total{server="read_statistics"} 10`)
+	assert.Contains(t, recorder.Body.String(), `dashica_clickhouse_read_bytes_total{server="read_statistics"} 2048`)
+}
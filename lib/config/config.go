@@ -4,8 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"time"
 
+	"github.com/go-viper/mapstructure/v2"
+
 	"github.com/knadh/koanf/providers/confmap"
 
 	"strings"
@@ -28,13 +31,22 @@ type Config struct {
 	// - disables File System embed (=hot reloading during development)
 	// - adds Observable Framework Reverse proxy (to prevent CORS) towards the Observable Framework server (=hot reloading of notebooks during dev)
 	// - enables /api/debug-calculate-alerts
-	DevMode  bool           `koanf:"dev_mode"`
-	Auth     AuthConfig     `koanf:"auth"`
-	Alerting AlertingConfig `koanf:"alerting"`
+	DevMode    bool             `koanf:"dev_mode"`
+	Auth       AuthConfig       `koanf:"auth"`
+	Alerting   AlertingConfig   `koanf:"alerting"`
+	QueryCache QueryCacheConfig `koanf:"query_cache"`
+	QueryLog   QueryLogConfig   `koanf:"query_log"`
+
+	// UnknownKeys are the keys of dashica_config.yaml which do not match any option (e.g. misspelled keys); they are
+	// ignored, but reported at startup and by "dashica config check".
+	UnknownKeys []string `koanf:"-"`
 }
 
 type ServerConfig struct {
 	Port int `koanf:"port"`
+	// ShutdownTimeout is how long a graceful shutdown waits for running requests and alert evaluations, before
+	// cancelling them.
+	ShutdownTimeout time.Duration `koanf:"shutdown_timeout"`
 }
 
 type LogConfig struct {
@@ -49,14 +61,121 @@ type ClickHouseConfig struct {
 	URL      string `koanf:"url"`
 	User     string `koanf:"user"`
 	Password string `koanf:"password"`
-	Database string `koanf:"database"`
+	// PasswordFile contains the password instead of Password (see ReadSecret); use ReadPassword.
+	PasswordFile string `koanf:"password_file"`
+	Database     string `koanf:"database"`
+
+	// Timeout of a single request, including reading the result (default 30s).
+	Timeout time.Duration `koanf:"timeout"`
+	// MaxRetries is how often a request is retried if ClickHouse is not reachable (default 2); the first retry
+	// waits RetryBackoff (default 200ms), which doubles for each further retry.
+	MaxRetries   int           `koanf:"max_retries"`
+	RetryBackoff time.Duration `koanf:"retry_backoff"`
+	// Compression of responses for queries which request it (e.g. dashboard queries): gzip (default), zstd or none.
+	Compression string `koanf:"compression"`
+	// MaxIdleConnections is the number of kept-alive connections to this server (default 10).
+	MaxIdleConnections int `koanf:"max_idle_connections"`
+	// TLSCAFile is a PEM file with the CA certificate(s) to trust for https:// URLs (default: the system roots).
+	TLSCAFile string `koanf:"tls_ca_file"`
+	// TLSCertFile and TLSKeyFile are an optional client certificate (PEM).
+	TLSCertFile string `koanf:"tls_cert_file"`
+	TLSKeyFile  string `koanf:"tls_key_file"`
+
+	// ReadOnly is the readonly setting sent with read-only queries: 2 (default) - or 0 to not send it, if the
+	// ClickHouse user profile already is read-only (ClickHouse refuses to change readonly then).
+	ReadOnly int `koanf:"readonly"`
+	// QueryGuards limit every query to this server (0: unlimited).
+	QueryGuards `koanf:",squash"`
+	// Explore are stricter guards for untrusted queries, i.e. those built in the Explore view.
+	Explore QueryGuards `koanf:"explore"`
+}
+
+// QueryGuards are ClickHouse limits per query; a query exceeding them fails with a clickhouse.QueryGuardError.
+type QueryGuards struct {
+	MaxExecutionTime time.Duration `koanf:"max_execution_time"`
+	MaxResultRows    int64         `koanf:"max_result_rows"`
+	MaxBytesToRead   int64         `koanf:"max_bytes_to_read"`
+}
+
+// QueryCacheConfig configures the in-memory cache of dashboard query results (see clickhouse.QueryCache).
+type QueryCacheConfig struct {
+	Enabled bool `koanf:"enabled"`
+	// DefaultTTL is how long results are cached, unless the query sets sql.CacheTTL. Relative time ranges (e.g.
+	// "last 24h") are rounded to this duration, so that identical dashboard loads hit the cache.
+	DefaultTTL time.Duration `koanf:"default_ttl"`
+	// MaxSizeBytes bounds the memory of all cached results; the least recently used results are evicted.
+	MaxSizeBytes int64 `koanf:"max_size_bytes"`
+	// MaxEntrySizeBytes bounds a single result; larger results are not cached.
+	MaxEntrySizeBytes int64 `koanf:"max_entry_size_bytes"`
+}
+
+// QueryLogConfig configures the audit log of all executed queries (see clickhouse.QueryLog), which is stored in the
+// dashica_query_log table of the alert_storage server.
+type QueryLogConfig struct {
+	Enabled bool `koanf:"enabled"`
+	// FlushInterval is the maximum time entries are buffered before they are inserted.
+	FlushInterval time.Duration `koanf:"flush_interval"`
+	// BatchSize is the number of entries which are inserted at once.
+	BatchSize int `koanf:"batch_size"`
+	// BufferSize is the number of buffered entries; further entries are dropped until the buffer is written.
+	BufferSize int `koanf:"buffer_size"`
 }
 
-// TODO: currently not supported
+const (
+	AuthModeBasic = "basic"
+	AuthModeOIDC  = "oidc"
+	AuthModeProxy = "proxy"
+)
+
+// AuthConfig configures authentication (see lib/auth); access rules per dashboard group are configured in Go via
+// RegisterDashboardGroup.
 type AuthConfig struct {
-	Enabled  bool   `koanf:"enabled"`
+	Enabled bool `koanf:"enabled"`
+	// Mode is one of AuthModeBasic (default), AuthModeOIDC or AuthModeProxy.
+	Mode string `koanf:"mode"`
+	// Username and Password (a bcrypt hash) configure a single basic auth user; see also Users.
 	Username string `koanf:"username"`
 	Password string `koanf:"password"`
+	// PasswordFile contains the bcrypt hash instead of Password (see ReadSecret); use ReadPassword.
+	PasswordFile string `koanf:"password_file"`
+	// Users maps basic auth user names to bcrypt password hashes.
+	Users map[string]string `koanf:"users"`
+	// Groups maps group names to user names. Users are members of these groups in addition to the groups from
+	// OIDC / the proxy groups header.
+	Groups map[string][]string `koanf:"groups"`
+	// PublicPaths are paths which are served without authentication, including everything below them (default:
+	// /public/).
+	PublicPaths []string        `koanf:"public_paths"`
+	OIDC        OIDCConfig      `koanf:"oidc"`
+	Proxy       ProxyAuthConfig `koanf:"proxy"`
+}
+
+// OIDCConfig configures login via an OpenID Connect provider (auth.mode: oidc).
+type OIDCConfig struct {
+	IssuerURL    string `koanf:"issuer_url"`
+	ClientID     string `koanf:"client_id"`
+	ClientSecret string `koanf:"client_secret"`
+	// RedirectURL is the public URL of /auth/callback, e.g. https://dashica.example.com/auth/callback
+	RedirectURL string   `koanf:"redirect_url"`
+	Scopes      []string `koanf:"scopes"`
+	// UsernameClaim is the ID token claim used as user name (default: preferred_username, falling back to sub).
+	UsernameClaim string `koanf:"username_claim"`
+	// GroupsClaim is the ID token claim containing the groups of the user (default: groups).
+	GroupsClaim string `koanf:"groups_claim"`
+	// SessionSecret signs the session cookie; at least 32 characters.
+	SessionSecret   string        `koanf:"session_secret"`
+	SessionDuration time.Duration `koanf:"session_duration"`
+}
+
+// ProxyAuthConfig configures authentication by a reverse proxy (auth.mode: proxy), which sends the user name in a
+// request header.
+type ProxyAuthConfig struct {
+	// UserHeader contains the user name (default: X-Forwarded-User).
+	UserHeader string `koanf:"user_header"`
+	// GroupsHeader optionally contains the comma-separated groups of the user (default: X-Forwarded-Groups).
+	GroupsHeader string `koanf:"groups_header"`
+	// TrustedProxies are the CIDRs (e.g. 10.0.0.0/8) of the proxies allowed to set UserHeader.
+	TrustedProxies []string `koanf:"trusted_proxies"`
 }
 
 type AlertingConfig struct {
@@ -64,23 +183,90 @@ type AlertingConfig struct {
 	HelvetikitIdGroup        string `koanf:"helvetikit_id_group"`
 	AlertCronMonitorSchedule string `koanf:"alert_cron_monitor_schedule"`
 	AlertCronMonitorUrl      string `koanf:"alert_cron_monitor_url"`
+	// Notifiers indexed by name; alerts.yaml references them via "notify: [name]". If helvetikit_alerting_url
+	// is set, a notifier named "helvetikit" is implicitly available (and used by alerts without "notify").
+	Notifiers map[string]NotifierConfig `koanf:"notifiers"`
+	// ReloadInterval is the interval in which src/*/alerts.yaml (and the referenced SQL files) are checked for
+	// changes. 0 disables polling (in dev mode, 5s is used then); a reload can always be triggered via SIGHUP.
+	ReloadInterval time.Duration `koanf:"reload_interval"`
+	// MaxConcurrentEvaluations limits how many alert queries run at the same time (default 4).
+	MaxConcurrentEvaluations int `koanf:"max_concurrent_evaluations"`
+	// EvaluationTimeout is the default timeout of a single alert evaluation (default 30s); alerts.yaml can
+	// override it per alert via "timeout".
+	EvaluationTimeout time.Duration `koanf:"evaluation_timeout"`
+	// Params can be referenced in the params (and clickhouse server alias) of alerts.yaml as ${config:NAME}; so
+	// that the same alerts can run with different values per environment.
+	Params map[string]string `koanf:"params"`
 }
 
-// TODO: currently not supported
+const (
+	NotifierTypeHelvetikit   = "helvetikit"
+	NotifierTypeSlack        = "slack"
+	NotifierTypeWebhook      = "webhook"
+	NotifierTypeEmail        = "email"
+	NotifierTypeAlertmanager = "alertmanager"
+)
+
+// NotifierConfig configures a single alert notification target. Which fields are used depends on Type.
+type NotifierConfig struct {
+	// Type is one of NotifierTypeHelvetikit, NotifierTypeSlack, NotifierTypeWebhook, NotifierTypeEmail,
+	// NotifierTypeAlertmanager
+	Type string `koanf:"type"`
+	// URL of the Helvetikit alerting endpoint, Slack incoming webhook, webhook or Alertmanager base URL.
+	URL string `koanf:"url"`
+
+	// helvetikit: the id group (prefixed with "dashica_")
+	IdGroup string `koanf:"id_group"`
+	// slack: channel override (only supported by legacy incoming webhooks)
+	Channel string `koanf:"channel"`
+	// webhook: Go text/template rendering the JSON body; see alerting.Notification for the available fields.
+	BodyTemplate string `koanf:"body_template"`
+	// webhook, alertmanager: additional HTTP headers (e.g. Authorization)
+	Headers map[string]string `koanf:"headers"`
+
+	// email
+	SmtpHost     string `koanf:"smtp_host"`
+	SmtpPort     int    `koanf:"smtp_port"`
+	SmtpUser     string `koanf:"smtp_user"`
+	SmtpPassword string `koanf:"smtp_password"`
+	// SmtpPasswordFile contains the password instead of SmtpPassword (see ReadSecret); use ReadSmtpPassword.
+	SmtpPasswordFile string   `koanf:"smtp_password_file"`
+	From             string   `koanf:"from"`
+	To               []string `koanf:"to"`
+}
+
+// LetsEncryptConfig enables HTTPS (see httpserver.Server): with certificates from an ACME CA (Let's Encrypt by
+// default, via the HTTP-01 challenge), or - if ManualCertfile and ManualKeyfile are set - with certificates from files.
 type LetsEncryptConfig struct {
-	Enabled               bool          `koanf:"enabled"`
-	DevUseStagingCa       bool          `koanf:"dev_use_staging_ca"`
-	DevCustomCa           string        `koanf:"dev_custom_ca"`
-	Email                 string        `koanf:"email"`
-	Domain                string        `koanf:"domain"`
-	DevHttpPort           int           `koanf:"dev_http_port"`
-	DevHttpsPort          int           `koanf:"dev_https_port"`
-	DevCertRenewInterval  time.Duration `koanf:"dev_cert_renew_interval"`
+	Enabled bool `koanf:"enabled"`
+	// DevUseStagingCa uses the Let's Encrypt staging CA (untrusted certificates, but high rate limits).
+	DevUseStagingCa bool `koanf:"dev_use_staging_ca"`
+	// DevCustomCa is the ACME directory URL of a custom CA, e.g. https://localhost:14000/dir for Pebble.
+	DevCustomCa string `koanf:"dev_custom_ca"`
+	// DevCustomCaRoot is a PEM file with the root certificate(s) to trust when connecting to DevCustomCa (e.g.
+	// Pebble's pebble.minica.pem).
+	DevCustomCaRoot string `koanf:"dev_custom_ca_root"`
+	Email           string `koanf:"email"`
+	Domain          string `koanf:"domain"`
+	// DevHttpPort serves the HTTP-01 challenge and redirects everything else to HTTPS.
+	DevHttpPort  int `koanf:"dev_http_port"`
+	DevHttpsPort int `koanf:"dev_https_port"`
+	// DevCertRenewInterval is how often the certificates are checked for renewal.
+	DevCertRenewInterval time.Duration `koanf:"dev_cert_renew_interval"`
+	// StoragePath is where ACME accounts and certificates are stored (default: certmagic's default, i.e.
+	// $HOME/.local/share/certmagic); use a persistent volume in containers.
+	StoragePath string `koanf:"storage_path"`
+	// ManualRefreshInterval is how often ManualCertfile and ManualKeyfile are re-read.
 	ManualRefreshInterval time.Duration `koanf:"manual_refresh_interval"`
 	ManualCertfile        string        `koanf:"manual_certfile"`
 	ManualKeyfile         string        `koanf:"manual_keyfile"`
 }
 
+// IsManual returns true if certificates are read from ManualCertfile and ManualKeyfile instead of using ACME.
+func (c LetsEncryptConfig) IsManual() bool {
+	return c.ManualCertfile != "" || c.ManualKeyfile != ""
+}
+
 // LoadConfig loads configuration from various sources and returns the parsed config
 func LoadConfig(appEnv string, forTesting bool) (*Config, error) {
 	k := koanf.New(".")
@@ -94,6 +280,9 @@ func LoadConfig(appEnv string, forTesting bool) (*Config, error) {
 	if err := loadDashicaConfig(k, appEnv); err != nil {
 		return nil, fmt.Errorf("loading dotenv variables: %w", err)
 	}
+	if err := interpolateEnvReferences(k); err != nil {
+		return nil, fmt.Errorf("interpolating environment variables: %w", err)
+	}
 
 	if !forTesting {
 		// Load environment variables
@@ -102,17 +291,31 @@ func LoadConfig(appEnv string, forTesting bool) (*Config, error) {
 		}
 	}
 
+	// the ClickHouse servers are only known now
+	if err := loadClickHouseDefaults(k); err != nil {
+		return nil, fmt.Errorf("loading default ClickHouse configuration: %w", err)
+	}
+
 	// Unmarshal configuration into struct
 	config := &Config{}
-	if err := k.Unmarshal("", config); err != nil {
+	err := k.Unmarshal("", config)
+	if err != nil {
 		return nil, fmt.Errorf("unmarshaling configuration: %w", err)
 	}
 
 	// for debugging:
 	//k.Print()
 
+	config.UnknownKeys, err = unknownKeys(appEnv)
+	if err != nil {
+		return nil, fmt.Errorf("checking for unknown configuration keys: %w", err)
+	}
+
 	// Validate config
 	if err := validateConfig(config); err != nil {
+		if len(config.UnknownKeys) > 0 {
+			return nil, fmt.Errorf("%w (unknown keys, misspelled?: %s)", err, strings.Join(config.UnknownKeys, ", "))
+		}
 		return nil, err
 	}
 
@@ -123,6 +326,7 @@ func loadDefaultConfig(k *koanf.Koanf) error {
 	// Setup default values
 	defaultConfig := map[string]interface{}{
 		"server.port":                         8081,
+		"server.shutdown_timeout":             30 * time.Second,
 		"log.to_stdout":                       true,
 		"letsencrypt.enabled":                 false,
 		"letsencrypt.dev_http_port":           80,
@@ -130,11 +334,56 @@ func loadDefaultConfig(k *koanf.Koanf) error {
 		"letsencrypt.dev_cert_renew_interval": time.Hour * 24,
 		"letsencrypt.manual_refresh_interval": time.Hour * 24,
 		"auth.password":                       "",
+		"auth.mode":                           AuthModeBasic,
+		"auth.public_paths":                   []string{"/public/"},
+		"auth.oidc.scopes":                    []string{"openid", "profile", "email"},
+		"auth.oidc.username_claim":            "preferred_username",
+		"auth.oidc.groups_claim":              "groups",
+		"auth.oidc.session_duration":          12 * time.Hour,
+		"auth.proxy.user_header":              "X-Forwarded-User",
+		"auth.proxy.groups_header":            "X-Forwarded-Groups",
+		"alerting.max_concurrent_evaluations": 4,
+		"alerting.evaluation_timeout":         30 * time.Second,
+		"query_cache.enabled":                 false,
+		"query_cache.default_ttl":             time.Minute,
+		"query_cache.max_size_bytes":          256 << 20,
+		"query_cache.max_entry_size_bytes":    16 << 20,
+		"query_log.enabled":                   false,
+		"query_log.flush_interval":            5 * time.Second,
+		"query_log.batch_size":                1000,
+		"query_log.buffer_size":               10000,
 	}
 
 	return k.Load(confmap.Provider(defaultConfig, "."), nil)
 }
 
+// loadClickHouseDefaults sets the defaults of every configured ClickHouse server (unless they are configured).
+func loadClickHouseDefaults(k *koanf.Koanf) error {
+	serverDefaults := map[string]interface{}{
+		"timeout":                    30 * time.Second,
+		"max_retries":                2,
+		"retry_backoff":              200 * time.Millisecond,
+		"compression":                "gzip",
+		"max_idle_connections":       10,
+		"readonly":                   2,
+		"explore.max_execution_time": 30 * time.Second,
+		"explore.max_result_rows":    100_000,
+		"explore.max_bytes_to_read":  10 << 30,
+	}
+	for _, server := range k.MapKeys("clickhouse") {
+		for key, value := range serverDefaults {
+			path := "clickhouse." + server + "." + key
+			if k.Exists(path) {
+				continue
+			}
+			if err := k.Set(path, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func loadDashicaConfig(k *koanf.Koanf, appEnv string) error {
 	err := k.Load(file.Provider(fmt.Sprintf("dashica_config.%s.yaml", appEnv)), yaml.Parser())
 	if err != nil && errors.Is(err, os.ErrNotExist) {
@@ -147,6 +396,35 @@ func loadDashicaConfig(k *koanf.Koanf, appEnv string) error {
 	}
 	return err
 }
+
+// unknownKeys returns the keys of dashica_config.yaml which are not decoded into Config, sorted. Environment
+// variables are not checked, as all of them are loaded (see loadEnvVariables).
+func unknownKeys(appEnv string) ([]string, error) {
+	k := koanf.New(".")
+	if err := loadDashicaConfig(k, appEnv); err != nil {
+		return nil, err
+	}
+	// like in LoadConfig; otherwise e.g. "timeout: ${CH_TIMEOUT}" could not be decoded into a duration.
+	if err := interpolateEnvReferences(k); err != nil {
+		return nil, err
+	}
+
+	var metadata mapstructure.Metadata
+	err := k.UnmarshalWithConf("", nil, koanf.UnmarshalConf{DecoderConfig: &mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.TextUnmarshallerHookFunc()),
+		Metadata:         &metadata,
+		Result:           &Config{},
+		WeaklyTypedInput: true,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(metadata.Unused)
+	return metadata.Unused, nil
+}
+
 func envTransformFunc(s string) string {
 	s = strings.ToLower(s)
 	s = strings.ReplaceAll(s, "__", ".")
@@ -175,18 +453,146 @@ func validateConfig(config *Config) error {
 		if ch.Database == "" {
 			return fmt.Errorf("ClickHouse '%s': database is required", key)
 		}
+		if err := validateSecret("password", ch.Password, ch.PasswordFile); err != nil {
+			return fmt.Errorf("ClickHouse '%s': %w", key, err)
+		}
+
+		if ch.Timeout <= 0 || ch.RetryBackoff <= 0 || ch.MaxRetries < 0 {
+			return fmt.Errorf("ClickHouse '%s': timeout and retry_backoff must be positive, max_retries must not be negative", key)
+		}
+		switch ch.Compression {
+		case "gzip", "zstd", "none":
+		default:
+			return fmt.Errorf("ClickHouse '%s': unknown compression '%s' (gzip, zstd or none)", key, ch.Compression)
+		}
+		if (ch.TLSCertFile == "") != (ch.TLSKeyFile == "") {
+			return fmt.Errorf("ClickHouse '%s': tls_cert_file and tls_key_file must be configured together", key)
+		}
+		if ch.ReadOnly != 0 && ch.ReadOnly != 2 {
+			// readonly=1 would forbid the settings Dashica sends with every query
+			return fmt.Errorf("ClickHouse '%s': readonly must be 2 or 0", key)
+		}
+		if ch.MaxExecutionTime < 0 || ch.MaxResultRows < 0 || ch.MaxBytesToRead < 0 ||
+			ch.Explore.MaxExecutionTime < 0 || ch.Explore.MaxResultRows < 0 || ch.Explore.MaxBytesToRead < 0 {
+			return fmt.Errorf("ClickHouse '%s': max_execution_time, max_result_rows and max_bytes_to_read must not be negative", key)
+		}
+	}
+
+	if config.Server.ShutdownTimeout <= 0 {
+		return fmt.Errorf("server.shutdown_timeout must be positive")
+	}
+
+	if config.Alerting.MaxConcurrentEvaluations < 1 {
+		return fmt.Errorf("alerting.max_concurrent_evaluations must be at least 1")
+	}
+	if config.Alerting.EvaluationTimeout <= 0 {
+		return fmt.Errorf("alerting.evaluation_timeout must be positive")
+	}
+
+	for name, notifier := range config.Alerting.Notifiers {
+		if err := validateNotifierConfig(notifier); err != nil {
+			return fmt.Errorf("alerting.notifiers '%s': %w", name, err)
+		}
+	}
+
+	if config.QueryCache.Enabled {
+		if config.QueryCache.DefaultTTL <= 0 {
+			return fmt.Errorf("query_cache.default_ttl must be positive")
+		}
+		if config.QueryCache.MaxSizeBytes <= 0 || config.QueryCache.MaxEntrySizeBytes <= 0 {
+			return fmt.Errorf("query_cache.max_size_bytes and query_cache.max_entry_size_bytes must be positive")
+		}
+	}
+
+	if config.QueryLog.Enabled {
+		if _, found := config.ClickHouse["alert_storage"]; !found {
+			return fmt.Errorf("query_log: the query log is stored in ClickHouse 'alert_storage', which is not configured")
+		}
+		if config.QueryLog.FlushInterval <= 0 || config.QueryLog.BatchSize <= 0 || config.QueryLog.BufferSize <= 0 {
+			return fmt.Errorf("query_log.flush_interval, query_log.batch_size and query_log.buffer_size must be positive")
+		}
+	}
+
+	if config.LetsEncrypt.Enabled {
+		if err := validateLetsEncryptConfig(config.LetsEncrypt); err != nil {
+			return fmt.Errorf("letsencrypt: %w", err)
+		}
 	}
 
 	if config.Auth.Enabled {
-		if config.Auth.Username == "" {
-			return fmt.Errorf("username must be configured if auth is enabled")
+		if err := validateAuthConfig(config.Auth); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func validateLetsEncryptConfig(letsEncrypt LetsEncryptConfig) error {
+	if letsEncrypt.DevHttpsPort <= 0 || letsEncrypt.DevHttpPort <= 0 {
+		return fmt.Errorf("dev_http_port and dev_https_port must be positive")
+	}
+	if letsEncrypt.IsManual() {
+		if letsEncrypt.ManualCertfile == "" || letsEncrypt.ManualKeyfile == "" {
+			return fmt.Errorf("both manual_certfile and manual_keyfile must be configured")
+		}
+		if letsEncrypt.ManualRefreshInterval <= 0 {
+			return fmt.Errorf("manual_refresh_interval must be positive")
+		}
+		return nil
+	}
+	if letsEncrypt.Domain == "" {
+		return fmt.Errorf("domain is required (or configure manual_certfile and manual_keyfile)")
+	}
+	if letsEncrypt.DevUseStagingCa && letsEncrypt.DevCustomCa != "" {
+		return fmt.Errorf("dev_use_staging_ca and dev_custom_ca are mutually exclusive")
+	}
+	return nil
+}
+
+func validateAuthConfig(auth AuthConfig) error {
+	switch auth.Mode {
+	case AuthModeBasic:
+		if err := validateSecret("password", auth.Password, auth.PasswordFile); err != nil {
+			return err
+		}
+		if (auth.Username == "" || (auth.Password == "" && auth.PasswordFile == "")) && len(auth.Users) == 0 {
+			return fmt.Errorf("username and password (or users) must be configured for mode %s", auth.Mode)
+		}
+		// the passwords are checked to be bcrypt hashes in auth.New
+	case AuthModeOIDC:
+		if auth.OIDC.IssuerURL == "" || auth.OIDC.ClientID == "" || auth.OIDC.RedirectURL == "" {
+			return fmt.Errorf("oidc.issuer_url, oidc.client_id and oidc.redirect_url are required for mode %s", auth.Mode)
+		}
+		if len(auth.OIDC.SessionSecret) < 32 {
+			return fmt.Errorf("oidc.session_secret must have at least 32 characters")
 		}
-		if config.Auth.Password == "" {
-			// TODO: and password is bcrypt string
-			return fmt.Errorf("password must be configured if auth is enabled")
+	case AuthModeProxy:
+		if len(auth.Proxy.TrustedProxies) == 0 {
+			return fmt.Errorf("proxy.trusted_proxies must be configured for mode %s; otherwise, anybody could send %s", auth.Mode, auth.Proxy.UserHeader)
 		}
+	default:
+		return fmt.Errorf("unknown mode '%s'", auth.Mode)
 	}
+	return nil
+}
 
+func validateNotifierConfig(notifier NotifierConfig) error {
+	switch notifier.Type {
+	case NotifierTypeHelvetikit, NotifierTypeSlack, NotifierTypeWebhook, NotifierTypeAlertmanager:
+		if notifier.URL == "" {
+			return fmt.Errorf("url is required for type %s", notifier.Type)
+		}
+	case NotifierTypeEmail:
+		if notifier.SmtpHost == "" || notifier.From == "" || len(notifier.To) == 0 {
+			return fmt.Errorf("smtp_host, from and to are required for type %s", notifier.Type)
+		}
+		if err := validateSecret("smtp_password", notifier.SmtpPassword, notifier.SmtpPasswordFile); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown type '%s'", notifier.Type)
+	}
 	return nil
 }
 
@@ -199,9 +605,33 @@ func PrintConfig(config *Config) {
 		fmt.Printf("    url: %s\n", ch.URL)
 		fmt.Printf("    user: %s\n", ch.User)
 		fmt.Printf("    password: %s\n", maskSecret(ch.Password))
+		if ch.PasswordFile != "" {
+			fmt.Printf("    password_file: %s\n", ch.PasswordFile)
+		}
 		fmt.Printf("    database: %s\n", ch.Database)
+		fmt.Printf("    timeout: %s\n", ch.Timeout)
+		fmt.Printf("    max_retries: %d\n", ch.MaxRetries)
+		fmt.Printf("    retry_backoff: %s\n", ch.RetryBackoff)
+		fmt.Printf("    compression: %s\n", ch.Compression)
+		fmt.Printf("    max_idle_connections: %d\n", ch.MaxIdleConnections)
+		fmt.Printf("    readonly: %d\n", ch.ReadOnly)
+		fmt.Printf("    max_execution_time: %s\n", ch.MaxExecutionTime)
+		fmt.Printf("    max_result_rows: %d\n", ch.MaxResultRows)
+		fmt.Printf("    max_bytes_to_read: %d\n", ch.MaxBytesToRead)
+		fmt.Printf("    explore.max_execution_time: %s\n", ch.Explore.MaxExecutionTime)
+		fmt.Printf("    explore.max_result_rows: %d\n", ch.Explore.MaxResultRows)
+		fmt.Printf("    explore.max_bytes_to_read: %d\n", ch.Explore.MaxBytesToRead)
+		if ch.TLSCAFile != "" || ch.TLSCertFile != "" {
+			fmt.Printf("    tls_ca_file: %s\n", ch.TLSCAFile)
+			fmt.Printf("    tls_cert_file: %s\n", ch.TLSCertFile)
+			fmt.Printf("    tls_key_file: %s\n", ch.TLSKeyFile)
+		}
 	}
 
+	fmt.Println("server:")
+	fmt.Printf("  port: %d\n", config.Server.Port)
+	fmt.Printf("  shutdown_timeout: %s\n", config.Server.ShutdownTimeout)
+
 	fmt.Println("log:")
 	fmt.Printf("  to_stdout: %v\n", config.Log.ToStdout)
 	fmt.Printf("  filename: %s\n", config.Log.FileName)
@@ -211,9 +641,20 @@ func PrintConfig(config *Config) {
 	fmt.Println("Let's Encrypt Configuration:")
 	fmt.Printf("  Enabled: %v\n", config.LetsEncrypt.Enabled)
 	if config.LetsEncrypt.Enabled {
-		fmt.Printf("  Email: %s\n", config.LetsEncrypt.Email)
-		fmt.Printf("  Domain: %s\n", config.LetsEncrypt.Domain)
-		// Print other Let's Encrypt settings if needed
+		fmt.Printf("  HTTP Port: %d\n", config.LetsEncrypt.DevHttpPort)
+		fmt.Printf("  HTTPS Port: %d\n", config.LetsEncrypt.DevHttpsPort)
+		if config.LetsEncrypt.IsManual() {
+			fmt.Printf("  Manual Certfile: %s\n", config.LetsEncrypt.ManualCertfile)
+			fmt.Printf("  Manual Keyfile: %s\n", config.LetsEncrypt.ManualKeyfile)
+			fmt.Printf("  Manual Refresh Interval: %s\n", config.LetsEncrypt.ManualRefreshInterval)
+		} else {
+			fmt.Printf("  Email: %s\n", config.LetsEncrypt.Email)
+			fmt.Printf("  Domain: %s\n", config.LetsEncrypt.Domain)
+			fmt.Printf("  Staging CA: %v\n", config.LetsEncrypt.DevUseStagingCa)
+			fmt.Printf("  Custom CA: %s\n", config.LetsEncrypt.DevCustomCa)
+			fmt.Printf("  Renew Check Interval: %s\n", config.LetsEncrypt.DevCertRenewInterval)
+			fmt.Printf("  Storage Path: %s\n", config.LetsEncrypt.StoragePath)
+		}
 	}
 
 	fmt.Printf("dev_mode: %v\n", config.DevMode)
@@ -221,13 +662,72 @@ func PrintConfig(config *Config) {
 	fmt.Println("Authentication Configuration:")
 	fmt.Printf("  Enabled: %v\n", config.Auth.Enabled)
 	if config.Auth.Enabled {
+		fmt.Printf("  Mode: %s\n", config.Auth.Mode)
 		fmt.Printf("  Username: %s\n", config.Auth.Username)
 		fmt.Printf("  Password: %s\n", maskSecret(config.Auth.Password))
+		if config.Auth.PasswordFile != "" {
+			fmt.Printf("  Password file: %s\n", config.Auth.PasswordFile)
+		}
+		for username := range config.Auth.Users {
+			fmt.Printf("  User: %s\n", username)
+		}
+		for group, users := range config.Auth.Groups {
+			fmt.Printf("  Group %s: %s\n", group, strings.Join(users, ", "))
+		}
+		fmt.Printf("  Public paths: %s\n", strings.Join(config.Auth.PublicPaths, ", "))
+		if config.Auth.Mode == AuthModeOIDC {
+			fmt.Printf("  OIDC issuer_url: %s\n", config.Auth.OIDC.IssuerURL)
+			fmt.Printf("  OIDC client_id: %s\n", config.Auth.OIDC.ClientID)
+			fmt.Printf("  OIDC client_secret: %s\n", maskSecret(config.Auth.OIDC.ClientSecret))
+			fmt.Printf("  OIDC redirect_url: %s\n", config.Auth.OIDC.RedirectURL)
+			fmt.Printf("  OIDC session_secret: %s\n", maskSecret(config.Auth.OIDC.SessionSecret))
+		}
+		if config.Auth.Mode == AuthModeProxy {
+			fmt.Printf("  Proxy user_header: %s\n", config.Auth.Proxy.UserHeader)
+			fmt.Printf("  Proxy trusted_proxies: %s\n", strings.Join(config.Auth.Proxy.TrustedProxies, ", "))
+		}
 	}
 
 	fmt.Println("Alerting Configuration:")
 	fmt.Printf("  helvetikit_alerting_url: %s\n", config.Alerting.HelvetikitAlertingUrl)
 	fmt.Printf("  helvetikit_id_group: %s\n", config.Alerting.HelvetikitIdGroup)
+	fmt.Printf("  reload_interval: %s\n", config.Alerting.ReloadInterval)
+	fmt.Printf("  max_concurrent_evaluations: %d\n", config.Alerting.MaxConcurrentEvaluations)
+	fmt.Printf("  evaluation_timeout: %s\n", config.Alerting.EvaluationTimeout)
+	fmt.Println("  params:")
+	for name, value := range config.Alerting.Params {
+		fmt.Printf("    %s: %s\n", name, value)
+	}
+	fmt.Println("  notifiers:")
+	for name, notifier := range config.Alerting.Notifiers {
+		fmt.Printf("    \"%s\":\n", name)
+		fmt.Printf("      type: %s\n", notifier.Type)
+		// webhook URLs (e.g. Slack) contain secrets
+		fmt.Printf("      url: %s\n", maskSecret(notifier.URL))
+		if notifier.Type == NotifierTypeEmail {
+			fmt.Printf("      smtp_host: %s\n", notifier.SmtpHost)
+			fmt.Printf("      smtp_user: %s\n", notifier.SmtpUser)
+			fmt.Printf("      smtp_password: %s\n", maskSecret(notifier.SmtpPassword))
+			if notifier.SmtpPasswordFile != "" {
+				fmt.Printf("      smtp_password_file: %s\n", notifier.SmtpPasswordFile)
+			}
+			fmt.Printf("      to: %v\n", notifier.To)
+		}
+	}
+	fmt.Println("Query Cache Configuration:")
+	fmt.Printf("  Enabled: %v\n", config.QueryCache.Enabled)
+	if config.QueryCache.Enabled {
+		fmt.Printf("  default_ttl: %s\n", config.QueryCache.DefaultTTL)
+		fmt.Printf("  max_size_bytes: %d\n", config.QueryCache.MaxSizeBytes)
+		fmt.Printf("  max_entry_size_bytes: %d\n", config.QueryCache.MaxEntrySizeBytes)
+	}
+	fmt.Println("Query Log Configuration:")
+	fmt.Printf("  Enabled: %v\n", config.QueryLog.Enabled)
+	if config.QueryLog.Enabled {
+		fmt.Printf("  flush_interval: %s\n", config.QueryLog.FlushInterval)
+		fmt.Printf("  batch_size: %d\n", config.QueryLog.BatchSize)
+		fmt.Printf("  buffer_size: %d\n", config.QueryLog.BufferSize)
+	}
 	fmt.Println("=========================================")
 }
 
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestConfig(t *testing.T, content string) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dashica_config.yaml"), []byte(content), 0o600))
+	t.Chdir(dir)
+}
+
+func TestLoadConfig_UnknownKeys(t *testing.T) {
+	writeTestConfig(t, `
+clickhouse:
+  default:
+    url: http://localhost:8123
+    database: default
+    pasword: secret
+    timeout: 5s
+    explore:
+      max_execution_tim: 10s
+alerting:
+  alert_cron_monitor_shedule: "* * * * *"
+query_cache:
+  enabled: true
+`)
+
+	cfg, err := LoadConfig("testing", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"alerting.alert_cron_monitor_shedule",
+		"clickhouse[default].explore.max_execution_tim",
+		"clickhouse[default].pasword",
+	}, cfg.UnknownKeys)
+}
+
+func TestLoadConfig_UnknownKeysAreReportedWithValidationErrors(t *testing.T) {
+	writeTestConfig(t, `
+clickhouse:
+  default:
+    urll: http://localhost:8123
+    database: default
+`)
+
+	_, err := LoadConfig("testing", true)
+	assert.ErrorContains(t, err, "URL is required (unknown keys, misspelled?: clickhouse[default].urll)")
+}
+
+func TestLoadConfig_EnvReferences(t *testing.T) {
+	writeTestConfig(t, `
+clickhouse:
+  default:
+    url: http://${CH_HOST}:8123
+    database: default
+    password: ${CH_PASSWORD}
+`)
+	t.Setenv("CH_HOST", "clickhouse.local")
+	t.Setenv("CH_PASSWORD", "secret")
+
+	cfg, err := LoadConfig("testing", true)
+	require.NoError(t, err)
+	assert.Equal(t, "http://clickhouse.local:8123", cfg.ClickHouse["default"].URL)
+	assert.Equal(t, "secret", cfg.ClickHouse["default"].Password)
+}
+
+func TestLoadConfig_EnvReferencesInTypedFields(t *testing.T) {
+	writeTestConfig(t, `
+clickhouse:
+  default:
+    url: http://localhost:8123
+    database: default
+    timeout: ${CH_TIMEOUT}
+    max_retries: ${CH_MAX_RETRIES}
+`)
+	t.Setenv("CH_TIMEOUT", "45s")
+	t.Setenv("CH_MAX_RETRIES", "5")
+
+	cfg, err := LoadConfig("testing", true)
+	require.NoError(t, err)
+	assert.Equal(t, 45*time.Second, cfg.ClickHouse["default"].Timeout)
+	assert.Equal(t, 5, cfg.ClickHouse["default"].MaxRetries)
+	assert.Empty(t, cfg.UnknownKeys)
+}
+
+func TestLoadConfig_EnvReferenceToUnsetVariable(t *testing.T) {
+	writeTestConfig(t, `
+clickhouse:
+  default:
+    url: http://localhost:8123
+    database: default
+    password: ${DASHICA_TEST_UNSET}
+`)
+
+	_, err := LoadConfig("testing", true)
+	assert.ErrorContains(t, err, "clickhouse.default.password: environment variable DASHICA_TEST_UNSET is not set")
+}
+
+func TestLoadConfig_PasswordFile(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("secret\n"), 0o600))
+	writeTestConfig(t, `
+clickhouse:
+  default:
+    url: http://localhost:8123
+    database: default
+    password_file: `+passwordFile+`
+`)
+
+	cfg, err := LoadConfig("testing", true)
+	require.NoError(t, err)
+	serverConfig := cfg.ClickHouse["default"]
+	password, err := serverConfig.ReadPassword()
+	require.NoError(t, err)
+	assert.Equal(t, "secret", password, "trailing line breaks are trimmed")
+
+	// rotated secrets are picked up
+	require.NoError(t, os.WriteFile(passwordFile, []byte("rotated"), 0o600))
+	password, err = serverConfig.ReadPassword()
+	require.NoError(t, err)
+	assert.Equal(t, "rotated", password)
+}
+
+func TestLoadConfig_PasswordAndPasswordFileAreMutuallyExclusive(t *testing.T) {
+	writeTestConfig(t, `
+clickhouse:
+  default:
+    url: http://localhost:8123
+    database: default
+    password: secret
+    password_file: /run/secrets/clickhouse
+`)
+
+	_, err := LoadConfig("testing", true)
+	assert.ErrorContains(t, err, "password and password_file are mutually exclusive")
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/knadh/koanf/v2"
+)
+
+// envReferenceSections are the sections of dashica_config.yaml in which ${NAME} references are interpolated.
+var envReferenceSections = []string{"clickhouse.", "auth.", "alerting."}
+
+// envReference matches ${NAME}; ${env:NAME} and ${config:NAME} in alerts.yaml params are resolved by the alerting
+// package instead.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// interpolateEnvReferences replaces ${NAME} in the string values of envReferenceSections by the environment variable
+// NAME; referencing an unset variable is an error.
+func interpolateEnvReferences(k *koanf.Koanf) error {
+	for key, value := range k.All() {
+		if !hasAnyPrefix(key, envReferenceSections) {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			interpolated, err := interpolateEnv(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			if err := k.Set(key, interpolated); err != nil {
+				return err
+			}
+		case []interface{}:
+			interpolated := make([]interface{}, len(v))
+			for i, item := range v {
+				interpolated[i] = item
+				if s, ok := item.(string); ok {
+					var err error
+					if interpolated[i], err = interpolateEnv(s); err != nil {
+						return fmt.Errorf("%s: %w", key, err)
+					}
+				}
+			}
+			if err := k.Set(key, interpolated); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func interpolateEnv(value string) (string, error) {
+	var err error
+	interpolated := envReference.ReplaceAllStringFunc(value, func(reference string) string {
+		name := envReference.FindStringSubmatch(reference)[1]
+		envValue, found := os.LookupEnv(name)
+		if !found && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+		return envValue
+	})
+	return interpolated, err
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretFile is a cached secret file, see ReadSecret.
+type secretFile struct {
+	modTime time.Time
+	size    int64
+	value   string
+}
+
+// secretFiles caches the secret files by path.
+var secretFiles sync.Map
+
+// ReadSecret returns the secret configured inline as value or - if file is set - the content of file (without
+// trailing line breaks), e.g. a mounted Docker / Kubernetes secret. The file is read again once its modification time
+// or size changes, so rotated secrets are picked up without a restart.
+func ReadSecret(value, file string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", fmt.Errorf("reading secret: %w", err)
+	}
+	if cached, found := secretFiles.Load(file); found {
+		if cached := cached.(*secretFile); cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+			return cached.value, nil
+		}
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("reading secret: %w", err)
+	}
+	secret := strings.TrimRight(string(content), "\r\n")
+	secretFiles.Store(file, &secretFile{modTime: info.ModTime(), size: info.Size(), value: secret})
+	return secret, nil
+}
+
+// ReadPassword returns Password, or the content of PasswordFile.
+func (c *ClickHouseConfig) ReadPassword() (string, error) {
+	return ReadSecret(c.Password, c.PasswordFile)
+}
+
+// ReadPassword returns the bcrypt hash Password, or the content of PasswordFile.
+func (c AuthConfig) ReadPassword() (string, error) {
+	return ReadSecret(c.Password, c.PasswordFile)
+}
+
+// ReadSmtpPassword returns SmtpPassword, or the content of SmtpPasswordFile.
+func (c NotifierConfig) ReadSmtpPassword() (string, error) {
+	return ReadSecret(c.SmtpPassword, c.SmtpPasswordFile)
+}
+
+// validateSecret checks that at most one of the inline secret and its file is configured, and that the file is
+// readable.
+func validateSecret(name, value, file string) error {
+	if file == "" {
+		return nil
+	}
+	if value != "" {
+		return fmt.Errorf("%s and %s_file are mutually exclusive", name, name)
+	}
+	if _, err := ReadSecret(value, file); err != nil {
+		return fmt.Errorf("%s_file: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package dashboard
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/sandstorm/dashica/lib/clickhouse"
+	"github.com/sandstorm/dashica/lib/dashboard/rendering"
+	"github.com/sandstorm/dashica/lib/dashboard/sql"
+	"github.com/sandstorm/dashica/lib/httpserver"
+	"github.com/sandstorm/dashica/lib/util/handler_collector"
+)
+
+// annotationsPath is the endpoint (below /api) returning the annotations of all sources of a dashboard.
+const annotationsPath = "annotations"
+
+// maxAnnotationsPerSource limits the number of annotations loaded per source, so a noisy source cannot flood the
+// charts; the most recent ones are kept.
+const maxAnnotationsPerSource = 1000
+
+// Annotation is an event drawn on every time chart (TimeBar, TimeLine, TimeHeatmap) of a dashboard: a vertical
+// marker at Time, or a band from Time to End.
+type Annotation struct {
+	// Time is the start of the event, in UNIX milliseconds.
+	Time int64 `json:"time"`
+	// End is the end of the event in UNIX milliseconds, nil for a point in time (e.g. a deployment).
+	End  *int64   `json:"end"`
+	Text string   `json:"text"`
+	Tags []string `json:"tags"`
+}
+
+// Annotations adds an annotation source, e.g. deploy events from a table. query must return the columns
+//
+//   - time: the start of the event (DateTime / DateTime64)
+//   - end: the end of the event, NULL for markers (Nullable(DateTime) / Nullable(DateTime64))
+//   - text: shown on hover
+//   - tags: Array(String); "error" and "warn" color the annotation red / orange
+//
+// Like widget queries, query is filtered by the dashboard time range (the user's SQL filter is not applied, as it
+// refers to the tables of the widgets). Annotations overlapping the time range are loaded, so use sql.SkipFilters()
+// for bands which may start before it. The dashboard variables and {__from:DateTime} / {__to:DateTime} are passed as
+// query parameters.
+//
+// Example:
+//
+//	dashboard.New().Annotations(sql.FromString(`
+//	    SELECT timestamp AS time, NULL AS end, concat('Deploy ', version) AS text, ['deploy'] AS tags
+//	    FROM deployments
+//	    WHERE {{DASHICA_FILTERS}}
+//	`))
+func (d *Builder) Annotations(query sql.SqlQueryable) *Builder {
+	cloned := *d
+	cloned.annotations = append(slices.Clone(d.annotations), query)
+	return &cloned
+}
+
+// AlertEventAnnotations is the built-in annotation source for the alert state changes stored in
+// dashica_alert_events (on the "alert_storage" ClickHouse server): each warn / error state of an alert whose group
+// matches alertGroupPattern (an ILIKE pattern, e.g. "%" or "http%") is drawn as band, e.g.:
+//
+//	dashboard.New().Annotations(dashboard.AlertEventAnnotations("http%"))
+func AlertEventAnnotations(alertGroupPattern string) sql.SqlQueryable {
+	return sql.FromStringWithoutFilters(fmt.Sprintf(alertEventAnnotationsQuery, quoteString(alertGroupPattern))).
+		With(sql.OnDatabase("alert_storage"))
+}
+
+const alertEventAnnotationsQuery = `
+SELECT
+    timestamp AS time,
+    if(end_ts = 0, now(), end_ts) AS end,
+    concat(alert_instance, ': ', status, if(ifNull(message, '') = '', '', concat(' - ', message))) AS text,
+    [status, alert_id_group] AS tags
+FROM (
+    SELECT
+        alert_id_group,
+        -- human-readable alert instance name, e.g. "http500 {host_name=web1}"
+        if(alert_labels = '', alert_id_key, concat(alert_id_key, ' {', decodeURLFormComponent(replaceAll(alert_labels, '&', ', ')), '}')) AS alert_instance,
+        timestamp,
+        status::String AS status,
+        message,
+        leadInFrame(timestamp) OVER (
+            PARTITION BY alert_id_group, alert_id_key, alert_labels
+            ORDER BY timestamp
+            ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING
+        ) AS end_ts
+    FROM
+        dashica_alert_events
+    WHERE
+        alert_id_group ILIKE %s
+        -- the state of every alert is stored at least once a day (see AlertResultStore), so one day before the
+        -- time range contains the states active at its beginning; and one day after it the end of its last states.
+        AND timestamp BETWEEN {__from:DateTime} - INTERVAL 1 DAY AND {__to:DateTime} + INTERVAL 1 DAY
+)
+WHERE
+    status IN ('warn', 'error')
+`
+
+// quoteString returns s as ClickHouse string literal.
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `'`, `\'`) + "'"
+}
+
+// buildAnnotationsComponent renders the (invisible) loader of the annotations (handled by
+// frontend/components/dashboardAnnotations.js), which stores them in the filter scope for the charts.
+func (d *Builder) buildAnnotationsComponent(ctx *rendering.DashboardContext) templ.Component {
+	htmlOut := fmt.Sprintf(`<div class="hidden" x-data="dashboardAnnotations" data-url="%s"></div>`,
+		html.EscapeString(ctx.CurrentHandlerUrl+"/api/"+annotationsPath))
+	return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+		_, err := io.WriteString(w, htmlOut)
+		return err
+	})
+}
+
+// collectAnnotationHandlers registers the annotations endpoint, if the dashboard has annotation sources.
+func (d *Builder) collectAnnotationHandlers(ctx *rendering.DashboardContext, registerHandler handler_collector.HandlerCollector) error {
+	if len(d.annotations) == 0 {
+		return nil
+	}
+	if err := registerHandler.Handle(annotationsPath, d.annotationsHandler(ctx)); err != nil {
+		return fmt.Errorf("annotations: %w", err)
+	}
+	return nil
+}
+
+// annotationsHandler returns the annotations of all sources as JSON array, sorted by time. Like the widget query
+// endpoints it reads the dashboard "filters" and the "vars" / "params" (see httpserver.QueryParameters).
+func (d *Builder) annotationsHandler(ctx *rendering.DashboardContext) http.Handler {
+	deps := ctx.Deps
+	untrusted := ctx.UntrustedContent
+	sources := d.annotations
+	source := clickhouse.QuerySource{Dashboard: ctx.CurrentHandlerUrl, Widget: annotationsPath, WidgetType: "annotations"}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		annotations := make([]Annotation, 0)
+		for i, query := range sources {
+			loaded, err := loadAnnotations(clickhouse.WithQuerySource(r.Context(), source), deps, untrusted, query, r)
+			if err != nil {
+				status := http.StatusInternalServerError
+				var guardErr *clickhouse.QueryGuardError
+				if errors.As(err, &guardErr) {
+					status = http.StatusUnprocessableEntity
+				}
+				http.Error(w, fmt.Sprintf("annotation source %d: %s", i+1, err), status)
+				return
+			}
+			annotations = append(annotations, loaded...)
+		}
+		slices.SortStableFunc(annotations, func(a, b Annotation) int {
+			return cmp.Compare(a.Time, b.Time)
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(annotations)
+	})
+}
+
+func loadAnnotations(ctx context.Context, deps rendering.Dependencies, untrusted bool, queryObj sql.SqlQueryable, r *http.Request) ([]Annotation, error) {
+	serverId := queryObj.Database()
+	if serverId == "" {
+		serverId = "default"
+	}
+	client, err := deps.ClickhouseClientManager.GetClient(serverId)
+	if err != nil {
+		return nil, fmt.Errorf("get clickhouse client: %w", err)
+	}
+
+	opts := clickhouse.DefaultQueryOptions()
+	opts.Untrusted = untrusted
+	opts.Settings["output_format_json_quote_64bit_integers"] = "0"
+	opts.Parameters, err = httpserver.QueryParameters(r)
+	if err != nil {
+		return nil, err
+	}
+
+	filters, err := httpserver.ParseDashboardFilters(r)
+	if err != nil {
+		return nil, err
+	}
+	if filters == nil {
+		return nil, fmt.Errorf("missing filters")
+	}
+	// only the time range: the SQL filter of the user refers to the columns of the widget tables
+	filters.SqlFilter = ""
+	filtered, err := filters.Apply(ctx, client, queryObj, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := sql.BuildWithFS(filtered.Query, deps.FileSystem)
+	if err != nil {
+		return nil, fmt.Errorf("building SQL query: %w", err)
+	}
+	// the time range is filtered in an inner subquery, as ClickHouse would resolve "time" in WHERE to the alias
+	query = fmt.Sprintf(`SELECT
+    toUnixTimestamp64Milli(toDateTime64(time, 3)) AS time,
+    toUnixTimestamp64Milli(toDateTime64(end, 3)) AS end,
+    ifNull(toString(text), '') AS text,
+    tags
+FROM (
+    SELECT * FROM (
+%s
+    )
+    WHERE time <= {__to:DateTime} AND ifNull(end, time) >= {__from:DateTime}
+    -- the most recent ones are kept (annotationsHandler sorts them by time again)
+    ORDER BY time DESC
+    LIMIT %d
+)`, strings.TrimRight(strings.TrimSpace(query), ";"), maxAnnotationsPerSource)
+
+	result, err := clickhouse.QueryJSON[Annotation](ctx, client, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("clickhouse query: %w", err)
+	}
+	return result.Data, nil
+}
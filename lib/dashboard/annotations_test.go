@@ -0,0 +1,130 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sandstorm/dashica/lib/dashboard/rendering"
+	"github.com/sandstorm/dashica/lib/dashboard/sql"
+)
+
+func TestAnnotations_Rendering(t *testing.T) {
+	d := New().Annotations(sql.FromString("SELECT timestamp AS time, NULL AS end, 'deploy' AS text, [] AS tags FROM deployments"))
+
+	var out bytes.Buffer
+	if err := d.buildAnnotationsComponent(&rendering.DashboardContext{CurrentHandlerUrl: "/sales"}).Render(context.Background(), &out); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if want := `<div class="hidden" x-data="dashboardAnnotations" data-url="/sales/api/annotations"></div>`; out.String() != want {
+		t.Errorf("rendered HTML = %q, want %q", out.String(), want)
+	}
+}
+
+func TestAnnotations_Handler(t *testing.T) {
+	var receivedQueries []string
+	var receivedParams url.Values
+	ctx := newTestDashboardContext(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receivedQueries = append(receivedQueries, string(body))
+		receivedParams = r.URL.Query()
+		if strings.Contains(string(body), "alerts") {
+			_, _ = w.Write([]byte(`{"meta":[],"data":[{"time":1700000100000,"end":1700000200000,"text":"http500: error","tags":["error","http"]}],"rows":1}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"meta":[],"data":[{"time":1700000300000,"end":null,"text":"Deploy v1.2","tags":["deploy"]}],"rows":1}`))
+	})
+	d := New().
+		Annotations(sql.FromString(`SELECT timestamp AS time, NULL AS end, version AS text, ['deploy'] AS tags FROM deployments WHERE {{DASHICA_FILTERS}}`)).
+		Annotations(sql.FromStringWithoutFilters(`SELECT timestamp AS time, end_ts AS end, message AS text, [status] AS tags FROM alerts`))
+
+	rec := httptest.NewRecorder()
+	filters := url.QueryEscape(`{"From":1700000000,"To":1700003600,"sqlFilter":"status = 500"}`)
+	vars := url.QueryEscape(`{"customer":["acme"]}`)
+	d.annotationsHandler(ctx).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales/api/annotations?filters="+filters+"&vars="+vars, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(receivedQueries) != 2 {
+		t.Fatalf("query count = %d, want 2", len(receivedQueries))
+	}
+	if !strings.Contains(receivedQueries[0], "FROM deployments WHERE (timestamp >= 1700000000 AND timestamp <= 1700003600)") {
+		t.Errorf("time range not applied to the filtered source:\n%s", receivedQueries[0])
+	}
+	if strings.Contains(receivedQueries[0], "status = 500") {
+		t.Errorf("SQL filter of the dashboard must not be applied to annotations:\n%s", receivedQueries[0])
+	}
+	if !strings.Contains(receivedQueries[1], "WHERE time <= {__to:DateTime} AND ifNull(end, time) >= {__from:DateTime}") {
+		t.Errorf("overlap filter missing:\n%s", receivedQueries[1])
+	}
+	if !strings.Contains(receivedQueries[1], "ORDER BY time DESC\n    LIMIT 1000") {
+		t.Errorf("the most recent annotations must be kept:\n%s", receivedQueries[1])
+	}
+	for name, want := range map[string]string{"param___from": "1700000000", "param___to": "1700003600", "param_customer": "['acme']"} {
+		if got := receivedParams.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	var annotations []Annotation
+	if err := json.Unmarshal(rec.Body.Bytes(), &annotations); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	end := int64(1700000200000)
+	want := []Annotation{
+		{Time: 1700000100000, End: &end, Text: "http500: error", Tags: []string{"error", "http"}},
+		{Time: 1700000300000, Text: "Deploy v1.2", Tags: []string{"deploy"}},
+	}
+	if !reflect.DeepEqual(annotations, want) {
+		t.Errorf("annotations = %+v, want %+v", annotations, want)
+	}
+}
+
+func TestAlertEventAnnotations(t *testing.T) {
+	query := AlertEventAnnotations(`o'brien\%`)
+	if query.Database() != "alert_storage" {
+		t.Errorf("database = %q, want alert_storage", query.Database())
+	}
+	if !query.ShouldSkipFilters() {
+		t.Errorf("alert events must be loaded without filters, so bands starting before the time range are shown")
+	}
+	if built := query.Build(); !strings.Contains(built, `alert_id_group ILIKE 'o\'brien\\%'`) {
+		t.Errorf("pattern not quoted:\n%s", built)
+	}
+	if built := query.Build(); !strings.Contains(built, "timestamp BETWEEN {__from:DateTime} - INTERVAL 1 DAY AND {__to:DateTime} + INTERVAL 1 DAY") {
+		t.Errorf("alert events not bounded by the time range:\n%s", built)
+	}
+}
+
+func TestAnnotations_RoundTrip(t *testing.T) {
+	orig := New().
+		Annotations(sql.FromString(`SELECT timestamp AS time, NULL AS end, version AS text, [] AS tags FROM deployments WHERE {{DASHICA_FILTERS}}`)).
+		Annotations(AlertEventAnnotations("%"))
+
+	b, err := MarshalDashboard(orig)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	got, err := UnmarshalDashboard(b)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	annotations := got.(*Builder).annotations
+	if len(annotations) != 2 {
+		t.Fatalf("annotation source count = %d, want 2", len(annotations))
+	}
+	for i, query := range annotations {
+		if query.Build() != orig.annotations[i].Build() || query.Database() != orig.annotations[i].Database() {
+			t.Errorf("source %d = %q on %q, want %q on %q", i, query.Build(), query.Database(), orig.annotations[i].Build(), orig.annotations[i].Database())
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/a-h/templ"
 	"github.com/sandstorm/dashica/lib/components/layout"
 	"github.com/sandstorm/dashica/lib/dashboard/rendering"
+	"github.com/sandstorm/dashica/lib/dashboard/sql"
 	"github.com/sandstorm/dashica/lib/dashboard/widget"
 	"github.com/sandstorm/dashica/lib/util"
 	"github.com/sandstorm/dashica/lib/util/handler_collector"
@@ -40,6 +41,9 @@ type Builder struct {
 	layout    layout.Layout
 	title     string
 	searchBar rendering.SearchBarOption
+	variables []*Variable
+	// annotations are the sources of the annotations drawn on the time charts, see Annotations.
+	annotations []sql.SqlQueryable
 }
 
 func (d *Builder) WithTitle(title string) *Builder {
@@ -80,10 +84,23 @@ func (d *Builder) FilterButton(title string, queryPart string) *Builder {
 }
 
 func (d *Builder) CollectHandlers(ctx *rendering.DashboardContext, handlerCollector handler_collector.HandlerCollector) error {
+	if err := d.validateVariables(); err != nil {
+		return err
+	}
 	components, err := util.MapHandleError(d.widgets, func(w widget.WidgetDefinition) (templ.Component, error) { return w.BuildComponents(ctx) })
 	if err != nil {
 		return fmt.Errorf("building components: %w", err)
 	}
+	if len(d.variables) > 0 {
+		variablesComponent, err := d.buildVariablesComponent(ctx)
+		if err != nil {
+			return fmt.Errorf("building variables: %w", err)
+		}
+		components = append([]templ.Component{variablesComponent}, components...)
+	}
+	if len(d.annotations) > 0 {
+		components = append([]templ.Component{d.buildAnnotationsComponent(ctx)}, components...)
+	}
 
 	err = handlerCollector.HandleRoot(templ.Handler(d.layout.Fn(*ctx, d.searchBar, templ.Join(components...))))
 	if err != nil {
@@ -99,6 +116,12 @@ func (d *Builder) CollectHandlers(ctx *rendering.DashboardContext, handlerCollec
 		return fmt.Errorf("registering open-in-explore handler: %w", err)
 	}
 
+	if err := d.collectVariableHandlers(ctx, handlerCollector.Nested("/api")); err != nil {
+		return err
+	}
+	if err := d.collectAnnotationHandlers(ctx, handlerCollector.Nested("/api")); err != nil {
+		return err
+	}
 	return d.widgets.CollectHandlers(ctx, handlerCollector.Nested("/api"))
 }
 
@@ -6,6 +6,7 @@ import (
 
 	"github.com/sandstorm/dashica/lib/components/layout"
 	"github.com/sandstorm/dashica/lib/dashboard/rendering"
+	"github.com/sandstorm/dashica/lib/dashboard/sql"
 	"github.com/sandstorm/dashica/lib/dashboard/widget"
 )
 
@@ -22,17 +23,95 @@ type dashboardDTO struct {
 	Layout    string                    `json:"layout,omitempty"`
 	SearchBar rendering.SearchBarOption `json:"searchBar"`
 	Widgets   widget.Widgets            `json:"widgets"`
+	Variables []*Variable               `json:"variables,omitempty"`
+	// Annotations are the annotation sources, each in the sql tagged envelope.
+	Annotations []json.RawMessage `json:"annotations,omitempty"`
+}
+
+// variableDTO is the wire form of a Variable; the options query uses the sql tagged envelope.
+type variableDTO struct {
+	Name      string          `json:"name"`
+	Label     string          `json:"label,omitempty"`
+	Query     json.RawMessage `json:"query"`
+	Multi     bool            `json:"multi,omitempty"`
+	Default   []string        `json:"default,omitempty"`
+	DependsOn []string        `json:"dependsOn,omitempty"`
+}
+
+func (v *Variable) MarshalJSON() ([]byte, error) {
+	query, err := sql.MarshalQueryable(v.query)
+	if err != nil {
+		return nil, fmt.Errorf("variable '%s': %w", v.name, err)
+	}
+	return json.Marshal(variableDTO{
+		Name:      v.name,
+		Label:     v.label,
+		Query:     query,
+		Multi:     v.multi,
+		Default:   v.defaults,
+		DependsOn: v.dependsOn,
+	})
+}
+
+func (v *Variable) UnmarshalJSON(b []byte) error {
+	var dto variableDTO
+	if err := json.Unmarshal(b, &dto); err != nil {
+		return err
+	}
+	query, err := sql.UnmarshalQueryable(dto.Query)
+	if err != nil {
+		return fmt.Errorf("variable '%s': %w", dto.Name, err)
+	}
+	*v = Variable{
+		name:      dto.Name,
+		label:     dto.Label,
+		query:     query,
+		multi:     dto.Multi,
+		defaults:  dto.Default,
+		dependsOn: dto.DependsOn,
+	}
+	return nil
 }
 
 func (d *Builder) MarshalJSON() ([]byte, error) {
+	annotations, err := marshalAnnotations(d.annotations)
+	if err != nil {
+		return nil, err
+	}
 	return json.Marshal(dashboardDTO{
-		Title:     d.title,
-		Layout:    d.layout.Name,
-		SearchBar: d.searchBar,
-		Widgets:   d.widgets,
+		Title:       d.title,
+		Layout:      d.layout.Name,
+		SearchBar:   d.searchBar,
+		Widgets:     d.widgets,
+		Variables:   d.variables,
+		Annotations: annotations,
 	})
 }
 
+func marshalAnnotations(sources []sql.SqlQueryable) ([]json.RawMessage, error) {
+	var out []json.RawMessage
+	for i, source := range sources {
+		b, err := sql.MarshalQueryable(source)
+		if err != nil {
+			return nil, fmt.Errorf("annotation source %d: %w", i+1, err)
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+func unmarshalAnnotations(raw []json.RawMessage) ([]sql.SqlQueryable, error) {
+	var out []sql.SqlQueryable
+	for i, b := range raw {
+		source, err := sql.UnmarshalQueryable(b)
+		if err != nil {
+			return nil, fmt.Errorf("annotation source %d: %w", i+1, err)
+		}
+		out = append(out, source)
+	}
+	return out, nil
+}
+
 func (d *Builder) UnmarshalJSON(b []byte) error {
 	var dto dashboardDTO
 	if err := json.Unmarshal(b, &dto); err != nil {
@@ -47,12 +126,18 @@ func (d *Builder) UnmarshalJSON(b []byte) error {
 		}
 		l = resolved
 	}
+	annotations, err := unmarshalAnnotations(dto.Annotations)
+	if err != nil {
+		return err
+	}
 
 	*d = Builder{
-		widgets:   dto.Widgets,
-		layout:    l,
-		title:     dto.Title,
-		searchBar: dto.SearchBar,
+		widgets:     dto.Widgets,
+		layout:      l,
+		title:       dto.Title,
+		searchBar:   dto.SearchBar,
+		variables:   dto.Variables,
+		annotations: annotations,
 	}
 	return nil
 }
@@ -76,11 +161,17 @@ func (d *Builder) MarshalForExplore() ([]byte, []string, error) {
 	notes, done := widget.BeginLenientMarshal()
 	defer done()
 
+	annotations, err := marshalAnnotations(d.annotations)
+	if err != nil {
+		return nil, nil, err
+	}
 	b, err := json.Marshal(dashboardDTO{
-		Title:     d.title,
-		Layout:    d.layout.Name,
-		SearchBar: d.searchBar,
-		Widgets:   d.widgets,
+		Title:       d.title,
+		Layout:      d.layout.Name,
+		SearchBar:   d.searchBar,
+		Widgets:     d.widgets,
+		Variables:   d.variables,
+		Annotations: annotations,
 	})
 	if err != nil {
 		return nil, nil, err
@@ -7,6 +7,7 @@ import (
 	"github.com/a-h/templ"
 	"github.com/rs/zerolog"
 	alerting2 "github.com/sandstorm/dashica/lib/alerting"
+	"github.com/sandstorm/dashica/lib/auth"
 	"github.com/sandstorm/dashica/lib/clickhouse"
 	"github.com/sandstorm/dashica/lib/config"
 )
@@ -69,6 +70,9 @@ type Dependencies struct {
 type MenuGroup struct {
 	Title   string
 	Entries []MenuGroupEntry
+	// Access restricts the group (menu entries and all handlers of its dashboards) to matching users; see
+	// auth.Allowed.
+	Access []auth.AccessRule
 }
 
 type MenuGroupEntry struct {
@@ -0,0 +1,79 @@
+package dashboard
+
+import (
+	"github.com/sandstorm/dashica/lib/clickhouse"
+	"github.com/sandstorm/dashica/lib/components/layout"
+	"github.com/sandstorm/dashica/lib/dashboard/sql"
+	"github.com/sandstorm/dashica/lib/dashboard/widget"
+)
+
+// SelfMonitoring is the built-in "Dashica self-monitoring" dashboard, showing the slowest widgets and the most
+// expensive dashboards from the query log (see clickhouse.QueryLog; query_log.enabled must be set).
+func SelfMonitoring() *Builder {
+	queryLog := func(opts ...sql.SqlBuilderOption) *sql.SqlQuery {
+		return sql.New(append([]sql.SqlBuilderOption{
+			sql.From(clickhouse.QueryLogTable),
+			sql.OnDatabase(clickhouse.QueryLogServer),
+			sql.NoCache(),
+		}, opts...)...)
+	}
+
+	return New().
+		WithTitle("Dashica self-monitoring").
+		WithLayout(layout.DefaultPage).
+		Widget(
+			widget.NewTimeBar(queryLog()).
+				Title("Queries").
+				X(sql.AutoBucket("timestamp")).
+				Y(sql.Count()).
+				Fill(sql.Field("if(error = '', 'OK', 'error')").WithAlias("status")).
+				Height(200),
+		).
+		Widget(
+			widget.NewTable(queryLog(
+				sql.Where("widget != ''"),
+				sql.Select(sql.Field("dashboard")),
+				sql.Select(sql.Field("widget")),
+				sql.Select(sql.Field("any(widget_type)").WithAlias("widget_type")),
+				sql.Select(sql.Count().WithAlias("queries")),
+				sql.Select(sql.Field("quantile(0.95)(duration_ms)").WithAlias("p95_duration_ms")),
+				sql.Select(sql.Field("max(duration_ms)").WithAlias("max_duration_ms")),
+				sql.Select(sql.Field("countIf(error != '')").WithAlias("errors")),
+				sql.GroupBy(sql.Field("dashboard")),
+				sql.GroupBy(sql.Field("widget")),
+				sql.OrderBy(sql.Field("p95_duration_ms DESC")),
+				sql.Limit(50),
+			)).
+				Title("Slowest widgets").
+				ColumnFormats(map[string]string{"p95_duration_ms": "ms", "max_duration_ms": "ms"}).
+				Height(400),
+		).
+		Widget(
+			widget.NewBarHorizontal(queryLog(
+				sql.Where("dashboard != ''"),
+				sql.OrderBy(sql.Field("read_gib DESC")),
+				sql.Limit(20),
+			)).
+				Title("Most expensive dashboards (GiB read)").
+				Y(sql.Field("dashboard")).
+				X(sql.Field("round(sum(read_bytes) / pow(2, 30), 2)").WithAlias("read_gib")).
+				MarginLeft(250).
+				Height(400),
+		).
+		Widget(
+			widget.NewTable(queryLog(
+				sql.Where("error != ''"),
+				sql.Select(sql.Field("timestamp")),
+				sql.Select(sql.Field("user")),
+				sql.Select(sql.Field("dashboard")),
+				sql.Select(sql.Field("widget")),
+				sql.Select(sql.Field("alert")),
+				sql.Select(sql.Field("server")),
+				sql.Select(sql.Field("error")),
+				sql.OrderBy(sql.Field("timestamp DESC")),
+				sql.Limit(100),
+			)).
+				Title("Failed queries").
+				Height(300),
+		)
+}
@@ -5,6 +5,7 @@ import (
 	"io/fs"
 	"os"
 	"strings"
+	"time"
 )
 
 // DashicaFiltersPlaceholder is replaced inside .sql files with the AND-joined Where()
@@ -28,6 +29,7 @@ type SqlFile struct {
 	shouldSkipFilters bool
 	where             []string
 	database          string
+	cacheTTL          time.Duration
 
 	// auto-bucket placeholder substitution
 	autoBucket     bool   // opt-in via AutoBucketPlaceholder()
@@ -42,6 +44,10 @@ func (f *SqlFile) Database() string {
 	return f.database
 }
 
+func (f *SqlFile) CacheTTL() time.Duration {
+	return f.cacheTTL
+}
+
 // FromFile creates a new SqlFile from the given path. The file MUST contain the
 // {{DASHICA_FILTERS}} placeholder so that dashboard time-range and user filters
 // are applied — otherwise queries silently scan the full table. Files that
@@ -154,22 +160,24 @@ func (f *SqlFile) AdjustBuckets(widthS int64) (SqlQueryable, *int64) {
 	return &cloned, &sizeMs
 }
 
-// With applies SqlBuilderOptions. Only Where() and OnDatabase() are meaningful for
-// SqlFile — Where() clauses get substituted into DASHICA_FILTERS placeholders by
-// Build(); OnDatabase() routes the query to a non-default ClickHouse. Other options
-// (Select, From, GroupBy, ...) are ignored because the file's SQL is already complete.
+// With applies SqlBuilderOptions. Only Where(), OnDatabase(), AutoBucketPlaceholder()
+// and CacheTTL() / NoCache() are meaningful for SqlFile — Where() clauses get
+// substituted into DASHICA_FILTERS placeholders by Build(); OnDatabase() routes the
+// query to a non-default ClickHouse. Other options (Select, From, GroupBy, ...) are
+// ignored because the file's SQL is already complete.
 func (b *SqlFile) With(opts ...SqlBuilderOption) SqlQueryable {
 	cloned := *b
 	cloned.where = append([]string(nil), b.where...)
 	// Run the options against a throwaway SqlQuery so the *same* option functions
 	// can target both query types — this captures Where() and OnDatabase() additions.
-	proxy := &SqlQuery{where: cloned.where, database: cloned.database, autoBucketPlaceholder: cloned.autoBucket}
+	proxy := &SqlQuery{where: cloned.where, database: cloned.database, autoBucketPlaceholder: cloned.autoBucket, cacheTTL: cloned.cacheTTL}
 	for _, opt := range opts {
 		opt(proxy)
 	}
 	cloned.where = proxy.where
 	cloned.database = proxy.database
 	cloned.autoBucket = proxy.autoBucketPlaceholder
+	cloned.cacheTTL = proxy.cacheTTL
 	return &cloned
 }
 
@@ -187,6 +195,9 @@ type SqlQueryable interface {
 	// Database returns the ClickHouse server alias this query should run against,
 	// or "" when it should use the "default" server.
 	Database() string
+	// CacheTTL returns how long the result may be cached: 0 means the configured
+	// default, a negative value disables caching (see CacheTTL() and NoCache()).
+	CacheTTL() time.Duration
 }
 
 var _ SqlQueryable = (*SqlQuery)(nil)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/fs"
 	"strings"
+	"time"
 )
 
 // SqlString is like SqlFile but carries its SQL inline instead of reading it
@@ -17,6 +18,7 @@ type SqlString struct {
 	shouldSkipFilters bool
 	where             []string
 	database          string
+	cacheTTL          time.Duration
 
 	// auto-bucket placeholder substitution (mirrors SqlFile)
 	autoBucket     bool   // opt-in via AutoBucketPlaceholder()
@@ -50,6 +52,10 @@ func (s *SqlString) Database() string {
 	return s.database
 }
 
+func (s *SqlString) CacheTTL() time.Duration {
+	return s.cacheTTL
+}
+
 // Build returns the inline SQL with placeholders substituted. Unlike
 // BuildWithFS it does not enforce the {{DASHICA_FILTERS}} placeholder (there is
 // no error channel); production request handling goes through BuildWithFS.
@@ -94,13 +100,14 @@ func (s *SqlString) AdjustBuckets(widthS int64) (SqlQueryable, *int64) {
 func (s *SqlString) With(opts ...SqlBuilderOption) SqlQueryable {
 	cloned := *s
 	cloned.where = append([]string(nil), s.where...)
-	proxy := &SqlQuery{where: cloned.where, database: cloned.database, autoBucketPlaceholder: cloned.autoBucket}
+	proxy := &SqlQuery{where: cloned.where, database: cloned.database, autoBucketPlaceholder: cloned.autoBucket, cacheTTL: cloned.cacheTTL}
 	for _, opt := range opts {
 		opt(proxy)
 	}
 	cloned.where = proxy.where
 	cloned.database = proxy.database
 	cloned.autoBucket = proxy.autoBucketPlaceholder
+	cloned.cacheTTL = proxy.cacheTTL
 	return &cloned
 }
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
 // This file makes the sql vocabulary serializable for the Explore builder
@@ -140,6 +141,7 @@ type queryDTO struct {
 	Table                 string            `json:"table,omitempty"`
 	Select                []json.RawMessage `json:"select,omitempty"`
 	GroupBy               []json.RawMessage `json:"groupBy,omitempty"`
+	Rollup                bool              `json:"rollup,omitempty"`
 	OrderBy               []json.RawMessage `json:"orderBy,omitempty"`
 	Limit                 int               `json:"limit,omitempty"`
 	FillStep              string            `json:"fillStep,omitempty"`
@@ -152,10 +154,11 @@ type queryDTO struct {
 	Sql string `json:"sql,omitempty"`
 
 	// shared
-	Where       []string `json:"where,omitempty"`
-	Database    string   `json:"database,omitempty"`
-	SkipFilters bool     `json:"skipFilters,omitempty"`
-	AutoBucket  bool     `json:"autoBucket,omitempty"` // file/raw placeholder opt-in
+	Where       []string      `json:"where,omitempty"`
+	Database    string        `json:"database,omitempty"`
+	SkipFilters bool          `json:"skipFilters,omitempty"`
+	AutoBucket  bool          `json:"autoBucket,omitempty"` // file/raw placeholder opt-in
+	CacheTTL    time.Duration `json:"cacheTtl,omitempty"`
 }
 
 func (q *SqlQuery) MarshalJSON() ([]byte, error) {
@@ -176,6 +179,7 @@ func (q *SqlQuery) MarshalJSON() ([]byte, error) {
 		Table:                 q.from,
 		Select:                sel,
 		GroupBy:               grp,
+		Rollup:                q.rollup,
 		OrderBy:               ord,
 		Limit:                 q.limit,
 		FillStep:              q.fillStep,
@@ -183,6 +187,7 @@ func (q *SqlQuery) MarshalJSON() ([]byte, error) {
 		Where:                 q.where,
 		Database:              q.database,
 		SkipFilters:           q.shouldSkipFilters,
+		CacheTTL:              q.cacheTTL,
 	})
 }
 
@@ -208,12 +213,14 @@ func (q *SqlQuery) UnmarshalJSON(b []byte) error {
 		from:                  dto.Table,
 		where:                 dto.Where,
 		groupBy:               grp,
+		rollup:                dto.Rollup,
 		orderBy:               ord,
 		limit:                 dto.Limit,
 		fillStep:              dto.FillStep,
 		shouldSkipFilters:     dto.SkipFilters,
 		database:              dto.Database,
 		autoBucketPlaceholder: dto.AutoBucketPlaceholder,
+		cacheTTL:              dto.CacheTTL,
 	}
 	return nil
 }
@@ -226,6 +233,7 @@ func (f *SqlFile) MarshalJSON() ([]byte, error) {
 		Database:    f.database,
 		SkipFilters: f.shouldSkipFilters,
 		AutoBucket:  f.autoBucket,
+		CacheTTL:    f.cacheTTL,
 	})
 }
 
@@ -240,6 +248,7 @@ func (f *SqlFile) UnmarshalJSON(b []byte) error {
 		where:             dto.Where,
 		database:          dto.Database,
 		autoBucket:        dto.AutoBucket,
+		cacheTTL:          dto.CacheTTL,
 	}
 	return nil
 }
@@ -252,6 +261,7 @@ func (s *SqlString) MarshalJSON() ([]byte, error) {
 		Database:    s.database,
 		SkipFilters: s.shouldSkipFilters,
 		AutoBucket:  s.autoBucket,
+		CacheTTL:    s.cacheTTL,
 	})
 }
 
@@ -266,6 +276,7 @@ func (s *SqlString) UnmarshalJSON(b []byte) error {
 		where:             dto.Where,
 		database:          dto.Database,
 		autoBucket:        dto.AutoBucket,
+		cacheTTL:          dto.CacheTTL,
 	}
 	return nil
 }
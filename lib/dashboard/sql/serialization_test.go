@@ -3,6 +3,7 @@ package sql
 import (
 	"encoding/json"
 	"testing"
+	"time"
 )
 
 // roundTripField marshals f via the envelope helper and unmarshals it back.
@@ -136,12 +137,27 @@ func TestQueryableRoundTripBuildIdentical(t *testing.T) {
 				WithFill("toIntervalHour(1)"),
 				OnDatabase("analytics"),
 				SkipFilters(),
+				CacheTTL(5*time.Minute),
+			),
+		},
+		{
+			"table with rollup",
+			New(
+				From("full_logs"),
+				Select(AutoBucket("timestamp")),
+				Select(Count().WithAlias("value")),
+				GroupBy(AutoBucket("timestamp")),
+				WithRollup(),
 			),
 		},
 		{
 			"file",
 			FromFile("src/p_wetell/overview.sql"),
 		},
+		{
+			"file without cache",
+			FromFile("src/p_wetell/overview.sql").With(NoCache()),
+		},
 		{
 			"raw string",
 			FromString("SELECT * FROM x WHERE {{DASHICA_FILTERS}}"),
@@ -164,6 +180,9 @@ func TestQueryableRoundTripBuildIdentical(t *testing.T) {
 			if got.Database() != tt.query.Database() {
 				t.Errorf("Database: got %q want %q", got.Database(), tt.query.Database())
 			}
+			if got.CacheTTL() != tt.query.CacheTTL() {
+				t.Errorf("CacheTTL: got %s want %s", got.CacheTTL(), tt.query.CacheTTL())
+			}
 			// marshal-idempotence: re-marshalling the reconstructed queryable
 			// yields byte-identical JSON.
 			first, _ := MarshalQueryable(tt.query)
@@ -3,6 +3,7 @@ package sql
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 // TODO REMOVE ME
@@ -14,12 +15,14 @@ type SqlQuery struct {
 	from                  string
 	where                 []string
 	groupBy               []SqlField
+	rollup                bool
 	orderBy               []SqlField
 	limit                 int
 	fillStep              string
 	shouldSkipFilters     bool
 	database              string
 	autoBucketPlaceholder bool // routed to *SqlFile via With(); ignored on *SqlQuery itself
+	cacheTTL              time.Duration
 }
 
 type SqlBuilderOption func(*SqlQuery)
@@ -66,6 +69,14 @@ func GroupBy(field SqlField) SqlBuilderOption {
 	}
 }
 
+// WithRollup emits `GROUP BY ... WITH ROLLUP`: the result additionally contains subtotal rows, and a grand total
+// row in which all GROUP BY columns have their default value (e.g. 1970-01-01 for a time bucket).
+func WithRollup() SqlBuilderOption {
+	return func(b *SqlQuery) {
+		b.rollup = true
+	}
+}
+
 func OrderBy(field SqlField) SqlBuilderOption {
 	return func(b *SqlQuery) {
 		b.orderBy = append(b.orderBy, field)
@@ -98,6 +109,22 @@ func OnDatabase(name string) SqlBuilderOption {
 	}
 }
 
+// CacheTTL caches the query result for ttl (instead of the query_cache.default_ttl), if the query cache is enabled
+// in dashica_config.yaml. Relative time ranges are rounded to ttl, so e.g. CacheTTL(5*time.Minute) trades up to
+// five minutes of freshness for fewer ClickHouse queries.
+func CacheTTL(ttl time.Duration) SqlBuilderOption {
+	return func(b *SqlQuery) {
+		b.cacheTTL = ttl
+	}
+}
+
+// NoCache always executes the query, even if the query cache is enabled.
+func NoCache() SqlBuilderOption {
+	return func(b *SqlQuery) {
+		b.cacheTTL = -1
+	}
+}
+
 func New(opts ...SqlBuilderOption) *SqlQuery {
 	b := &SqlQuery{}
 	for _, opt := range opts {
@@ -117,6 +144,10 @@ func (b *SqlQuery) ShouldSkipFilters() bool {
 	return b.shouldSkipFilters
 }
 
+func (b *SqlQuery) CacheTTL() time.Duration {
+	return b.cacheTTL
+}
+
 // AdjustBuckets returns a clone with every AutoBucket field rebaked at the
 // rounding function chosen for the given time-range width, plus the chosen
 // bucket size in milliseconds. Returns the receiver and nil if the query has
@@ -220,6 +251,8 @@ func (b *SqlQuery) Build() string {
 
 			if i < len(b.groupBy)-1 {
 				sb.WriteString(",")
+			} else if b.rollup {
+				sb.WriteString(" WITH ROLLUP")
 			}
 			sb.WriteString("\n")
 		}
@@ -141,3 +141,19 @@ func TestWithoutFill(t *testing.T) {
 		t.Errorf("Expected no WITH FILL when WithFill not set, got:\n%s", query.Build())
 	}
 }
+
+func TestWithRollup(t *testing.T) {
+	query := New(
+		From("metrics"),
+		Select(Field("user")),
+		Select(Field("time")),
+		GroupBy(Field("user")),
+		GroupBy(Field("time")),
+		WithRollup(),
+	)
+
+	want := "GROUP BY\n    user,\n    time WITH ROLLUP"
+	if !strings.Contains(query.Build(), want) {
+		t.Errorf("Expected SQL to contain %q, got:\n%s", want, query.Build())
+	}
+}
@@ -0,0 +1,242 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/sandstorm/dashica/lib/clickhouse"
+	"github.com/sandstorm/dashica/lib/dashboard/rendering"
+	"github.com/sandstorm/dashica/lib/dashboard/sql"
+	"github.com/sandstorm/dashica/lib/httpserver"
+	"github.com/sandstorm/dashica/lib/util/handler_collector"
+)
+
+// Variable is a dashboard-level template variable (like Grafana's $customer dropdown): a dropdown or multi-select
+// above the widgets, whose options come from an SQL query. The selected values are passed to EVERY widget query of
+// the dashboard as ClickHouse query parameter {name:Array(String)} (an empty array if nothing is selected), e.g.:
+//
+//	WHERE empty({customer:Array(String)}) OR has({customer:Array(String)}, customer)
+//
+// The selection is stored in the URL (?var-customer=acme&var-customer=globex), so links keep it.
+type Variable struct {
+	name  string
+	label string
+	// query returns the options in its first column.
+	query sql.SqlQueryable
+	// multi renders a multi-select instead of a single-select dropdown.
+	multi bool
+	// defaults are selected if the URL contains no selection for the variable.
+	defaults []string
+	// dependsOn are the variables passed to query as {name:Array(String)}; the options are re-loaded (and stale
+	// selected values dropped) when one of them changes.
+	dependsOn []string
+}
+
+// VariableOption configures a Variable, see Builder.Variable.
+type VariableOption func(*Variable)
+
+// VariableLabel sets the label shown above the dropdown (default: the variable name).
+func VariableLabel(label string) VariableOption {
+	return func(v *Variable) {
+		v.label = label
+	}
+}
+
+// VariableMulti allows selecting multiple values.
+func VariableMulti() VariableOption {
+	return func(v *Variable) {
+		v.multi = true
+	}
+}
+
+// VariableDefault sets the values selected on first page load.
+func VariableDefault(values ...string) VariableOption {
+	return func(v *Variable) {
+		v.defaults = append([]string(nil), values...)
+	}
+}
+
+// VariableDependsOn makes the options query depend on the given (previously declared) variables, e.g. a project
+// variable whose query filters by {customer:Array(String)}.
+func VariableDependsOn(names ...string) VariableOption {
+	return func(v *Variable) {
+		v.dependsOn = append([]string(nil), names...)
+	}
+}
+
+// Variable adds a template variable named name, whose options are the first column of query; see Variable.
+func (d *Builder) Variable(name string, query sql.SqlQueryable, opts ...VariableOption) *Builder {
+	v := &Variable{name: name, label: name, query: query}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	cloned := *d
+	cloned.variables = append(slices.Clone(d.variables), v)
+	return &cloned
+}
+
+// validateVariables checks the variable names, and that variables only depend on variables declared before them.
+func (d *Builder) validateVariables() error {
+	declared := make(map[string]bool, len(d.variables))
+	for _, v := range d.variables {
+		if !httpserver.IsValidVariableName(v.name) {
+			return fmt.Errorf("variable '%s': name must be a valid ClickHouse parameter name", v.name)
+		}
+		if declared[v.name] {
+			return fmt.Errorf("variable '%s' is declared twice", v.name)
+		}
+		for _, dependency := range v.dependsOn {
+			if !declared[dependency] {
+				return fmt.Errorf("variable '%s' depends on '%s', which must be declared before it", v.name, dependency)
+			}
+		}
+		declared[v.name] = true
+	}
+	return nil
+}
+
+// buildVariablesComponent renders the variable dropdowns (handled by frontend/components/dashboardVariable.js).
+func (d *Builder) buildVariablesComponent(ctx *rendering.DashboardContext) (templ.Component, error) {
+	var out strings.Builder
+	out.WriteString(`<div class="flex flex-wrap items-end gap-4 mb-4">`)
+	for _, v := range d.variables {
+		defaults := v.defaults
+		if defaults == nil {
+			defaults = []string{}
+		}
+		defaultJSON, err := json.Marshal(defaults)
+		if err != nil {
+			return nil, fmt.Errorf("variable '%s': marshal defaults: %w", v.name, err)
+		}
+		dependsOn := v.dependsOn
+		if dependsOn == nil {
+			dependsOn = []string{}
+		}
+		dependsOnJSON, err := json.Marshal(dependsOn)
+		if err != nil {
+			return nil, fmt.Errorf("variable '%s': marshal dependsOn: %w", v.name, err)
+		}
+
+		multiple := ""
+		emptyOption := `<option value="">All</option>`
+		if v.multi {
+			multiple = " multiple"
+			emptyOption = ""
+		}
+		fmt.Fprintf(&out, `
+<div class="form-control" x-data="dashboardVariable" data-name="%s" data-options-url="%s" data-default="%s" data-depends-on="%s">
+  <label class="label"><span class="label-text font-medium">%s</span></label>
+  <select class="select select-sm select-bordered"%s @change="select($event)">%s
+    <template x-for="option in options" :key="option">
+      <option :value="option" :selected="isSelected(option)" x-text="option"></option>
+    </template>
+  </select>
+</div>`,
+			html.EscapeString(v.name),
+			html.EscapeString(ctx.CurrentHandlerUrl+"/api/"+v.optionsPath()),
+			html.EscapeString(string(defaultJSON)),
+			html.EscapeString(string(dependsOnJSON)),
+			html.EscapeString(v.label),
+			multiple,
+			emptyOption,
+		)
+	}
+	out.WriteString(`</div>`)
+
+	htmlOut := out.String()
+	return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+		_, err := io.WriteString(w, htmlOut)
+		return err
+	}), nil
+}
+
+func (v *Variable) optionsPath() string {
+	return "variables/" + v.name + "/options"
+}
+
+// collectVariableHandlers registers the options endpoint of every variable.
+func (d *Builder) collectVariableHandlers(ctx *rendering.DashboardContext, registerHandler handler_collector.HandlerCollector) error {
+	for _, v := range d.variables {
+		if err := registerHandler.Handle(v.optionsPath(), v.optionsHandler(ctx)); err != nil {
+			return fmt.Errorf("variable '%s': %w", v.name, err)
+		}
+	}
+	return nil
+}
+
+// optionsHandler returns the options of v as JSON array of strings; the values of the variables v depends on are
+// passed as "vars" (see httpserver.QueryParameters).
+func (v *Variable) optionsHandler(ctx *rendering.DashboardContext) http.Handler {
+	deps := ctx.Deps
+	untrusted := ctx.UntrustedContent
+	source := clickhouse.QuerySource{Dashboard: ctx.CurrentHandlerUrl, Widget: v.optionsPath(), WidgetType: "variable"}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		options, err := v.loadOptions(clickhouse.WithQuerySource(r.Context(), source), deps, untrusted, r)
+		if err != nil {
+			status := http.StatusInternalServerError
+			var guardErr *clickhouse.QueryGuardError
+			if errors.As(err, &guardErr) {
+				status = http.StatusUnprocessableEntity
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(options)
+	})
+}
+
+func (v *Variable) loadOptions(ctx context.Context, deps rendering.Dependencies, untrusted bool, r *http.Request) ([]string, error) {
+	serverId := v.query.Database()
+	if serverId == "" {
+		serverId = "default"
+	}
+	client, err := deps.ClickhouseClientManager.GetClient(serverId)
+	if err != nil {
+		return nil, fmt.Errorf("get clickhouse client: %w", err)
+	}
+
+	opts := clickhouse.DefaultQueryOptions()
+	opts.Untrusted = untrusted
+	opts.Parameters, err = httpserver.QueryParameters(r)
+	if err != nil {
+		return nil, err
+	}
+	query, err := sql.BuildWithFS(v.query, deps.FileSystem)
+	if err != nil {
+		return nil, fmt.Errorf("building SQL query: %w", err)
+	}
+
+	result, err := clickhouse.QueryJSON[map[string]json.RawMessage](ctx, client, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("clickhouse query: %w", err)
+	}
+	if len(result.Meta) == 0 {
+		return []string{}, nil
+	}
+	column := result.Meta[0].Name
+	options := make([]string, 0, len(result.Data))
+	for _, row := range result.Data {
+		options = append(options, optionValue(row[column]))
+	}
+	return options, nil
+}
+
+// optionValue converts a JSON value of the options query to the option string: strings are unquoted, numbers etc.
+// are used verbatim.
+func optionValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
@@ -0,0 +1,141 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/clickhouse"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/sandstorm/dashica/lib/dashboard/rendering"
+	"github.com/sandstorm/dashica/lib/dashboard/sql"
+)
+
+// newTestDashboardContext returns the context of the dashboard /sales, whose "default" ClickHouse server is answered
+// by handler.
+func newTestDashboardContext(t *testing.T, handler http.HandlerFunc) *rendering.DashboardContext {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &rendering.DashboardContext{
+		CurrentHandlerUrl: "/sales",
+		Deps: rendering.Dependencies{
+			Logger: zerolog.Nop(),
+			ClickhouseClientManager: clickhouse.NewManager(&config.Config{
+				ClickHouse: map[string]config.ClickHouseConfig{"default": {URL: server.URL}},
+			}, zerolog.Nop()),
+		},
+	}
+}
+
+func TestVariables_Validation(t *testing.T) {
+	query := sql.New(sql.From("projects"))
+	for _, tc := range []struct {
+		name    string
+		builder *Builder
+		wantErr string
+	}{
+		{"valid", New().Variable("customer", query).Variable("project", query, VariableDependsOn("customer")), ""},
+		{"invalid name", New().Variable("customer-id", query), "name must be a valid ClickHouse parameter name"},
+		{"declared twice", New().Variable("customer", query).Variable("customer", query), "declared twice"},
+		{"dependency declared later", New().Variable("project", query, VariableDependsOn("customer")).Variable("customer", query), "must be declared before it"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.builder.validateVariables()
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestVariables_Rendering(t *testing.T) {
+	d := New().
+		Variable("customer", sql.New(sql.From("customers")), VariableLabel("Customer"), VariableDefault("acme")).
+		Variable("project", sql.New(sql.From("projects")), VariableMulti(), VariableDependsOn("customer"))
+
+	component, err := d.buildVariablesComponent(&rendering.DashboardContext{CurrentHandlerUrl: "/sales"})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	var out bytes.Buffer
+	if err := component.Render(context.Background(), &out); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	for _, want := range []string{
+		`data-name="customer" data-options-url="/sales/api/variables/customer/options" data-default="[&#34;acme&#34;]" data-depends-on="[]"`,
+		`<option value="">All</option>`,
+		`data-name="project" data-options-url="/sales/api/variables/project/options" data-default="[]" data-depends-on="[&#34;customer&#34;]"`,
+		`<select class="select select-sm select-bordered" multiple`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("rendered HTML does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestVariable_OptionsHandler(t *testing.T) {
+	var receivedParams url.Values
+	ctx := newTestDashboardContext(t, func(w http.ResponseWriter, r *http.Request) {
+		receivedParams = r.URL.Query()
+		_, _ = w.Write([]byte(`{"meta":[{"name":"project","type":"String"}],"data":[{"project":"web"},{"project":42}],"rows":2}`))
+	})
+	v := &Variable{name: "project", query: sql.New(sql.From("projects"), sql.Where("has({customer:Array(String)}, customer)"))}
+
+	rec := httptest.NewRecorder()
+	vars := url.QueryEscape(`{"customer":["acme","o'brien"]}`)
+	v.optionsHandler(ctx).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales/api/variables/project/options?vars="+vars, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := receivedParams.Get("param_customer"); got != `['acme','o\'brien']` {
+		t.Errorf("param_customer = %q", got)
+	}
+	var options []string
+	if err := json.Unmarshal(rec.Body.Bytes(), &options); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if want := []string{"web", "42"}; !reflect.DeepEqual(options, want) {
+		t.Errorf("options = %v, want %v", options, want)
+	}
+}
+
+func TestVariables_RoundTrip(t *testing.T) {
+	orig := New().
+		Variable("customer", sql.New(sql.From("customers")), VariableLabel("Customer"), VariableDefault("acme")).
+		Variable("project", sql.New(sql.From("projects")), VariableMulti(), VariableDependsOn("customer"))
+
+	b, err := MarshalDashboard(orig)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	got, err := UnmarshalDashboard(b)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	variables := got.(*Builder).variables
+	if len(variables) != 2 {
+		t.Fatalf("variable count = %d, want 2", len(variables))
+	}
+	customer, project := variables[0], variables[1]
+	if customer.name != "customer" || customer.label != "Customer" || !reflect.DeepEqual(customer.defaults, []string{"acme"}) {
+		t.Errorf("customer = %+v", customer)
+	}
+	if project.name != "project" || !project.multi || !reflect.DeepEqual(project.dependsOn, []string{"customer"}) {
+		t.Errorf("project = %+v", project)
+	}
+	if project.query.Build() != orig.variables[1].query.Build() {
+		t.Errorf("query = %q, want %q", project.query.Build(), orig.variables[1].query.Build())
+	}
+}
@@ -71,7 +71,7 @@ func (a *AlertDetail) CollectHandlers(ctx *rendering.DashboardContext, registerH
 			return
 		}
 
-		client, err := ctx.Deps.ClickhouseClientManager.GetClient("default")
+		client, err := ctx.Deps.ClickhouseClientManager.GetClient(alertDef.ClickHouseServer())
 		if err != nil {
 			http.Error(w, "get clickhouse client: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -106,7 +106,7 @@ func (a *AlertDetail) CollectHandlers(ctx *rendering.DashboardContext, registerH
 		query = strings.ReplaceAll(query, sql.DashicaFiltersPlaceholder, filterClause)
 
 		// Add threshold info so frontend can draw threshold lines
-		resolvedAlertIf, err := json.Marshal(alertDef.AlertIf)
+		resolvedAlertIf, err := json.Marshal(alertDef.Thresholds())
 		if err != nil {
 			http.Error(w, "json marshal alert_if: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -31,7 +31,10 @@ func (a *AlertOverview) BuildComponents(ctx *rendering.DashboardContext) (templ.
 		a.id = ctx.NextWidgetId()
 	}
 
-	chartPropsJSON, err := json.Marshal(map[string]interface{}{})
+	chartPropsJSON, err := json.Marshal(map[string]interface{}{
+		// silences and acknowledgements are managed through the alerting API
+		"silenceApiUrl": httpserver.AlertingApiPrefix,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("alertOverview: failed to marshal chart props: %w", err)
 	}
@@ -42,14 +45,17 @@ func (a *AlertOverview) BuildComponents(ctx *rendering.DashboardContext) (templ.
 const alertOverviewQuery = `
 WITH
     (leadInFrame(timestamp) OVER (
-        PARTITION BY alert_id_group, alert_id_key
+        PARTITION BY alert_id_group, alert_id_key, alert_labels
         ORDER BY timestamp
         ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING
         )) as end_ts_expr
 SELECT
-    concat(alert_id_group, '#', alert_id_key) as alert_id,
+    concat(alert_id_group, '#', alert_id_key, if(alert_labels = '', '', concat('?', alert_labels))) as alert_id,
     alert_id_group,
     alert_id_key,
+    alert_labels,
+    -- human-readable alert instance name, e.g. "http500 {host_name=web1}"
+    if(alert_labels = '', alert_id_key, concat(alert_id_key, ' {', decodeURLFormComponent(replaceAll(alert_labels, '&', ', ')), '}')) as alert_instance,
     timestamp::DateTime64 as start,
     status::String as status,
     message,
@@ -59,7 +65,7 @@ FROM
 WHERE
     alert_id_group ILIKE {alert_group_pattern:String}
 ORDER BY
-    alert_id_group, alert_id_key, timestamp
+    alert_id_group, alert_id_key, alert_labels, timestamp
 `
 
 func (a *AlertOverview) CollectHandlers(ctx *rendering.DashboardContext, registerHandler handler_collector.HandlerCollector) error {
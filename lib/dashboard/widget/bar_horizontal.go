@@ -200,7 +200,7 @@ func (b *BarHorizontal) CollectHandlers(ctx *rendering.DashboardContext, registe
 		b.id = ctx.NextWidgetId()
 	}
 	query := b.buildQuery()
-	return RegisterQueryHandlers(b.id, "barHorizontal", query, ctx, registerHandler)
+	return RegisterQueryHandlers(b.id, "barHorizontal", query, ctx, registerHandler, WithExportName(b.title))
 }
 
 var _ InteractiveWidget = (*BarHorizontal)(nil)
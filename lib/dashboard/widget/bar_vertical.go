@@ -271,7 +271,7 @@ func (b *BarVertical) CollectHandlers(ctx *rendering.DashboardContext, registerH
 	}
 
 	query := b.buildQuery()
-	return RegisterQueryHandlers(b.id, "barVertical", query, ctx, registerHandler)
+	return RegisterQueryHandlers(b.id, "barVertical", query, ctx, registerHandler, WithExportName(b.title))
 }
 
 var _ InteractiveWidget = (*BarVertical)(nil)
@@ -0,0 +1,246 @@
+package widget
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/a-h/templ"
+	"github.com/sandstorm/dashica/lib/dashboard/rendering"
+	"github.com/sandstorm/dashica/lib/util/handler_collector"
+
+	"github.com/sandstorm/dashica/lib/dashboard/sql"
+)
+
+// Kpi renders a single big number (e.g. "requests today"), optionally with a
+// sparkline, the change against an earlier period and a threshold color.
+type Kpi struct {
+	// sql is the underlying query builder; adjust it with AdjustQuery. The
+	// query must aggregate to a single value (e.g. sql.Count()).
+	sql sql.SqlQueryable
+	// value is the aggregated measure shown as the big number.
+	value sql.SqlField `dashica-gen:"role=measure"`
+	// sparkline is the time bucket of the sparkline drawn below the number,
+	// e.g. sql.AutoBucket("timestamp"). The total is computed WITH ROLLUP over
+	// the buckets. Zero value: no sparkline.
+	sparkline sql.TimestampedField
+	// unit formats the number. Zero value: a plain number with SI prefix
+	// (1.2k, 3.4M).
+	unit KpiUnit
+	// suffix is appended to the formatted number, e.g. "req/s".
+	suffix string
+	// compareWith shows the change (in percent) against the same time range
+	// shifted back by this offset, e.g. "1d" or "1w" (see
+	// httpserver.ParseCompareOffset). Zero value: no comparison.
+	compareWith string
+	// thresholds maps the lower bound of a step (a number) to the CSS color of
+	// the value from this bound upwards, e.g. {"0": "green", "80": "orange",
+	// "95": "red"}. Zero value: the default text color.
+	thresholds map[string]string
+	// title is the label shown above the number.
+	title string
+	// id is the stable widget id; assigned automatically when empty.
+	id string
+	// height is the widget height in pixels.
+	height int
+}
+
+// KpiUnit is the number format of a Kpi. Same enum-safety trick as StackOrder.
+// Zero value = a plain number with SI prefix.
+type KpiUnit struct{ v string }
+
+var (
+	// UnitBytes formats the value as bytes (1.5 KiB, 3.2 GiB).
+	UnitBytes = KpiUnit{"bytes"}
+	// UnitPercent formats the value as percent; 0.5 is shown as 50 %.
+	UnitPercent = KpiUnit{"percent"}
+	// UnitMilliseconds formats a duration given in milliseconds (350 ms, 1.2 s).
+	UnitMilliseconds = KpiUnit{"ms"}
+	// UnitSeconds formats a duration given in seconds (45 s, 3.5 min, 2 h).
+	UnitSeconds = KpiUnit{"s"}
+)
+
+func NewKpi(sql sql.SqlQueryable) *Kpi {
+	return &Kpi{
+		sql:    sql,
+		height: 150,
+	}
+}
+
+func (k *Kpi) Value(value sql.SqlField) *Kpi {
+	cloned := *k
+	cloned.value = value
+	return &cloned
+}
+
+// Sparkline draws the value per time bucket below the number, e.g.
+// Sparkline(sql.AutoBucket("timestamp")).
+func (k *Kpi) Sparkline(x sql.TimestampedField) *Kpi {
+	cloned := *k
+	cloned.sparkline = x
+	return &cloned
+}
+
+func (k *Kpi) Unit(unit KpiUnit) *Kpi {
+	cloned := *k
+	cloned.unit = unit
+	return &cloned
+}
+
+func (k *Kpi) Suffix(suffix string) *Kpi {
+	cloned := *k
+	cloned.suffix = suffix
+	return &cloned
+}
+
+// CompareWith shows the change against the same time range shifted back by
+// offset ("1d", "1w", or a duration like "12h"). The comparison query is derived
+// from the widget query; it is only run if the dashboard time filter applies.
+func (k *Kpi) CompareWith(offset string) *Kpi {
+	cloned := *k
+	cloned.compareWith = offset
+	return &cloned
+}
+
+// Thresholds colors the value: each key is the (numeric) lower bound of a step,
+// the value its CSS color, e.g. {"0": "green", "80": "orange", "95": "red"}.
+func (k *Kpi) Thresholds(thresholds map[string]string) *Kpi {
+	cloned := *k
+	cloned.thresholds = thresholds
+	return &cloned
+}
+
+func (k *Kpi) Title(title string) *Kpi {
+	cloned := *k
+	cloned.title = title
+	return &cloned
+}
+
+func (k *Kpi) Id(id string) *Kpi {
+	cloned := *k
+	cloned.id = id
+	return &cloned
+}
+
+func (k *Kpi) Height(height int) *Kpi {
+	cloned := *k
+	cloned.height = height
+	return &cloned
+}
+
+func (k *Kpi) AdjustQuery(opts ...sql.SqlBuilderOption) *Kpi {
+	cloned := *k
+	cloned.sql = cloned.sql.With(opts...)
+	return &cloned
+}
+
+func (k *Kpi) BuildComponents(ctx *rendering.DashboardContext) (templ.Component, error) {
+	if len(k.id) == 0 {
+		k.id = ctx.NextWidgetId()
+	}
+
+	chartProps, err := k.buildChartProps()
+	if err != nil {
+		return nil, fmt.Errorf("kpi: %w", err)
+	}
+	chartPropsJSON, err := json.Marshal(chartProps)
+	if err != nil {
+		return nil, fmt.Errorf("kpi: failed to marshal chart props: %w", err)
+	}
+
+	return chartComponent(ctx, k, k.id, "kpi", string(chartPropsJSON), k.height), nil
+}
+
+// kpiThreshold is one color step of a Kpi, as sent to the frontend.
+type kpiThreshold struct {
+	Value float64 `json:"value"`
+	Color string  `json:"color"`
+}
+
+func (k *Kpi) buildChartProps() (map[string]interface{}, error) {
+	if k.value == nil {
+		return nil, fmt.Errorf("value field is required")
+	}
+	props := make(map[string]interface{})
+
+	props["height"] = k.height
+	props["value"] = k.value.Alias()
+
+	if k.sparkline != nil {
+		props["x"] = k.sparkline.Alias()
+		props["xBucketSize"] = k.sparkline.XBucketSizeMs()
+	}
+	if k.unit.v != "" {
+		props["unit"] = k.unit.v
+	}
+	if k.suffix != "" {
+		props["suffix"] = k.suffix
+	}
+	if k.compareWith != "" {
+		if _, err := compareOffset(k.compareWith); err != nil {
+			return nil, err
+		}
+		props["compareWith"] = k.compareWith
+	}
+	if len(k.thresholds) > 0 {
+		thresholds, err := k.parseThresholds()
+		if err != nil {
+			return nil, err
+		}
+		props["thresholds"] = thresholds
+	}
+	if k.title != "" {
+		props["title"] = k.title
+	}
+
+	return props, nil
+}
+
+// parseThresholds returns the threshold steps sorted by their lower bound.
+func (k *Kpi) parseThresholds() ([]kpiThreshold, error) {
+	thresholds := make([]kpiThreshold, 0, len(k.thresholds))
+	for value, color := range k.thresholds {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("threshold '%s' is not a number", value)
+		}
+		thresholds = append(thresholds, kpiThreshold{Value: v, Color: color})
+	}
+	sort.Slice(thresholds, func(i, j int) bool {
+		return thresholds[i].Value < thresholds[j].Value
+	})
+	return thresholds, nil
+}
+
+func (k *Kpi) buildQuery() sql.SqlQueryable {
+	query := k.sql.With(sql.Select(k.value))
+
+	// One row per bucket for the sparkline, plus the ROLLUP total row (with the
+	// default bucket, i.e. 1970-01-01) for the big number.
+	if k.sparkline != nil {
+		query = query.With(
+			sql.PrependSelect(k.sparkline),
+			sql.GroupBy(k.sparkline),
+			sql.WithRollup(),
+			sql.OrderBy(k.sparkline),
+		)
+	}
+	return query
+}
+
+func (k *Kpi) CollectHandlers(ctx *rendering.DashboardContext, registerHandler handler_collector.HandlerCollector) error {
+	if len(k.id) == 0 {
+		k.id = ctx.NextWidgetId()
+	}
+
+	offset, err := compareOffset(k.compareWith)
+	if err != nil {
+		return fmt.Errorf("kpi: %w", err)
+	}
+
+	query := k.buildQuery()
+	return RegisterQueryHandlers(k.id, "kpi", query, ctx, registerHandler, WithCompareOffset(offset), WithExportName(k.title))
+}
+
+var _ InteractiveWidget = (*Kpi)(nil)
@@ -0,0 +1,155 @@
+package widget
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sandstorm/dashica/lib/dashboard/sql"
+)
+
+func TestKpi_BuildChartProps(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(*Kpi) *Kpi
+		expected map[string]interface{}
+	}{
+		{
+			name: "Value only",
+			setup: func(k *Kpi) *Kpi {
+				return k.Value(sql.Count())
+			},
+			expected: map[string]interface{}{
+				"height": float64(150),
+				"value":  "cnt",
+			},
+		},
+		{
+			name: "With sparkline, unit, comparison and thresholds",
+			setup: func(k *Kpi) *Kpi {
+				return k.Value(sql.Field("avg(duration_ms)").WithAlias("latency")).
+					Sparkline(sql.Timestamp15Min()).
+					Unit(UnitMilliseconds).
+					Suffix("p50").
+					CompareWith("1w").
+					Thresholds(map[string]string{"500": "orange", "0": "green", "1000": "red"}).
+					Title("Latency")
+			},
+			expected: map[string]interface{}{
+				"height":      float64(150),
+				"value":       "latency",
+				"x":           "time",
+				"xBucketSize": float64(15 * 60 * 1000),
+				"unit":        "ms",
+				"suffix":      "p50",
+				"compareWith": "1w",
+				"thresholds": []interface{}{
+					map[string]interface{}{"value": float64(0), "color": "green"},
+					map[string]interface{}{"value": float64(500), "color": "orange"},
+					map[string]interface{}{"value": float64(1000), "color": "red"},
+				},
+				"title": "Latency",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			widget := tt.setup(NewKpi(sql.New(sql.From("test_table"))))
+
+			props, err := widget.buildChartProps()
+			if err != nil {
+				t.Fatalf("buildChartProps: %v", err)
+			}
+			propsJSON, err := json.Marshal(props)
+			if err != nil {
+				t.Fatalf("Failed to marshal props: %v", err)
+			}
+
+			var actualProps map[string]interface{}
+			if err := json.Unmarshal(propsJSON, &actualProps); err != nil {
+				t.Fatalf("Failed to unmarshal props: %v", err)
+			}
+
+			if !reflect.DeepEqual(tt.expected, actualProps) {
+				t.Errorf("Props mismatch\n\nExpected:\n%v\n\nActual:\n%v", tt.expected, actualProps)
+			}
+		})
+	}
+}
+
+func TestKpi_BuildChartPropsErrors(t *testing.T) {
+	base := NewKpi(sql.New(sql.From("test_table")))
+	for name, tc := range map[string]struct {
+		widget  *Kpi
+		wantErr string
+	}{
+		"missing value":     {base, "value field is required"},
+		"invalid offset":    {base.Value(sql.Count()).CompareWith("yesterday"), "invalid comparison offset"},
+		"invalid threshold": {base.Value(sql.Count()).Thresholds(map[string]string{"high": "red"}), "threshold 'high' is not a number"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := tc.widget.buildChartProps()
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestKpi_SQLGeneration(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       func(*Kpi) *Kpi
+		expectedSQL string
+	}{
+		{
+			name: "Value only",
+			setup: func(k *Kpi) *Kpi {
+				return k.Value(sql.Count())
+			},
+			expectedSQL: `-- WARNING: This is an auto-generated query file, generated from TODO.
+-- DO NOT MODIFY MANUALLY; as changes will be overwritten
+SELECT
+    count(*) AS cnt
+FROM
+    events
+WHERE
+    (timestamp > now() - INTERVAL 1 DAY);`,
+		},
+		{
+			name: "With sparkline",
+			setup: func(k *Kpi) *Kpi {
+				return k.Value(sql.Count()).Sparkline(sql.Timestamp15Min())
+			},
+			expectedSQL: `-- WARNING: This is an auto-generated query file, generated from TODO.
+-- DO NOT MODIFY MANUALLY; as changes will be overwritten
+SELECT
+    toStartOfFifteenMinutes(timestamp)::DateTime64 AS time,
+    count(*) AS cnt
+FROM
+    events
+WHERE
+    (timestamp > now() - INTERVAL 1 DAY)
+GROUP BY
+    time WITH ROLLUP
+ORDER BY
+    time;`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			widget := tt.setup(NewKpi(newTimeLineTestBaseQuery()))
+
+			actualSQL := widget.buildQuery().Build()
+			if actualSQL != tt.expectedSQL {
+				t.Errorf("SQL mismatch\n\nExpected:\n%s\n\nActual:\n%s\n\nDiff:\n%s",
+					tt.expectedSQL,
+					actualSQL,
+					diffStrings(tt.expectedSQL, actualSQL))
+			}
+		})
+	}
+}
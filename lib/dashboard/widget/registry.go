@@ -311,6 +311,7 @@ func init() {
 	Register("timeHeatmap", CategoryChart, func() WidgetDefinition { return NewTimeHeatmap(nil) })
 	Register("timeHeatmapOrdinal", CategoryChart, func() WidgetDefinition { return NewTimeHeatmapOrdinal(nil) })
 	Register("stats", CategoryChart, func() WidgetDefinition { return NewStats(nil) })
+	Register("kpi", CategoryChart, func() WidgetDefinition { return NewKpi(nil) })
 	Register("table", CategoryChart, func() WidgetDefinition { return NewTable(nil) })
 	Register("markdown", CategoryChart, func() WidgetDefinition { return NewMarkdown() })
 	Register("grid", CategoryContainer, func() WidgetDefinition { return NewGrid() })
@@ -84,7 +84,8 @@ func TestRoundTrip_stableJSON(t *testing.T) {
 			Title("Line").Height(200).
 			X(sql.AutoBucket("timestamp")).
 			Y(sql.Count()).
-			Stroke("level"),
+			Stroke("level").
+			CompareWith("1w"),
 		"barVertical": NewBarVertical(baseQuery).
 			Title("Bars").Height(200).
 			X(sql.Enum("level")).
@@ -106,7 +107,19 @@ func TestRoundTrip_stableJSON(t *testing.T) {
 		"stats": NewStats(baseQuery).
 			TitleField(sql.Field("level")).
 			FillField(sql.Count()),
-		"table": NewTable(baseQuery).Title("Rows").Height(300).Limit(50),
+		"kpi": NewKpi(baseQuery).
+			Title("Errors").
+			Value(sql.Count()).
+			Sparkline(sql.AutoBucket("timestamp")).
+			Unit(UnitPercent).
+			CompareWith("1d").
+			Thresholds(map[string]string{"0": "green", "100": "red"}),
+		"table": NewTable(baseQuery).Title("Rows").Height(300).Limit(50).
+			PageSize(100).
+			ColumnFormats(map[string]string{"bytes": "bytes", "payload": "json"}).
+			CellColors(map[string]string{"status": "status_color"}).
+			HiddenColumns("id").
+			ColumnLinks(map[string]string{"host": "/dashboards/host?host_name={host}"}),
 		"markdown": NewMarkdown().
 			Content("# Hello").Title("Docs"),
 		"grid": NewGrid().
@@ -111,12 +111,9 @@ func serveSpeedscope(query sql.SqlQueryable, deps rendering.Dependencies, w http
 	opts.Settings["format_custom_field_delimiter"] = " "
 	opts.Settings["date_time_input_format"] = "best_effort"
 
-	if paramsStr := r.URL.Query().Get("params"); paramsStr != "" {
-		var params map[string]string
-		if err := json.Unmarshal([]byte(paramsStr), &params); err != nil {
-			return fmt.Errorf("unmarshalling params: %w", err)
-		}
-		opts.Parameters = params
+	opts.Parameters, err = httpserver.QueryParameters(r)
+	if err != nil {
+		return err
 	}
 
 	q := query
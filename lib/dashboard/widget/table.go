@@ -3,6 +3,9 @@ package widget
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/a-h/templ"
 	"github.com/sandstorm/dashica/lib/dashboard/rendering"
@@ -23,8 +26,34 @@ type Table struct {
 	height int
 	// limit caps the number of rows fetched via a SQL LIMIT clause.
 	limit int
+	// pageSize > 0 loads the rows page by page: sorting and paging run in
+	// ClickHouse (ORDER BY / LIMIT / OFFSET), so large tables stay responsive;
+	// limit is not applied then. Zero value: all rows (up to limit) at once,
+	// sorted and paged in the browser.
+	pageSize int
+	// columnFormats maps a column to its format: "bytes", "percent" (0.5 is
+	// shown as 50 %), "ms" / "s" (durations), "timestamp" (DateTime or UNIX
+	// seconds / milliseconds) or "json" (pretty-printed). Zero value: the
+	// values as returned by the query.
+	columnFormats map[string]string
+	// cellColors maps a column to the query column holding the CSS background
+	// color of its cells, e.g. {"status": "status_color"} with
+	// multiIf(status >= 500, 'red', status >= 400, 'orange', '') AS status_color.
+	// The color columns are hidden.
+	cellColors map[string]string
+	// hiddenColumns are not shown as table columns (but in the record details),
+	// e.g. ids only needed for columnLinks.
+	hiddenColumns []string
+	// columnLinks turns the cells of a column into links; the value is a URL
+	// template whose {column} placeholders are replaced by the row, e.g.
+	// {"host": "/dashboards/host?host_name={host}"}. For links to a dashboard,
+	// the query parameters become its widget params and the time range is kept.
+	columnLinks map[string]string
 }
 
+// tableColumnFormats are the supported values of Table.columnFormats.
+var tableColumnFormats = []string{"bytes", "json", "ms", "percent", "s", "timestamp"}
+
 func NewTable(sql sql.SqlQueryable) *Table {
 	return &Table{
 		sql:    sql,
@@ -57,6 +86,44 @@ func (b *Table) Limit(limit int) *Table {
 	return &cloned
 }
 
+// PageSize loads the rows page by page, sorted in ClickHouse. Use it for large
+// (log) tables; Limit does not apply then.
+func (b *Table) PageSize(pageSize int) *Table {
+	cloned := *b
+	cloned.pageSize = pageSize
+	return &cloned
+}
+
+// ColumnFormats sets the format of columns, e.g.
+// {"bytes_sent": "bytes", "duration_ms": "ms", "payload": "json"}.
+func (b *Table) ColumnFormats(formats map[string]string) *Table {
+	cloned := *b
+	cloned.columnFormats = formats
+	return &cloned
+}
+
+// CellColors colors the cells of a column by another column of the query
+// holding a CSS color, e.g. {"status": "status_color"}.
+func (b *Table) CellColors(colors map[string]string) *Table {
+	cloned := *b
+	cloned.cellColors = colors
+	return &cloned
+}
+
+func (b *Table) HiddenColumns(columns ...string) *Table {
+	cloned := *b
+	cloned.hiddenColumns = columns
+	return &cloned
+}
+
+// ColumnLinks turns the cells of a column into links, e.g. to drill down into
+// another dashboard: {"host": "/dashboards/host?host_name={host}"}.
+func (b *Table) ColumnLinks(links map[string]string) *Table {
+	cloned := *b
+	cloned.columnLinks = links
+	return &cloned
+}
+
 func (b *Table) AdjustQuery(opts ...sql.SqlBuilderOption) *Table {
 	cloned := *b
 	cloned.sql = cloned.sql.With(opts...)
@@ -68,7 +135,10 @@ func (b *Table) BuildComponents(ctx *rendering.DashboardContext) (templ.Componen
 		b.id = ctx.NextWidgetId()
 	}
 
-	chartProps := b.buildChartProps()
+	chartProps, err := b.buildChartProps()
+	if err != nil {
+		return nil, fmt.Errorf("table: %w", err)
+	}
 	chartPropsJSON, err := json.Marshal(chartProps)
 	if err != nil {
 		return nil, fmt.Errorf("table: failed to marshal chart props: %w", err)
@@ -77,7 +147,7 @@ func (b *Table) BuildComponents(ctx *rendering.DashboardContext) (templ.Componen
 	return chartComponent(ctx, b, b.id, "table", string(chartPropsJSON), b.height), nil
 }
 
-func (b *Table) buildChartProps() map[string]interface{} {
+func (b *Table) buildChartProps() (map[string]interface{}, error) {
 	props := make(map[string]interface{})
 
 	// Required fields
@@ -87,8 +157,36 @@ func (b *Table) buildChartProps() map[string]interface{} {
 	if b.title != "" {
 		props["title"] = b.title
 	}
+	if b.pageSize > 0 {
+		props["pageSize"] = b.pageSize
+	}
+	if len(b.columnFormats) > 0 {
+		for column, format := range b.columnFormats {
+			if !slices.Contains(tableColumnFormats, format) {
+				return nil, fmt.Errorf("column '%s': unknown format '%s', supported: %s", column, format, strings.Join(tableColumnFormats, ", "))
+			}
+		}
+		props["columnFormats"] = b.columnFormats
+	}
+	if len(b.cellColors) > 0 {
+		props["cellColors"] = b.cellColors
+	}
+	// the color columns are never shown
+	hidden := slices.Clone(b.hiddenColumns)
+	for _, colorColumn := range b.cellColors {
+		if !slices.Contains(hidden, colorColumn) {
+			hidden = append(hidden, colorColumn)
+		}
+	}
+	if len(hidden) > 0 {
+		sort.Strings(hidden)
+		props["hiddenColumns"] = hidden
+	}
+	if len(b.columnLinks) > 0 {
+		props["columnLinks"] = b.columnLinks
+	}
 
-	return props
+	return props, nil
 }
 
 func (b *Table) CollectHandlers(ctx *rendering.DashboardContext, registerHandler handler_collector.HandlerCollector) error {
@@ -96,12 +194,13 @@ func (b *Table) CollectHandlers(ctx *rendering.DashboardContext, registerHandler
 		b.id = ctx.NextWidgetId()
 	}
 
-	// Build the SQL query
-	query := b.sql.With(
-		sql.Limit(b.limit),
-	)
+	// Build the SQL query; a paginated table is limited per page
+	query := b.sql
+	if b.pageSize <= 0 {
+		query = query.With(sql.Limit(b.limit))
+	}
 
-	return RegisterQueryHandlers(b.id, "table", query, ctx, registerHandler)
+	return RegisterQueryHandlers(b.id, "table", query, ctx, registerHandler, WithExportName(b.title), WithPageSize(b.pageSize))
 }
 
 var _ InteractiveWidget = (*Table)(nil)
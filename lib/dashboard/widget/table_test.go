@@ -1,12 +1,35 @@
 package widget
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/clickhouse"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/sandstorm/dashica/lib/dashboard/rendering"
 	"github.com/sandstorm/dashica/lib/dashboard/sql"
+	"github.com/sandstorm/dashica/lib/httpserver"
 )
 
+// newTestDashboardContext returns a dashboard context whose "default" ClickHouse server is answered by handler.
+func newTestDashboardContext(t *testing.T, handler http.HandlerFunc) *rendering.DashboardContext {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &rendering.DashboardContext{
+		Deps: rendering.Dependencies{
+			Logger: zerolog.Nop(),
+			ClickhouseClientManager: clickhouse.NewManager(&config.Config{
+				ClickHouse: map[string]config.ClickHouseConfig{"default": {URL: server.URL}},
+			}, zerolog.Nop()),
+		},
+	}
+}
+
 func TestTableDefaultLimit(t *testing.T) {
 	query := sql.New(sql.From("test_table"))
 	table := NewTable(query)
@@ -87,3 +110,75 @@ func TestTableAppliesLimitToQuery(t *testing.T) {
 		t.Errorf("Expected query to contain 'LIMIT 500', got:\n%s", sqlStr)
 	}
 }
+
+func TestTable_BuildChartProps(t *testing.T) {
+	table := NewTable(sql.New(sql.From("logs"))).
+		Title("Logs").
+		PageSize(100).
+		ColumnFormats(map[string]string{"bytes_sent": "bytes", "payload": "json"}).
+		CellColors(map[string]string{"status": "status_color"}).
+		HiddenColumns("id").
+		ColumnLinks(map[string]string{"host": "/dashboards/host?host_name={host}"})
+
+	props, err := table.buildChartProps()
+	if err != nil {
+		t.Fatalf("buildChartProps: %v", err)
+	}
+	propsJSON, err := json.Marshal(props)
+	if err != nil {
+		t.Fatalf("Failed to marshal props: %v", err)
+	}
+	var actualProps map[string]interface{}
+	if err := json.Unmarshal(propsJSON, &actualProps); err != nil {
+		t.Fatalf("Failed to unmarshal props: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"height":        float64(500),
+		"title":         "Logs",
+		"pageSize":      float64(100),
+		"columnFormats": map[string]interface{}{"bytes_sent": "bytes", "payload": "json"},
+		"cellColors":    map[string]interface{}{"status": "status_color"},
+		// the color column is hidden as well
+		"hiddenColumns": []interface{}{"id", "status_color"},
+		"columnLinks":   map[string]interface{}{"host": "/dashboards/host?host_name={host}"},
+	}
+	if !reflect.DeepEqual(expected, actualProps) {
+		t.Errorf("Props mismatch\n\nExpected:\n%v\n\nActual:\n%v", expected, actualProps)
+	}
+}
+
+func TestTable_BuildChartPropsUnknownFormat(t *testing.T) {
+	table := NewTable(sql.New(sql.From("logs"))).ColumnFormats(map[string]string{"size": "megabytes"})
+
+	_, err := table.buildChartProps()
+	if err == nil || !strings.Contains(err.Error(), "column 'size': unknown format 'megabytes'") {
+		t.Fatalf("error = %v, want unknown format", err)
+	}
+}
+
+func TestTable_PaginationReplacesLimit(t *testing.T) {
+	// only EXPLAIN queries are sent by the debug endpoint
+	ctx := newTestDashboardContext(t, func(w http.ResponseWriter, r *http.Request) {})
+	collector := newRecordingCollector()
+	table := NewTable(sql.New(sql.From("logs"))).Limit(500).PageSize(100)
+	if err := table.CollectHandlers(ctx, collector); err != nil {
+		t.Fatalf("CollectHandlers: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	collector.handlers["1/debug"].ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/1/debug?page=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var debugInfo httpserver.DebugInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &debugInfo); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if strings.Contains(debugInfo.Query, "LIMIT 500") {
+		t.Errorf("the limit must not be applied to a paginated table:\n%s", debugInfo.Query)
+	}
+	if !strings.HasSuffix(debugInfo.Query, "\nLIMIT 101 OFFSET 100") {
+		t.Errorf("expected the second page, got:\n%s", debugInfo.Query)
+	}
+}
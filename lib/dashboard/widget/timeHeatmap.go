@@ -178,7 +178,7 @@ func (h *TimeHeatmap) CollectHandlers(ctx *rendering.DashboardContext, registerH
 	}
 
 	query := h.buildQuery()
-	return RegisterQueryHandlers(h.id, "timeHeatmap", query, ctx, registerHandler)
+	return RegisterQueryHandlers(h.id, "timeHeatmap", query, ctx, registerHandler, WithExportName(h.title))
 }
 
 var _ InteractiveWidget = (*TimeHeatmap)(nil)
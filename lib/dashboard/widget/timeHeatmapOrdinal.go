@@ -212,7 +212,7 @@ func (h *TimeHeatmapOrdinal) CollectHandlers(ctx *rendering.DashboardContext, re
 	}
 
 	query := h.buildQuery()
-	return RegisterQueryHandlers(h.id, "timeHeatmapOrdinal", query, ctx, registerHandler)
+	return RegisterQueryHandlers(h.id, "timeHeatmapOrdinal", query, ctx, registerHandler, WithExportName(h.title))
 }
 
 var _ InteractiveWidget = (*TimeHeatmapOrdinal)(nil)
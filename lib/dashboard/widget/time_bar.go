@@ -47,6 +47,11 @@ type TimeBar struct {
 	color *color.ColorScale
 	// tipChannels adds extra labeled channels to the hover tooltip.
 	tipChannels map[string]string
+	// compareWith additionally runs the query over the time range shifted back
+	// by this offset, e.g. "1d" or "1w" (see httpserver.ParseCompareOffset), and
+	// draws it re-aligned onto the current x axis as dashed line of the bucket totals.
+	// Zero value: no comparison.
+	compareWith string
 	// stack configures the Observable Plot stack transform (order, offset,
 	// reverse) applied to the fill series.
 	stack StackOptions `dashica-gen:"method=StackOptions"`
@@ -207,6 +212,16 @@ func (b *TimeBar) TipChannels(channels map[string]string) *TimeBar {
 	return &cloned
 }
 
+// CompareWith overlays the same query over the time range shifted back by
+// offset ("1d", "1w", or a duration like "12h") as dashed line of the bucket totals, e.g. to
+// judge whether a spike is normal for this time of the week. The comparison
+// is only run if the dashboard time filter applies.
+func (b *TimeBar) CompareWith(offset string) *TimeBar {
+	cloned := *b
+	cloned.compareWith = offset
+	return &cloned
+}
+
 func (b *TimeBar) AdjustQuery(opts ...sql.SqlBuilderOption) *TimeBar {
 	cloned := *b
 	cloned.sql = cloned.sql.With(opts...)
@@ -277,6 +292,9 @@ func (b *TimeBar) buildChartProps() map[string]interface{} {
 	if len(b.tipChannels) > 0 {
 		props["tip"] = map[string]interface{}{"channels": b.tipChannels}
 	}
+	if b.compareWith != "" {
+		props["compareWith"] = b.compareWith
+	}
 	if b.stack.Order.v != "" {
 		props["order"] = b.stack.Order.v
 	}
@@ -327,8 +345,13 @@ func (b *TimeBar) CollectHandlers(ctx *rendering.DashboardContext, registerHandl
 		b.id = ctx.NextWidgetId()
 	}
 
+	offset, err := compareOffset(b.compareWith)
+	if err != nil {
+		return fmt.Errorf("timeBar: %w", err)
+	}
+
 	query := b.buildQuery()
-	return RegisterQueryHandlers(b.id, "timeBar", query, ctx, registerHandler)
+	return RegisterQueryHandlers(b.id, "timeBar", query, ctx, registerHandler, WithCompareOffset(offset), WithExportName(b.title))
 }
 
 var _ InteractiveWidget = (*TimeBar)(nil)
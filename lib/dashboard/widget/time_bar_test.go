@@ -2,6 +2,7 @@ package widget
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/sandstorm/dashica/lib/dashboard/color"
@@ -57,6 +58,21 @@ func TestTimeBar_BuildChartProps(t *testing.T) {
 				"title":       "Events Over Time",
 			},
 		},
+		{
+			name: "With comparison to the previous week",
+			setup: func(b *TimeBar) *TimeBar {
+				return b.X(sql.Timestamp15Min()).
+					Y(sql.Count()).
+					CompareWith("1w")
+			},
+			expected: map[string]interface{}{
+				"height":      float64(200),
+				"x":           "time",
+				"xBucketSize": float64(15 * 60 * 1000),
+				"y":           "cnt",
+				"compareWith": "1w",
+			},
+		},
 		{
 			name: "With custom dimensions",
 			setup: func(b *TimeBar) *TimeBar {
@@ -645,3 +661,16 @@ func TestTimeBar_BuildComponents_AutoGeneratesId(t *testing.T) {
 		t.Error("Expected widget.id to be auto-generated, but it was empty")
 	}
 }
+
+func TestTimeBar_CollectHandlers_InvalidCompareOffset(t *testing.T) {
+	widget := NewTimeBar(newTimeBarTestBaseQuery()).
+		X(sql.Timestamp15Min()).
+		Y(sql.Count()).
+		CompareWith("last week").
+		Id("widget-1")
+
+	err := widget.CollectHandlers(&rendering.DashboardContext{}, newRecordingCollector())
+	if err == nil || !strings.Contains(err.Error(), "invalid comparison offset 'last week'") {
+		t.Fatalf("error = %v, want invalid comparison offset", err)
+	}
+}
@@ -55,6 +55,11 @@ type TimeLine struct {
 	color *color.ColorScale
 	// tipChannels adds extra labeled channels to the hover tooltip.
 	tipChannels map[string]string
+	// compareWith additionally runs the query over the time range shifted back
+	// by this offset, e.g. "1d" or "1w" (see httpserver.ParseCompareOffset), and
+	// draws it re-aligned onto the current x axis as dashed line.
+	// Zero value: no comparison.
+	compareWith string
 	// fillStep makes the x (time) axis use ClickHouse `WITH FILL STEP <step>`, so
 	// empty time buckets are synthesized instead of the line interpolating across
 	// them. It is a raw interval expression, e.g. "toIntervalHour(1)". Any
@@ -191,6 +196,16 @@ func (b *TimeLine) WithFillStep(step string) *TimeLine {
 	return &cloned
 }
 
+// CompareWith overlays the same query over the time range shifted back by
+// offset ("1d", "1w", or a duration like "12h") as dashed line, e.g. to
+// judge whether a spike is normal for this time of the week. The comparison
+// is only run if the dashboard time filter applies.
+func (b *TimeLine) CompareWith(offset string) *TimeLine {
+	cloned := *b
+	cloned.compareWith = offset
+	return &cloned
+}
+
 func (b *TimeLine) AdjustQuery(opts ...sql.SqlBuilderOption) *TimeLine {
 	cloned := *b
 	cloned.sql = cloned.sql.With(opts...)
@@ -257,6 +272,9 @@ func (b *TimeLine) buildChartProps() map[string]interface{} {
 	if len(b.tipChannels) > 0 {
 		props["tip"] = map[string]interface{}{"channels": b.tipChannels}
 	}
+	if b.compareWith != "" {
+		props["compareWith"] = b.compareWith
+	}
 
 	return props
 }
@@ -314,8 +332,13 @@ func (b *TimeLine) CollectHandlers(ctx *rendering.DashboardContext, registerHand
 		b.id = ctx.NextWidgetId()
 	}
 
+	offset, err := compareOffset(b.compareWith)
+	if err != nil {
+		return fmt.Errorf("timeLine: %w", err)
+	}
+
 	query := b.buildQuery()
-	return RegisterQueryHandlers(b.id, "timeLine", query, ctx, registerHandler)
+	return RegisterQueryHandlers(b.id, "timeLine", query, ctx, registerHandler, WithCompareOffset(offset), WithExportName(b.title))
 }
 
 var _ InteractiveWidget = (*TimeLine)(nil)
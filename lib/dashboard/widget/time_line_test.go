@@ -55,6 +55,21 @@ func TestTimeLine_BuildChartProps(t *testing.T) {
 				"stroke":      "#ff0000",
 			},
 		},
+		{
+			name: "With comparison to the previous day",
+			setup: func(b *TimeLine) *TimeLine {
+				return b.X(sql.Timestamp15Min()).
+					Y(sql.Count()).
+					CompareWith("1d")
+			},
+			expected: map[string]interface{}{
+				"height":      float64(150),
+				"x":           "time",
+				"xBucketSize": float64(15 * 60 * 1000),
+				"y":           "cnt",
+				"compareWith": "1d",
+			},
+		},
 		{
 			name: "With field stroke, facets, color, and tooltip channels",
 			setup: func(b *TimeLine) *TimeLine {
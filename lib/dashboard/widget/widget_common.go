@@ -1,10 +1,13 @@
 package widget
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/a-h/templ"
+	"github.com/sandstorm/dashica/lib/clickhouse"
 	"github.com/sandstorm/dashica/lib/dashboard/rendering"
 	"github.com/sandstorm/dashica/lib/dashboard/sql"
 	"github.com/sandstorm/dashica/lib/httpserver"
@@ -50,24 +53,71 @@ type InteractiveWidget interface {
 	CollectHandlers(ctx *rendering.DashboardContext, registerHandler handler_collector.HandlerCollector) error
 }
 
-// RegisterQueryHandlers is a helper function that registers both query and debug endpoints for a widget
+// QueryHandlerOption configures the httpserver.QueryHandler of a widget, see RegisterQueryHandlers.
+type QueryHandlerOption func(*httpserver.QueryHandler)
+
+// WithCompareOffset additionally runs the widget query over the time range shifted back by offset, see
+// httpserver.QueryHandler.CompareOffset. 0 disables the comparison.
+func WithCompareOffset(offset time.Duration) QueryHandlerOption {
+	return func(qh *httpserver.QueryHandler) {
+		qh.CompareOffset = offset
+	}
+}
+
+// WithExportName sets the base of the file name of exports (see httpserver.QueryHandler.HandleExport), usually the
+// widget title. Defaults to the widget id.
+func WithExportName(name string) QueryHandlerOption {
+	return func(qh *httpserver.QueryHandler) {
+		qh.ExportName = name
+	}
+}
+
+// WithPageSize paginates the query result, see httpserver.QueryHandler.PageSize. 0 disables pagination.
+func WithPageSize(pageSize int) QueryHandlerOption {
+	return func(qh *httpserver.QueryHandler) {
+		qh.PageSize = pageSize
+	}
+}
+
+// compareOffset parses the compareWith option of a widget (see httpserver.ParseCompareOffset); "" disables the
+// comparison.
+func compareOffset(compareWith string) (time.Duration, error) {
+	if compareWith == "" {
+		return 0, nil
+	}
+	return httpserver.ParseCompareOffset(compareWith)
+}
+
+// RegisterQueryHandlers is a helper function that registers the query, debug and export endpoints for a widget
 // widgetId: the unique identifier for the widget (used to generate endpoint paths)
 // widgetName: the name of the widget type (used in error messages)
 // query: the SQL query to execute
 // ctx: the dashboard rendering context
 // registerHandler: the handler collector to register handlers with
-func RegisterQueryHandlers(widgetId, widgetName string, query sql.SqlQueryable, ctx *rendering.DashboardContext, registerHandler handler_collector.HandlerCollector) error {
+// opts: optional QueryHandler settings, e.g. WithCompareOffset or WithExportName
+func RegisterQueryHandlers(widgetId, widgetName string, query sql.SqlQueryable, ctx *rendering.DashboardContext, registerHandler handler_collector.HandlerCollector, opts ...QueryHandlerOption) error {
 	qh := httpserver.QueryHandler{
 		ClickhouseClientManager: ctx.Deps.ClickhouseClientManager,
 		Logger:                  ctx.Deps.Logger,
 		FileSystem:              ctx.Deps.FileSystem,
+		// queries built in Explore run with the stricter Explore guards
+		Untrusted: ctx.UntrustedContent,
 	}
+	for _, opt := range opts {
+		opt(&qh)
+	}
+	if qh.ExportName == "" {
+		qh.ExportName = widgetId
+	}
+	// for the query log
+	source := clickhouse.QuerySource{Dashboard: ctx.CurrentHandlerUrl, Widget: widgetId, WidgetType: widgetName}
 
 	// Register query endpoint
 	err := registerHandler.Handle(widgetId+"/query", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(clickhouse.WithQuerySource(r.Context(), source))
 		err := qh.HandleQuery(query, w, r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), queryErrorStatus(err))
 		}
 	}))
 	if err != nil {
@@ -76,9 +126,22 @@ func RegisterQueryHandlers(widgetId, widgetName string, query sql.SqlQueryable,
 
 	// Register debug endpoint
 	err = registerHandler.Handle(widgetId+"/debug", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(clickhouse.WithQuerySource(r.Context(), source))
 		err := qh.HandleDebug(query, w, r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), queryErrorStatus(err))
+		}
+	}))
+	if err != nil {
+		return fmt.Errorf("%s: %w", widgetName, err)
+	}
+
+	// Register export endpoint (?format=csv|parquet|jsonl)
+	err = registerHandler.Handle(widgetId+"/export", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(clickhouse.WithQuerySource(r.Context(), source))
+		err := qh.HandleExport(query, w, r)
+		if err != nil {
+			http.Error(w, err.Error(), queryErrorStatus(err))
 		}
 	}))
 	if err != nil {
@@ -87,3 +150,12 @@ func RegisterQueryHandlers(widgetId, widgetName string, query sql.SqlQueryable,
 
 	return nil
 }
+
+// queryErrorStatus is 422 if a query guard tripped (the user has to change the query), and 500 otherwise.
+func queryErrorStatus(err error) int {
+	var guardErr *clickhouse.QueryGuardError
+	if errors.As(err, &guardErr) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
@@ -53,6 +53,8 @@ func (e *exploreImpl) handleValues(w http.ResponseWriter, r *http.Request) error
 		Count json.Number `json:"count"`
 	}
 	opts := clickhouse.DefaultQueryOptions()
+	// table and column come from the browser; a GROUP BY over a huge table must not run unbounded
+	opts.Untrusted = true
 	res, err := clickhouse.QueryJSON[row](r.Context(), client, query, opts)
 	if err != nil {
 		return fmt.Errorf("querying values: %w", err)
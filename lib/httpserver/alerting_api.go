@@ -0,0 +1,191 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	alerting2 "github.com/sandstorm/dashica/lib/alerting"
+	"github.com/sandstorm/dashica/lib/auth"
+	"github.com/sandstorm/dashica/lib/config"
+)
+
+// AlertingApiPrefix is the URL prefix the alerting API (see NewAlertingApiHandler) is mounted at.
+const AlertingApiPrefix = "/api/alerting/"
+
+type alertingApiHandler struct {
+	logger            zerolog.Logger
+	alertManager      *alerting2.AlertManager
+	alertSilenceStore *alerting2.AlertSilenceStore
+}
+
+// NewAlertingApiHandler serves the API for managing silences and acknowledgements:
+//
+//	GET    /api/alerting/silences       -> active and upcoming silences / acknowledgements
+//	POST   /api/alerting/silences       -> create a silence (JSON body, see alerting.Silence)
+//	DELETE /api/alerting/silences/{id}  -> expire a silence / acknowledgement now
+//	POST   /api/alerting/acks           -> acknowledge a firing alert: {"alert_id": "...", "author": "...", "comment": "..."}
+//
+// POST requests must be sent with "Content-Type: application/json"; this way, browsers send them cross-site only
+// after a CORS preflight (which is not answered), so other sites cannot silence alerts via a plain HTML form.
+//
+// The handler does not check access itself; it is restricted via Dashica.RestrictAlertingApi.
+func NewAlertingApiHandler(logger zerolog.Logger, alertManager *alerting2.AlertManager, alertSilenceStore *alerting2.AlertSilenceStore) http.Handler {
+	h := alertingApiHandler{
+		logger:            logger,
+		alertManager:      alertManager,
+		alertSilenceStore: alertSilenceStore,
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET "+AlertingApiPrefix+"silences", errorHandler(h.listSilences))
+	mux.Handle("POST "+AlertingApiPrefix+"silences", errorHandler(h.createSilence))
+	mux.Handle("DELETE "+AlertingApiPrefix+"silences/{id}", errorHandler(h.expireSilence))
+	mux.Handle("POST "+AlertingApiPrefix+"acks", errorHandler(h.acknowledge))
+	return mux
+}
+
+// errorHandler converts a returned error into a 500 with the error text; handlers report client errors themselves.
+type errorHandler func(w http.ResponseWriter, r *http.Request) error
+
+func (h errorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h(w, r); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h alertingApiHandler) listSilences(w http.ResponseWriter, r *http.Request) error {
+	return writeJSON(w, http.StatusOK, h.alertSilenceStore.Silences(time.Now()))
+}
+
+type createSilenceRequest struct {
+	AlertIdGroup   string `json:"alert_id_group"`
+	AlertIdPattern string `json:"alert_id_pattern"`
+	// StartsAt defaults to now
+	StartsAt *config.Time `json:"starts_at"`
+	EndsAt   *config.Time `json:"ends_at"`
+	// Duration (e.g. "2h") can be given instead of EndsAt
+	Duration string `json:"duration"`
+	Author   string `json:"author"`
+	Comment  string `json:"comment"`
+}
+
+func (h alertingApiHandler) createSilence(w http.ResponseWriter, r *http.Request) error {
+	if !requireJSON(w, r) {
+		return nil
+	}
+	var req createSilenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "decoding silence: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	silence := alerting2.Silence{
+		Kind:           alerting2.SilenceKindSilence,
+		AlertIdGroup:   req.AlertIdGroup,
+		AlertIdPattern: req.AlertIdPattern,
+		StartsAt:       config.Time(time.Now()),
+		EndsAt:         req.EndsAt,
+		Author:         authorOf(r, req.Author),
+		Comment:        req.Comment,
+	}
+	if req.StartsAt != nil {
+		silence.StartsAt = *req.StartsAt
+	}
+	if req.Duration != "" {
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			http.Error(w, "invalid duration: "+err.Error(), http.StatusBadRequest)
+			return nil
+		}
+		endsAt := config.Time(time.Time(silence.StartsAt).Add(duration))
+		silence.EndsAt = &endsAt
+	}
+
+	silence, err := h.alertSilenceStore.CreateSilence(silence)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	h.logger.Info().
+		Str("silenceId", silence.Id).
+		Str("author", silence.Author).
+		Str("alertIdGroup", silence.AlertIdGroup).
+		Str("alertIdPattern", silence.AlertIdPattern).
+		Msg("silence created")
+	return writeJSON(w, http.StatusCreated, silence)
+}
+
+func (h alertingApiHandler) expireSilence(w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if err := h.alertSilenceStore.ExpireSilence(id, time.Now()); errors.Is(err, alerting2.ErrSilenceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("expiring silence: %w", err)
+	}
+	h.logger.Info().Str("silenceId", id).Str("author", authorOf(r, "")).Msg("silence expired")
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type acknowledgeRequest struct {
+	AlertId string `json:"alert_id"`
+	Author  string `json:"author"`
+	Comment string `json:"comment"`
+}
+
+func (h alertingApiHandler) acknowledge(w http.ResponseWriter, r *http.Request) error {
+	if !requireJSON(w, r) {
+		return nil
+	}
+	var req acknowledgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "decoding acknowledgement: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	ack, err := h.alertManager.Acknowledge(alerting2.AlertIdFromString(req.AlertId), authorOf(r, req.Author), req.Comment)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	h.logger.Info().Str("alertId", req.AlertId).Str("author", ack.Author).Msg("alert acknowledged")
+	return writeJSON(w, http.StatusCreated, ack)
+}
+
+// requireJSON returns true if the request body is declared as JSON; otherwise, it responds with 415.
+func requireJSON(w http.ResponseWriter, r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		return false
+	}
+	return true
+}
+
+// authorOf returns the authenticated user (so authors cannot be spoofed); otherwise the given author.
+func authorOf(r *http.Request, author string) string {
+	if user := auth.UserFromContext(r.Context()); user != nil {
+		return user.Name
+	}
+	if author != "" {
+		return author
+	}
+	if username, _, ok := r.BasicAuth(); ok {
+		return username
+	}
+	return "unknown"
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return fmt.Errorf("encoding response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/alerting"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAlertingApiTestHandler returns the alerting API backed by a fake ClickHouse, in which the alert
+// src/shop/alerts.yaml#http_500 is firing; the returned function lists the executed INSERT queries.
+func newAlertingApiTestHandler(t *testing.T) (http.Handler, func() []string) {
+	var mu sync.Mutex
+	var inserts []string
+	clickhouseClientManager := newTestClickhouseManager(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		query := string(body)
+		if strings.Contains(query, "INSERT") {
+			mu.Lock()
+			inserts = append(inserts, query)
+			mu.Unlock()
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": [{"alert_id_group": "src/shop/alerts.yaml", "alert_id_key": "http_500", "alert_labels": "", "latest_timestamp": "2025-03-01 10:00:00", "latest_status": "error", "latest_message": "too many errors"}], "rows": 1}`))
+	})
+	client, err := clickhouseClientManager.GetClient("default")
+	require.NoError(t, err)
+	alertResultStore := alerting.NewAlertResultStore(zerolog.Nop(), client)
+	require.NoError(t, alertResultStore.LoadAlertStatusIntoMemory())
+	alertSilenceStore := alerting.NewAlertSilenceStore(zerolog.Nop(), client)
+	alertManager := alerting.NewAlertManager(&config.Config{}, zerolog.Nop(), nil, nil, alertResultStore, alertSilenceStore)
+
+	return NewAlertingApiHandler(zerolog.Nop(), alertManager, alertSilenceStore), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), inserts...)
+	}
+}
+
+func serveAlertingApi(handler http.Handler, method string, path string, contentType string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, AlertingApiPrefix+path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestAlertingApi_CreateAndExpireSilence(t *testing.T) {
+	handler, inserts := newAlertingApiTestHandler(t)
+
+	recorder := serveAlertingApi(handler, http.MethodPost, "silences", "application/json; charset=utf-8",
+		`{"alert_id_group": "src/shop/*", "duration": "2h", "author": "ops", "comment": "deployment"}`)
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	var silence alerting.Silence
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &silence))
+	assert.NotEmpty(t, silence.Id)
+	assert.Equal(t, "ops", silence.Author)
+	require.NotNil(t, silence.EndsAt)
+	assert.Len(t, inserts(), 1)
+
+	recorder = serveAlertingApi(handler, http.MethodGet, "silences", "", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), silence.Id)
+
+	recorder = serveAlertingApi(handler, http.MethodDelete, "silences/"+silence.Id, "", "")
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Len(t, inserts(), 2)
+}
+
+func TestAlertingApi_Acknowledge(t *testing.T) {
+	handler, inserts := newAlertingApiTestHandler(t)
+
+	recorder := serveAlertingApi(handler, http.MethodPost, "acks", "application/json",
+		`{"alert_id": "src/shop/alerts.yaml#http_500", "author": "ops", "comment": "looking into it"}`)
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	var ack alerting.Silence
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &ack))
+	assert.Equal(t, alerting.SilenceKindAck, ack.Kind)
+	assert.Equal(t, "src/shop/alerts.yaml#http_500", ack.AlertIdPattern)
+	assert.Len(t, inserts(), 1)
+
+	t.Run("NotFiring", func(t *testing.T) {
+		recorder := serveAlertingApi(handler, http.MethodPost, "acks", "application/json", `{"alert_id": "src/shop/alerts.yaml#other"}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "is not firing")
+	})
+}
+
+// TestAlertingApi_RequiresJSON tests that POST requests which a cross-site HTML form could send are rejected.
+func TestAlertingApi_RequiresJSON(t *testing.T) {
+	handler, inserts := newAlertingApiTestHandler(t)
+
+	for _, path := range []string{"silences", "acks"} {
+		for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded", "multipart/form-data; boundary=x"} {
+			t.Run(path+" "+contentType, func(t *testing.T) {
+				recorder := serveAlertingApi(handler, http.MethodPost, path, contentType,
+					`{"alert_id_group": "*", "alert_id": "src/shop/alerts.yaml#http_500", "duration": "2h"}`)
+				assert.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+			})
+		}
+	}
+	assert.Empty(t, inserts())
+}
+
+func TestAlertingApi_ExpireUnknownSilence(t *testing.T) {
+	handler := NewAlertingApiHandler(zerolog.Nop(), nil, alerting.NewAlertSilenceStore(zerolog.Nop(), nil))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, AlertingApiPrefix+"silences/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "silence unknown: silence not found")
+}
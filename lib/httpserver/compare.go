@@ -0,0 +1,77 @@
+package httpserver
+
+import (
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sandstorm/dashica/lib/dashboard/sql"
+	querying2 "github.com/sandstorm/dashica/lib/httpserver/querying"
+)
+
+// ComparePeriodColumn is the column added to the result rows of comparing queries (see QueryHandler.CompareOffset):
+// ComparePeriodCurrent for the selected time range, ComparePeriodPrevious for the shifted one.
+const (
+	ComparePeriodColumn   = "dashica_period"
+	ComparePeriodCurrent  = "current"
+	ComparePeriodPrevious = "previous"
+)
+
+// ParseCompareOffset parses a comparison offset like "1d", "1w" or any time.ParseDuration string (e.g. "12h").
+func ParseCompareOffset(s string) (time.Duration, error) {
+	var offset time.Duration
+	var err error
+	switch {
+	case strings.HasSuffix(s, "w"):
+		offset, err = parseDays(strings.TrimSuffix(s, "w"), 7)
+	case strings.HasSuffix(s, "d"):
+		offset, err = parseDays(strings.TrimSuffix(s, "d"), 1)
+	default:
+		offset, err = time.ParseDuration(s)
+	}
+	if err != nil || offset < time.Second {
+		return 0, fmt.Errorf("invalid comparison offset '%s' (e.g. 1d, 1w, 12h)", s)
+	}
+	return offset, nil
+}
+
+func parseDays(s string, factor int) (time.Duration, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(n*factor) * 24 * time.Hour, nil
+}
+
+// compareQuery returns query (over the selected time range) UNION ALL queryObj over the time range shifted back by
+// offset, with ComparePeriodColumn telling them apart. {__from:...} / {__to:...} parameters in the shifted query are
+// renamed to __compare_from / __compare_to, which are added to params.
+func compareQuery(query string, queryObj sql.SqlQueryable, filters DashboardFilters, timeRange *querying2.TimeRange, offset time.Duration, fileSystem fs.ReadFileFS, params map[string]string) (string, error) {
+	offsetS := int64(offset / time.Second)
+	fromS := *timeRange.From/1000 - offsetS
+	toS := *timeRange.To/1000 - offsetS
+
+	// the shifted range is absolute, so the previous period has exactly the same width (and auto buckets)
+	shiftedFilters := DashboardFilters{SqlFilter: filters.SqlFilter, From: float64(fromS), To: float64(toS)}
+	previousObj := queryObj.With(sql.Where(shiftedFilters.SqlClause()))
+	previousObj, _ = previousObj.AdjustBuckets(timeRange.WidthS())
+	previous, err := sql.BuildWithFS(previousObj, fileSystem)
+	if err != nil {
+		return "", fmt.Errorf("building SQL query for the previous period: %w", err)
+	}
+	previous = strings.NewReplacer("{__from:", "{__compare_from:", "{__to:", "{__compare_to:").Replace(previous)
+	params["__compare_from"] = fmt.Sprintf("%d", fromS)
+	params["__compare_to"] = fmt.Sprintf("%d", toS)
+
+	return fmt.Sprintf("SELECT '%s' AS %s, * FROM (\n%s\n)\nUNION ALL\nSELECT '%s' AS %s, * FROM (\n%s\n)",
+		ComparePeriodCurrent, ComparePeriodColumn, trimQuery(query),
+		ComparePeriodPrevious, ComparePeriodColumn, trimQuery(previous),
+	), nil
+}
+
+// trimQuery removes the trailing semicolon, so query can be used as subquery.
+func trimQuery(query string) string {
+	return strings.TrimRight(strings.TrimSpace(query), ";")
+}
@@ -0,0 +1,51 @@
+package httpserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sandstorm/dashica/lib/dashboard/sql"
+	querying2 "github.com/sandstorm/dashica/lib/httpserver/querying"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCompareOffset(t *testing.T) {
+	for input, expected := range map[string]time.Duration{
+		"1d":  24 * time.Hour,
+		"1w":  7 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+	} {
+		offset, err := ParseCompareOffset(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, expected, offset, input)
+	}
+
+	for _, input := range []string{"", "w", "1x", "-1d", "0s", "500ms"} {
+		_, err := ParseCompareOffset(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestCompareQuery(t *testing.T) {
+	from, to := int64(1_700_000_000_000), int64(1_700_003_600_000)
+	timeRange := &querying2.TimeRange{From: &from, To: &to}
+	queryObj := sql.New(
+		sql.From("events"),
+		sql.Select(sql.Count()),
+		sql.Where("timestamp > {__from:DateTime}"),
+	)
+	params := map[string]string{}
+
+	query, err := compareQuery("SELECT 1;", queryObj, DashboardFilters{SqlFilter: "status = 500"}, timeRange, 24*time.Hour, nil, params)
+	require.NoError(t, err)
+
+	assert.Contains(t, query, "SELECT 'current' AS dashica_period, * FROM (\nSELECT 1\n)\nUNION ALL\nSELECT 'previous' AS dashica_period, * FROM (\n")
+	assert.Contains(t, query, "timestamp > {__compare_from:DateTime}")
+	assert.Contains(t, query, "timestamp >= 1699913600 AND timestamp <= 1699917200 AND (status = 500)")
+	assert.Equal(t, map[string]string{
+		"__compare_from": "1699913600",
+		"__compare_to":   "1699917200",
+	}, params)
+}
@@ -0,0 +1,115 @@
+package httpserver
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sandstorm/dashica/lib/clickhouse"
+	"github.com/sandstorm/dashica/lib/dashboard/sql"
+)
+
+// exportFormat is a download format of the export endpoint, see QueryHandler.HandleExport.
+type exportFormat struct {
+	// clickhouseFormat is the ClickHouse output format, streamed unchanged to the client.
+	clickhouseFormat string
+	extension        string
+}
+
+// exportFormats maps the "format" parameter of the export endpoint to the ClickHouse output format.
+var exportFormats = map[string]exportFormat{
+	"csv":     {clickhouseFormat: "CSVWithNames", extension: "csv"},
+	"parquet": {clickhouseFormat: "Parquet", extension: "parquet"},
+	"jsonl":   {clickhouseFormat: "JSONEachRow", extension: "jsonl"},
+}
+
+// HandleExport streams the result of the query as download in the format given by the "format" parameter (csv,
+// parquet or jsonl; default csv). Like HandleQuery, the dashboard filters, the widget params / variables and the
+// automatic time bucketing are applied; the comparison with a previous period (CompareOffset) is not exported, and
+// the query cache is bypassed.
+//
+// The file name is derived from ExportName, e.g. "Requests per Minute" -> requests-per-minute.csv.
+func (qh QueryHandler) HandleExport(queryObj sql.SqlQueryable, w http.ResponseWriter, r *http.Request) error {
+	formatName := r.URL.Query().Get("format")
+	if formatName == "" {
+		formatName = "csv"
+	}
+	format, ok := exportFormats[formatName]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown export format '%s', supported: %s", formatName, strings.Join(ExportFormats(), ", ")), http.StatusBadRequest)
+		return nil
+	}
+
+	serverId := queryObj.Database()
+	if serverId == "" {
+		serverId = "default"
+	}
+	client, err := qh.ClickhouseClientManager.GetClient(serverId)
+	if err != nil {
+		return fmt.Errorf("get clickhouse client: %w", err)
+	}
+
+	opts := clickhouse.DefaultQueryOptions()
+	opts.Format = format.clickhouseFormat
+	opts.Settings["date_time_input_format"] = "best_effort" // support ISO 8601 dates (which is used in date picker by browser)
+	opts.Compression = true                                 // exports can be large
+	opts.Untrusted = qh.Untrusted
+
+	opts.Parameters, err = QueryParameters(r)
+	if err != nil {
+		return err
+	}
+
+	filters, err := ParseDashboardFilters(r)
+	if err != nil {
+		return err
+	}
+	q := queryObj
+	if filters != nil && !queryObj.ShouldSkipFilters() {
+		// same buckets as the chart, so the export matches what the user sees
+		filtered, err := filters.Apply(r.Context(), client, queryObj, &opts)
+		if err != nil {
+			return err
+		}
+		q = filtered.Query
+	}
+	query, err := sql.BuildWithFS(q, qh.FileSystem)
+	if err != nil {
+		return fmt.Errorf("building SQL query: %w", err)
+	}
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": exportFilename(qh.ExportName, format.extension),
+	}))
+	err = client.QueryToHandler(r.Context(), query, opts, w)
+	if err != nil {
+		// if nothing was streamed yet, the error is shown instead of downloaded
+		w.Header().Del("Content-Disposition")
+		return fmt.Errorf("clickhouse query: %w", err)
+	}
+	return nil
+}
+
+// ExportFormats returns the names of the supported export formats, sorted.
+func ExportFormats() []string {
+	names := make([]string, 0, len(exportFormats))
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var nonFilenameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// exportFilename turns name (e.g. a widget title) into a file name safe for all platforms.
+func exportFilename(name string, extension string) string {
+	base := strings.Trim(nonFilenameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if base == "" {
+		base = "export"
+	}
+	return base + "." + extension
+}
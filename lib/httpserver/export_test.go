@@ -0,0 +1,103 @@
+package httpserver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/clickhouse"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/sandstorm/dashica/lib/dashboard/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClickhouseManager returns a manager whose "default" ClickHouse server is answered by handler.
+func newTestClickhouseManager(t *testing.T, handler http.HandlerFunc) *clickhouse.Manager {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return clickhouse.NewManager(&config.Config{
+		ClickHouse: map[string]config.ClickHouseConfig{"default": {URL: server.URL}},
+	}, zerolog.Nop())
+}
+
+func TestExportFilename(t *testing.T) {
+	assert.Equal(t, "requests-per-minute.csv", exportFilename("Requests per Minute", "csv"))
+	assert.Equal(t, "5xx-errors-by-host.parquet", exportFilename(" 5xx Errors / by Host! ", "parquet"))
+	assert.Equal(t, "export.jsonl", exportFilename("", "jsonl"))
+	assert.Equal(t, "export.csv", exportFilename("äöü", "csv"))
+}
+
+func TestHandleExport(t *testing.T) {
+	var receivedQuery string
+	var receivedParams url.Values
+	clickhouseClientManager := newTestClickhouseManager(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receivedQuery = string(body)
+		receivedParams = r.URL.Query()
+		if receivedParams.Get("param_fail") != "" {
+			http.Error(w, "Code: 60. DB::Exception: Unknown table", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("\"count\"\n42\n"))
+	})
+
+	qh := QueryHandler{
+		ClickhouseClientManager: clickhouseClientManager,
+		Logger:                  zerolog.Nop(),
+		ExportName:              "Requests per Minute",
+	}
+	queryObj := sql.New(
+		sql.From("http_logs"),
+		sql.Select(sql.AutoBucket("timestamp")),
+		sql.Select(sql.Count()),
+		sql.GroupBy(sql.AutoBucket("timestamp")),
+	)
+	filters := url.QueryEscape(`{"From":1700000000,"To":1700003600,"sqlFilter":"status = 500"}`)
+
+	t.Run("csv", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		err := qh.HandleExport(queryObj, rec, httptest.NewRequest(http.MethodGet, "/export?filters="+filters, nil))
+		require.NoError(t, err)
+
+		assert.Equal(t, "\"count\"\n42\n", rec.Body.String())
+		assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename=requests-per-minute.csv`, rec.Header().Get("Content-Disposition"))
+		assert.Equal(t, "CSVWithNames", receivedParams.Get("default_format"))
+		assert.Equal(t, "1700000000", receivedParams.Get("param___from"))
+		assert.Contains(t, receivedQuery, "timestamp >= 1700000000 AND timestamp <= 1700003600 AND (status = 500)")
+		// a 1h range is bucketed by minute, like in the chart
+		assert.Contains(t, receivedQuery, "toStartOfMinute(timestamp)")
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		err := qh.HandleExport(queryObj, rec, httptest.NewRequest(http.MethodGet, "/export?format=parquet&filters="+filters, nil))
+		require.NoError(t, err)
+
+		assert.Equal(t, "Parquet", receivedParams.Get("default_format"))
+		assert.Equal(t, "application/vnd.apache.parquet", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename=requests-per-minute.parquet`, rec.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		err := qh.HandleExport(queryObj, rec, httptest.NewRequest(http.MethodGet, "/export?format=xlsx", nil))
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "supported: csv, jsonl, parquet")
+	})
+
+	t.Run("query error is not downloaded", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		params := url.QueryEscape(`{"fail":"1"}`)
+		err := qh.HandleExport(queryObj, rec, httptest.NewRequest(http.MethodGet, "/export?params="+params, nil))
+		require.Error(t, err)
+
+		assert.Empty(t, rec.Header().Get("Content-Disposition"))
+	})
+}
@@ -0,0 +1,60 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// sortColumn is one column of the "sort" parameter of a paginated query (in the format of Tabulator's sorters).
+type sortColumn struct {
+	Field string `json:"field"`
+	// Dir is "asc" or "desc".
+	Dir string `json:"dir"`
+}
+
+// paginatedQuery wraps query to return the page (0-based) given by the "page" parameter of r, sorted by the "sort"
+// parameter (JSON array of sortColumn; default: the order of query). One row more than pageSize is returned, so the
+// UI knows whether there is a next page without counting all rows.
+func paginatedQuery(query string, pageSize int, r *http.Request) (string, error) {
+	page := 0
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		var err error
+		page, err = strconv.Atoi(pageStr)
+		if err != nil || page < 0 {
+			return "", fmt.Errorf("invalid page '%s'", pageStr)
+		}
+	}
+
+	var orderBy []string
+	if sortStr := r.URL.Query().Get("sort"); sortStr != "" {
+		var sortColumns []sortColumn
+		if err := json.Unmarshal([]byte(sortStr), &sortColumns); err != nil {
+			return "", fmt.Errorf("unmarshalling sort: %w", err)
+		}
+		for _, column := range sortColumns {
+			dir := strings.ToUpper(column.Dir)
+			if dir != "ASC" && dir != "DESC" {
+				return "", fmt.Errorf("invalid sort direction '%s' of column '%s'", column.Dir, column.Field)
+			}
+			orderBy = append(orderBy, quoteIdentifier(column.Field)+" "+dir)
+		}
+	}
+
+	var result strings.Builder
+	result.WriteString("SELECT * FROM (\n")
+	result.WriteString(strings.TrimRight(strings.TrimSpace(query), ";"))
+	result.WriteString("\n)")
+	if len(orderBy) > 0 {
+		result.WriteString("\nORDER BY " + strings.Join(orderBy, ", "))
+	}
+	fmt.Fprintf(&result, "\nLIMIT %d OFFSET %d", pageSize+1, page*pageSize)
+	return result.String(), nil
+}
+
+// quoteIdentifier returns name as ClickHouse identifier, e.g. `status`; the sort columns are sent by the browser.
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(strings.ReplaceAll(name, `\`, `\\`), "`", "\\`") + "`"
+}
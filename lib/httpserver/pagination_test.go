@@ -0,0 +1,36 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaginatedQuery(t *testing.T) {
+	request := func(params string) *http.Request {
+		return httptest.NewRequest(http.MethodGet, "/query?"+params, nil)
+	}
+
+	query, err := paginatedQuery("SELECT * FROM logs ORDER BY timestamp DESC;\n", 100, request(""))
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM (\nSELECT * FROM logs ORDER BY timestamp DESC\n)\nLIMIT 101 OFFSET 0", query)
+
+	sortParam := url.QueryEscape(`[{"field":"status","dir":"desc"},{"field":"host` + "`" + `name","dir":"asc"}]`)
+	query, err = paginatedQuery("SELECT * FROM logs", 100, request("page=2&sort="+sortParam))
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM (\nSELECT * FROM logs\n)\nORDER BY `status` DESC, `host\\`name` ASC\nLIMIT 101 OFFSET 200", query)
+
+	for _, params := range []string{
+		"page=-1",
+		"page=abc",
+		"sort=" + url.QueryEscape(`[{"field":"status","dir":"desc; DROP TABLE logs"}]`),
+		"sort=" + url.QueryEscape(`{"field":"status"}`),
+	} {
+		_, err := paginatedQuery("SELECT 1", 100, request(params))
+		assert.Error(t, err, params)
+	}
+}
@@ -29,7 +29,7 @@ func (qh queryAlertChartHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 		return fmt.Errorf("alert definition %s not found", alertId)
 	}
 
-	client, err := qh.clickhouseClientManager.GetClient("default")
+	client, err := qh.clickhouseClientManager.GetClient(alertDefinition.ClickHouseServer())
 	if err != nil {
 		return fmt.Errorf("get clickhouse client for %s: %w", alertDefinition.QueryPath, err)
 	}
@@ -62,7 +62,7 @@ func (qh queryAlertChartHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	}
 	query = strings.ReplaceAll(query, sql.DashicaFiltersPlaceholder, filterClause)
 
-	resolvedAlertIf, err := json.Marshal(alertDefinition.AlertIf)
+	resolvedAlertIf, err := json.Marshal(alertDefinition.Thresholds())
 	if err != nil {
 		return fmt.Errorf("json marshal: %w", err)
 	}
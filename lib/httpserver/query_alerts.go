@@ -21,14 +21,17 @@ type queryAlertsHandler struct {
 const QUERY_ALERTS_QUERY = `
 WITH
     (leadInFrame(timestamp) OVER (
-        PARTITION BY alert_id_group, alert_id_key
+        PARTITION BY alert_id_group, alert_id_key, alert_labels
         ORDER BY timestamp
         ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING
         )) as end_ts_expr
 SELECT
-    concat(alert_id_group, '#', alert_id_key) as alert_id,
+    concat(alert_id_group, '#', alert_id_key, if(alert_labels = '', '', concat('?', alert_labels))) as alert_id,
     alert_id_group,
     alert_id_key,
+    alert_labels,
+    -- human-readable alert instance name, e.g. "http500 {host_name=web1}"
+    if(alert_labels = '', alert_id_key, concat(alert_id_key, ' {', decodeURLFormComponent(replaceAll(alert_labels, '&', ', ')), '}')) as alert_instance,
     timestamp::DateTime64 as start,
     status::String as status,
     message,
@@ -37,13 +40,13 @@ SELECT
 FROM
     dashica_alert_events
 ORDER BY
-    alert_id_group, alert_id_key, timestamp
+    alert_id_group, alert_id_key, alert_labels, timestamp
 `
 
 func (qa queryAlertsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
 	if qa.devMode {
 		// rescan on every alert
-		err := qa.alertManager.DiscoverAlertDefinitions()
+		err := qa.alertManager.ReloadAlertDefinitions(r.Context())
 		if err != nil {
 			return err
 		}
@@ -20,6 +20,17 @@ type QueryHandler struct {
 	ClickhouseClientManager *clickhouse.Manager
 	Logger                  zerolog.Logger
 	FileSystem              fs.ReadFileFS
+	// Untrusted queries (built in Explore) run with the stricter Explore guards of the server.
+	Untrusted bool
+	// CompareOffset > 0 additionally runs the query over the time range shifted back by CompareOffset (e.g. one
+	// week) in the same request; the rows are told apart by ComparePeriodColumn, and the offset is returned as
+	// X-Dashica-Compare-Offset (in ms). Queries which skip the dashboard filters are not compared.
+	CompareOffset time.Duration
+	// PageSize > 0 paginates the result (e.g. of a Table): the "page" (0-based) and "sort" parameters select the
+	// LIMIT / OFFSET and ORDER BY, see paginatedQuery. HandleExport always returns all rows.
+	PageSize int
+	// ExportName is the base of the file name of HandleExport downloads, e.g. the widget title.
+	ExportName string
 }
 
 type DashboardFilters struct {
@@ -30,6 +41,16 @@ type DashboardFilters struct {
 	// LEGACY; WAS SENT FROM UI
 	From interface{}
 	To   interface{}
+
+	// nowSql replaces now() in relative time ranges; see RoundNow.
+	nowSql string
+}
+
+// RoundNow makes relative time ranges (e.g. "last 24h") end at the current time truncated to step, instead of
+// now(). So the resulting SQL stays identical for step, and repeated dashboard loads can be served from the query
+// cache.
+func (f *DashboardFilters) RoundNow(step time.Duration) {
+	f.nowSql = fmt.Sprintf("toDateTime(%d)", time.Now().Truncate(step).Unix())
 }
 
 // SqlStringForAllTables is the legacy per-table filter map used by the deprecated query
@@ -142,7 +163,61 @@ func (f *DashboardFilters) ResolveTimeRangeFromDbAsTime(ctx context.Context, cli
 	return result, nil
 }
 
+// ParseDashboardFilters returns the dashboard filters sent in the "filters" parameter of r; or nil if there are none.
+func ParseDashboardFilters(r *http.Request) (*DashboardFilters, error) {
+	rawFilters := r.URL.Query().Get("filters")
+	if rawFilters == "" {
+		return nil, nil
+	}
+	var filters DashboardFilters
+	if err := json.Unmarshal([]byte(rawFilters), &filters); err != nil {
+		return nil, fmt.Errorf("unmarshalling filters: %w", err)
+	}
+	return &filters, nil
+}
+
+// FilteredQuery is a query with the dashboard filters applied, see DashboardFilters.Apply.
+type FilteredQuery struct {
+	Query sql.SqlQueryable
+	// FilterClause is the WHERE clause added to Query; empty if there is none (or the query skips the filters).
+	FilterClause string
+	TimeRange    *querying2.TimeRange
+	// BucketSizeMs is the bucket size chosen for TimeRange; nil if the query has no automatic buckets.
+	BucketSizeMs *int64
+}
+
+// Apply applies the filters to queryObj, the same way for all dashboard queries: the SQL filter is added as WHERE
+// clause (unless queryObj skips the filters), the resolved time range is passed as __from / __to parameters in
+// opts, and the automatic buckets are adjusted to it. If opts.Cache is set, relative time ranges end at the rounded
+// current time (see RoundNow).
+func (f *DashboardFilters) Apply(ctx context.Context, client *clickhouse.Client, queryObj sql.SqlQueryable, opts *clickhouse.QueryOptions) (*FilteredQuery, error) {
+	if opts.Cache {
+		f.RoundNow(opts.CacheTTL)
+	}
+	result := &FilteredQuery{Query: queryObj}
+	if !queryObj.ShouldSkipFilters() {
+		result.FilterClause = f.SqlClause()
+		if result.FilterClause != "" {
+			result.Query = queryObj.With(sql.Where(result.FilterClause))
+		}
+	}
+
+	timeRange, err := f.ResolveTimeRangeFromDbAsTime(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("resolving time range: %w", err)
+	}
+	opts.Parameters["__from"] = fmt.Sprintf("%d", *timeRange.From/1000)
+	opts.Parameters["__to"] = fmt.Sprintf("%d", *timeRange.To/1000)
+	result.TimeRange = timeRange
+	result.Query, result.BucketSizeMs = result.Query.AdjustBuckets(timeRange.WidthS())
+	return result, nil
+}
+
 func (f *DashboardFilters) calculateLegacyFilters() {
+	now := f.nowSql
+	if now == "" {
+		now = "now()"
+	}
 	if f.TimeRange == "custom" {
 		// Format from frontend (flatpickr / brush): "YYYY-MM-DD HH:MM to YYYY-MM-DD HH:MM",
 		// interpreted in the local time zone of the server.
@@ -156,35 +231,35 @@ func (f *DashboardFilters) calculateLegacyFilters() {
 			}
 		}
 	} else if f.TimeRange == "5m" {
-		f.From = "now() - INTERVAL 5 MINUTE"
-		f.To = "now()"
+		f.From = now + " - INTERVAL 5 MINUTE"
+		f.To = now
 	} else if f.TimeRange == "15m" {
-		f.From = "now() - INTERVAL 15 MINUTE"
-		f.To = "now()"
+		f.From = now + " - INTERVAL 15 MINUTE"
+		f.To = now
 	} else if f.TimeRange == "1h" {
-		f.From = "now() - INTERVAL 1 HOUR"
-		f.To = "now()"
+		f.From = now + " - INTERVAL 1 HOUR"
+		f.To = now
 	} else if f.TimeRange == "3h" {
-		f.From = "now() - INTERVAL 3 HOUR"
-		f.To = "now()"
+		f.From = now + " - INTERVAL 3 HOUR"
+		f.To = now
 	} else if f.TimeRange == "6h" {
-		f.From = "now() - INTERVAL 6 HOUR"
-		f.To = "now()"
+		f.From = now + " - INTERVAL 6 HOUR"
+		f.To = now
 	} else if f.TimeRange == "12h" {
-		f.From = "now() - INTERVAL 12 HOUR"
-		f.To = "now()"
+		f.From = now + " - INTERVAL 12 HOUR"
+		f.To = now
 	} else if f.TimeRange == "24h" {
-		f.From = "now() - INTERVAL 1 DAY"
-		f.To = "now()"
+		f.From = now + " - INTERVAL 1 DAY"
+		f.To = now
 	} else if f.TimeRange == "48h" {
-		f.From = "now() - INTERVAL 2 DAY"
-		f.To = "now()"
+		f.From = now + " - INTERVAL 2 DAY"
+		f.To = now
 	} else if f.TimeRange == "168h" {
-		f.From = "now() - INTERVAL 7 DAY"
-		f.To = "now()"
+		f.From = now + " - INTERVAL 7 DAY"
+		f.To = now
 	} else if f.TimeRange == "720h" {
-		f.From = "now() - INTERVAL 30 DAY"
-		f.To = "now()"
+		f.From = now + " - INTERVAL 30 DAY"
+		f.To = now
 	}
 
 }
@@ -206,38 +281,36 @@ func (qh QueryHandler) HandleQuery(queryObj sql.SqlQueryable, w http.ResponseWri
 	opts := clickhouse.DefaultQueryOptions()
 	opts.Settings["output_format_arrow_compression_method"] = "none" // compression not supported by arrow JS
 	opts.Settings["date_time_input_format"] = "best_effort"          // support ISO 8601 dates (which is used in date picker by browser)
-
-	paramsStr := r.URL.Query().Get("params")
-	if paramsStr != "" {
-		var params map[string]string
-		err = json.Unmarshal([]byte(paramsStr), &params)
-		if err != nil {
-			return fmt.Errorf("unmarshalling params: %w", err)
+	opts.Compression = true                                          // chart data can be large
+	opts.Untrusted = qh.Untrusted
+	if cache := qh.ClickhouseClientManager.QueryCache(); cache != nil && queryObj.CacheTTL() >= 0 {
+		opts.Cache = true
+		opts.CacheTTL = queryObj.CacheTTL()
+		if opts.CacheTTL == 0 {
+			opts.CacheTTL = cache.DefaultTTL()
 		}
-		opts.Parameters = params
 	}
 
-	rawFilters := r.URL.Query().Get("filters")
+	opts.Parameters, err = QueryParameters(r)
+	if err != nil {
+		return err
+	}
+
+	filters, err := ParseDashboardFilters(r)
+	if err != nil {
+		return err
+	}
 	q := queryObj
 	var resolvedTimeRange *querying2.TimeRange
-	if rawFilters != "" && !queryObj.ShouldSkipFilters() {
-		var filters DashboardFilters
-		err = json.Unmarshal([]byte(rawFilters), &filters)
+	var bucketSizeMs *int64
+	if filters != nil && !queryObj.ShouldSkipFilters() {
+		filtered, err := filters.Apply(r.Context(), client, queryObj, &opts)
 		if err != nil {
-			return fmt.Errorf("unmarshalling filters: %w", err)
-		}
-		if clause := filters.SqlClause(); clause != "" {
-			q = queryObj.With(sql.Where(clause))
+			return err
 		}
+		q, resolvedTimeRange, bucketSizeMs = filtered.Query, filtered.TimeRange, filtered.BucketSizeMs
 
 		// add resolved time range to response, so that charts also show the full range if they have no data at beginning or end
-		resolvedTimeRange, err = filters.ResolveTimeRangeFromDbAsTime(r.Context(), client)
-		if err != nil {
-			return fmt.Errorf("resolving time range: %w", err)
-		}
-		opts.Parameters["__from"] = fmt.Sprintf("%d", *resolvedTimeRange.From/1000)
-		opts.Parameters["__to"] = fmt.Sprintf("%d", *resolvedTimeRange.To/1000)
-
 		resolvedTimeRangeJson, err := json.Marshal(resolvedTimeRange)
 		if err != nil {
 			return fmt.Errorf("JSON marshalling: %w", err)
@@ -245,10 +318,6 @@ func (qh QueryHandler) HandleQuery(queryObj sql.SqlQueryable, w http.ResponseWri
 		w.Header().Add("X-Dashica-Resolved-Time-Range", string(resolvedTimeRangeJson))
 	}
 
-	var bucketSizeMs *int64
-	if resolvedTimeRange != nil {
-		q, bucketSizeMs = q.AdjustBuckets(resolvedTimeRange.WidthS())
-	}
 	query, err := sql.BuildWithFS(q, qh.FileSystem)
 	if err != nil {
 		return fmt.Errorf("building SQL query: %w", err)
@@ -256,6 +325,19 @@ func (qh QueryHandler) HandleQuery(queryObj sql.SqlQueryable, w http.ResponseWri
 	if bucketSizeMs != nil {
 		w.Header().Add("X-Dashica-Bucket-Size", fmt.Sprintf("%d", *bucketSizeMs))
 	}
+	if qh.CompareOffset > 0 && resolvedTimeRange != nil {
+		query, err = compareQuery(query, queryObj, *filters, resolvedTimeRange, qh.CompareOffset, qh.FileSystem, opts.Parameters)
+		if err != nil {
+			return err
+		}
+		w.Header().Add("X-Dashica-Compare-Offset", fmt.Sprintf("%d", qh.CompareOffset.Milliseconds()))
+	}
+	if qh.PageSize > 0 {
+		query, err = paginatedQuery(query, qh.PageSize, r)
+		if err != nil {
+			return err
+		}
+	}
 
 	err = client.QueryToHandler(r.Context(), query, opts, w)
 	if err != nil {
@@ -303,52 +385,36 @@ func (qh QueryHandler) HandleDebug(queryObj sql.SqlQueryable, w http.ResponseWri
 
 	opts := clickhouse.DefaultQueryOptions()
 	opts.Settings["date_time_input_format"] = "best_effort" // support ISO 8601 dates
+	opts.Untrusted = qh.Untrusted
 
-	paramsStr := r.URL.Query().Get("params")
-	if paramsStr != "" {
-		var params map[string]string
-		err = json.Unmarshal([]byte(paramsStr), &params)
-		if err != nil {
-			return fmt.Errorf("unmarshalling params: %w", err)
-		}
-		opts.Parameters = params
+	opts.Parameters, err = QueryParameters(r)
+	if err != nil {
+		return err
 	}
 
-	rawFilters := r.URL.Query().Get("filters")
+	filters, err := ParseDashboardFilters(r)
+	if err != nil {
+		return err
+	}
 	q := queryObj
 	var resolvedTimeRange *querying2.TimeRange
+	var bucketSizeMs *int64
 
 	debugInfo := DebugInfo{
 		Stats: make(map[string]interface{}),
 	}
 
-	if rawFilters != "" && !queryObj.ShouldSkipFilters() {
-		var filters DashboardFilters
-		err = json.Unmarshal([]byte(rawFilters), &filters)
-		if err != nil {
-			return fmt.Errorf("unmarshalling filters: %w", err)
-		}
-		filterClause := filters.SqlClause()
-		if filterClause != "" {
-			q = queryObj.With(sql.Where(filterClause))
-		}
-
-		// add resolved time range
-		resolvedTimeRange, err = filters.ResolveTimeRangeFromDbAsTime(r.Context(), client)
+	if filters != nil && !queryObj.ShouldSkipFilters() {
+		filtered, err := filters.Apply(r.Context(), client, queryObj, &opts)
 		if err != nil {
-			return fmt.Errorf("resolving time range: %w", err)
+			return err
 		}
-		opts.Parameters["__from"] = fmt.Sprintf("%d", *resolvedTimeRange.From/1000)
-		opts.Parameters["__to"] = fmt.Sprintf("%d", *resolvedTimeRange.To/1000)
+		q, resolvedTimeRange, bucketSizeMs = filtered.Query, filtered.TimeRange, filtered.BucketSizeMs
 
 		debugInfo.Stats["resolvedTimeRange"] = resolvedTimeRange
-		debugInfo.Stats["filterClause"] = filterClause
+		debugInfo.Stats["filterClause"] = filtered.FilterClause
 	}
 
-	var bucketSizeMs *int64
-	if resolvedTimeRange != nil {
-		q, bucketSizeMs = q.AdjustBuckets(resolvedTimeRange.WidthS())
-	}
 	query, err := sql.BuildWithFS(q, qh.FileSystem)
 	if err != nil {
 		return fmt.Errorf("building SQL query: %w", err)
@@ -356,6 +422,20 @@ func (qh QueryHandler) HandleDebug(queryObj sql.SqlQueryable, w http.ResponseWri
 	if bucketSizeMs != nil {
 		debugInfo.Stats["bucketSizeMs"] = *bucketSizeMs
 	}
+	if qh.CompareOffset > 0 && resolvedTimeRange != nil {
+		query, err = compareQuery(query, queryObj, *filters, resolvedTimeRange, qh.CompareOffset, qh.FileSystem, opts.Parameters)
+		if err != nil {
+			return err
+		}
+		debugInfo.Stats["compareOffsetMs"] = qh.CompareOffset.Milliseconds()
+	}
+	if qh.PageSize > 0 {
+		query, err = paginatedQuery(query, qh.PageSize, r)
+		if err != nil {
+			return err
+		}
+		debugInfo.Stats["pageSize"] = qh.PageSize
+	}
 
 	debugInfo.Query = query
 
@@ -364,6 +444,7 @@ func (qh QueryHandler) HandleDebug(queryObj sql.SqlQueryable, w http.ResponseWri
 		explainOpts := clickhouse.DefaultQueryOptions()
 		explainOpts.Format = "TSVRaw" // Plain text format
 		explainOpts.Parameters = opts.Parameters
+		explainOpts.Untrusted = opts.Untrusted
 		// Copy settings from original query
 		for k, v := range opts.Settings {
 			explainOpts.Settings[k] = v
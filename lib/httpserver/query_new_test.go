@@ -0,0 +1,41 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboardFilters_RoundNow(t *testing.T) {
+	filters := DashboardFilters{TimeRange: "1h"}
+	assert.Equal(t, "timestamp >= (now() - INTERVAL 1 HOUR) AND timestamp <= (now())", filters.SqlClause())
+
+	filters.RoundNow(time.Minute)
+	now := time.Now().Truncate(time.Minute).Unix()
+	assert.Equal(t, fmt.Sprintf("timestamp >= (toDateTime(%d) - INTERVAL 1 HOUR) AND timestamp <= (toDateTime(%d))", now, now), filters.SqlClause())
+}
+
+func TestQueryParameters(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?"+url.Values{
+		"params": {`{"path":"^/api","customer":"overridden"}`},
+		"vars":   {`{"customer":["acme","o'brien\\"],"project":[]}`},
+	}.Encode(), nil)
+
+	params, err := QueryParameters(r)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"path":     "^/api",
+		"customer": `['acme','o\'brien\\']`,
+		"project":  "[]",
+	}, params)
+
+	r = httptest.NewRequest(http.MethodGet, "/?"+url.Values{"vars": {`{"a;DROP":["x"]}`}}.Encode(), nil)
+	_, err = QueryParameters(r)
+	assert.ErrorContains(t, err, "invalid variable name")
+}
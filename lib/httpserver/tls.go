@@ -0,0 +1,284 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/caddyserver/certmagic"
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/sandstorm/dashica/lib/logging"
+)
+
+// Server serves handler via plain HTTP on cfg.Server.Port; or - if cfg.LetsEncrypt is enabled - via HTTPS on
+// LetsEncrypt.DevHttpsPort, with a redirect to HTTPS (and the ACME HTTP-01 challenge) on LetsEncrypt.DevHttpPort.
+//
+// The certificates are either obtained and renewed via ACME (Let's Encrypt, or a custom CA such as Pebble), or read
+// from ManualCertfile / ManualKeyfile, which are re-read every ManualRefreshInterval.
+type Server struct {
+	cfg     *config.Config
+	logger  zerolog.Logger
+	handler http.Handler
+	// ctx is cancelled on Shutdown; it stops obtaining / reloading certificates.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mutex protecting servers and shuttingDown
+	mu           sync.Mutex
+	servers      []*http.Server
+	shuttingDown bool
+}
+
+func NewServer(cfg *config.Config, logger zerolog.Logger, handler http.Handler) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		cfg:     cfg,
+		logger:  logger,
+		handler: handler,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// ListenAndServe serves until Shutdown is called (then it returns http.ErrServerClosed), or a server fails.
+func (s *Server) ListenAndServe() error {
+	letsEncrypt := s.cfg.LetsEncrypt
+	if !letsEncrypt.Enabled {
+		s.logger.Info().
+			Int("port", s.cfg.Server.Port).
+			Msg("Starting Dashica HTTP server")
+		return s.serve(&http.Server{Addr: fmt.Sprintf(":%d", s.cfg.Server.Port), Handler: s.handler})
+	}
+
+	logger := s.logger.With().
+		Str(logging.EventDataset, logging.EventDataset_Dashica_Tls).
+		Logger()
+	httpHandler := redirectToHttps(letsEncrypt.DevHttpsPort)
+	serverErrors := make(chan error, 2)
+	var httpServer *http.Server
+	serveHttp := func() {
+		logger.Info().
+			Int("port", letsEncrypt.DevHttpPort).
+			Msg("Starting HTTP server (redirecting to HTTPS)")
+		httpServer = &http.Server{Addr: fmt.Sprintf(":%d", letsEncrypt.DevHttpPort), Handler: httpHandler}
+		go func() {
+			serverErrors <- s.serve(httpServer)
+		}()
+	}
+
+	var tlsConfig *tls.Config
+	if letsEncrypt.IsManual() {
+		reloader, err := newCertificateReloader(letsEncrypt.ManualCertfile, letsEncrypt.ManualKeyfile, logger)
+		if err != nil {
+			return err
+		}
+		go reloader.watch(s.ctx, letsEncrypt.ManualRefreshInterval)
+		tlsConfig = &tls.Config{
+			GetCertificate: reloader.getCertificate,
+			NextProtos:     []string{"h2", "http/1.1"},
+		}
+		serveHttp()
+	} else {
+		magic, issuer, err := newCertmagicConfig(letsEncrypt)
+		if err != nil {
+			return err
+		}
+		httpHandler = issuer.HTTPChallengeHandler(httpHandler)
+		// the HTTP server must be running before obtaining certificates, as it answers the HTTP-01 challenge.
+		serveHttp()
+
+		logger.Info().
+			Str("domain", letsEncrypt.Domain).
+			Str("ca", issuer.CA).
+			Msg("Obtaining certificate via ACME")
+		if err := magic.ManageSync(s.ctx, []string{letsEncrypt.Domain}); err != nil {
+			// otherwise, the HTTP server would keep running (and holding its port) after ListenAndServe returned.
+			_ = httpServer.Close()
+			return fmt.Errorf("obtaining certificate for %s: %w", letsEncrypt.Domain, err)
+		}
+		tlsConfig = magic.TLSConfig()
+		tlsConfig.NextProtos = append([]string{"h2", "http/1.1"}, tlsConfig.NextProtos...)
+	}
+
+	go func() {
+		logger.Info().
+			Int("port", letsEncrypt.DevHttpsPort).
+			Msg("Starting Dashica HTTPS server")
+		serverErrors <- s.serve(&http.Server{
+			Addr:      fmt.Sprintf(":%d", letsEncrypt.DevHttpsPort),
+			Handler:   s.handler,
+			TLSConfig: tlsConfig,
+		})
+	}()
+	return <-serverErrors
+}
+
+// serve runs server (via TLS if server.TLSConfig is set), unless Shutdown was already called.
+func (s *Server) serve(server *http.Server) error {
+	s.mu.Lock()
+	if s.shuttingDown {
+		s.mu.Unlock()
+		return http.ErrServerClosed
+	}
+	s.servers = append(s.servers, server)
+	s.mu.Unlock()
+
+	if server.TLSConfig != nil {
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServe()
+}
+
+// Shutdown stops accepting connections and waits for running requests (e.g. streamed query results) until ctx is
+// done; then, remaining connections are closed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.shuttingDown = true
+	servers := slices.Clone(s.servers)
+	s.mu.Unlock()
+	s.cancel()
+
+	var errs []error
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutting down server %s: %w", server.Addr, err))
+			_ = server.Close()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// newCertmagicConfig configures certmagic for the HTTP-01 challenge only (the TLS-ALPN challenge would need port 443).
+func newCertmagicConfig(letsEncrypt config.LetsEncryptConfig) (*certmagic.Config, *certmagic.ACMEIssuer, error) {
+	var magic *certmagic.Config
+	cache := certmagic.NewCache(certmagic.CacheOptions{
+		GetConfigForCert: func(certmagic.Certificate) (*certmagic.Config, error) {
+			return magic, nil
+		},
+		RenewCheckInterval: letsEncrypt.DevCertRenewInterval,
+	})
+	magicTemplate := certmagic.Config{}
+	if letsEncrypt.StoragePath != "" {
+		magicTemplate.Storage = &certmagic.FileStorage{Path: letsEncrypt.StoragePath}
+	}
+	magic = certmagic.New(cache, magicTemplate)
+
+	issuerTemplate := certmagic.ACMEIssuer{
+		CA:                      certmagic.LetsEncryptProductionCA,
+		Email:                   letsEncrypt.Email,
+		Agreed:                  true,
+		DisableTLSALPNChallenge: true,
+		AltHTTPPort:             letsEncrypt.DevHttpPort,
+	}
+	if letsEncrypt.DevUseStagingCa {
+		issuerTemplate.CA = certmagic.LetsEncryptStagingCA
+	}
+	if letsEncrypt.DevCustomCa != "" {
+		issuerTemplate.CA = letsEncrypt.DevCustomCa
+	}
+	if letsEncrypt.DevCustomCaRoot != "" {
+		rootPem, err := os.ReadFile(letsEncrypt.DevCustomCaRoot)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading dev_custom_ca_root: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(rootPem) {
+			return nil, nil, fmt.Errorf("dev_custom_ca_root %s contains no PEM certificates", letsEncrypt.DevCustomCaRoot)
+		}
+		issuerTemplate.TrustedRoots = roots
+	}
+	issuer := certmagic.NewACMEIssuer(magic, issuerTemplate)
+	magic.Issuers = []certmagic.Issuer{issuer}
+	return magic, issuer, nil
+}
+
+// redirectToHttps redirects all requests to the same URL on httpsPort.
+func redirectToHttps(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// certificateReloader serves a certificate from files, which are re-read periodically (e.g. after being renewed by
+// an external tool).
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+	logger   zerolog.Logger
+
+	// mutex protecting certificate
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+}
+
+func newCertificateReloader(certFile, keyFile string, logger zerolog.Logger) (*certificateReloader, error) {
+	r := &certificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   logger,
+	}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload re-reads the certificate files; it returns true if the certificate changed.
+func (r *certificateReloader) reload() (bool, error) {
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("loading certificate %s / %s: %w", r.certFile, r.keyFile, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changed := r.certificate == nil || !bytes.Equal(r.certificate.Certificate[0], certificate.Certificate[0])
+	r.certificate = &certificate
+	return changed, nil
+}
+
+// watch reloads the certificate every interval until ctx is done; on errors, the previous certificate is kept.
+func (r *certificateReloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.reload()
+			if err != nil {
+				r.logger.Error().Err(err).Msg("Failed to reload certificate, keeping the previous one")
+			} else if changed {
+				r.logger.Info().Str("certfile", r.certFile).Msg("Reloaded changed certificate")
+			}
+		}
+	}
+}
+
+func (r *certificateReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.certificate == nil {
+		return nil, errors.New("no certificate loaded")
+	}
+	return r.certificate, nil
+}
@@ -0,0 +1,149 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSelfSignedCertificate writes a self-signed certificate for commonName to certFile / keyFile.
+func writeSelfSignedCertificate(t *testing.T, certFile, keyFile, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	_, err := newCertificateReloader(certFile, keyFile, zerolog.Nop())
+	require.Error(t, err, "missing files must fail on startup")
+
+	writeSelfSignedCertificate(t, certFile, keyFile, "first.example.com")
+	reloader, err := newCertificateReloader(certFile, keyFile, zerolog.Nop())
+	require.NoError(t, err)
+	assertServedCommonName(t, reloader, "first.example.com")
+
+	changed, err := reloader.reload()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	writeSelfSignedCertificate(t, certFile, keyFile, "second.example.com")
+	changed, err = reloader.reload()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assertServedCommonName(t, reloader, "second.example.com")
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0o600))
+	_, err = reloader.reload()
+	require.Error(t, err)
+	assertServedCommonName(t, reloader, "second.example.com")
+}
+
+func assertServedCommonName(t *testing.T, reloader *certificateReloader, expected string) {
+	certificate, err := reloader.getCertificate(nil)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, expected, leaf.Subject.CommonName)
+}
+
+func TestRedirectToHttps(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsPort int
+		url       string
+		expected  string
+	}{
+		{name: "DefaultPort", httpsPort: 443, url: "http://dashica.example.com/home?x=1", expected: "https://dashica.example.com/home?x=1"},
+		{name: "CustomPort", httpsPort: 8443, url: "http://dashica.example.com:8080/home", expected: "https://dashica.example.com:8443/home"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			redirectToHttps(tt.httpsPort).ServeHTTP(recorder, httptest.NewRequest("GET", tt.url, nil))
+			assert.Equal(t, 301, recorder.Code)
+			assert.Equal(t, tt.expected, recorder.Header().Get("Location"))
+		})
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	server := NewServer(&config.Config{Server: config.ServerConfig{Port: 0}}, zerolog.Nop(), http.NotFoundHandler())
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- server.ListenAndServe() }()
+	require.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return len(server.servers) == 1
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, server.Shutdown(context.Background()))
+	assert.ErrorIs(t, <-serverErr, http.ErrServerClosed)
+	assert.ErrorIs(t, server.ListenAndServe(), http.ErrServerClosed, "no restart after Shutdown")
+}
+
+func TestServer_FailedAcmeStopsHttpServer(t *testing.T) {
+	// a CA which is not available
+	ca := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(ca.Close)
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	httpPort := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	server := NewServer(&config.Config{LetsEncrypt: config.LetsEncryptConfig{
+		Enabled:      true,
+		Domain:       "dashica.example.com",
+		StoragePath:  t.TempDir(),
+		DevCustomCa:  ca.URL + "/directory",
+		DevHttpPort:  httpPort,
+		DevHttpsPort: 0,
+	}}, zerolog.Nop(), http.NotFoundHandler())
+	require.ErrorContains(t, server.ListenAndServe(), "obtaining certificate for dashica.example.com")
+
+	// the port of the HTTP server is free again
+	require.Eventually(t, func() bool {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", httpPort))
+		if err != nil {
+			return false
+		}
+		_ = listener.Close()
+		return true
+	}, time.Second, 10*time.Millisecond)
+}
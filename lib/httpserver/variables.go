@@ -0,0 +1,60 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// variableName is the syntax of ClickHouse query parameter names (and thus of dashboard variable names).
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// IsValidVariableName returns true if name can be used as dashboard variable, i.e. as ClickHouse query parameter.
+func IsValidVariableName(name string) bool {
+	return variableName.MatchString(name)
+}
+
+// QueryParameters returns the ClickHouse query parameters of r:
+//   - "params": the widget params (TextInput, CheckboxGroup, ...) as JSON object of strings
+//   - "vars": the dashboard variables as JSON object of string arrays; each is passed as Array(String) literal,
+//     so queries can use {name:Array(String)}. Variables take precedence over widget params of the same name.
+func QueryParameters(r *http.Request) (map[string]string, error) {
+	params := make(map[string]string)
+	if paramsStr := r.URL.Query().Get("params"); paramsStr != "" {
+		if err := json.Unmarshal([]byte(paramsStr), &params); err != nil {
+			return nil, fmt.Errorf("unmarshalling params: %w", err)
+		}
+	}
+
+	if varsStr := r.URL.Query().Get("vars"); varsStr != "" {
+		var vars map[string][]string
+		if err := json.Unmarshal([]byte(varsStr), &vars); err != nil {
+			return nil, fmt.Errorf("unmarshalling vars: %w", err)
+		}
+		for name, values := range vars {
+			if !IsValidVariableName(name) {
+				return nil, fmt.Errorf("invalid variable name '%s'", name)
+			}
+			params[name] = ArrayParameter(values)
+		}
+	}
+	return params, nil
+}
+
+// ArrayParameter formats values as ClickHouse Array(String) literal, e.g. ['a','b'], for use as query parameter.
+func ArrayParameter(values []string) string {
+	var result strings.Builder
+	result.WriteString("[")
+	for i, value := range values {
+		if i > 0 {
+			result.WriteString(",")
+		}
+		result.WriteString("'")
+		result.WriteString(strings.ReplaceAll(strings.ReplaceAll(value, `\`, `\\`), `'`, `\'`))
+		result.WriteString("'")
+	}
+	result.WriteString("]")
+	return result.String()
+}
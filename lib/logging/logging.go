@@ -22,6 +22,8 @@ const (
 	EventDataset_Dashica_Alerting_Manager        = "dashica.alerting.manager"
 	EventDataset_Dashica_Alerting_Evaluator      = "dashica.alerting.evaluator"
 	EventDataset_Dashica_Alerting_BatchEvaluator = "dashica.alerting.batch_evaluator"
+	EventDataset_Dashica_Auth                    = "dashica.auth"
+	EventDataset_Dashica_Tls                     = "dashica.tls"
 	// EventDataset_Dashica_Startup should only be used in main.go
 	EventDataset_Dashica_Startup = "dashica.startup"
 )
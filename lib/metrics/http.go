@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	httpRequestsTotal = Default.NewCounterVec(
+		"dashica_http_requests_total",
+		"Number of HTTP requests by registered handler path and status code.",
+		"handler", "code")
+	httpRequestDuration = Default.NewHistogramVec(
+		"dashica_http_request_duration_seconds",
+		"Duration of HTTP requests by registered handler path.",
+		DefaultDurationBuckets,
+		"handler")
+)
+
+// InstrumentHandler records dashica_http_requests_total and dashica_http_request_duration_seconds for handler.
+// handlerPath is the path the handler is registered at (not the request path, to keep the number of series bounded).
+func InstrumentHandler(handlerPath string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(recorder, r)
+		httpRequestDuration.Observe(time.Since(start).Seconds(), handlerPath)
+		httpRequestsTotal.Inc(handlerPath, strconv.Itoa(recorder.status))
+	})
+}
+
+// statusRecorder remembers the status code written to the wrapped http.ResponseWriter.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap allows http.ResponseController to reach the underlying ResponseWriter (e.g. for flushing).
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
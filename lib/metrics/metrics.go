@@ -0,0 +1,263 @@
+// Package metrics implements the subset of Prometheus metrics Dashica needs (counters, gauges and histograms with
+// labels), exposed in the Prometheus text exposition format via Registry.ServeHTTP.
+//
+// Metrics are declared as package-level variables in the package they belong to, registered in Default:
+//
+//	var queriesTotal = metrics.Default.NewCounterVec("dashica_clickhouse_queries_total", "...", "server", "status")
+//	queriesTotal.Inc("default", "ok")
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default is the registry exposed at /metrics.
+var Default = NewRegistry()
+
+// DefaultDurationBuckets are histogram buckets (in seconds) suitable for query and request durations.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+)
+
+// Registry holds metric families, in registration order.
+type Registry struct {
+	// mutex protecting families and all their series
+	mu       sync.Mutex
+	families []*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+type family struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	// buckets are the upper bounds of a histogram (sorted, without +Inf)
+	buckets []float64
+	// series indexed by seriesKey(labelValues)
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	// value of a counter or gauge
+	value float64
+	// bucketCounts, count and sum of a histogram; bucketCounts are not cumulative.
+	bucketCounts []uint64
+	count        uint64
+	sum          float64
+}
+
+func (r *Registry) register(name, help, kind string, buckets []float64, labelNames []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, f := range r.families {
+		if f.name == name {
+			panic(fmt.Sprintf("metrics: %s registered twice", name))
+		}
+	}
+	f := &family{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+	r.families = append(r.families, f)
+	return f
+}
+
+// seriesFor returns the series of labelValues, creating it if needed; r.mu must be held.
+func (f *family) seriesFor(labelValues []string) *series {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values (%s), got %d", f.name, len(f.labelNames), strings.Join(f.labelNames, ", "), len(labelValues)))
+	}
+	key := seriesKey(labelValues)
+	s, exists := f.series[key]
+	if !exists {
+		s = &series{labelValues: slices.Clone(labelValues)}
+		if f.kind == kindHistogram {
+			s.bucketCounts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func seriesKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	registry *Registry
+	family   *family
+}
+
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{registry: r, family: r.register(name, help, kindCounter, nil, labelNames)}
+}
+
+// Add adds v (which must not be negative) to the counter with the given label values.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("metrics: counter %s cannot decrease", c.family.name))
+	}
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+	c.family.seriesFor(labelValues).value += v
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct {
+	registry *Registry
+	family   *family
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{registry: r, family: r.register(name, help, kindGauge, nil, labelNames)}
+}
+
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	g.registry.mu.Lock()
+	defer g.registry.mu.Unlock()
+	g.family.seriesFor(labelValues).value = v
+}
+
+// Delete removes the series with the given label values (it is not exposed anymore).
+func (g *GaugeVec) Delete(labelValues ...string) {
+	g.registry.mu.Lock()
+	defer g.registry.mu.Unlock()
+	delete(g.family.series, seriesKey(labelValues))
+}
+
+// Reset removes all series.
+func (g *GaugeVec) Reset() {
+	g.registry.mu.Lock()
+	defer g.registry.mu.Unlock()
+	g.family.series = make(map[string]*series)
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	registry *Registry
+	family   *family
+}
+
+// NewHistogramVec registers a histogram with the given bucket upper bounds (see DefaultDurationBuckets).
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &HistogramVec{registry: r, family: r.register(name, help, kindHistogram, buckets, labelNames)}
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.registry.mu.Lock()
+	defer h.registry.mu.Unlock()
+	s := h.family.seriesFor(labelValues)
+	if i, _ := slices.BinarySearch(h.family.buckets, v); i < len(s.bucketCounts) {
+		s.bucketCounts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+// ServeHTTP writes all metrics in the Prometheus text exposition format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	out := bufio.NewWriter(w)
+	r.write(out)
+	_ = out.Flush()
+}
+
+func (r *Registry) write(out *bufio.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, f := range r.families {
+		fmt.Fprintf(out, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(out, "# TYPE %s %s\n", f.name, f.kind)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			s := f.series[key]
+			if f.kind != kindHistogram {
+				fmt.Fprintf(out, "%s%s %s\n", f.name, formatLabels(f.labelNames, s.labelValues, ""), formatFloat(s.value))
+				continue
+			}
+			cumulative := uint64(0)
+			for i, upperBound := range f.buckets {
+				cumulative += s.bucketCounts[i]
+				fmt.Fprintf(out, "%s_bucket%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, formatFloat(upperBound)), cumulative)
+			}
+			fmt.Fprintf(out, "%s_bucket%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, "+Inf"), s.count)
+			fmt.Fprintf(out, "%s_sum%s %s\n", f.name, formatLabels(f.labelNames, s.labelValues, ""), formatFloat(s.sum))
+			fmt.Fprintf(out, "%s_count%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, ""), s.count)
+		}
+	}
+}
+
+// formatLabels renders {name="value",...}; le is added for histogram buckets if not empty.
+func formatLabels(labelNames, labelValues []string, le string) string {
+	if len(labelNames) == 0 && le == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range labelNames {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, escapeLabelValue(labelValues[i]))
+	}
+	if le != "" {
+		if len(labelNames) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `le="%s"`, le)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
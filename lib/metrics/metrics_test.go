@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	registry := NewRegistry()
+	queries := registry.NewCounterVec("test_queries_total", "Number of queries.", "server", "status")
+	states := registry.NewGaugeVec("test_alert_state", "Current alert state.", "alert_id")
+	durations := registry.NewHistogramVec("test_duration_seconds", "Query duration.", []float64{1, 0.1}, "server")
+
+	queries.Inc("default", "ok")
+	queries.Add(2, "default", "ok")
+	queries.Inc("staging", "error")
+	states.Set(1, `src/shop/alerts.yaml#"http"`)
+	states.Set(1, "deleted")
+	states.Delete("deleted")
+	durations.Observe(0.05, "default")
+	durations.Observe(0.5, "default")
+	durations.Observe(5, "default")
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(recorder.Result().Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `# HELP test_queries_total Number of queries.
+# TYPE test_queries_total counter
+test_queries_total{server="default",status="ok"} 3
+test_queries_total{server="staging",status="error"} 1
+# HELP test_alert_state Current alert state.
+# TYPE test_alert_state gauge
+test_alert_state{alert_id="src/shop/alerts.yaml#\"http\""} 1
+# HELP test_duration_seconds Query duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{server="default",le="0.1"} 1
+test_duration_seconds_bucket{server="default",le="1"} 2
+test_duration_seconds_bucket{server="default",le="+Inf"} 3
+test_duration_seconds_sum{server="default"} 5.55
+test_duration_seconds_count{server="default"} 3
+`, string(body))
+}
+
+func TestRegistry_PanicsOnWrongLabelCount(t *testing.T) {
+	registry := NewRegistry()
+	queries := registry.NewCounterVec("test_queries_total", "Number of queries.", "server")
+	assert.Panics(t, func() { queries.Inc("default", "ok") })
+	assert.Panics(t, func() { registry.NewGaugeVec("test_queries_total", "duplicate") })
+}
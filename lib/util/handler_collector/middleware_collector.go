@@ -0,0 +1,40 @@
+package handler_collector
+
+import (
+	"net/http"
+)
+
+// WithMiddleware returns a HandlerCollector wrapping all handlers registered on it (and its nested collectors)
+// with middleware, e.g. auth.RequireAccess.
+func WithMiddleware(inner HandlerCollector, middleware func(http.Handler) http.Handler) HandlerCollector {
+	return &middlewareCollectorImpl{
+		inner:      inner,
+		middleware: middleware,
+	}
+}
+
+type middlewareCollectorImpl struct {
+	inner      HandlerCollector
+	middleware func(http.Handler) http.Handler
+}
+
+func (c middlewareCollectorImpl) Handle(path string, handler http.Handler) error {
+	return c.inner.Handle(path, c.middleware(handler))
+}
+
+func (c middlewareCollectorImpl) HandleRoot(handler http.Handler) error {
+	return c.inner.HandleRoot(c.middleware(handler))
+}
+
+func (c middlewareCollectorImpl) IsRegistered(path string) bool {
+	return c.inner.IsRegistered(path)
+}
+
+func (c middlewareCollectorImpl) Nested(prefix string) HandlerCollector {
+	return &middlewareCollectorImpl{
+		inner:      c.inner.Nested(prefix),
+		middleware: c.middleware,
+	}
+}
+
+var _ HandlerCollector = (*middlewareCollectorImpl)(nil)
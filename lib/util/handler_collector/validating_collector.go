@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/rs/zerolog"
+	"github.com/sandstorm/dashica/lib/metrics"
 )
 
 type HandlerCollector interface {
@@ -51,7 +52,7 @@ func (c validatingCollectorImpl) Handle(path string, handler http.Handler) error
 		Msg("Registering handler")
 
 	(*c.seen)[fullPath] = true
-	c.mux.Handle(fullPath, handler)
+	c.mux.Handle(fullPath, metrics.InstrumentHandler(fullPath, handler))
 	return nil
 }
 
@@ -64,7 +65,7 @@ func (c validatingCollectorImpl) HandleRoot(handler http.Handler) error {
 		Msg("Registering handler")
 
 	(*c.seen)[c.prefix] = true
-	c.mux.Handle(c.prefix, handler)
+	c.mux.Handle(c.prefix, metrics.InstrumentHandler(c.prefix, handler))
 	return nil
 }
 